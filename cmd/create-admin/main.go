@@ -0,0 +1,96 @@
+// Command create-admin bootstraps the first administrator account. If a user
+// with the given email already exists it is promoted to admin, otherwise a new
+// admin user is created.
+package main
+
+import (
+	"context"
+	"ecomm/internal/domain"
+	"ecomm/internal/password"
+	"ecomm/internal/repository"
+	"errors"
+	"flag"
+	"log"
+	"os"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/joho/godotenv"
+)
+
+func main() {
+	name := flag.String("name", "Admin", "name of the admin user")
+	email := flag.String("email", "", "email of the admin user")
+	flag.Parse()
+
+	if *email == "" {
+		log.Fatal("-email is required")
+	}
+
+	if err := godotenv.Load(); err != nil {
+		log.Printf("Warning: Error loading .env file: %v", err)
+	}
+
+	connString := os.Getenv("CONN_STRING")
+	if connString == "" {
+		log.Fatal("CONN_STRING is not set")
+	}
+
+	pool, err := pgxpool.New(context.Background(), connString)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer pool.Close()
+
+	repo := repository.NewRepository(pool)
+
+	user, err := repo.GetUser(*email)
+	if err == nil {
+		if err := repo.SetUserAdmin(user.ID, true); err != nil {
+			log.Fatal(err)
+		}
+		log.Printf("Promoted existing user %s to admin", user.Email)
+		return
+	}
+
+	if !errors.Is(err, domain.ErrUserNotFound) {
+		log.Fatal(err)
+	}
+
+	// The password is read from the environment so that it does not end up
+	// in the shell history.
+	adminPassword := os.Getenv("ADMIN_PASSWORD")
+	if adminPassword == "" {
+		log.Fatal("ADMIN_PASSWORD is not set")
+	}
+
+	hasher, err := password.HasherFromEnv()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	checker, err := password.CheckerFromEnv(hasher)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	if err := checker.Check(adminPassword, *email, *name); err != nil {
+		log.Fatal(err)
+	}
+
+	hashed, err := hasher.Hash(adminPassword)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	user, err = repo.CreateUser(&domain.User{
+		Name:     *name,
+		Email:    *email,
+		Password: hashed,
+		IsAdmin:  true,
+	})
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	log.Printf("Created admin user %s (%s)", user.Email, user.ID)
+}
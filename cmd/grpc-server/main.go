@@ -2,12 +2,22 @@ package main
 
 import (
 	"context"
+	"ecomm/internal/audit"
+	"ecomm/internal/controller/auth"
+	"ecomm/internal/mailer"
+	"ecomm/internal/notify"
+	"ecomm/internal/oidc"
+	"ecomm/internal/pricing"
 	"ecomm/internal/repository"
 	"ecomm/internal/service"
+	"ecomm/internal/storage"
 	"ecomm/proto"
+	"fmt"
 	"log"
 	"net"
+	"net/smtp"
 	"os"
+	"time"
 
 	"github.com/jackc/pgx/v5/pgxpool"
 	"github.com/joho/godotenv"
@@ -29,10 +39,47 @@ func main() {
 		log.Fatal(err)
 	}
 
+	oidcProviders, err := oidc.ProvidersFromEnv()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	fileStorage, err := storage.FromEnv()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	notificationInterval, err := intervalFromEnv("STOCK_NOTIFICATION_INTERVAL", time.Minute)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	priceScheduleInterval, err := intervalFromEnv("PRICE_SCHEDULE_INTERVAL", time.Minute)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	mail := newMailer()
 	productRepo := repository.NewRepository(pool)
-	productService := service.NewService(productRepo)
+	productService := service.NewService(productRepo, mail, oidcProviders, fileStorage)
+
+	notifier := notify.NewMailNotifier(mail, notify.AdminEmailsFromEnv())
+	go notify.NewWorker(productRepo, notifier, notificationInterval).Run(context.Background())
+	go pricing.NewWorker(productRepo, priceScheduleInterval).Run(context.Background())
+
+	jwtManager, err := auth.NewTokenGenerator()
+	if err != nil {
+		log.Fatal(err)
+	}
 
-	server := grpc.NewServer()
+	server := grpc.NewServer(
+		grpc.MaxRecvMsgSize(service.MaxMessageBytes),
+		grpc.ChainUnaryInterceptor(
+			auth.UnaryPermissionInterceptor(jwtManager, service.NewAPIKeyValidator(productRepo), service.NewAccountChecker(productRepo), service.MethodPermissions),
+			auth.UnaryImpersonationInterceptor(service.ImpersonationBlockedMethods),
+			audit.UnaryServerInterceptor(productRepo, service.AuditedMethods),
+		),
+	)
 	proto.RegisterApiServiceServer(server, productService)
 
 	listener, err := net.Listen("tcp", ":8081")
@@ -45,3 +92,38 @@ func main() {
 		log.Fatal(err)
 	}
 }
+
+func newMailer() mailer.Mailer {
+	addr := os.Getenv("SMTP_ADDR")
+	if addr == "" {
+		log.Println("SMTP_ADDR is not set, emails will be logged instead of sent")
+		return mailer.NewLogMailer()
+	}
+
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	var auth smtp.Auth
+	if username := os.Getenv("SMTP_USERNAME"); username != "" {
+		auth = smtp.PlainAuth("", username, os.Getenv("SMTP_PASSWORD"), host)
+	}
+
+	return mailer.NewSMTPMailer(addr, os.Getenv("SMTP_FROM"), auth)
+}
+
+// intervalFromEnv returns how often a background worker runs, as set by the
+// environment variable name. It defaults to def.
+func intervalFromEnv(name string, def time.Duration) (time.Duration, error) {
+	value := os.Getenv(name)
+	if value == "" {
+		return def, nil
+	}
+
+	interval, err := time.ParseDuration(value)
+	if err != nil || interval <= 0 {
+		return 0, fmt.Errorf("invalid %s %q", name, value)
+	}
+	return interval, nil
+}
@@ -2,6 +2,7 @@ package main
 
 import (
 	"ecomm/internal/controller"
+	"ecomm/internal/storage"
 	"ecomm/proto"
 	"log"
 
@@ -30,6 +31,16 @@ func main() {
 	productHandler := controller.NewHandler(client)
 
 	router := controller.NewRouter(productHandler)
+	if err := router.SetTrustedProxies(controller.TrustedProxiesFromEnv()); err != nil {
+		log.Fatal(err)
+	}
+
+	// Files in the local storage are served by the gateway; other storage
+	// drivers serve them themselves.
+	if dir, urlPath, ok := storage.LocalServeConfigFromEnv(); ok {
+		router.Static(urlPath, dir)
+	}
+
 	if err := router.Run(":8080"); err != nil {
 		panic(err)
 	}
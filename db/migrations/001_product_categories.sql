@@ -0,0 +1,72 @@
+-- Moves products from the free-text category column to the categories table.
+--
+-- Category strings that only differ in case, punctuation or a trailing "s"
+-- ("Electronics", "electronics", "Electronic") are folded into one category,
+-- named after the most common spelling. Slugs keep letters and digits of any
+-- script the database locale knows; strings without any get a slug derived
+-- from their hash, as the service does, so that they are not folded together.
+-- The folded categories are created at the top level; they can be arranged
+-- into a hierarchy afterwards.
+
+BEGIN;
+
+CREATE TABLE categories (
+  id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+  parent_id UUID,
+  name varchar NOT NULL,
+  slug varchar NOT NULL,
+  position int NOT NULL DEFAULT 0,
+  created_at bigint NOT NULL DEFAULT EXTRACT (EPOCH FROM CURRENT_TIMESTAMP),
+  updated_at bigint NOT NULL DEFAULT EXTRACT (EPOCH FROM CURRENT_TIMESTAMP)
+);
+
+ALTER TABLE categories ADD CONSTRAINT unique_category_slug UNIQUE (slug);
+ALTER TABLE categories ADD FOREIGN KEY (parent_id) REFERENCES categories (id);
+CREATE INDEX categories_parent_id_idx ON categories (parent_id);
+
+CREATE FUNCTION pg_temp.category_slug(name text) RETURNS text AS $$
+  SELECT CASE
+    WHEN trim(name) = '' THEN 'uncategorized'
+    ELSE COALESCE(
+      NULLIF(trim(both '-' FROM regexp_replace(lower(trim(name)), '[^[:alnum:]]+', '-', 'g')), ''),
+      'category-' || left(md5(lower(trim(name))), 8)
+    )
+  END
+$$ LANGUAGE sql IMMUTABLE;
+
+CREATE TEMPORARY TABLE product_categories ON COMMIT DROP AS
+SELECT product_id, name, COALESCE(NULLIF(regexp_replace(slug, 's$', ''), ''), slug) AS fold_key
+FROM (
+  SELECT id AS product_id, trim(category) AS name, pg_temp.category_slug(category) AS slug
+  FROM products
+) normalized;
+
+CREATE TEMPORARY TABLE folded_categories ON COMMIT DROP AS
+SELECT fold_key, COALESCE(NULLIF(name, ''), 'Uncategorized') AS name, pg_temp.category_slug(name) AS slug
+FROM (
+  SELECT fold_key, mode() WITHIN GROUP (ORDER BY name) AS name
+  FROM product_categories
+  GROUP BY fold_key
+) grouped;
+
+INSERT INTO categories(name, slug)
+SELECT name, slug FROM folded_categories ORDER BY name;
+
+ALTER TABLE products ADD COLUMN category_id UUID;
+
+UPDATE products p SET category_id = c.id
+FROM product_categories pc
+JOIN folded_categories fc ON fc.fold_key = pc.fold_key
+JOIN categories c ON c.slug = fc.slug
+WHERE pc.product_id = p.id;
+
+ALTER TABLE products ALTER COLUMN category_id SET NOT NULL;
+ALTER TABLE products ADD FOREIGN KEY (category_id) REFERENCES categories (id);
+CREATE INDEX products_category_id_idx ON products (category_id);
+ALTER TABLE products DROP COLUMN category;
+
+INSERT INTO role_permissions(role_id, permission)
+SELECT id, 'categories:manage' FROM roles WHERE name = 'catalog_manager'
+ON CONFLICT DO NOTHING;
+
+COMMIT;
@@ -0,0 +1,37 @@
+-- Adds option types such as size or colour and the product variants built
+-- from them. Existing products have no options or variants, and existing
+-- order items reference no variant.
+
+BEGIN;
+
+CREATE TABLE product_options (
+  id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+  product_id UUID NOT NULL,
+  name varchar NOT NULL,
+  option_values varchar[] NOT NULL,
+  position int NOT NULL DEFAULT 0
+);
+
+ALTER TABLE product_options ADD CONSTRAINT unique_product_option UNIQUE (product_id, name);
+ALTER TABLE product_options ADD FOREIGN KEY (product_id) REFERENCES products (id) ON DELETE CASCADE;
+
+CREATE TABLE product_variants (
+  id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+  product_id UUID NOT NULL,
+  sku varchar NOT NULL,
+  options jsonb NOT NULL,
+  price decimal(10,2),
+  count_in_stock int NOT NULL DEFAULT 0,
+  image varchar,
+  created_at bigint NOT NULL DEFAULT EXTRACT (EPOCH FROM CURRENT_TIMESTAMP),
+  updated_at bigint NOT NULL DEFAULT EXTRACT (EPOCH FROM CURRENT_TIMESTAMP)
+);
+
+ALTER TABLE product_variants ADD CONSTRAINT unique_variant_sku UNIQUE (sku);
+ALTER TABLE product_variants ADD CONSTRAINT unique_variant_options UNIQUE (product_id, options);
+ALTER TABLE product_variants ADD FOREIGN KEY (product_id) REFERENCES products (id) ON DELETE CASCADE;
+
+ALTER TABLE order_items ADD COLUMN variant_id UUID;
+ALTER TABLE order_items ADD FOREIGN KEY (variant_id) REFERENCES product_variants (id) ON DELETE SET NULL;
+
+COMMIT;
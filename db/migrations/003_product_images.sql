@@ -0,0 +1,25 @@
+-- Adds uploaded product images. Each image keeps the storage keys of the
+-- original and its resized renditions. The image column of products still
+-- holds images given by URL.
+
+BEGIN;
+
+CREATE TABLE product_images (
+  id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+  product_id UUID NOT NULL,
+  storage_key varchar NOT NULL,
+  thumbnail_key varchar NOT NULL,
+  medium_key varchar NOT NULL,
+  content_type varchar NOT NULL,
+  width int NOT NULL,
+  height int NOT NULL,
+  size_bytes bigint NOT NULL,
+  alt_text varchar NOT NULL DEFAULT '',
+  position int NOT NULL DEFAULT 0,
+  created_at bigint NOT NULL DEFAULT EXTRACT (EPOCH FROM CURRENT_TIMESTAMP)
+);
+
+ALTER TABLE product_images ADD FOREIGN KEY (product_id) REFERENCES products (id) ON DELETE CASCADE;
+CREATE INDEX product_images_product_id_idx ON product_images (product_id, position);
+
+COMMIT;
@@ -0,0 +1,13 @@
+-- Adds SKUs to products so that bulk imports can match existing products.
+-- Existing products keep a NULL SKU until one is assigned.
+
+BEGIN;
+
+ALTER TABLE products ADD COLUMN sku varchar;
+ALTER TABLE products ADD CONSTRAINT unique_product_sku UNIQUE (sku);
+
+INSERT INTO role_permissions(role_id, permission)
+SELECT id, unnest(ARRAY['products:import', 'products:export']) FROM roles WHERE name = 'catalog_manager'
+ON CONFLICT DO NOTHING;
+
+COMMIT;
@@ -0,0 +1,40 @@
+-- Adds the stock ledger. The current stock of every product and variant is
+-- recorded as an opening adjustment so that count_in_stock matches the sum
+-- of the movements from the start.
+
+BEGIN;
+
+CREATE TABLE inventory_movements (
+  id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+  product_id UUID NOT NULL,
+  variant_id UUID,
+  type varchar NOT NULL,
+  quantity int NOT NULL,
+  balance int NOT NULL,
+  reason varchar NOT NULL DEFAULT '',
+  order_id UUID,
+  actor_id UUID,
+  created_at bigint NOT NULL DEFAULT EXTRACT (EPOCH FROM CURRENT_TIMESTAMP)
+);
+
+ALTER TABLE inventory_movements ADD CONSTRAINT inventory_movement_type CHECK (type IN ('receipt', 'sale', 'return', 'adjustment'));
+ALTER TABLE inventory_movements ADD CONSTRAINT inventory_movement_quantity CHECK (quantity <> 0);
+ALTER TABLE inventory_movements ADD FOREIGN KEY (product_id) REFERENCES products (id) ON DELETE CASCADE;
+ALTER TABLE inventory_movements ADD FOREIGN KEY (variant_id) REFERENCES product_variants (id) ON DELETE CASCADE;
+ALTER TABLE inventory_movements ADD FOREIGN KEY (order_id) REFERENCES orders (id) ON DELETE SET NULL;
+ALTER TABLE inventory_movements ADD FOREIGN KEY (actor_id) REFERENCES users (id) ON DELETE SET NULL;
+CREATE INDEX inventory_movements_product_id_idx ON inventory_movements (product_id, created_at);
+
+INSERT INTO inventory_movements(product_id, type, quantity, balance, reason)
+SELECT id, 'adjustment', count_in_stock, count_in_stock, 'opening balance'
+FROM products WHERE count_in_stock <> 0;
+
+INSERT INTO inventory_movements(product_id, variant_id, type, quantity, balance, reason)
+SELECT product_id, id, 'adjustment', count_in_stock, count_in_stock, 'opening balance'
+FROM product_variants WHERE count_in_stock <> 0;
+
+INSERT INTO role_permissions(role_id, permission)
+SELECT id, unnest(ARRAY['inventory:read', 'inventory:manage']) FROM roles WHERE name IN ('catalog_manager', 'fulfilment')
+ON CONFLICT DO NOTHING;
+
+COMMIT;
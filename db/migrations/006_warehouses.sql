@@ -0,0 +1,68 @@
+-- Adds warehouses. The existing stock and its ledger are moved into a "main"
+-- warehouse, which also receives stock movements that do not name a
+-- warehouse until another one is given a lower priority.
+
+BEGIN;
+
+CREATE TABLE warehouses (
+  id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+  code varchar NOT NULL,
+  name varchar NOT NULL,
+  latitude double precision,
+  longitude double precision,
+  priority int NOT NULL DEFAULT 0,
+  active boolean NOT NULL DEFAULT true,
+  created_at bigint NOT NULL DEFAULT EXTRACT (EPOCH FROM CURRENT_TIMESTAMP),
+  updated_at bigint NOT NULL DEFAULT EXTRACT (EPOCH FROM CURRENT_TIMESTAMP)
+);
+
+ALTER TABLE warehouses ADD CONSTRAINT unique_warehouse_code UNIQUE (code);
+ALTER TABLE warehouses ADD CONSTRAINT warehouse_location CHECK ((latitude IS NULL) = (longitude IS NULL));
+
+INSERT INTO warehouses(code, name) VALUES ('main', 'Main warehouse');
+
+CREATE TABLE warehouse_stock (
+  warehouse_id UUID NOT NULL,
+  product_id UUID NOT NULL,
+  variant_id UUID,
+  quantity int NOT NULL DEFAULT 0
+);
+
+ALTER TABLE warehouse_stock ADD CONSTRAINT warehouse_stock_quantity CHECK (quantity >= 0);
+ALTER TABLE warehouse_stock ADD FOREIGN KEY (warehouse_id) REFERENCES warehouses (id);
+ALTER TABLE warehouse_stock ADD FOREIGN KEY (product_id) REFERENCES products (id) ON DELETE CASCADE;
+ALTER TABLE warehouse_stock ADD FOREIGN KEY (variant_id) REFERENCES product_variants (id) ON DELETE CASCADE;
+CREATE UNIQUE INDEX warehouse_stock_item_idx ON warehouse_stock (product_id, COALESCE(variant_id, '00000000-0000-0000-0000-000000000000'), warehouse_id);
+
+INSERT INTO warehouse_stock(warehouse_id, product_id, quantity)
+SELECT w.id, p.id, p.count_in_stock
+FROM products p, warehouses w
+WHERE w.code = 'main' AND p.count_in_stock <> 0;
+
+INSERT INTO warehouse_stock(warehouse_id, product_id, variant_id, quantity)
+SELECT w.id, v.product_id, v.id, v.count_in_stock
+FROM product_variants v, warehouses w
+WHERE w.code = 'main' AND v.count_in_stock <> 0;
+
+CREATE TABLE order_item_allocations (
+  order_item_id UUID NOT NULL,
+  warehouse_id UUID NOT NULL,
+  quantity int NOT NULL,
+  PRIMARY KEY (order_item_id, warehouse_id)
+);
+
+ALTER TABLE order_item_allocations ADD FOREIGN KEY (order_item_id) REFERENCES order_items (id) ON DELETE CASCADE;
+ALTER TABLE order_item_allocations ADD FOREIGN KEY (warehouse_id) REFERENCES warehouses (id);
+
+ALTER TABLE orders ADD COLUMN shipping_latitude double precision;
+ALTER TABLE orders ADD COLUMN shipping_longitude double precision;
+
+ALTER TABLE inventory_movements ADD COLUMN warehouse_id UUID;
+UPDATE inventory_movements SET warehouse_id = (SELECT id FROM warehouses WHERE code = 'main');
+ALTER TABLE inventory_movements ALTER COLUMN warehouse_id SET NOT NULL;
+ALTER TABLE inventory_movements ADD FOREIGN KEY (warehouse_id) REFERENCES warehouses (id);
+
+ALTER TABLE inventory_movements DROP CONSTRAINT inventory_movement_type;
+ALTER TABLE inventory_movements ADD CONSTRAINT inventory_movement_type CHECK (type IN ('receipt', 'sale', 'return', 'adjustment', 'transfer'));
+
+COMMIT;
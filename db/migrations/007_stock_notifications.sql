@@ -0,0 +1,42 @@
+-- Adds reorder thresholds with low-stock alerts and back-in-stock
+-- subscriptions. Thresholds default to 0, so existing products raise an
+-- alert the next time they sell out.
+
+BEGIN;
+
+ALTER TABLE products ADD COLUMN reorder_threshold int NOT NULL DEFAULT 0;
+ALTER TABLE products ADD CONSTRAINT product_reorder_threshold CHECK (reorder_threshold >= 0);
+
+CREATE TABLE stock_alerts (
+  id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+  product_id UUID NOT NULL,
+  variant_id UUID,
+  threshold int NOT NULL,
+  stock int NOT NULL,
+  created_at bigint NOT NULL DEFAULT EXTRACT (EPOCH FROM CURRENT_TIMESTAMP),
+  notified_at bigint,
+  resolved_at bigint,
+  claimed_at bigint
+);
+
+ALTER TABLE stock_alerts ADD FOREIGN KEY (product_id) REFERENCES products (id) ON DELETE CASCADE;
+ALTER TABLE stock_alerts ADD FOREIGN KEY (variant_id) REFERENCES product_variants (id) ON DELETE CASCADE;
+CREATE UNIQUE INDEX stock_alerts_open_idx ON stock_alerts (product_id, COALESCE(variant_id, '00000000-0000-0000-0000-000000000000')) WHERE resolved_at IS NULL;
+CREATE INDEX stock_alerts_created_at_idx ON stock_alerts (created_at);
+
+CREATE TABLE stock_subscriptions (
+  id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+  user_id UUID NOT NULL,
+  product_id UUID NOT NULL,
+  variant_id UUID,
+  created_at bigint NOT NULL DEFAULT EXTRACT (EPOCH FROM CURRENT_TIMESTAMP),
+  notified_at bigint,
+  claimed_at bigint
+);
+
+ALTER TABLE stock_subscriptions ADD FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE CASCADE;
+ALTER TABLE stock_subscriptions ADD FOREIGN KEY (product_id) REFERENCES products (id) ON DELETE CASCADE;
+ALTER TABLE stock_subscriptions ADD FOREIGN KEY (variant_id) REFERENCES product_variants (id) ON DELETE CASCADE;
+CREATE UNIQUE INDEX stock_subscriptions_pending_idx ON stock_subscriptions (user_id, product_id, COALESCE(variant_id, '00000000-0000-0000-0000-000000000000')) WHERE notified_at IS NULL;
+
+COMMIT;
@@ -0,0 +1,12 @@
+-- Adds the product lifecycle. Existing products are active; deleting a
+-- product now archives it instead of removing the row.
+
+BEGIN;
+
+ALTER TABLE products ADD COLUMN status varchar NOT NULL DEFAULT 'active';
+ALTER TABLE products ADD COLUMN deleted_at bigint;
+ALTER TABLE products ADD CONSTRAINT product_status CHECK (status IN ('draft', 'active', 'archived'));
+ALTER TABLE products ADD CONSTRAINT product_deleted_at CHECK ((status = 'archived') = (deleted_at IS NOT NULL));
+CREATE INDEX products_status_idx ON products (status);
+
+COMMIT;
@@ -0,0 +1,29 @@
+-- Adds row versions to products, orders and users for optimistic concurrency
+-- control. Existing rows start at version 1.
+
+BEGIN;
+
+ALTER TABLE products ADD COLUMN version bigint NOT NULL DEFAULT 1;
+ALTER TABLE orders ADD COLUMN version bigint NOT NULL DEFAULT 1;
+ALTER TABLE users ADD COLUMN version bigint NOT NULL DEFAULT 1;
+
+CREATE FUNCTION increment_version() RETURNS trigger AS $$
+BEGIN
+  NEW.version := OLD.version + 1;
+  RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER products_version
+  BEFORE UPDATE ON products
+  FOR EACH ROW EXECUTE FUNCTION increment_version();
+
+CREATE TRIGGER orders_version
+  BEFORE UPDATE ON orders
+  FOR EACH ROW EXECUTE FUNCTION increment_version();
+
+CREATE TRIGGER users_version
+  BEFORE UPDATE ON users
+  FOR EACH ROW EXECUTE FUNCTION increment_version();
+
+COMMIT;
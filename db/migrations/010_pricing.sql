@@ -0,0 +1,48 @@
+-- Adds sale prices, the price history and scheduled price changes. The
+-- history starts with the current price of every product.
+
+BEGIN;
+
+ALTER TABLE products ADD COLUMN sale_price decimal(10,2);
+ALTER TABLE products ADD COLUMN sale_starts_at bigint;
+ALTER TABLE products ADD COLUMN sale_ends_at bigint;
+ALTER TABLE products ADD CONSTRAINT product_sale CHECK (
+  (sale_price IS NULL) = (sale_starts_at IS NULL)
+  AND (sale_ends_at IS NULL OR sale_ends_at > sale_starts_at)
+);
+
+CREATE TABLE price_history (
+  id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+  product_id UUID NOT NULL,
+  price decimal(10,2) NOT NULL,
+  sale_price decimal(10,2),
+  sale_starts_at bigint,
+  sale_ends_at bigint,
+  reason varchar NOT NULL,
+  actor_id UUID,
+  created_at bigint NOT NULL DEFAULT EXTRACT (EPOCH FROM CURRENT_TIMESTAMP)
+);
+
+ALTER TABLE price_history ADD FOREIGN KEY (product_id) REFERENCES products (id) ON DELETE CASCADE;
+ALTER TABLE price_history ADD FOREIGN KEY (actor_id) REFERENCES users (id) ON DELETE SET NULL;
+CREATE INDEX price_history_product_id_idx ON price_history (product_id, created_at);
+
+INSERT INTO price_history(product_id, price, reason)
+SELECT id, price, 'created' FROM products;
+
+CREATE TABLE scheduled_price_changes (
+  id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+  product_id UUID NOT NULL,
+  price decimal(10,2) NOT NULL,
+  effective_at bigint NOT NULL,
+  actor_id UUID,
+  created_at bigint NOT NULL DEFAULT EXTRACT (EPOCH FROM CURRENT_TIMESTAMP),
+  applied_at bigint
+);
+
+ALTER TABLE scheduled_price_changes ADD FOREIGN KEY (product_id) REFERENCES products (id) ON DELETE CASCADE;
+ALTER TABLE scheduled_price_changes ADD FOREIGN KEY (actor_id) REFERENCES users (id) ON DELETE SET NULL;
+CREATE INDEX scheduled_price_changes_product_id_idx ON scheduled_price_changes (product_id, effective_at);
+CREATE INDEX scheduled_price_changes_pending_idx ON scheduled_price_changes (effective_at) WHERE applied_at IS NULL;
+
+COMMIT;
@@ -0,0 +1,44 @@
+-- Lets users be erased. Orders outlive the user they belonged to, and the
+-- audit log may have personal data scrubbed from its snapshots while a user
+-- is erased. Names, emails and shipping locations already in the log are
+-- scrubbed, as new events record them by field name only.
+
+BEGIN;
+
+ALTER TABLE orders ALTER COLUMN user_id DROP NOT NULL;
+ALTER TABLE orders DROP CONSTRAINT orders_user_id_fkey;
+ALTER TABLE orders ADD FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE SET NULL;
+
+CREATE OR REPLACE FUNCTION reject_audit_event_change() RETURNS trigger AS $$
+BEGIN
+  IF TG_OP = 'UPDATE' AND current_setting('audit.erasure', true) = 'on'
+    AND (NEW.id, NEW.actor_id, NEW.action, NEW.target_type, NEW.target_id, NEW.request_id, NEW.metadata, NEW.created_at)
+    IS NOT DISTINCT FROM
+    (OLD.id, OLD.actor_id, OLD.action, OLD.target_type, OLD.target_id, OLD.request_id, OLD.metadata, OLD.created_at)
+  THEN
+    RETURN NEW;
+  END IF;
+  RAISE EXCEPTION 'audit_events is append-only';
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE FUNCTION scrub_audit_fields(snapshot jsonb, fields text[], placeholder text) RETURNS jsonb AS $$
+  SELECT CASE WHEN jsonb_typeof(snapshot) = 'object' THEN (
+    SELECT COALESCE(jsonb_object_agg(key, CASE WHEN key = ANY (fields) THEN to_jsonb(placeholder) ELSE value END), '{}')
+    FROM jsonb_each(snapshot)
+  ) ELSE snapshot END
+$$ LANGUAGE sql IMMUTABLE;
+
+SELECT set_config('audit.erasure', 'on', true);
+
+UPDATE audit_events SET
+  before = scrub_audit_fields(before, ARRAY['name', 'email'], '[PERSONAL]'),
+  after = scrub_audit_fields(after, ARRAY['name', 'email'], '[PERSONAL]')
+WHERE target_type = 'user';
+
+UPDATE audit_events SET
+  before = scrub_audit_fields(before, ARRAY['shipping_location'], '[PERSONAL]'),
+  after = scrub_audit_fields(after, ARRAY['shipping_location'], '[PERSONAL]')
+WHERE target_type = 'order';
+
+COMMIT;
@@ -0,0 +1,18 @@
+-- Adds account status. Users can be suspended until a given time or disabled
+-- until they are re-enabled. Existing users are active.
+
+BEGIN;
+
+ALTER TABLE users ADD COLUMN IF NOT EXISTS status varchar NOT NULL DEFAULT 'active';
+ALTER TABLE users ADD COLUMN IF NOT EXISTS status_reason varchar;
+ALTER TABLE users ADD COLUMN suspended_until bigint;
+
+-- Suspensions used to have no end, so the users suspended so far stay
+-- blocked as disabled users.
+UPDATE users SET status = 'disabled' WHERE status = 'suspended';
+
+ALTER TABLE users DROP CONSTRAINT IF EXISTS valid_user_status;
+ALTER TABLE users ADD CONSTRAINT valid_user_status CHECK (status IN ('active', 'suspended', 'disabled'));
+ALTER TABLE users ADD CONSTRAINT user_suspended_until CHECK ((status = 'suspended') = (suspended_until IS NOT NULL));
+
+COMMIT;
@@ -0,0 +1,16 @@
+-- Stops password rehashes from changing the version of users. A rehash after
+-- a login only replaces the stored hash with an equivalent one, so it must not
+-- fail the conditional updates of clients that read the user before. Password
+-- changes also set updated_at and still increment the version.
+
+BEGIN;
+
+DROP TRIGGER users_version ON users;
+
+CREATE TRIGGER users_version
+  BEFORE UPDATE ON users
+  FOR EACH ROW
+  WHEN ((to_jsonb(OLD) - 'password') IS DISTINCT FROM (to_jsonb(NEW) - 'password'))
+  EXECUTE FUNCTION increment_version();
+
+COMMIT;
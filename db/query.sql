@@ -1,34 +1,129 @@
+CREATE TABLE categories (
+  id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+  parent_id UUID,
+  name varchar NOT NULL,
+  slug varchar NOT NULL,
+  position int NOT NULL DEFAULT 0,
+  created_at bigint NOT NULL DEFAULT EXTRACT (EPOCH FROM CURRENT_TIMESTAMP),
+  updated_at bigint NOT NULL DEFAULT EXTRACT (EPOCH FROM CURRENT_TIMESTAMP)
+);
+
+ALTER TABLE categories ADD CONSTRAINT unique_category_slug UNIQUE (slug);
+ALTER TABLE categories ADD FOREIGN KEY (parent_id) REFERENCES categories (id);
+CREATE INDEX categories_parent_id_idx ON categories (parent_id);
+
 CREATE TABLE products (
   id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+  sku varchar,
   name varchar NOT NULL,
   image varchar NOT NULL,
-  category varchar NOT NULL,
+  category_id UUID NOT NULL,
   description text,
   rating int NOT NULL,
   num_reviews int NOT NULL DEFAULT 0,
   price decimal(10,2) NOT NULL,
+  -- A sale sells the product for sale_price from sale_starts_at until
+  -- sale_ends_at, or indefinitely when sale_ends_at is NULL.
+  sale_price decimal(10,2),
+  sale_starts_at bigint,
+  sale_ends_at bigint,
   count_in_stock int NOT NULL,
+  reorder_threshold int NOT NULL DEFAULT 0,
+  -- Drafts are only visible to staff. Deleting a product archives it, which
+  -- keeps it for the orders that reference it; deleted_at is set while it is
+  -- archived.
+  status varchar NOT NULL DEFAULT 'active',
+  deleted_at bigint,
+  version bigint NOT NULL DEFAULT 1,
   created_at bigint NOT NULL DEFAULT EXTRACT (EPOCH FROM CURRENT_TIMESTAMP),
   updated_at bigint NOT NULL DEFAULT EXTRACT (EPOCH FROM CURRENT_TIMESTAMP)
 );
 
+ALTER TABLE products ADD CONSTRAINT unique_product_sku UNIQUE (sku);
+ALTER TABLE products ADD CONSTRAINT product_reorder_threshold CHECK (reorder_threshold >= 0);
+ALTER TABLE products ADD CONSTRAINT product_status CHECK (status IN ('draft', 'active', 'archived'));
+ALTER TABLE products ADD CONSTRAINT product_deleted_at CHECK ((status = 'archived') = (deleted_at IS NOT NULL));
+ALTER TABLE products ADD CONSTRAINT product_sale CHECK (
+  (sale_price IS NULL) = (sale_starts_at IS NULL)
+  AND (sale_ends_at IS NULL OR sale_ends_at > sale_starts_at)
+);
+ALTER TABLE products ADD FOREIGN KEY (category_id) REFERENCES categories (id);
+CREATE INDEX products_category_id_idx ON products (category_id);
+CREATE INDEX products_status_idx ON products (status);
+
+-- Option types such as size or colour, with the values a product offers.
+CREATE TABLE product_options (
+  id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+  product_id UUID NOT NULL,
+  name varchar NOT NULL,
+  option_values varchar[] NOT NULL,
+  position int NOT NULL DEFAULT 0
+);
+
+ALTER TABLE product_options ADD CONSTRAINT unique_product_option UNIQUE (product_id, name);
+ALTER TABLE product_options ADD FOREIGN KEY (product_id) REFERENCES products (id) ON DELETE CASCADE;
+
+-- A variant picks one value of every option of its product. Price and image
+-- fall back to the product's when NULL.
+CREATE TABLE product_variants (
+  id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+  product_id UUID NOT NULL,
+  sku varchar NOT NULL,
+  options jsonb NOT NULL,
+  price decimal(10,2),
+  count_in_stock int NOT NULL DEFAULT 0,
+  image varchar,
+  created_at bigint NOT NULL DEFAULT EXTRACT (EPOCH FROM CURRENT_TIMESTAMP),
+  updated_at bigint NOT NULL DEFAULT EXTRACT (EPOCH FROM CURRENT_TIMESTAMP)
+);
+
+ALTER TABLE product_variants ADD CONSTRAINT unique_variant_sku UNIQUE (sku);
+ALTER TABLE product_variants ADD CONSTRAINT unique_variant_options UNIQUE (product_id, options);
+ALTER TABLE product_variants ADD FOREIGN KEY (product_id) REFERENCES products (id) ON DELETE CASCADE;
+
+-- Uploaded product images. The keys locate the original and its resized
+-- renditions in the file storage.
+CREATE TABLE product_images (
+  id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+  product_id UUID NOT NULL,
+  storage_key varchar NOT NULL,
+  thumbnail_key varchar NOT NULL,
+  medium_key varchar NOT NULL,
+  content_type varchar NOT NULL,
+  width int NOT NULL,
+  height int NOT NULL,
+  size_bytes bigint NOT NULL,
+  alt_text varchar NOT NULL DEFAULT '',
+  position int NOT NULL DEFAULT 0,
+  created_at bigint NOT NULL DEFAULT EXTRACT (EPOCH FROM CURRENT_TIMESTAMP)
+);
+
+ALTER TABLE product_images ADD FOREIGN KEY (product_id) REFERENCES products (id) ON DELETE CASCADE;
+CREATE INDEX product_images_product_id_idx ON product_images (product_id, position);
+
 CREATE TABLE orders (
   id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
   payment_method varchar NOT NULL,
   tax_price decimal(10,2) NOT NULL,
   shipping_price decimal(10,2) NOT NULL,
   total_price decimal(10,2) NOT NULL,
-  user_id UUID NOT NULL,
+  user_id UUID,
+  shipping_latitude double precision,
+  shipping_longitude double precision,
+  version bigint NOT NULL DEFAULT 1,
   created_at bigint NOT NULL DEFAULT EXTRACT (EPOCH FROM CURRENT_TIMESTAMP),
   updated_at bigint NOT NULL DEFAULT EXTRACT (EPOCH FROM CURRENT_TIMESTAMP)
 );
 
-ALTER TABLE orders ADD FOREIGN KEY (user_id) REFERENCES users (id);
+-- Orders are kept for accounting when a user is erased, so the user reference
+-- is cleared instead of blocking the deletion.
+ALTER TABLE orders ADD FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE SET NULL;
 
 CREATE TABLE order_items (
   id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
   order_id UUID NOT NULL,
   product_id UUID NOT NULL,
+  variant_id UUID,
   name varchar NOT NULL,
   quantity int NOT NULL,
   image varchar NOT NULL,
@@ -41,11 +136,17 @@ CREATE TABLE users (
   email varchar NOT NULL,
   password varchar NOT NULL,
   is_admin boolean NOT NULL DEFAULT FALSE,
+  status varchar NOT NULL DEFAULT 'active',
+  status_reason varchar,
+  suspended_until bigint,
+  version bigint NOT NULL DEFAULT 1,
   created_at bigint NOT NULL DEFAULT EXTRACT (EPOCH FROM CURRENT_TIMESTAMP),
   updated_at bigint NOT NULL DEFAULT EXTRACT (EPOCH FROM CURRENT_TIMESTAMP)
 );
 
 ALTER TABLE users ADD CONSTRAINT unique_email UNIQUE (email);
+ALTER TABLE users ADD CONSTRAINT valid_user_status CHECK (status IN ('active', 'suspended', 'disabled'));
+ALTER TABLE users ADD CONSTRAINT user_suspended_until CHECK ((status = 'suspended') = (suspended_until IS NOT NULL));
 
 CREATE TABLE sessions (
   id UUID PRIMARY KEY,
@@ -56,7 +157,359 @@ CREATE TABLE sessions (
   expires_at bigint NOT NULL DEFAULT EXTRACT (EPOCH FROM CURRENT_TIMESTAMP)
 );
 
-ALTER TABLE sessions ADD FOREIGN KEY (email) REFERENCES users (email);
+ALTER TABLE sessions ADD FOREIGN KEY (email) REFERENCES users (email) ON UPDATE CASCADE;
 
 ALTER TABLE order_items ADD FOREIGN KEY (order_id) REFERENCES orders (id);
-ALTER TABLE order_items ADD FOREIGN KEY (product_id) REFERENCES products (id);
\ No newline at end of file
+ALTER TABLE order_items ADD FOREIGN KEY (product_id) REFERENCES products (id);
+ALTER TABLE order_items ADD FOREIGN KEY (variant_id) REFERENCES product_variants (id) ON DELETE SET NULL;
+
+-- Warehouses hold the stock. Orders are allocated to them by the fulfilment
+-- strategy, which prefers lower priorities and, given a shipping location,
+-- closer warehouses.
+CREATE TABLE warehouses (
+  id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+  code varchar NOT NULL,
+  name varchar NOT NULL,
+  latitude double precision,
+  longitude double precision,
+  priority int NOT NULL DEFAULT 0,
+  active boolean NOT NULL DEFAULT true,
+  created_at bigint NOT NULL DEFAULT EXTRACT (EPOCH FROM CURRENT_TIMESTAMP),
+  updated_at bigint NOT NULL DEFAULT EXTRACT (EPOCH FROM CURRENT_TIMESTAMP)
+);
+
+ALTER TABLE warehouses ADD CONSTRAINT unique_warehouse_code UNIQUE (code);
+ALTER TABLE warehouses ADD CONSTRAINT warehouse_location CHECK ((latitude IS NULL) = (longitude IS NULL));
+
+INSERT INTO warehouses(code, name) VALUES ('main', 'Main warehouse');
+
+-- The stock of a product, or of one of its variants, in a warehouse. The
+-- count_in_stock of products and variants is the total over all warehouses.
+CREATE TABLE warehouse_stock (
+  warehouse_id UUID NOT NULL,
+  product_id UUID NOT NULL,
+  variant_id UUID,
+  quantity int NOT NULL DEFAULT 0
+);
+
+ALTER TABLE warehouse_stock ADD CONSTRAINT warehouse_stock_quantity CHECK (quantity >= 0);
+ALTER TABLE warehouse_stock ADD FOREIGN KEY (warehouse_id) REFERENCES warehouses (id);
+ALTER TABLE warehouse_stock ADD FOREIGN KEY (product_id) REFERENCES products (id) ON DELETE CASCADE;
+ALTER TABLE warehouse_stock ADD FOREIGN KEY (variant_id) REFERENCES product_variants (id) ON DELETE CASCADE;
+CREATE UNIQUE INDEX warehouse_stock_item_idx ON warehouse_stock (product_id, COALESCE(variant_id, '00000000-0000-0000-0000-000000000000'), warehouse_id);
+
+-- The warehouses an order line ships from. Split fulfilment allocates one
+-- line to several warehouses.
+CREATE TABLE order_item_allocations (
+  order_item_id UUID NOT NULL,
+  warehouse_id UUID NOT NULL,
+  quantity int NOT NULL,
+  PRIMARY KEY (order_item_id, warehouse_id)
+);
+
+ALTER TABLE order_item_allocations ADD FOREIGN KEY (order_item_id) REFERENCES order_items (id) ON DELETE CASCADE;
+ALTER TABLE order_item_allocations ADD FOREIGN KEY (warehouse_id) REFERENCES warehouses (id);
+
+-- The stock ledger. Every change to the count_in_stock of a product or
+-- variant is recorded here, so count_in_stock is the running total of the
+-- movements and balance is the stock right after the movement. Movements
+-- with a variant_id belong to the variant's stock, the others to the
+-- product's. Each movement happens in one warehouse; transfers are recorded
+-- as a pair of movements that leave the total unchanged.
+CREATE TABLE inventory_movements (
+  id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+  product_id UUID NOT NULL,
+  variant_id UUID,
+  warehouse_id UUID NOT NULL,
+  type varchar NOT NULL,
+  quantity int NOT NULL,
+  balance int NOT NULL,
+  reason varchar NOT NULL DEFAULT '',
+  order_id UUID,
+  actor_id UUID,
+  created_at bigint NOT NULL DEFAULT EXTRACT (EPOCH FROM CURRENT_TIMESTAMP)
+);
+
+ALTER TABLE inventory_movements ADD CONSTRAINT inventory_movement_type CHECK (type IN ('receipt', 'sale', 'return', 'adjustment', 'transfer'));
+ALTER TABLE inventory_movements ADD CONSTRAINT inventory_movement_quantity CHECK (quantity <> 0);
+ALTER TABLE inventory_movements ADD FOREIGN KEY (product_id) REFERENCES products (id) ON DELETE CASCADE;
+ALTER TABLE inventory_movements ADD FOREIGN KEY (variant_id) REFERENCES product_variants (id) ON DELETE CASCADE;
+ALTER TABLE inventory_movements ADD FOREIGN KEY (warehouse_id) REFERENCES warehouses (id);
+ALTER TABLE inventory_movements ADD FOREIGN KEY (order_id) REFERENCES orders (id) ON DELETE SET NULL;
+ALTER TABLE inventory_movements ADD FOREIGN KEY (actor_id) REFERENCES users (id) ON DELETE SET NULL;
+CREATE INDEX inventory_movements_product_id_idx ON inventory_movements (product_id, created_at);
+
+-- Every change to the pricing of a product, with the pricing it resulted in.
+CREATE TABLE price_history (
+  id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+  product_id UUID NOT NULL,
+  price decimal(10,2) NOT NULL,
+  sale_price decimal(10,2),
+  sale_starts_at bigint,
+  sale_ends_at bigint,
+  reason varchar NOT NULL,
+  actor_id UUID,
+  created_at bigint NOT NULL DEFAULT EXTRACT (EPOCH FROM CURRENT_TIMESTAMP)
+);
+
+ALTER TABLE price_history ADD FOREIGN KEY (product_id) REFERENCES products (id) ON DELETE CASCADE;
+ALTER TABLE price_history ADD FOREIGN KEY (actor_id) REFERENCES users (id) ON DELETE SET NULL;
+CREATE INDEX price_history_product_id_idx ON price_history (product_id, created_at);
+
+-- Changes of the regular price that take effect at effective_at. applied_at
+-- is set once the change has been made.
+CREATE TABLE scheduled_price_changes (
+  id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+  product_id UUID NOT NULL,
+  price decimal(10,2) NOT NULL,
+  effective_at bigint NOT NULL,
+  actor_id UUID,
+  created_at bigint NOT NULL DEFAULT EXTRACT (EPOCH FROM CURRENT_TIMESTAMP),
+  applied_at bigint
+);
+
+ALTER TABLE scheduled_price_changes ADD FOREIGN KEY (product_id) REFERENCES products (id) ON DELETE CASCADE;
+ALTER TABLE scheduled_price_changes ADD FOREIGN KEY (actor_id) REFERENCES users (id) ON DELETE SET NULL;
+CREATE INDEX scheduled_price_changes_product_id_idx ON scheduled_price_changes (product_id, effective_at);
+CREATE INDEX scheduled_price_changes_pending_idx ON scheduled_price_changes (effective_at) WHERE applied_at IS NULL;
+
+-- A stock alert is opened when the stock of a product, or of one of its
+-- variants, falls to or below the product's reorder threshold, and resolved
+-- once it rises above it again. notified_at is set when the alert has been
+-- sent to the admins.
+CREATE TABLE stock_alerts (
+  id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+  product_id UUID NOT NULL,
+  variant_id UUID,
+  threshold int NOT NULL,
+  stock int NOT NULL,
+  created_at bigint NOT NULL DEFAULT EXTRACT (EPOCH FROM CURRENT_TIMESTAMP),
+  notified_at bigint,
+  resolved_at bigint,
+  claimed_at bigint
+);
+
+ALTER TABLE stock_alerts ADD FOREIGN KEY (product_id) REFERENCES products (id) ON DELETE CASCADE;
+ALTER TABLE stock_alerts ADD FOREIGN KEY (variant_id) REFERENCES product_variants (id) ON DELETE CASCADE;
+CREATE UNIQUE INDEX stock_alerts_open_idx ON stock_alerts (product_id, COALESCE(variant_id, '00000000-0000-0000-0000-000000000000')) WHERE resolved_at IS NULL;
+CREATE INDEX stock_alerts_created_at_idx ON stock_alerts (created_at);
+
+-- Customers subscribe to out-of-stock products and variants and are emailed
+-- once when they are back in stock.
+CREATE TABLE stock_subscriptions (
+  id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+  user_id UUID NOT NULL,
+  product_id UUID NOT NULL,
+  variant_id UUID,
+  created_at bigint NOT NULL DEFAULT EXTRACT (EPOCH FROM CURRENT_TIMESTAMP),
+  notified_at bigint,
+  claimed_at bigint
+);
+
+ALTER TABLE stock_subscriptions ADD FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE CASCADE;
+ALTER TABLE stock_subscriptions ADD FOREIGN KEY (product_id) REFERENCES products (id) ON DELETE CASCADE;
+ALTER TABLE stock_subscriptions ADD FOREIGN KEY (variant_id) REFERENCES product_variants (id) ON DELETE CASCADE;
+CREATE UNIQUE INDEX stock_subscriptions_pending_idx ON stock_subscriptions (user_id, product_id, COALESCE(variant_id, '00000000-0000-0000-0000-000000000000')) WHERE notified_at IS NULL;
+CREATE TABLE password_reset_tokens (
+  id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+  user_id UUID NOT NULL,
+  token_hash varchar NOT NULL,
+  expires_at bigint NOT NULL,
+  used_at bigint,
+  created_at bigint NOT NULL DEFAULT EXTRACT (EPOCH FROM CURRENT_TIMESTAMP)
+);
+
+ALTER TABLE password_reset_tokens ADD CONSTRAINT unique_token_hash UNIQUE (token_hash);
+ALTER TABLE password_reset_tokens ADD FOREIGN KEY (user_id) REFERENCES users (id);
+
+CREATE TABLE email_change_tokens (
+  id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+  user_id UUID NOT NULL,
+  new_email varchar NOT NULL,
+  token_hash varchar NOT NULL,
+  expires_at bigint NOT NULL,
+  used_at bigint,
+  created_at bigint NOT NULL DEFAULT EXTRACT (EPOCH FROM CURRENT_TIMESTAMP)
+);
+
+ALTER TABLE email_change_tokens ADD CONSTRAINT unique_email_change_token_hash UNIQUE (token_hash);
+ALTER TABLE email_change_tokens ADD FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE CASCADE;
+
+CREATE TABLE roles (
+  id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+  name varchar NOT NULL,
+  description text NOT NULL DEFAULT '',
+  created_at bigint NOT NULL DEFAULT EXTRACT (EPOCH FROM CURRENT_TIMESTAMP)
+);
+
+ALTER TABLE roles ADD CONSTRAINT unique_role_name UNIQUE (name);
+
+CREATE TABLE role_permissions (
+  role_id UUID NOT NULL,
+  permission varchar NOT NULL,
+  PRIMARY KEY (role_id, permission)
+);
+
+ALTER TABLE role_permissions ADD FOREIGN KEY (role_id) REFERENCES roles (id) ON DELETE CASCADE;
+
+CREATE TABLE user_roles (
+  user_id UUID NOT NULL,
+  role_id UUID NOT NULL,
+  created_at bigint NOT NULL DEFAULT EXTRACT (EPOCH FROM CURRENT_TIMESTAMP),
+  PRIMARY KEY (user_id, role_id)
+);
+
+ALTER TABLE user_roles ADD FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE CASCADE;
+ALTER TABLE user_roles ADD FOREIGN KEY (role_id) REFERENCES roles (id) ON DELETE CASCADE;
+
+INSERT INTO roles(name, description) VALUES
+  ('catalog_manager', 'Manages the product catalog'),
+  ('fulfilment', 'Processes and ships orders'),
+  ('support', 'Assists customers with their accounts and orders');
+
+INSERT INTO role_permissions(role_id, permission)
+SELECT id, unnest(ARRAY['products:create', 'products:update', 'products:delete', 'products:import', 'products:export', 'categories:manage', 'inventory:read', 'inventory:manage']) FROM roles WHERE name = 'catalog_manager';
+
+INSERT INTO role_permissions(role_id, permission)
+SELECT id, unnest(ARRAY['orders:read', 'inventory:read', 'inventory:manage']) FROM roles WHERE name = 'fulfilment';
+
+INSERT INTO role_permissions(role_id, permission)
+SELECT id, unnest(ARRAY['users:read', 'orders:read']) FROM roles WHERE name = 'support';
+
+CREATE TABLE login_throttles (
+  key varchar PRIMARY KEY,
+  failures int NOT NULL DEFAULT 0,
+  locked_until bigint NOT NULL DEFAULT 0,
+  last_failure_at bigint NOT NULL DEFAULT EXTRACT (EPOCH FROM CURRENT_TIMESTAMP)
+);
+
+CREATE TABLE user_mfa (
+  user_id UUID PRIMARY KEY,
+  secret varchar NOT NULL,
+  enabled boolean NOT NULL DEFAULT FALSE,
+  last_used_step bigint NOT NULL DEFAULT 0,
+  created_at bigint NOT NULL DEFAULT EXTRACT (EPOCH FROM CURRENT_TIMESTAMP),
+  enabled_at bigint
+);
+
+ALTER TABLE user_mfa ADD FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE CASCADE;
+
+CREATE TABLE mfa_recovery_codes (
+  id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+  user_id UUID NOT NULL,
+  code_hash varchar NOT NULL,
+  used_at bigint,
+  created_at bigint NOT NULL DEFAULT EXTRACT (EPOCH FROM CURRENT_TIMESTAMP)
+);
+
+ALTER TABLE mfa_recovery_codes ADD FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE CASCADE;
+
+CREATE TABLE api_keys (
+  id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+  name varchar NOT NULL,
+  prefix varchar NOT NULL,
+  key_hash varchar NOT NULL,
+  scopes text[] NOT NULL DEFAULT '{}',
+  created_by UUID,
+  expires_at bigint,
+  last_used_at bigint,
+  revoked_at bigint,
+  created_at bigint NOT NULL DEFAULT EXTRACT (EPOCH FROM CURRENT_TIMESTAMP)
+);
+
+ALTER TABLE api_keys ADD CONSTRAINT unique_api_key_prefix UNIQUE (prefix);
+ALTER TABLE api_keys ADD FOREIGN KEY (created_by) REFERENCES users (id) ON DELETE SET NULL;
+
+CREATE TABLE oidc_login_states (
+  state varchar PRIMARY KEY,
+  provider varchar NOT NULL,
+  code_verifier varchar NOT NULL,
+  nonce varchar NOT NULL,
+  expires_at bigint NOT NULL,
+  created_at bigint NOT NULL DEFAULT EXTRACT (EPOCH FROM CURRENT_TIMESTAMP)
+);
+
+CREATE TABLE user_identities (
+  provider varchar NOT NULL,
+  subject varchar NOT NULL,
+  user_id UUID NOT NULL,
+  email varchar NOT NULL,
+  created_at bigint NOT NULL DEFAULT EXTRACT (EPOCH FROM CURRENT_TIMESTAMP),
+  PRIMARY KEY (provider, subject)
+);
+
+ALTER TABLE user_identities ADD FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE CASCADE;
+
+CREATE TABLE audit_events (
+  id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+  actor_id UUID,
+  action varchar NOT NULL,
+  target_type varchar NOT NULL,
+  target_id varchar NOT NULL,
+  before jsonb,
+  after jsonb,
+  request_id varchar,
+  metadata jsonb NOT NULL DEFAULT '{}',
+  created_at bigint NOT NULL DEFAULT EXTRACT (EPOCH FROM CURRENT_TIMESTAMP)
+);
+
+CREATE INDEX audit_events_target_idx ON audit_events (target_type, target_id);
+CREATE INDEX audit_events_actor_idx ON audit_events (actor_id, created_at);
+CREATE INDEX audit_events_created_at_idx ON audit_events (created_at);
+
+-- The audit log is append-only: rows can be inserted but never changed or
+-- removed. The only exception is erasing a user, which sets audit.erasure for
+-- its transaction and may then scrub personal data from the snapshots.
+CREATE FUNCTION reject_audit_event_change() RETURNS trigger AS $$
+BEGIN
+  IF TG_OP = 'UPDATE' AND current_setting('audit.erasure', true) = 'on'
+    AND (NEW.id, NEW.actor_id, NEW.action, NEW.target_type, NEW.target_id, NEW.request_id, NEW.metadata, NEW.created_at)
+    IS NOT DISTINCT FROM
+    (OLD.id, OLD.actor_id, OLD.action, OLD.target_type, OLD.target_id, OLD.request_id, OLD.metadata, OLD.created_at)
+  THEN
+    RETURN NEW;
+  END IF;
+  RAISE EXCEPTION 'audit_events is append-only';
+END;
+$$ LANGUAGE plpgsql;
+
+-- scrub_audit_fields replaces the values of fields in an audit snapshot,
+-- keeping the field names.
+CREATE FUNCTION scrub_audit_fields(snapshot jsonb, fields text[], placeholder text) RETURNS jsonb AS $$
+  SELECT CASE WHEN jsonb_typeof(snapshot) = 'object' THEN (
+    SELECT COALESCE(jsonb_object_agg(key, CASE WHEN key = ANY (fields) THEN to_jsonb(placeholder) ELSE value END), '{}')
+    FROM jsonb_each(snapshot)
+  ) ELSE snapshot END
+$$ LANGUAGE sql IMMUTABLE;
+
+CREATE TRIGGER audit_events_append_only
+  BEFORE UPDATE OR DELETE ON audit_events
+  FOR EACH ROW EXECUTE FUNCTION reject_audit_event_change();
+
+CREATE TRIGGER audit_events_no_truncate
+  BEFORE TRUNCATE ON audit_events
+  FOR EACH STATEMENT EXECUTE FUNCTION reject_audit_event_change();
+
+-- Products, orders and users carry a version that every update increments,
+-- so that clients can detect changes made since they read a row. Updates of
+-- users that only rehash the password leave the version alone.
+CREATE FUNCTION increment_version() RETURNS trigger AS $$
+BEGIN
+  NEW.version := OLD.version + 1;
+  RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER products_version
+  BEFORE UPDATE ON products
+  FOR EACH ROW EXECUTE FUNCTION increment_version();
+
+CREATE TRIGGER orders_version
+  BEFORE UPDATE ON orders
+  FOR EACH ROW EXECUTE FUNCTION increment_version();
+
+CREATE TRIGGER users_version
+  BEFORE UPDATE ON users
+  FOR EACH ROW
+  WHEN ((to_jsonb(OLD) - 'password') IS DISTINCT FROM (to_jsonb(NEW) - 'password'))
+  EXECUTE FUNCTION increment_version();
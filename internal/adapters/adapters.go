@@ -3,48 +3,168 @@ package adapters
 import (
 	"ecomm/internal/domain"
 	"ecomm/proto"
+	"encoding/json"
+	"time"
+
+	"google.golang.org/protobuf/types/known/fieldmaskpb"
 )
 
+// ToProtoProduct prices the product as of now.
 func ToProtoProduct(product domain.Product) *proto.Product {
+	price, compareAt := product.CurrentPrice(uint64(time.Now().Unix()))
 	return &proto.Product{
-		Id:              product.ID,
-		Name:            product.Name,
-		Image:           product.Image,
-		Category:        product.Category,
-		Description:     product.Description,
-		Rating:          int32(product.Rating),
-		NumberOfReviews: int32(product.NumberOfReviews),
-		Price:           float64(product.Price),
-		CountInStock:    int32(product.CountInStock),
-		CreatedAt:       product.CreatedAt,
-		UpdatedAt:       product.UpdatedAt,
+		Id:               product.ID,
+		Sku:              product.SKU,
+		Name:             product.Name,
+		Image:            product.Image,
+		Category:         product.Category,
+		Description:      product.Description,
+		Rating:           int32(product.Rating),
+		NumberOfReviews:  int32(product.NumberOfReviews),
+		Price:            price,
+		CompareAtPrice:   compareAt,
+		Sale:             ToProtoProductSale(product.Sale),
+		CountInStock:     int32(product.CountInStock),
+		ReorderThreshold: int32(product.ReorderThreshold),
+		Status:           product.Status,
+		DeletedAt:        product.DeletedAt,
+		Version:          product.Version,
+		CreatedAt:        product.CreatedAt,
+		UpdatedAt:        product.UpdatedAt,
+		CategoryId:       product.CategoryID,
+		Options:          ToProtoProductOptions(product.Options),
+		Variants:         ToProtoProductVariants(product.Variants, product),
+		Images:           ToProtoProductImages(product.Images),
+	}
+}
+
+func ToProtoProductSale(sale *domain.ProductSale) *proto.ProductSale {
+	if sale == nil {
+		return nil
+	}
+	return &proto.ProductSale{
+		Price:    sale.Price,
+		StartsAt: sale.StartsAt,
+		EndsAt:   sale.EndsAt,
+	}
+}
+
+func FromProtoProductSale(sale *proto.ProductSale) *domain.ProductSale {
+	if sale == nil {
+		return nil
+	}
+	return &domain.ProductSale{
+		Price:    sale.Price,
+		StartsAt: sale.StartsAt,
+		EndsAt:   sale.EndsAt,
+	}
+}
+
+func ToProtoProductImage(image domain.ProductImage) *proto.ProductImage {
+	return &proto.ProductImage{
+		Id:           image.ID,
+		ProductId:    image.ProductID,
+		Url:          image.URL,
+		ThumbnailUrl: image.ThumbnailURL,
+		MediumUrl:    image.MediumURL,
+		ContentType:  image.ContentType,
+		Width:        int32(image.Width),
+		Height:       int32(image.Height),
+		SizeBytes:    image.SizeBytes,
+		AltText:      image.AltText,
+		Position:     int32(image.Position),
+		CreatedAt:    image.CreatedAt,
+	}
+}
+
+func ToProtoProductImages(images []*domain.ProductImage) []*proto.ProductImage {
+	protoImages := make([]*proto.ProductImage, len(images))
+	for i, image := range images {
+		protoImages[i] = ToProtoProductImage(*image)
+	}
+	return protoImages
+}
+
+func ToProtoProductOptions(options []domain.ProductOption) []*proto.ProductOption {
+	protoOptions := make([]*proto.ProductOption, len(options))
+	for i, option := range options {
+		protoOptions[i] = &proto.ProductOption{
+			Name:   option.Name,
+			Values: option.Values,
+		}
 	}
+	return protoOptions
+}
+
+// ToProtoProductVariant resolves the price and image the variant inherits
+// from its product. Variants without a price of their own follow the
+// product's sales.
+func ToProtoProductVariant(variant domain.ProductVariant, product domain.Product) *proto.ProductVariant {
+	price, _ := product.CurrentPrice(uint64(time.Now().Unix()))
+	protoVariant := &proto.ProductVariant{
+		Id:           variant.ID,
+		ProductId:    variant.ProductID,
+		Sku:          variant.SKU,
+		Options:      variant.Options,
+		Price:        price,
+		CountInStock: int32(variant.CountInStock),
+		Image:        variant.Image,
+		CreatedAt:    variant.CreatedAt,
+		UpdatedAt:    variant.UpdatedAt,
+	}
+	if variant.Price != nil {
+		protoVariant.Price = *variant.Price
+		protoVariant.PriceOverride = true
+	}
+	if variant.Image == "" {
+		protoVariant.Image = product.Image
+	}
+	return protoVariant
+}
+
+func ToProtoProductVariants(variants []*domain.ProductVariant, product domain.Product) []*proto.ProductVariant {
+	protoVariants := make([]*proto.ProductVariant, len(variants))
+	for i, variant := range variants {
+		protoVariants[i] = ToProtoProductVariant(*variant, product)
+	}
+	return protoVariants
 }
 
 func ToProtoCreateProductRequest(product *domain.CreateProductRequest) *proto.CreateProductRequest {
 	return &proto.CreateProductRequest{
+		Sku:             product.SKU,
 		Name:            product.Name,
 		Image:           product.Image,
-		Category:        product.Category,
+		CategoryId:      product.CategoryID,
 		Description:     product.Description,
 		Rating:          int32(product.Rating),
 		NumberOfReviews: int32(product.NumberOfReviews),
 		Price:           float64(product.Price),
 		CountInStock:    int32(product.CountInStock),
+		Status:          product.Status,
 	}
 }
 
 func ToProtoUpdateProductRequest(product *domain.UpdateProductRequest) *proto.UpdateProductRequest {
 	return &proto.UpdateProductRequest{
 		Id:              product.ID,
+		Sku:             product.SKU,
 		Name:            product.Name,
 		Image:           product.Image,
-		Category:        product.Category,
+		CategoryId:      product.CategoryID,
 		Description:     product.Description,
 		Rating:          int32(product.Rating),
 		NumberOfReviews: int32(product.NumberOfReviews),
 		Price:           product.Price,
 		CountInStock:    int32(product.CountInStock),
+		Status:          product.Status,
+	}
+}
+
+func ToProtoListProductsRequest(req *domain.ListProductsRequest) *proto.ListProductsRequest {
+	return &proto.ListProductsRequest{
+		Query:  req.Query,
+		Status: req.Status,
 	}
 }
 
@@ -56,29 +176,85 @@ func ToProtoProducts(products []*domain.Product) []*proto.Product {
 	return protoProducts
 }
 
+func ToProtoCategory(category domain.Category) *proto.Category {
+	return &proto.Category{
+		Id:           category.ID,
+		ParentId:     category.ParentID,
+		Name:         category.Name,
+		Slug:         category.Slug,
+		Position:     int32(category.Position),
+		ProductCount: int32(category.ProductCount),
+		CreatedAt:    category.CreatedAt,
+		UpdatedAt:    category.UpdatedAt,
+	}
+}
+
+func ToProtoCreateCategoryRequest(category *domain.CreateCategoryRequest) *proto.CreateCategoryRequest {
+	return &proto.CreateCategoryRequest{
+		ParentId: category.ParentID,
+		Name:     category.Name,
+		Slug:     category.Slug,
+		Position: int32(category.Position),
+	}
+}
+
+// ToProtoUpdateCategoryRequest masks the fields that were not given, so that
+// they keep their current value.
+func ToProtoUpdateCategoryRequest(category *domain.UpdateCategoryRequest) *proto.UpdateCategoryRequest {
+	req := &proto.UpdateCategoryRequest{
+		Id:   category.ID,
+		Name: category.Name,
+		Slug: category.Slug,
+	}
+
+	paths := []string{"name"}
+	if category.Slug != "" {
+		paths = append(paths, "slug")
+	}
+	if category.ParentID != nil {
+		req.ParentId = *category.ParentID
+		paths = append(paths, "parent_id")
+	}
+	if category.Position != nil {
+		req.Position = int32(*category.Position)
+		paths = append(paths, "position")
+	}
+	req.UpdateMask = &fieldmaskpb.FieldMask{Paths: paths}
+	return req
+}
+
 func ToProtoOrder(order domain.Order) *proto.Order {
 	orderItems := make([]*proto.OrderItem, len(order.OrderItems))
 	for i, item := range order.OrderItems {
 		orderItems[i] = &proto.OrderItem{
 			Id:        item.ID,
 			ProductId: item.ProductID,
+			VariantId: item.VariantID,
 			Name:      item.Name,
 			Quantity:  int32(item.Quantity),
 			Image:     item.Image,
 			Price:     float64(item.Price),
 		}
+		for _, allocation := range item.Allocations {
+			orderItems[i].Allocations = append(orderItems[i].Allocations, &proto.OrderItemAllocation{
+				WarehouseId: allocation.WarehouseID,
+				Quantity:    int32(allocation.Quantity),
+			})
+		}
 	}
 
 	return &proto.Order{
-		Id:            order.ID,
-		PaymentMethod: order.PaymentMethod,
-		TaxPrice:      float64(order.TaxPrice),
-		ShippingPrice: float64(order.ShippingPrice),
-		TotalPrice:    float64(order.TotalPrice),
-		OrderItems:    orderItems,
-		UserId:        order.UserID,
-		CreatedAt:     order.CreatedAt,
-		UpdatedAt:     order.UpdatedAt,
+		Id:               order.ID,
+		PaymentMethod:    order.PaymentMethod,
+		TaxPrice:         float64(order.TaxPrice),
+		ShippingPrice:    float64(order.ShippingPrice),
+		TotalPrice:       float64(order.TotalPrice),
+		OrderItems:       orderItems,
+		UserId:           order.UserID,
+		Version:          order.Version,
+		CreatedAt:        order.CreatedAt,
+		UpdatedAt:        order.UpdatedAt,
+		ShippingLocation: ToProtoLocation(order.ShippingLocation),
 	}
 }
 
@@ -96,6 +272,7 @@ func ToProtoCreateOrderRequest(order *domain.CreateOrderRequest) *proto.CreateOr
 		orderItems[i] = &proto.OrderItem{
 			Id:        item.ID,
 			ProductId: item.ProductID,
+			VariantId: item.VariantID,
 			Name:      item.Name,
 			Quantity:  int32(item.Quantity),
 			Image:     item.Image,
@@ -104,24 +281,28 @@ func ToProtoCreateOrderRequest(order *domain.CreateOrderRequest) *proto.CreateOr
 	}
 
 	return &proto.CreateOrderRequest{
-		PaymentMethod: order.PaymentMethod,
-		TaxPrice:      order.TaxPrice,
-		ShippingPrice: order.ShippingPrice,
-		TotalPrice:    order.TotalPrice,
-		OrderItems:    orderItems,
-		UserId:        order.UserID,
+		PaymentMethod:    order.PaymentMethod,
+		TaxPrice:         order.TaxPrice,
+		ShippingPrice:    order.ShippingPrice,
+		TotalPrice:       order.TotalPrice,
+		OrderItems:       orderItems,
+		UserId:           order.UserID,
+		ShippingLocation: ToProtoLocation(order.ShippingLocation),
 	}
 }
 
 func ToProtoUser(user domain.User) *proto.User {
 	return &proto.User{
-		Id:        user.ID,
-		Name:      user.Name,
-		Email:     user.Email,
-		Password:  user.Password,
-		IsAdmin:   user.IsAdmin,
-		CreatedAt: user.CreatedAt,
-		UpdatedAt: user.UpdatedAt,
+		Id:             user.ID,
+		Name:           user.Name,
+		Email:          user.Email,
+		IsAdmin:        user.IsAdmin,
+		Status:         user.Status,
+		StatusReason:   user.StatusReason,
+		SuspendedUntil: user.SuspendedUntil,
+		Version:        user.Version,
+		CreatedAt:      user.CreatedAt,
+		UpdatedAt:      user.UpdatedAt,
 	}
 }
 
@@ -138,16 +319,43 @@ func ToProtoCreateUserRequest(user *domain.CreateUserRequest) *proto.CreateUserR
 		Name:     user.Name,
 		Email:    user.Email,
 		Password: user.Password,
-		IsAdmin:  user.IsAdmin,
 	}
 }
 
 func ToProtoUpdateUserRequest(user *domain.UpdateUserRequest) *proto.UpdateUserRequest {
 	return &proto.UpdateUserRequest{
-		Name:     user.Name,
-		Email:    user.Email,
-		Password: user.Password,
-		IsAdmin:  user.IsAdmin,
+		Id:   user.ID,
+		Name: user.Name,
+	}
+}
+
+func ToProtoListUsersRequest(req *domain.ListUsersRequest) *proto.ListUsersRequest {
+	return &proto.ListUsersRequest{
+		Query:  req.Query,
+		Limit:  int32(req.Limit),
+		Offset: int32(req.Offset),
+	}
+}
+
+func ToProtoAdminUpdateUserRequest(req *domain.AdminUpdateUserRequest) *proto.AdminUpdateUserRequest {
+	return &proto.AdminUpdateUserRequest{
+		UserId: req.UserID,
+		Name:   req.Name,
+		Email:  req.Email,
+	}
+}
+
+func ToProtoSetUserAdminRequest(req *domain.SetUserAdminRequest) *proto.SetUserAdminRequest {
+	return &proto.SetUserAdminRequest{
+		UserId:  req.UserID,
+		IsAdmin: *req.IsAdmin,
+	}
+}
+
+func ToProtoImpersonateUserRequest(req *domain.ImpersonateUserRequest) *proto.ImpersonateUserRequest {
+	return &proto.ImpersonateUserRequest{
+		UserId: req.UserID,
+		Reason: req.Reason,
 	}
 }
 
@@ -155,6 +363,7 @@ func ToProtoLoginUserRequest(req *domain.LoginRequest) *proto.LoginRequest {
 	return &proto.LoginRequest{
 		Email:    req.Email,
 		Password: req.Password,
+		ClientIp: req.ClientIP,
 	}
 }
 
@@ -163,3 +372,488 @@ func ToProtoRefreshTokenRequest(req *domain.RefreshAccessTokenRequest) *proto.Re
 		RefreshToken: req.RefreshToken,
 	}
 }
+
+func ToProtoRequestPasswordResetRequest(req *domain.RequestPasswordResetRequest) *proto.RequestPasswordResetRequest {
+	return &proto.RequestPasswordResetRequest{
+		Email: req.Email,
+	}
+}
+
+func ToProtoResetPasswordRequest(req *domain.ResetPasswordRequest) *proto.ResetPasswordRequest {
+	return &proto.ResetPasswordRequest{
+		Token:    req.Token,
+		Password: req.Password,
+	}
+}
+
+func ToProtoChangePasswordRequest(req *domain.ChangePasswordRequest) *proto.ChangePasswordRequest {
+	return &proto.ChangePasswordRequest{
+		UserId:          req.UserID,
+		SessionId:       req.SessionID,
+		CurrentPassword: req.CurrentPassword,
+		NewPassword:     req.NewPassword,
+	}
+}
+
+func ToProtoRequestEmailChangeRequest(req *domain.RequestEmailChangeRequest) *proto.RequestEmailChangeRequest {
+	return &proto.RequestEmailChangeRequest{
+		UserId:          req.UserID,
+		CurrentPassword: req.CurrentPassword,
+		NewEmail:        req.NewEmail,
+	}
+}
+
+func ToProtoRole(role domain.Role) *proto.Role {
+	return &proto.Role{
+		Id:          role.ID,
+		Name:        role.Name,
+		Description: role.Description,
+		Permissions: role.Permissions,
+		CreatedAt:   role.CreatedAt,
+	}
+}
+
+func ToProtoRoles(roles []*domain.Role) []*proto.Role {
+	protoRoles := make([]*proto.Role, len(roles))
+	for i, role := range roles {
+		protoRoles[i] = ToProtoRole(*role)
+	}
+	return protoRoles
+}
+
+func ToProtoAssignUserRoleRequest(req *domain.AssignUserRoleRequest) *proto.AssignUserRoleRequest {
+	return &proto.AssignUserRoleRequest{
+		UserId: req.UserID,
+		Role:   req.Role,
+	}
+}
+
+func ToProtoAPIKey(key domain.APIKey) *proto.APIKey {
+	return &proto.APIKey{
+		Id:         key.ID,
+		Name:       key.Name,
+		Prefix:     key.Prefix,
+		Scopes:     key.Scopes,
+		CreatedBy:  key.CreatedBy,
+		ExpiresAt:  key.ExpiresAt,
+		LastUsedAt: key.LastUsedAt,
+		RevokedAt:  key.RevokedAt,
+		CreatedAt:  key.CreatedAt,
+	}
+}
+
+func ToProtoAPIKeys(keys []*domain.APIKey) []*proto.APIKey {
+	protoKeys := make([]*proto.APIKey, len(keys))
+	for i, key := range keys {
+		protoKeys[i] = ToProtoAPIKey(*key)
+	}
+	return protoKeys
+}
+
+func ToProtoCreateAPIKeyRequest(req *domain.CreateAPIKeyRequest) *proto.CreateAPIKeyRequest {
+	return &proto.CreateAPIKeyRequest{
+		Name:      req.Name,
+		Scopes:    req.Scopes,
+		ExpiresAt: req.ExpiresAt,
+	}
+}
+
+// ToProtoAuditEvent encodes the JSON columns of an audit event as strings.
+// They were read from jsonb columns, so encoding them cannot fail.
+func ToProtoAuditEvent(event domain.AuditEvent) *proto.AuditEvent {
+	return &proto.AuditEvent{
+		Id:         event.ID,
+		ActorId:    event.ActorID,
+		Action:     event.Action,
+		TargetType: event.TargetType,
+		TargetId:   event.TargetID,
+		Before:     encodeJSONObject(event.Before),
+		After:      encodeJSONObject(event.After),
+		RequestId:  event.RequestID,
+		Metadata:   encodeJSONObject(event.Metadata),
+		CreatedAt:  event.CreatedAt,
+	}
+}
+
+func ToProtoAuditEvents(events []*domain.AuditEvent) []*proto.AuditEvent {
+	protoEvents := make([]*proto.AuditEvent, len(events))
+	for i, event := range events {
+		protoEvents[i] = ToProtoAuditEvent(*event)
+	}
+	return protoEvents
+}
+
+func FromProtoAuditEvent(event *proto.AuditEvent) *domain.AuditEvent {
+	return &domain.AuditEvent{
+		ID:         event.Id,
+		ActorID:    event.ActorId,
+		Action:     event.Action,
+		TargetType: event.TargetType,
+		TargetID:   event.TargetId,
+		Before:     decodeJSONObject(event.Before),
+		After:      decodeJSONObject(event.After),
+		RequestID:  event.RequestId,
+		Metadata:   decodeJSONObject(event.Metadata),
+		CreatedAt:  event.CreatedAt,
+	}
+}
+
+func FromProtoAuditEvents(events []*proto.AuditEvent) []*domain.AuditEvent {
+	domainEvents := make([]*domain.AuditEvent, len(events))
+	for i, event := range events {
+		domainEvents[i] = FromProtoAuditEvent(event)
+	}
+	return domainEvents
+}
+
+func ToProtoListAuditEventsRequest(filter *domain.AuditEventFilter) *proto.ListAuditEventsRequest {
+	return &proto.ListAuditEventsRequest{
+		ActorId:    filter.ActorID,
+		Action:     filter.Action,
+		TargetType: filter.TargetType,
+		TargetId:   filter.TargetID,
+		RequestId:  filter.RequestID,
+		Since:      filter.Since,
+		Until:      filter.Until,
+		Limit:      int32(filter.Limit),
+		Offset:     int32(filter.Offset),
+	}
+}
+
+func encodeJSONObject(object map[string]any) string {
+	if object == nil {
+		return ""
+	}
+	data, _ := json.Marshal(object)
+	return string(data)
+}
+
+func decodeJSONObject(data string) map[string]any {
+	if data == "" {
+		return nil
+	}
+	var object map[string]any
+	_ = json.Unmarshal([]byte(data), &object)
+	return object
+}
+
+func ToProtoSetProductOptionsRequest(request *domain.SetProductOptionsRequest) *proto.SetProductOptionsRequest {
+	options := make([]*proto.ProductOption, len(request.Options))
+	for i, option := range request.Options {
+		options[i] = &proto.ProductOption{
+			Name:   option.Name,
+			Values: option.Values,
+		}
+	}
+
+	return &proto.SetProductOptionsRequest{
+		ProductId: request.ProductID,
+		Options:   options,
+	}
+}
+
+func ToProtoCreateProductVariantRequest(variant *domain.ProductVariantRequest) *proto.CreateProductVariantRequest {
+	return &proto.CreateProductVariantRequest{
+		ProductId:    variant.ProductID,
+		Sku:          variant.SKU,
+		Options:      variant.Options,
+		Price:        variant.Price,
+		CountInStock: int32(variant.CountInStock),
+		Image:        variant.Image,
+	}
+}
+
+func ToProtoUpdateProductVariantRequest(variant *domain.ProductVariantRequest) *proto.UpdateProductVariantRequest {
+	return &proto.UpdateProductVariantRequest{
+		Id:           variant.ID,
+		ProductId:    variant.ProductID,
+		Sku:          variant.SKU,
+		Options:      variant.Options,
+		Price:        variant.Price,
+		CountInStock: int32(variant.CountInStock),
+		Image:        variant.Image,
+	}
+}
+
+func ToProtoInventoryMovement(movement domain.InventoryMovement) *proto.InventoryMovement {
+	return &proto.InventoryMovement{
+		Id:          movement.ID,
+		ProductId:   movement.ProductID,
+		VariantId:   movement.VariantID,
+		Type:        movement.Type,
+		Quantity:    int32(movement.Quantity),
+		Balance:     int32(movement.Balance),
+		Reason:      movement.Reason,
+		OrderId:     movement.OrderID,
+		ActorId:     movement.ActorID,
+		CreatedAt:   movement.CreatedAt,
+		WarehouseId: movement.WarehouseID,
+	}
+}
+
+func ToProtoInventoryMovements(movements []*domain.InventoryMovement) []*proto.InventoryMovement {
+	protoMovements := make([]*proto.InventoryMovement, len(movements))
+	for i, movement := range movements {
+		protoMovements[i] = ToProtoInventoryMovement(*movement)
+	}
+	return protoMovements
+}
+
+func ToProtoStockDiscrepancies(discrepancies []*domain.StockDiscrepancy) []*proto.StockDiscrepancy {
+	protoDiscrepancies := make([]*proto.StockDiscrepancy, len(discrepancies))
+	for i, discrepancy := range discrepancies {
+		protoDiscrepancies[i] = &proto.StockDiscrepancy{
+			ProductId:   discrepancy.ProductID,
+			VariantId:   discrepancy.VariantID,
+			Recorded:    int32(discrepancy.Recorded),
+			Ledger:      int32(discrepancy.Ledger),
+			WarehouseId: discrepancy.WarehouseID,
+		}
+	}
+	return protoDiscrepancies
+}
+
+func ToProtoAdjustStockRequest(req *domain.AdjustStockRequest) *proto.AdjustStockRequest {
+	return &proto.AdjustStockRequest{
+		ProductId:   req.ProductID,
+		VariantId:   req.VariantID,
+		Type:        req.Type,
+		Quantity:    int32(req.Quantity),
+		Reason:      req.Reason,
+		WarehouseId: req.WarehouseID,
+	}
+}
+
+func ToProtoListStockMovementsRequest(filter *domain.InventoryMovementFilter) *proto.ListStockMovementsRequest {
+	return &proto.ListStockMovementsRequest{
+		ProductId:   filter.ProductID,
+		VariantId:   filter.VariantID,
+		Type:        filter.Type,
+		Limit:       int32(filter.Limit),
+		Offset:      int32(filter.Offset),
+		WarehouseId: filter.WarehouseID,
+	}
+}
+
+func ToProtoTransferStockRequest(req *domain.TransferStockRequest) *proto.TransferStockRequest {
+	return &proto.TransferStockRequest{
+		ProductId:       req.ProductID,
+		VariantId:       req.VariantID,
+		FromWarehouseId: req.FromWarehouseID,
+		ToWarehouseId:   req.ToWarehouseID,
+		Quantity:        int32(req.Quantity),
+		Reason:          req.Reason,
+	}
+}
+
+func ToProtoLocation(location *domain.Location) *proto.Location {
+	if location == nil {
+		return nil
+	}
+	return &proto.Location{
+		Latitude:  location.Latitude,
+		Longitude: location.Longitude,
+	}
+}
+
+func FromProtoLocation(location *proto.Location) *domain.Location {
+	if location == nil {
+		return nil
+	}
+	return &domain.Location{
+		Latitude:  location.Latitude,
+		Longitude: location.Longitude,
+	}
+}
+
+func ToProtoWarehouse(warehouse domain.Warehouse) *proto.Warehouse {
+	return &proto.Warehouse{
+		Id:        warehouse.ID,
+		Code:      warehouse.Code,
+		Name:      warehouse.Name,
+		Location:  ToProtoLocation(warehouse.Location),
+		Priority:  int32(warehouse.Priority),
+		Active:    warehouse.Active,
+		CreatedAt: warehouse.CreatedAt,
+		UpdatedAt: warehouse.UpdatedAt,
+	}
+}
+
+func ToProtoWarehouses(warehouses []*domain.Warehouse) []*proto.Warehouse {
+	protoWarehouses := make([]*proto.Warehouse, len(warehouses))
+	for i, warehouse := range warehouses {
+		protoWarehouses[i] = ToProtoWarehouse(*warehouse)
+	}
+	return protoWarehouses
+}
+
+func ToProtoCreateWarehouseRequest(req *domain.CreateWarehouseRequest) *proto.CreateWarehouseRequest {
+	return &proto.CreateWarehouseRequest{
+		Code:     req.Code,
+		Name:     req.Name,
+		Location: ToProtoLocation(req.Location),
+		Priority: int32(req.Priority),
+	}
+}
+
+// ToProtoUpdateWarehouseRequest masks the fields that were not given, so that
+// they keep their current value.
+func ToProtoUpdateWarehouseRequest(req *domain.UpdateWarehouseRequest) *proto.UpdateWarehouseRequest {
+	protoReq := &proto.UpdateWarehouseRequest{
+		Id:   req.ID,
+		Name: req.Name,
+	}
+
+	paths := []string{"name"}
+	if req.Location != nil {
+		protoReq.Location = ToProtoLocation(req.Location)
+		paths = append(paths, "location")
+	}
+	if req.Priority != nil {
+		protoReq.Priority = int32(*req.Priority)
+		paths = append(paths, "priority")
+	}
+	if req.Active != nil {
+		protoReq.Active = *req.Active
+		paths = append(paths, "active")
+	}
+	protoReq.UpdateMask = &fieldmaskpb.FieldMask{Paths: paths}
+	return protoReq
+}
+
+func ToProtoWarehouseStock(stock []*domain.WarehouseStock) []*proto.WarehouseStock {
+	protoStock := make([]*proto.WarehouseStock, len(stock))
+	for i, level := range stock {
+		protoStock[i] = &proto.WarehouseStock{
+			WarehouseId: level.WarehouseID,
+			ProductId:   level.ProductID,
+			VariantId:   level.VariantID,
+			Quantity:    int32(level.Quantity),
+		}
+	}
+	return protoStock
+}
+
+func ToProtoSetReorderThresholdRequest(req *domain.SetReorderThresholdRequest) *proto.SetReorderThresholdRequest {
+	return &proto.SetReorderThresholdRequest{
+		ProductId: req.ProductID,
+		Threshold: int32(req.Threshold),
+	}
+}
+
+func ToProtoStockAlerts(alerts []*domain.StockAlert) []*proto.StockAlert {
+	protoAlerts := make([]*proto.StockAlert, len(alerts))
+	for i, alert := range alerts {
+		protoAlerts[i] = &proto.StockAlert{
+			Id:          alert.ID,
+			ProductId:   alert.ProductID,
+			ProductName: alert.ProductName,
+			VariantId:   alert.VariantID,
+			Threshold:   int32(alert.Threshold),
+			Stock:       int32(alert.Stock),
+			CreatedAt:   alert.CreatedAt,
+			NotifiedAt:  alert.NotifiedAt,
+			ResolvedAt:  alert.ResolvedAt,
+		}
+	}
+	return protoAlerts
+}
+
+func ToProtoListStockAlertsRequest(filter *domain.StockAlertFilter) *proto.ListStockAlertsRequest {
+	return &proto.ListStockAlertsRequest{
+		OpenOnly: filter.OpenOnly,
+		Limit:    int32(filter.Limit),
+		Offset:   int32(filter.Offset),
+	}
+}
+
+func ToProtoStockSubscription(subscription domain.StockSubscription) *proto.StockSubscription {
+	return &proto.StockSubscription{
+		Id:         subscription.ID,
+		UserId:     subscription.UserID,
+		ProductId:  subscription.ProductID,
+		VariantId:  subscription.VariantID,
+		CreatedAt:  subscription.CreatedAt,
+		NotifiedAt: subscription.NotifiedAt,
+	}
+}
+
+func ToProtoStockSubscriptions(subscriptions []*domain.StockSubscription) []*proto.StockSubscription {
+	protoSubscriptions := make([]*proto.StockSubscription, len(subscriptions))
+	for i, subscription := range subscriptions {
+		protoSubscriptions[i] = ToProtoStockSubscription(*subscription)
+	}
+	return protoSubscriptions
+}
+
+func ToProtoSubscribeBackInStockRequest(req *domain.SubscribeBackInStockRequest) *proto.SubscribeBackInStockRequest {
+	return &proto.SubscribeBackInStockRequest{
+		UserId:    req.UserID,
+		ProductId: req.ProductID,
+		VariantId: req.VariantID,
+	}
+}
+
+func ToProtoPriceHistory(entries []*domain.PriceHistoryEntry) []*proto.PriceHistoryEntry {
+	protoEntries := make([]*proto.PriceHistoryEntry, len(entries))
+	for i, entry := range entries {
+		protoEntries[i] = &proto.PriceHistoryEntry{
+			Id:        entry.ID,
+			ProductId: entry.ProductID,
+			Price:     entry.Price,
+			Sale:      ToProtoProductSale(entry.Sale),
+			Reason:    entry.Reason,
+			ActorId:   entry.ActorID,
+			CreatedAt: entry.CreatedAt,
+		}
+	}
+	return protoEntries
+}
+
+func ToProtoListPriceHistoryRequest(filter *domain.PriceHistoryFilter) *proto.ListPriceHistoryRequest {
+	return &proto.ListPriceHistoryRequest{
+		ProductId: filter.ProductID,
+		Limit:     int32(filter.Limit),
+		Offset:    int32(filter.Offset),
+	}
+}
+
+func ToProtoScheduledPriceChange(change domain.ScheduledPriceChange) *proto.ScheduledPriceChange {
+	return &proto.ScheduledPriceChange{
+		Id:          change.ID,
+		ProductId:   change.ProductID,
+		Price:       change.Price,
+		EffectiveAt: change.EffectiveAt,
+		ActorId:     change.ActorID,
+		CreatedAt:   change.CreatedAt,
+		AppliedAt:   change.AppliedAt,
+	}
+}
+
+func ToProtoScheduledPriceChanges(changes []*domain.ScheduledPriceChange) []*proto.ScheduledPriceChange {
+	protoChanges := make([]*proto.ScheduledPriceChange, len(changes))
+	for i, change := range changes {
+		protoChanges[i] = ToProtoScheduledPriceChange(*change)
+	}
+	return protoChanges
+}
+
+func ToProtoSetProductSaleRequest(req *domain.SetProductSaleRequest) *proto.SetProductSaleRequest {
+	return &proto.SetProductSaleRequest{
+		ProductId:       req.ProductID,
+		ExpectedVersion: req.ExpectedVersion,
+		Sale: &proto.ProductSale{
+			Price:    req.Price,
+			StartsAt: req.StartsAt,
+			EndsAt:   req.EndsAt,
+		},
+	}
+}
+
+func ToProtoSchedulePriceChangeRequest(req *domain.SchedulePriceChangeRequest) *proto.SchedulePriceChangeRequest {
+	return &proto.SchedulePriceChangeRequest{
+		ProductId:   req.ProductID,
+		Price:       req.Price,
+		EffectiveAt: req.EffectiveAt,
+	}
+}
@@ -0,0 +1,167 @@
+// Package audit records administrative and account changes made through the
+// gRPC API.
+//
+// The interceptor attaches an Entry to the context of every audited call.
+// Service methods describe what they changed with Record, and the interceptor
+// writes the event once the call has succeeded.
+package audit
+
+import (
+	"context"
+	"ecomm/internal/domain"
+	"encoding/json"
+	"reflect"
+	"sync"
+
+	"google.golang.org/grpc/metadata"
+)
+
+// RequestIDKey is the metadata key carrying the gateway request ID.
+const RequestIDKey = "x-request-id"
+
+const redacted = "[REDACTED]"
+
+// sensitiveFields are never written to the audit log. A change to one of them
+// is recorded as a redacted value.
+var sensitiveFields = map[string]bool{
+	"password":      true,
+	"refresh_token": true,
+	"secret":        true,
+	"key":           true,
+	"key_hash":      true,
+	"token":         true,
+	"token_hash":    true,
+}
+
+// Entry is the part of an audit event filled in by the service method.
+type Entry struct {
+	mu         sync.Mutex
+	targetType string
+	targetID   string
+	before     any
+	after      any
+	metadata   map[string]any
+}
+
+type entryKey struct{}
+
+func newContext(ctx context.Context, entry *Entry) context.Context {
+	return context.WithValue(ctx, entryKey{}, entry)
+}
+
+func fromContext(ctx context.Context) *Entry {
+	entry, _ := ctx.Value(entryKey{}).(*Entry)
+	return entry
+}
+
+// Record describes the object changed by the current call. before is nil for
+// creations and after is nil for deletions. It is a no-op for calls that are
+// not audited.
+func Record(ctx context.Context, targetType, targetID string, before, after any) {
+	entry := fromContext(ctx)
+	if entry == nil {
+		return
+	}
+
+	entry.mu.Lock()
+	defer entry.mu.Unlock()
+	entry.targetType = targetType
+	entry.targetID = targetID
+	entry.before = before
+	entry.after = after
+}
+
+// AddMetadata attaches extra context, such as a role name, to the event.
+func AddMetadata(ctx context.Context, key string, value any) {
+	entry := fromContext(ctx)
+	if entry == nil {
+		return
+	}
+
+	entry.mu.Lock()
+	defer entry.mu.Unlock()
+	if entry.metadata == nil {
+		entry.metadata = make(map[string]any)
+	}
+	entry.metadata[key] = value
+}
+
+// RequestID returns the request ID forwarded by the gateway, if any.
+func RequestID(ctx context.Context) string {
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		return ""
+	}
+
+	values := md.Get(RequestIDKey)
+	if len(values) == 0 {
+		return ""
+	}
+	return values[0]
+}
+
+// Diff converts before and after to JSON objects and keeps only the fields
+// that differ between them. Sensitive fields are redacted.
+func Diff(before, after any) (map[string]any, map[string]any, error) {
+	beforeFields, err := toFields(before)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	afterFields, err := toFields(after)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if beforeFields != nil && afterFields != nil {
+		for field, value := range beforeFields {
+			if other, ok := afterFields[field]; ok && reflect.DeepEqual(value, other) {
+				delete(beforeFields, field)
+				delete(afterFields, field)
+			}
+		}
+	}
+
+	redact(beforeFields)
+	redact(afterFields)
+	return beforeFields, afterFields, nil
+}
+
+func toFields(value any) (map[string]any, error) {
+	if value == nil {
+		return nil, nil
+	}
+	if v := reflect.ValueOf(value); v.Kind() == reflect.Pointer && v.IsNil() {
+		return nil, nil
+	}
+
+	data, err := json.Marshal(value)
+	if err != nil {
+		return nil, err
+	}
+
+	var fields map[string]any
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return nil, err
+	}
+	return fields, nil
+}
+
+// omitPersonal replaces the values of the personal fields of targetType. The
+// log is append-only, so a change to one of them is recorded by field name
+// only.
+func omitPersonal(targetType string, fields map[string]any) {
+	for _, field := range domain.PersonalAuditFields[targetType] {
+		if _, ok := fields[field]; ok {
+			fields[field] = domain.PersonalAuditValue
+		}
+	}
+}
+
+func redact(fields map[string]any) {
+	for field := range fields {
+		if sensitiveFields[field] {
+			fields[field] = redacted
+		}
+	}
+}
@@ -0,0 +1,124 @@
+package audit
+
+import (
+	"context"
+	"ecomm/internal/domain"
+	"testing"
+
+	"google.golang.org/grpc"
+)
+
+func TestDiffKeepsChangedFieldsAndRedactsSecrets(t *testing.T) {
+	before := domain.User{ID: "1", Name: "Ada", Email: "ada@example.com", Password: "old-hash"}
+	after := domain.User{ID: "1", Name: "Ada Lovelace", Email: "ada@example.com", Password: "new-hash"}
+
+	beforeFields, afterFields, err := Diff(before, &after)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(beforeFields) != 2 || len(afterFields) != 2 {
+		t.Fatalf("expected name and password only, got %v and %v", beforeFields, afterFields)
+	}
+	if beforeFields["name"] != "Ada" || afterFields["name"] != "Ada Lovelace" {
+		t.Errorf("unexpected name diff: %v -> %v", beforeFields["name"], afterFields["name"])
+	}
+	if beforeFields["password"] != redacted || afterFields["password"] != redacted {
+		t.Errorf("password was not redacted: %v -> %v", beforeFields["password"], afterFields["password"])
+	}
+}
+
+func TestDiffOfCreationKeepsAllFields(t *testing.T) {
+	var before *domain.Product
+	beforeFields, afterFields, err := Diff(before, &domain.Product{ID: "p1", Name: "Lamp"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if beforeFields != nil {
+		t.Errorf("expected no before state, got %v", beforeFields)
+	}
+	if afterFields["id"] != "p1" || afterFields["name"] != "Lamp" {
+		t.Errorf("unexpected after state: %v", afterFields)
+	}
+}
+
+type memoryStore struct {
+	events []*domain.AuditEvent
+}
+
+func (s *memoryStore) CreateAuditEvent(event *domain.AuditEvent) error {
+	s.events = append(s.events, event)
+	return nil
+}
+
+func TestInterceptorOnlyRecordsSuccessfulAuditedCalls(t *testing.T) {
+	store := new(memoryStore)
+	interceptor := UnaryServerInterceptor(store, map[string]string{"/api/Update": "product.update"})
+
+	handler := func(ctx context.Context, req any) (any, error) {
+		Record(ctx, "product", "p1", map[string]any{"price": 1}, map[string]any{"price": 2})
+		return nil, nil
+	}
+
+	if _, err := interceptor(context.Background(), nil, &grpc.UnaryServerInfo{FullMethod: "/api/List"}, handler); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := interceptor(context.Background(), nil, &grpc.UnaryServerInfo{FullMethod: "/api/Update"}, handler); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(store.events) != 1 {
+		t.Fatalf("expected one event, got %d", len(store.events))
+	}
+
+	event := store.events[0]
+	if event.Action != "product.update" || event.TargetType != "product" || event.TargetID != "p1" {
+		t.Errorf("unexpected event %+v", event)
+	}
+	if event.Before["price"] != float64(1) || event.After["price"] != float64(2) {
+		t.Errorf("unexpected diff %v -> %v", event.Before, event.After)
+	}
+}
+
+func TestInterceptorRecordsPersonalFieldsByNameOnly(t *testing.T) {
+	store := new(memoryStore)
+	interceptor := UnaryServerInterceptor(store, map[string]string{
+		"/api/UpdateUser":    "user.update",
+		"/api/UpdateProduct": "product.update",
+	})
+
+	updateUser := func(ctx context.Context, req any) (any, error) {
+		before := &domain.User{ID: "u1", Name: "Ada", Email: "ada@example.com"}
+		after := &domain.User{ID: "u1", Name: "Ada Lovelace", Email: "ada@example.com", IsAdmin: true}
+		Record(ctx, "user", "u1", before, after)
+		return nil, nil
+	}
+	updateProduct := func(ctx context.Context, req any) (any, error) {
+		Record(ctx, "product", "p1", &domain.Product{ID: "p1", Name: "Lamp"}, &domain.Product{ID: "p1", Name: "Desk lamp"})
+		return nil, nil
+	}
+
+	if _, err := interceptor(context.Background(), nil, &grpc.UnaryServerInfo{FullMethod: "/api/UpdateUser"}, updateUser); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := interceptor(context.Background(), nil, &grpc.UnaryServerInfo{FullMethod: "/api/UpdateProduct"}, updateProduct); err != nil {
+		t.Fatal(err)
+	}
+
+	user := store.events[0]
+	if user.Before["name"] != domain.PersonalAuditValue || user.After["name"] != domain.PersonalAuditValue {
+		t.Errorf("name was recorded: %v -> %v", user.Before["name"], user.After["name"])
+	}
+	if _, ok := user.After["email"]; ok {
+		t.Errorf("unchanged email was recorded: %v", user.After["email"])
+	}
+	if user.After["is_admin"] != true {
+		t.Errorf("expected is_admin in the diff, got %v", user.After)
+	}
+
+	product := store.events[1]
+	if product.After["name"] != "Desk lamp" {
+		t.Errorf("product name should be kept, got %v", product.After["name"])
+	}
+}
@@ -0,0 +1,95 @@
+package audit
+
+import (
+	"context"
+	"ecomm/internal/controller/auth"
+	"ecomm/internal/domain"
+	"ecomm/pkg"
+	"strings"
+
+	"google.golang.org/grpc"
+)
+
+// Store persists audit events.
+type Store interface {
+	CreateAuditEvent(event *domain.AuditEvent) error
+}
+
+// UnaryServerInterceptor writes an audit event for every successful call to
+// one of the methods in actions, which maps full method names to action names
+// such as "product.update". It must run after auth.UnaryPermissionInterceptor
+// so that the caller's claims are available.
+//
+// The change has already been committed when the event is written, so a
+// failure to write it is logged rather than returned to the caller.
+func UnaryServerInterceptor(store Store, actions map[string]string) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		action, ok := actions[info.FullMethod]
+		if !ok {
+			return handler(ctx, req)
+		}
+
+		entry := new(Entry)
+		resp, err := handler(newContext(ctx, entry), req)
+		if err != nil {
+			return resp, err
+		}
+
+		event, eventErr := newEvent(ctx, action, entry)
+		if eventErr == nil {
+			eventErr = store.CreateAuditEvent(event)
+		}
+		if eventErr != nil {
+			pkg.ErrorLogger.Printf("failed to write audit event for %s: %v", info.FullMethod, eventErr)
+		}
+
+		return resp, nil
+	}
+}
+
+func newEvent(ctx context.Context, action string, entry *Entry) (*domain.AuditEvent, error) {
+	entry.mu.Lock()
+	defer entry.mu.Unlock()
+
+	before, after, err := Diff(entry.before, entry.after)
+	if err != nil {
+		return nil, err
+	}
+
+	targetType := entry.targetType
+	if targetType == "" {
+		targetType, _, _ = strings.Cut(action, ".")
+	}
+	omitPersonal(targetType, before)
+	omitPersonal(targetType, after)
+
+	event := &domain.AuditEvent{
+		Action:     action,
+		TargetType: targetType,
+		TargetID:   entry.targetID,
+		Before:     before,
+		After:      after,
+		RequestID:  RequestID(ctx),
+		Metadata:   entry.metadata,
+	}
+
+	if claims := auth.ClaimsFromContext(ctx); claims != nil {
+		event.ActorID = claims.ID
+		if claims.Impersonating() {
+			event.ActorID = claims.Act.ID
+			setMetadata(event, "impersonated_user_id", claims.ID)
+		}
+		if claims.APIKeyID != "" {
+			setMetadata(event, "api_key_id", claims.APIKeyID)
+		}
+	}
+
+	return event, nil
+}
+
+func setMetadata(event *domain.AuditEvent, key string, value any) {
+	if event.Metadata == nil {
+		event.Metadata = make(map[string]any)
+	}
+	event.Metadata[key] = value
+}
@@ -0,0 +1,282 @@
+// Package catalog reads and writes product catalogs in the CSV and JSON
+// formats used for bulk import and export.
+package catalog
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+const (
+	FormatCSV  = "csv"
+	FormatJSON = "json"
+)
+
+// Row is one product of a catalog file. Products are matched by SKU and
+// reference their category by slug.
+type Row struct {
+	// Line is the line of a CSV file or the index of a JSON array element,
+	// both counting from 1.
+	Line            int     `json:"-"`
+	SKU             string  `json:"sku" validate:"required,max=64"`
+	Name            string  `json:"name" validate:"required,max=255"`
+	Category        string  `json:"category" validate:"required"`
+	Image           string  `json:"image" validate:"required"`
+	Description     string  `json:"description"`
+	Price           float64 `json:"price" validate:"gte=0"`
+	CountInStock    int     `json:"count_in_stock" validate:"gte=0"`
+	Rating          int     `json:"rating" validate:"gte=0,lte=5"`
+	NumberOfReviews int     `json:"number_of_reviews" validate:"gte=0"`
+}
+
+// RowError reports a row that could not be parsed.
+type RowError struct {
+	Line int
+	Err  error
+}
+
+// Columns are the CSV columns in the order they are exported.
+var Columns = []string{"sku", "name", "category", "image", "description", "price", "count_in_stock", "rating", "number_of_reviews"}
+
+var requiredColumns = []string{"sku", "name", "category", "image", "price", "count_in_stock"}
+
+// Decode parses a catalog file. Rows that cannot be parsed are returned as
+// RowErrors so that the rest of the file can still be processed; the error
+// is only set if the file as a whole is malformed.
+func Decode(format string, r io.Reader) ([]Row, []RowError, error) {
+	switch format {
+	case FormatCSV:
+		return decodeCSV(r)
+	case FormatJSON:
+		return decodeJSON(r)
+	default:
+		return nil, nil, fmt.Errorf("unsupported format %q", format)
+	}
+}
+
+func decodeCSV(r io.Reader) ([]Row, []RowError, error) {
+	reader := csv.NewReader(r)
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if err != nil {
+		if errors.Is(err, io.EOF) {
+			return nil, nil, errors.New("file is empty")
+		}
+		return nil, nil, err
+	}
+
+	// Spreadsheet programs like to prepend a byte order mark.
+	if len(header) > 0 {
+		header[0] = strings.TrimPrefix(header[0], "\ufeff")
+	}
+
+	index := make(map[string]int, len(header))
+	for i, column := range header {
+		column = strings.ToLower(strings.TrimSpace(column))
+		if !slices.Contains(Columns, column) {
+			return nil, nil, fmt.Errorf("unknown column %q", column)
+		}
+		if _, ok := index[column]; ok {
+			return nil, nil, fmt.Errorf("column %q appears twice", column)
+		}
+		index[column] = i
+	}
+	for _, column := range requiredColumns {
+		if _, ok := index[column]; !ok {
+			return nil, nil, fmt.Errorf("missing column %q", column)
+		}
+	}
+
+	var rows []Row
+	var rowErrors []RowError
+	for {
+		record, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			var parseErr *csv.ParseError
+			if errors.As(err, &parseErr) {
+				rowErrors = append(rowErrors, RowError{Line: parseErr.StartLine, Err: parseErr.Err})
+				continue
+			}
+			return nil, nil, err
+		}
+		// FieldPos panics unless the last Read succeeded.
+		line, _ := reader.FieldPos(0)
+
+		row, err := csvRow(index, record)
+		if err != nil {
+			rowErrors = append(rowErrors, RowError{Line: line, Err: err})
+			continue
+		}
+		row.Line = line
+		rows = append(rows, row)
+	}
+
+	return rows, rowErrors, nil
+}
+
+func csvRow(index map[string]int, record []string) (Row, error) {
+	field := func(column string) string {
+		if i, ok := index[column]; ok && i < len(record) {
+			return strings.TrimSpace(record[i])
+		}
+		return ""
+	}
+
+	var err error
+	number := func(column string) int {
+		value := field(column)
+		if value == "" || err != nil {
+			return 0
+		}
+		n, parseErr := strconv.Atoi(value)
+		if parseErr != nil {
+			err = fmt.Errorf("%s must be a whole number", column)
+		}
+		return n
+	}
+
+	row := Row{
+		SKU:             field("sku"),
+		Name:            field("name"),
+		Category:        field("category"),
+		Image:           field("image"),
+		Description:     field("description"),
+		CountInStock:    number("count_in_stock"),
+		Rating:          number("rating"),
+		NumberOfReviews: number("number_of_reviews"),
+	}
+
+	if price := field("price"); price != "" && err == nil {
+		if row.Price, err = strconv.ParseFloat(price, 64); err != nil {
+			err = errors.New("price must be a number")
+		}
+	}
+
+	return row, err
+}
+
+func decodeJSON(r io.Reader) ([]Row, []RowError, error) {
+	// Every element is decoded on its own so that one malformed product
+	// does not fail the whole file.
+	var elements []json.RawMessage
+	if err := json.NewDecoder(r).Decode(&elements); err != nil {
+		return nil, nil, fmt.Errorf("file must contain a JSON array of products: %w", err)
+	}
+
+	var rows []Row
+	var rowErrors []RowError
+	for i, element := range elements {
+		decoder := json.NewDecoder(bytes.NewReader(element))
+		decoder.DisallowUnknownFields()
+
+		var row Row
+		if err := decoder.Decode(&row); err != nil {
+			rowErrors = append(rowErrors, RowError{Line: i + 1, Err: err})
+			continue
+		}
+		row.Line = i + 1
+		rows = append(rows, row)
+	}
+
+	return rows, rowErrors, nil
+}
+
+// Encoder writes rows in one of the catalog formats.
+type Encoder interface {
+	Encode(row Row) error
+	// Close finishes the file. It does not close the underlying writer.
+	Close() error
+}
+
+func NewEncoder(format string, w io.Writer) (Encoder, error) {
+	switch format {
+	case FormatCSV:
+		return &csvEncoder{writer: csv.NewWriter(w)}, nil
+	case FormatJSON:
+		return &jsonEncoder{w: w}, nil
+	default:
+		return nil, fmt.Errorf("unsupported format %q", format)
+	}
+}
+
+type csvEncoder struct {
+	writer        *csv.Writer
+	headerWritten bool
+}
+
+func (e *csvEncoder) writeHeader() error {
+	if e.headerWritten {
+		return nil
+	}
+	e.headerWritten = true
+	return e.writer.Write(Columns)
+}
+
+func (e *csvEncoder) Encode(row Row) error {
+	if err := e.writeHeader(); err != nil {
+		return err
+	}
+
+	return e.writer.Write([]string{
+		row.SKU,
+		row.Name,
+		row.Category,
+		row.Image,
+		row.Description,
+		strconv.FormatFloat(row.Price, 'f', -1, 64),
+		strconv.Itoa(row.CountInStock),
+		strconv.Itoa(row.Rating),
+		strconv.Itoa(row.NumberOfReviews),
+	})
+}
+
+func (e *csvEncoder) Close() error {
+	if err := e.writeHeader(); err != nil {
+		return err
+	}
+	e.writer.Flush()
+	return e.writer.Error()
+}
+
+// jsonEncoder writes a JSON array one element at a time so that large
+// catalogs never have to be held in memory.
+type jsonEncoder struct {
+	w     io.Writer
+	count int
+}
+
+func (e *jsonEncoder) Encode(row Row) error {
+	data, err := json.Marshal(row)
+	if err != nil {
+		return err
+	}
+
+	separator := ",\n"
+	if e.count == 0 {
+		separator = "[\n"
+	}
+	e.count++
+
+	_, err = io.WriteString(e.w, separator+string(data))
+	return err
+}
+
+func (e *jsonEncoder) Close() error {
+	closing := "\n]\n"
+	if e.count == 0 {
+		closing = "[]\n"
+	}
+	_, err := io.WriteString(e.w, closing)
+	return err
+}
@@ -0,0 +1,112 @@
+package catalog
+
+import (
+	"bytes"
+	"encoding/csv"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestDecodeCSV(t *testing.T) {
+	input := "\ufeffName,SKU,category,image,price,count_in_stock\n" +
+		"T-shirt,TS-1,apparel,tshirt.jpg,19.99,10\n" +
+		"Mug,MUG-1,kitchen,mug.jpg,cheap,5\n" +
+		"Cap,CAP-1,apparel,cap.jpg,9.5,two\n"
+
+	rows, rowErrors, err := Decode(FormatCSV, strings.NewReader(input))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []Row{{Line: 2, SKU: "TS-1", Name: "T-shirt", Category: "apparel", Image: "tshirt.jpg", Price: 19.99, CountInStock: 10}}
+	if !reflect.DeepEqual(rows, want) {
+		t.Errorf("rows = %+v; want %+v", rows, want)
+	}
+
+	if len(rowErrors) != 2 || rowErrors[0].Line != 3 || rowErrors[1].Line != 4 {
+		t.Errorf("row errors = %+v; want lines 3 and 4", rowErrors)
+	}
+
+	if _, _, err := Decode(FormatCSV, strings.NewReader("sku,name\n")); err == nil {
+		t.Error("Decode() accepted a header without the required columns")
+	}
+}
+
+func TestDecodeCSVMalformedRows(t *testing.T) {
+	input := "sku,name,category,image,price,count_in_stock\n" +
+		"a\"b,x,c,i,1,1\n" +
+		"TS-1,T-shirt,apparel,tshirt.jpg,19.99\n" +
+		"MUG-1,Mug,kitchen,mug.jpg,5,3,extra\n" +
+		"CAP-1,Cap,apparel,cap.jpg,9.5,2\n"
+
+	rows, rowErrors, err := Decode(FormatCSV, strings.NewReader(input))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(rows) != 1 || rows[0].SKU != "CAP-1" || rows[0].Line != 5 {
+		t.Errorf("rows = %+v; want CAP-1 on line 5", rows)
+	}
+
+	wantErrs := []error{csv.ErrBareQuote, csv.ErrFieldCount, csv.ErrFieldCount}
+	if len(rowErrors) != len(wantErrs) {
+		t.Fatalf("row errors = %+v; want %d", rowErrors, len(wantErrs))
+	}
+	for i, want := range wantErrs {
+		if rowErrors[i].Line != i+2 || !errors.Is(rowErrors[i].Err, want) {
+			t.Errorf("row error %d = %+v; want %v on line %d", i, rowErrors[i], want, i+2)
+		}
+	}
+}
+
+func TestDecodeJSON(t *testing.T) {
+	input := `[{"sku": "TS-1", "name": "T-shirt", "price": 19.99}, {"sku": "MUG-1", "price": "cheap"}, {"colour": "red"}]`
+
+	rows, rowErrors, err := Decode(FormatJSON, strings.NewReader(input))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(rows) != 1 || rows[0].SKU != "TS-1" || rows[0].Line != 1 {
+		t.Errorf("rows = %+v; want the first element only", rows)
+	}
+	if len(rowErrors) != 2 || rowErrors[0].Line != 2 || rowErrors[1].Line != 3 {
+		t.Errorf("row errors = %+v; want elements 2 and 3", rowErrors)
+	}
+}
+
+func TestEncodeRoundTrip(t *testing.T) {
+	rows := []Row{
+		{SKU: "TS-1", Name: "T-shirt, red", Category: "apparel", Image: "tshirt.jpg", Description: "Soft\ncotton", Price: 19.99, CountInStock: 10, Rating: 4, NumberOfReviews: 12},
+		{SKU: "MUG-1", Name: "Mug", Category: "kitchen", Image: "mug.jpg", Price: 5, CountInStock: 3},
+	}
+
+	for _, format := range []string{FormatCSV, FormatJSON} {
+		var buf bytes.Buffer
+		encoder, err := NewEncoder(format, &buf)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, row := range rows {
+			if err := encoder.Encode(row); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if err := encoder.Close(); err != nil {
+			t.Fatal(err)
+		}
+
+		decoded, rowErrors, err := Decode(format, &buf)
+		if err != nil || len(rowErrors) != 0 {
+			t.Fatalf("%s: Decode() = %v, %v", format, rowErrors, err)
+		}
+		for i := range decoded {
+			decoded[i].Line = 0
+		}
+		if !reflect.DeepEqual(decoded, rows) {
+			t.Errorf("%s: round trip = %+v; want %+v", format, decoded, rows)
+		}
+	}
+}
@@ -0,0 +1,70 @@
+package controller
+
+import (
+	"ecomm/internal/adapters"
+	"ecomm/internal/domain"
+	"ecomm/proto"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+func (ph *Handler) ChangePassword(ctx *gin.Context) {
+	claims, err := ph.jwtManager.GetUserClaims(ctx)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	var request domain.ChangePasswordRequest
+	if err := ctx.ShouldBindJSON(&request); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	request.UserID = claims.ID
+	request.SessionID = claims.RegisteredClaims.ID
+	if _, err := ph.client.ChangePassword(outgoingContext(ctx), adapters.ToProtoChangePasswordRequest(&request)); err != nil {
+		ctx.JSON(httpStatus(err), gin.H{"error": errorMessage(err)})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"message": "Password changed successfully, other sessions have been signed out"})
+}
+
+func (ph *Handler) RequestEmailChange(ctx *gin.Context) {
+	claims, err := ph.jwtManager.GetUserClaims(ctx)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	var request domain.RequestEmailChangeRequest
+	if err := ctx.ShouldBindJSON(&request); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	request.UserID = claims.ID
+	if _, err := ph.client.RequestEmailChange(outgoingContext(ctx), adapters.ToProtoRequestEmailChangeRequest(&request)); err != nil {
+		ctx.JSON(httpStatus(err), gin.H{"error": errorMessage(err)})
+		return
+	}
+
+	ctx.JSON(http.StatusAccepted, gin.H{"message": "A confirmation link has been sent to the new email address"})
+}
+
+func (ph *Handler) ConfirmEmailChange(ctx *gin.Context) {
+	var request domain.ConfirmEmailChangeRequest
+	if err := ctx.ShouldBindJSON(&request); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if _, err := ph.client.ConfirmEmailChange(outgoingContext(ctx), &proto.ConfirmEmailChangeRequest{Token: request.Token}); err != nil {
+		ctx.JSON(httpStatus(err), gin.H{"error": errorMessage(err)})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"message": "Email changed successfully, please log in again"})
+}
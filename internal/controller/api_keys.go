@@ -0,0 +1,65 @@
+package controller
+
+import (
+	"context"
+	"ecomm/internal/adapters"
+	"ecomm/internal/controller/auth"
+	"ecomm/internal/domain"
+	"ecomm/proto"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ValidateAPIKey implements auth.APIKeyValidator by asking the gRPC server to
+// resolve the key.
+func (ph *Handler) ValidateAPIKey(ctx context.Context, key string) (*auth.Claims, error) {
+	response, err := ph.client.AuthenticateAPIKey(ctx, &proto.AuthenticateAPIKeyRequest{Key: key})
+	if err != nil {
+		return nil, err
+	}
+
+	claims := &auth.Claims{
+		Permissions: response.Scopes,
+		APIKeyID:    response.ApiKeyId,
+	}
+	claims.Subject = "apikey:" + response.ApiKeyId
+	return claims, nil
+}
+
+func (ph *Handler) CreateAPIKey(ctx *gin.Context) {
+	var request domain.CreateAPIKeyRequest
+	if err := ctx.ShouldBindJSON(&request); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	createRequest := adapters.ToProtoCreateAPIKeyRequest(&request)
+	response, err := ph.client.CreateAPIKey(outgoingContext(ctx), createRequest)
+	if err != nil {
+		ctx.JSON(httpStatus(err), gin.H{"error": errorMessage(err)})
+		return
+	}
+
+	ctx.JSON(http.StatusCreated, response)
+}
+
+func (ph *Handler) ListAPIKeys(ctx *gin.Context) {
+	keys, err := ph.client.ListAPIKeys(outgoingContext(ctx), &proto.ListAPIKeysRequest{})
+	if err != nil {
+		ctx.JSON(httpStatus(err), gin.H{"error": errorMessage(err)})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, keys)
+}
+
+func (ph *Handler) RevokeAPIKey(ctx *gin.Context) {
+	_, err := ph.client.RevokeAPIKey(outgoingContext(ctx), &proto.RevokeAPIKeyRequest{Id: ctx.Param("id")})
+	if err != nil {
+		ctx.JSON(httpStatus(err), gin.H{"error": errorMessage(err)})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"message": "API key revoked successfully"})
+}
@@ -0,0 +1,25 @@
+package controller
+
+import (
+	"ecomm/internal/adapters"
+	"ecomm/internal/domain"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+func (ph *Handler) ListAuditEvents(ctx *gin.Context) {
+	var filter domain.AuditEventFilter
+	if err := ctx.ShouldBindQuery(&filter); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	response, err := ph.client.ListAuditEvents(outgoingContext(ctx), adapters.ToProtoListAuditEventsRequest(&filter))
+	if err != nil {
+		ctx.JSON(httpStatus(err), gin.H{"error": errorMessage(err)})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"events": adapters.FromProtoAuditEvents(response.Events)})
+}
@@ -0,0 +1,95 @@
+package auth
+
+import (
+	"context"
+	"strings"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// UnaryPermissionInterceptor authenticates calls carrying a Bearer token in
+// the "authorization" metadata or an API key in the "x-api-key" metadata and
+// stores the claims in the call context. Tokens of users that accounts no
+// longer reports as active are rejected. Methods listed in permissions are
+// rejected unless the caller holds the mapped permission.
+func UnaryPermissionInterceptor(jwtManager *JWTManager, apiKeys APIKeyValidator, accounts AccountChecker, permissions map[string]string) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		claims, err := claimsFromMetadata(ctx, jwtManager, apiKeys)
+		if err != nil {
+			return nil, err
+		}
+
+		if claims != nil && claims.ID != "" && accounts != nil {
+			if err := accounts.CheckAccount(ctx, claims); err != nil {
+				return nil, err
+			}
+		}
+
+		permission, ok := permissions[info.FullMethod]
+		if ok {
+			if claims == nil {
+				return nil, status.Error(codes.Unauthenticated, "missing credentials")
+			}
+			if !claims.HasPermission(permission) {
+				return nil, status.Errorf(codes.PermissionDenied, "missing permission %s", permission)
+			}
+		}
+
+		if claims != nil {
+			ctx = ContextWithClaims(ctx, claims)
+		}
+
+		return handler(ctx, req)
+	}
+}
+
+// UnaryImpersonationInterceptor rejects calls to the blocked methods made with
+// an impersonation token. It must run after UnaryPermissionInterceptor.
+func UnaryImpersonationInterceptor(blocked map[string]bool) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		if claims := ClaimsFromContext(ctx); claims != nil && claims.Impersonating() && blocked[info.FullMethod] {
+			return nil, status.Error(codes.PermissionDenied, "not allowed while impersonating a user")
+		}
+
+		return handler(ctx, req)
+	}
+}
+
+func claimsFromMetadata(ctx context.Context, jwtManager *JWTManager, apiKeys APIKeyValidator) (*Claims, error) {
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		return nil, nil
+	}
+
+	if values := md.Get("x-api-key"); len(values) > 0 {
+		if apiKeys == nil {
+			return nil, status.Error(codes.Unauthenticated, "api keys are not accepted")
+		}
+
+		claims, err := apiKeys.ValidateAPIKey(ctx, values[0])
+		if err != nil {
+			return nil, status.Error(codes.Unauthenticated, "invalid api key")
+		}
+		return claims, nil
+	}
+
+	values := md.Get("authorization")
+	if len(values) == 0 {
+		return nil, nil
+	}
+
+	fields := strings.Fields(values[0])
+	if len(fields) != 2 || fields[0] != "Bearer" {
+		return nil, status.Error(codes.Unauthenticated, "invalid authorization metadata")
+	}
+
+	claims, err := jwtManager.ValidateToken(fields[1])
+	if err != nil {
+		return nil, status.Error(codes.Unauthenticated, err.Error())
+	}
+
+	return claims, nil
+}
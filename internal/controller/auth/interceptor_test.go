@@ -0,0 +1,78 @@
+package auth
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	protectedMethod = "/service.ApiService/Protected"
+	publicMethod    = "/service.ApiService/Public"
+)
+
+// revokingChecker withdraws every permission, as for a user whose roles were
+// removed after the token was issued.
+type revokingChecker struct{}
+
+func (revokingChecker) CheckAccount(ctx context.Context, claims *Claims) error {
+	claims.Permissions = nil
+	return nil
+}
+
+func callInterceptor(t *testing.T, accounts AccountChecker, method, token string) (*Claims, error) {
+	t.Helper()
+
+	jwtManager := &JWTManager{key: []byte("test-key")}
+	interceptor := UnaryPermissionInterceptor(jwtManager, nil, accounts, map[string]string{protectedMethod: "orders:read"})
+
+	ctx := context.Background()
+	if token != "" {
+		signed, _, err := jwtManager.GenerateToken("jane@example.com", "user-1", "session-1", false, []string{token}, time.Now().Add(time.Hour))
+		if err != nil {
+			t.Fatalf("GenerateToken() error = %v", err)
+		}
+		ctx = metadata.NewIncomingContext(ctx, metadata.Pairs("authorization", "Bearer "+signed))
+	}
+
+	var claims *Claims
+	_, err := interceptor(ctx, nil, &grpc.UnaryServerInfo{FullMethod: method}, func(ctx context.Context, req any) (any, error) {
+		claims = ClaimsFromContext(ctx)
+		return nil, nil
+	})
+	return claims, err
+}
+
+func TestUnaryPermissionInterceptor(t *testing.T) {
+	tests := []struct {
+		name       string
+		method     string
+		permission string
+		accounts   AccountChecker
+		want       codes.Code
+	}{
+		{"public without credentials", publicMethod, "", nil, codes.OK},
+		{"protected without credentials", protectedMethod, "", nil, codes.Unauthenticated},
+		{"missing permission", protectedMethod, "users:read", nil, codes.PermissionDenied},
+		{"granted permission", protectedMethod, "orders:read", nil, codes.OK},
+		{"wildcard permission", protectedMethod, "*", nil, codes.OK},
+		{"permission revoked since issued", protectedMethod, "orders:read", revokingChecker{}, codes.PermissionDenied},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			claims, err := callInterceptor(t, tt.accounts, tt.method, tt.permission)
+			if got := status.Code(err); got != tt.want {
+				t.Fatalf("code = %v; want %v", got, tt.want)
+			}
+			if err == nil && tt.permission != "" && claims == nil {
+				t.Fatal("claims missing from handler context")
+			}
+		})
+	}
+}
@@ -1,8 +1,11 @@
 package auth
 
 import (
+	"context"
+	"ecomm/internal/domain"
 	"fmt"
 	"os"
+	"slices"
 	"time"
 
 	"github.com/gin-gonic/gin"
@@ -10,12 +13,64 @@ import (
 )
 
 type Claims struct {
-	ID      string `json:"id"`
-	Email   string `json:"email"`
-	IsAdmin bool   `json:"is_admin"`
+	ID          string   `json:"id"`
+	Email       string   `json:"email"`
+	IsAdmin     bool     `json:"is_admin"`
+	Permissions []string `json:"permissions,omitempty"`
+	// APIKeyID is set instead of ID and Email when the caller authenticated
+	// with an API key rather than a user session.
+	APIKeyID string `json:"api_key_id,omitempty"`
+	// Act identifies the admin acting on behalf of the user when the token
+	// was issued through impersonation.
+	Act *Actor `json:"act,omitempty"`
 	jwt.RegisteredClaims
 }
 
+// Actor is the party acting on behalf of the token subject, as in the "act"
+// claim of RFC 8693.
+type Actor struct {
+	ID    string `json:"sub"`
+	Email string `json:"email"`
+}
+
+// Impersonating reports whether the token was issued to an admin acting as
+// another user.
+func (c *Claims) Impersonating() bool {
+	return c.Act != nil
+}
+
+// APIKeyValidator resolves an API key to the claims it grants.
+type APIKeyValidator interface {
+	ValidateAPIKey(ctx context.Context, key string) (*Claims, error)
+}
+
+// AccountChecker reports whether the user a token was issued to may still use
+// it. Access tokens outlive suspensions and demotions, so every authenticated
+// request is checked, and implementations may withdraw from claims the
+// privileges the user has lost since the token was issued.
+type AccountChecker interface {
+	CheckAccount(ctx context.Context, claims *Claims) error
+}
+
+// HasPermission reports whether the claims grant permission, either directly
+// or through the wildcard permission held by admins.
+func (c *Claims) HasPermission(permission string) bool {
+	return slices.Contains(c.Permissions, domain.PermissionAll) || slices.Contains(c.Permissions, permission)
+}
+
+type claimsKey struct{}
+
+func ContextWithClaims(ctx context.Context, claims *Claims) context.Context {
+	return context.WithValue(ctx, claimsKey{}, claims)
+}
+
+// ClaimsFromContext returns the claims stored by the gRPC interceptor, or nil
+// for unauthenticated calls.
+func ClaimsFromContext(ctx context.Context) *Claims {
+	claims, _ := ctx.Value(claimsKey{}).(*Claims)
+	return claims
+}
+
 type JWTManager struct {
 	key []byte
 }
@@ -29,11 +84,12 @@ func NewTokenGenerator() (*JWTManager, error) {
 	return &JWTManager{key: []byte(key)}, nil
 }
 
-func (t *JWTManager) GenerateToken(email, userID, sessionID string, isAdmin bool, expiresAt time.Time) (string, *Claims, error) {
+func (t *JWTManager) GenerateToken(email, userID, sessionID string, isAdmin bool, permissions []string, expiresAt time.Time) (string, *Claims, error) {
 	claims := Claims{
-		ID:      userID,
-		Email:   email,
-		IsAdmin: isAdmin,
+		ID:          userID,
+		Email:       email,
+		IsAdmin:     isAdmin,
+		Permissions: permissions,
 		RegisteredClaims: jwt.RegisteredClaims{
 			ID:        sessionID,
 			Issuer:    "ecomm",
@@ -63,13 +119,74 @@ func (t *JWTManager) ValidateToken(tokenString string) (*Claims, error) {
 		return nil, err
 	}
 
-	if claims, ok := token.Claims.(*Claims); ok && token.Valid {
+	// MFA challenge tokens are signed with the same key but must never be
+	// accepted as access or refresh tokens.
+	if claims, ok := token.Claims.(*Claims); ok && token.Valid && !slices.Contains(claims.Audience, mfaAudience) {
 		return claims, nil
 	}
 
 	return nil, fmt.Errorf("invalid token")
 }
 
+// GenerateImpersonationToken returns an access token for the user that
+// records actor as the party actually making the requests.
+func (t *JWTManager) GenerateImpersonationToken(email, userID, tokenID string, permissions []string, actor Actor, expiresAt time.Time) (string, *Claims, error) {
+	claims := Claims{
+		ID:          userID,
+		Email:       email,
+		Permissions: permissions,
+		Act:         &actor,
+		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        tokenID,
+			Issuer:    "ecomm",
+			Subject:   email,
+			IssuedAt:  jwt.NewNumericDate(time.Now()),
+			ExpiresAt: jwt.NewNumericDate(expiresAt),
+		},
+	}
+
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+	tokenString, err := token.SignedString(t.key)
+	if err != nil {
+		return "", nil, err
+	}
+
+	return tokenString, &claims, nil
+}
+
+const mfaAudience = "mfa"
+
+// GenerateMFAChallenge returns a short-lived token proving that the user
+// passed the password step of a login that still needs a second factor.
+func (t *JWTManager) GenerateMFAChallenge(userID string, expiresAt time.Time) (string, error) {
+	claims := jwt.RegisteredClaims{
+		Issuer:    "ecomm",
+		Subject:   userID,
+		Audience:  jwt.ClaimStrings{mfaAudience},
+		IssuedAt:  jwt.NewNumericDate(time.Now()),
+		ExpiresAt: jwt.NewNumericDate(expiresAt),
+	}
+
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+	return token.SignedString(t.key)
+}
+
+// ValidateMFAChallenge returns the ID of the user the challenge was issued to.
+func (t *JWTManager) ValidateMFAChallenge(tokenString string) (string, error) {
+	claims := new(jwt.RegisteredClaims)
+	_, err := jwt.ParseWithClaims(tokenString, claims, func(token *jwt.Token) (interface{}, error) {
+		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
+			return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
+		}
+		return t.key, nil
+	}, jwt.WithAudience(mfaAudience))
+	if err != nil {
+		return "", err
+	}
+
+	return claims.Subject, nil
+}
+
 func (t *JWTManager) GetUserClaims(ctx *gin.Context) (*Claims, error) {
 	claims := ctx.MustGet("claims").(*Claims)
 	return claims, nil
@@ -7,8 +7,29 @@ import (
 	"github.com/gin-gonic/gin"
 )
 
-func JWTAuthMiddleware(jwtManager *JWTManager) gin.HandlerFunc {
+// JWTAuthMiddleware authenticates requests with a Bearer token. If apiKeys is
+// not nil, requests may authenticate with an X-API-Key header instead. Whether
+// the user's account is still active is checked once, by the gRPC server.
+func JWTAuthMiddleware(jwtManager *JWTManager, apiKeys APIKeyValidator) gin.HandlerFunc {
 	return func(ctx *gin.Context) {
+		if apiKey := ctx.GetHeader("X-API-Key"); apiKey != "" {
+			if apiKeys == nil {
+				ctx.JSON(http.StatusUnauthorized, gin.H{"error": "API keys are not accepted on this route"})
+				ctx.Abort()
+				return
+			}
+
+			claims, err := apiKeys.ValidateAPIKey(ctx.Request.Context(), apiKey)
+			if err != nil {
+				ctx.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid API key"})
+				ctx.Abort()
+				return
+			}
+
+			ctx.Set("claims", claims)
+			return
+		}
+
 		authHeader := ctx.GetHeader("Authorization")
 		if authHeader == "" {
 			ctx.JSON(http.StatusUnauthorized, gin.H{"error": "Missing Authorization header"})
@@ -35,6 +56,8 @@ func JWTAuthMiddleware(jwtManager *JWTManager) gin.HandlerFunc {
 	}
 }
 
+// JWTAdminMiddleware only admits tokens issued to admins. The gRPC server
+// withdraws the admin rights of users demoted since the token was issued.
 func JWTAdminMiddleware(jwtManager *JWTManager) gin.HandlerFunc {
 	return func(ctx *gin.Context) {
 		authHeader := ctx.GetHeader("Authorization")
@@ -68,3 +91,24 @@ func JWTAdminMiddleware(jwtManager *JWTManager) gin.HandlerFunc {
 		ctx.Set("claims", claims)
 	}
 }
+
+// RequirePermission aborts the request unless the claims set by
+// JWTAuthMiddleware grant the given permission. It must be registered after
+// JWTAuthMiddleware.
+func RequirePermission(permission string) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		value, ok := ctx.Get("claims")
+		if !ok {
+			ctx.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+			ctx.Abort()
+			return
+		}
+
+		claims := value.(*Claims)
+		if !claims.HasPermission(permission) {
+			ctx.JSON(http.StatusForbidden, gin.H{"error": "Missing permission " + permission})
+			ctx.Abort()
+			return
+		}
+	}
+}
@@ -0,0 +1,45 @@
+package auth
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestRequirePermission(t *testing.T) {
+	tests := []struct {
+		name   string
+		claims *Claims
+		want   int
+	}{
+		{"no claims", nil, http.StatusUnauthorized},
+		{"missing permission", &Claims{Permissions: []string{"users:read"}}, http.StatusForbidden},
+		{"granted permission", &Claims{Permissions: []string{"orders:read"}}, http.StatusOK},
+		{"wildcard permission", &Claims{Permissions: []string{"*"}}, http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			recorder := httptest.NewRecorder()
+			ctx, _ := gin.CreateTestContext(recorder)
+			ctx.Request = httptest.NewRequest(http.MethodGet, "/", nil)
+			if tt.claims != nil {
+				ctx.Set("claims", tt.claims)
+			}
+
+			RequirePermission("orders:read")(ctx)
+
+			if tt.want == http.StatusOK {
+				if ctx.IsAborted() {
+					t.Fatalf("aborted with %d; want passed through", recorder.Code)
+				}
+				return
+			}
+			if !ctx.IsAborted() || recorder.Code != tt.want {
+				t.Fatalf("status = %d, aborted = %v; want %d", recorder.Code, ctx.IsAborted(), tt.want)
+			}
+		})
+	}
+}
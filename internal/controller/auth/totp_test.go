@@ -0,0 +1,60 @@
+package auth
+
+import (
+	"encoding/base32"
+	"strings"
+	"testing"
+	"time"
+)
+
+// rfc6238Secret is the SHA1 test key from RFC 6238, appendix B.
+var rfc6238Secret = base32.StdEncoding.WithPadding(base32.NoPadding).EncodeToString([]byte("12345678901234567890"))
+
+func TestValidateTOTP(t *testing.T) {
+	tests := []struct {
+		unix int64
+		code string
+	}{
+		{59, "287082"},
+		{1111111109, "081804"},
+		{1111111111, "050471"},
+		{1234567890, "005924"},
+		{2000000000, "279037"},
+	}
+
+	for _, tt := range tests {
+		step, ok := ValidateTOTP(rfc6238Secret, tt.code, time.Unix(tt.unix, 0))
+		if !ok {
+			t.Errorf("ValidateTOTP(%d, %s) = false, want true", tt.unix, tt.code)
+			continue
+		}
+		if want := tt.unix / totpPeriod; step != want {
+			t.Errorf("ValidateTOTP(%d, %s) step = %d, want %d", tt.unix, tt.code, step, want)
+		}
+	}
+}
+
+func TestValidateTOTPSkew(t *testing.T) {
+	now := time.Unix(1111111111, 0)
+
+	if _, ok := ValidateTOTP(rfc6238Secret, "050471", now.Add(totpPeriod*time.Second)); !ok {
+		t.Error("code from the previous period was rejected")
+	}
+	if _, ok := ValidateTOTP(rfc6238Secret, "050471", now.Add(3*totpPeriod*time.Second)); ok {
+		t.Error("code from three periods ago was accepted")
+	}
+	if _, ok := ValidateTOTP(rfc6238Secret, "000000", now); ok {
+		t.Error("wrong code was accepted")
+	}
+}
+
+func TestTOTPURI(t *testing.T) {
+	uri := TOTPURI("ecomm", "jane@example.com", "JBSWY3DPEHPK3PXP")
+
+	if !strings.HasPrefix(uri, "otpauth://totp/ecomm:jane@example.com?") {
+		t.Errorf("unexpected URI prefix: %s", uri)
+	}
+	if !strings.Contains(uri, "secret=JBSWY3DPEHPK3PXP") {
+		t.Errorf("URI does not contain the secret: %s", uri)
+	}
+}
@@ -0,0 +1,199 @@
+package controller
+
+import (
+	"ecomm/internal/catalog"
+	"ecomm/proto"
+	"errors"
+	"io"
+	"net/http"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// maxImportUploadBytes bounds the multipart body of catalog imports. The
+// service enforces the exact limit on the file itself.
+const maxImportUploadBytes = 6 << 20
+
+func (ph *Handler) ImportProducts(ctx *gin.Context) {
+	ctx.Request.Body = http.MaxBytesReader(ctx.Writer, ctx.Request.Body, maxImportUploadBytes)
+
+	fileHeader, err := ctx.FormFile("file")
+	if err != nil {
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			ctx.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": "upload is too large"})
+			return
+		}
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "a catalog file is required in the \"file\" field"})
+		return
+	}
+
+	// The format defaults to the file extension.
+	format := ctx.Query("format")
+	if format == "" {
+		format = strings.TrimPrefix(strings.ToLower(filepath.Ext(fileHeader.Filename)), ".")
+	}
+
+	file, err := fileHeader.Open()
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	defer file.Close()
+
+	data, err := io.ReadAll(file)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	result, err := ph.client.ImportProducts(outgoingContext(ctx), &proto.ImportProductsRequest{
+		Format: format,
+		Data:   data,
+		DryRun: ctx.Query("dry_run") == "true",
+	})
+	if err != nil {
+		ctx.JSON(httpStatus(err), gin.H{"error": errorMessage(err)})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, result)
+}
+
+// ExportProducts streams the whole catalog as a file, fetching it from the
+// service one page at a time.
+func (ph *Handler) ExportProducts(ctx *gin.Context) {
+	format := ctx.DefaultQuery("format", catalog.FormatCSV)
+	if format != catalog.FormatCSV && format != catalog.FormatJSON {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "format must be csv or json"})
+		return
+	}
+
+	categories, err := ph.client.ListCategories(outgoingContext(ctx), &proto.ListCategoriesRequest{})
+	if err != nil {
+		ctx.JSON(httpStatus(err), gin.H{"error": errorMessage(err)})
+		return
+	}
+
+	slugs := make(map[string]string)
+	var collectSlugs func([]*proto.Category)
+	collectSlugs = func(categories []*proto.Category) {
+		for _, category := range categories {
+			slugs[category.Id] = category.Slug
+			collectSlugs(category.Children)
+		}
+	}
+	collectSlugs(categories.Categories)
+
+	// The first page is fetched before anything is written so that errors
+	// can still be reported with a status code.
+	page, err := ph.client.ExportProducts(outgoingContext(ctx), &proto.ExportProductsRequest{})
+	if err != nil {
+		ctx.JSON(httpStatus(err), gin.H{"error": errorMessage(err)})
+		return
+	}
+
+	encoder, err := catalog.NewEncoder(format, ctx.Writer)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	contentType := "text/csv"
+	if format == catalog.FormatJSON {
+		contentType = "application/json"
+	}
+	ctx.Header("Content-Type", contentType)
+	ctx.Header("Content-Disposition", `attachment; filename="products.`+format+`"`)
+	ctx.Header("Trailer", exportSkippedTrailer+", "+exportErrorTrailer)
+	ctx.Status(http.StatusOK)
+
+	skipped := 0
+	for {
+		for _, product := range page.Products {
+			// Imports require a SKU, so products without one could not be
+			// imported back. They are left out and counted instead.
+			if product.Sku == "" {
+				skipped++
+				continue
+			}
+
+			// Imports set the regular price, which is the compare-at
+			// price while a sale runs.
+			price := product.Price
+			if product.CompareAtPrice != 0 {
+				price = product.CompareAtPrice
+			}
+
+			row := catalog.Row{
+				SKU:             product.Sku,
+				Name:            product.Name,
+				Category:        slugs[product.CategoryId],
+				Image:           product.Image,
+				Description:     product.Description,
+				Price:           price,
+				CountInStock:    int(product.CountInStock),
+				Rating:          int(product.Rating),
+				NumberOfReviews: int(product.NumberOfReviews),
+			}
+			if err := encoder.Encode(row); err != nil {
+				ctx.Error(err)
+				return
+			}
+		}
+		ctx.Writer.Flush()
+
+		if page.NextAfterId == "" {
+			break
+		}
+
+		page, err = ph.client.ExportProducts(outgoingContext(ctx), &proto.ExportProductsRequest{
+			AfterId: page.NextAfterId,
+		})
+		if err != nil {
+			abortExport(ctx, err)
+			return
+		}
+	}
+
+	if err := encoder.Close(); err != nil {
+		ctx.Error(err)
+		return
+	}
+	if skipped > 0 {
+		ctx.Writer.Header().Set(exportSkippedTrailer, strconv.Itoa(skipped))
+	}
+}
+
+const (
+	// exportSkippedTrailer counts the products an export left out because
+	// they have no SKU.
+	exportSkippedTrailer = "X-Skipped-Products"
+	// exportErrorTrailer reports why an export stopped early when the
+	// connection cannot be cut.
+	exportErrorTrailer = "X-Export-Error"
+)
+
+// abortExport ends an export that failed after the response started. The
+// status code has been sent, so the connection is closed before the response
+// ends, which clients see as a truncated body rather than a complete file.
+// Where the connection cannot be taken over, as with HTTP/2, the error is sent
+// in a trailer.
+func abortExport(ctx *gin.Context, err error) {
+	ctx.Error(err)
+
+	// gin's own Hijack panics when the connection does not support it, so
+	// the underlying writer is asked instead.
+	var writer http.ResponseWriter = ctx.Writer
+	if unwrapper, ok := writer.(interface{ Unwrap() http.ResponseWriter }); ok {
+		writer = unwrapper.Unwrap()
+	}
+	if conn, _, hijackErr := http.NewResponseController(writer).Hijack(); hijackErr == nil {
+		conn.Close()
+		return
+	}
+	ctx.Writer.Header().Set(exportErrorTrailer, errorMessage(err))
+}
@@ -0,0 +1,130 @@
+package controller
+
+import (
+	"context"
+	"ecomm/proto"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// fakeExportClient serves the catalog in pages and fails the page after the
+// last one it holds unless that page ends the export. Calling any other
+// method panics on the nil embedded interface.
+type fakeExportClient struct {
+	proto.ApiServiceClient
+	pages [][]*proto.Product
+	// complete tells whether the last page ends the export.
+	complete bool
+}
+
+func (c *fakeExportClient) ListCategories(ctx context.Context, in *proto.ListCategoriesRequest, opts ...grpc.CallOption) (*proto.ListCategoriesResponse, error) {
+	return &proto.ListCategoriesResponse{Categories: []*proto.Category{{Id: "category-1", Slug: "mugs"}}}, nil
+}
+
+func (c *fakeExportClient) ExportProducts(ctx context.Context, in *proto.ExportProductsRequest, opts ...grpc.CallOption) (*proto.ExportProductsResponse, error) {
+	page := 0
+	if in.AfterId != "" {
+		previous, _ := strconv.Atoi(strings.TrimPrefix(in.AfterId, "page-"))
+		page = previous + 1
+	}
+	if page >= len(c.pages) {
+		return nil, status.Error(codes.Unavailable, "connection reset")
+	}
+
+	res := &proto.ExportProductsResponse{Products: c.pages[page]}
+	if page < len(c.pages)-1 || !c.complete {
+		res.NextAfterId = "page-" + strconv.Itoa(page)
+	}
+	return res, nil
+}
+
+func exportedProduct(sku string) *proto.Product {
+	return &proto.Product{Id: "product-" + sku, Sku: sku, Name: "Mug", CategoryId: "category-1", Image: "mug.png"}
+}
+
+func exportServer(t *testing.T, client proto.ApiServiceClient) *httptest.Server {
+	t.Helper()
+
+	engine := gin.New()
+	engine.GET("/products/export", (&Handler{client: client}).ExportProducts)
+	server := httptest.NewServer(engine)
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestExportProductsSkipsProductsWithoutSKU(t *testing.T) {
+	client := &fakeExportClient{
+		pages: [][]*proto.Product{
+			{exportedProduct("MUG-1"), exportedProduct("")},
+			{exportedProduct("MUG-2")},
+		},
+		complete: true,
+	}
+
+	res, err := http.Get(exportServer(t, client).URL + "/products/export")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer res.Body.Close()
+
+	body, err := io.ReadAll(res.Body)
+	if err != nil {
+		t.Fatalf("reading the export: %v", err)
+	}
+	if lines := strings.Split(strings.TrimSpace(string(body)), "\n"); len(lines) != 3 ||
+		!strings.HasPrefix(lines[1], "MUG-1,") || !strings.HasPrefix(lines[2], "MUG-2,") {
+		t.Fatalf("export = %q; want a header and MUG-1 and MUG-2", body)
+	}
+	if skipped := res.Trailer.Get(exportSkippedTrailer); skipped != "1" {
+		t.Fatalf("%s = %q; want 1", exportSkippedTrailer, skipped)
+	}
+	if message := res.Trailer.Get(exportErrorTrailer); message != "" {
+		t.Fatalf("%s = %q; want none", exportErrorTrailer, message)
+	}
+}
+
+func TestExportProductsCutsTheConnectionWhenAPageFails(t *testing.T) {
+	client := &fakeExportClient{pages: [][]*proto.Product{{exportedProduct("MUG-1")}}}
+
+	res, err := http.Get(exportServer(t, client).URL + "/products/export")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		t.Fatalf("status = %d; want 200 from the first page", res.StatusCode)
+	}
+	body, err := io.ReadAll(res.Body)
+	if !errors.Is(err, io.ErrUnexpectedEOF) {
+		t.Fatalf("reading the export = %q, %v; want an unexpected EOF", body, err)
+	}
+}
+
+func TestExportProductsReportsFailedPagesInATrailer(t *testing.T) {
+	client := &fakeExportClient{pages: [][]*proto.Product{{exportedProduct("MUG-1")}}}
+
+	// The recorder cannot be hijacked, as with HTTP/2.
+	recorder := httptest.NewRecorder()
+	ctx, _ := gin.CreateTestContext(recorder)
+	ctx.Request = httptest.NewRequest(http.MethodGet, "/products/export", nil)
+
+	(&Handler{client: client}).ExportProducts(ctx)
+
+	if message := recorder.Result().Trailer.Get(exportErrorTrailer); message != "connection reset" {
+		t.Fatalf("%s = %q; want the page error", exportErrorTrailer, message)
+	}
+	if len(ctx.Errors) != 1 {
+		t.Fatalf("recorded errors = %v; want the page error", ctx.Errors)
+	}
+}
@@ -0,0 +1,63 @@
+package controller
+
+import (
+	"ecomm/internal/adapters"
+	"ecomm/internal/domain"
+	"ecomm/proto"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+func (ph *Handler) ListCategories(ctx *gin.Context) {
+	categories, err := ph.client.ListCategories(outgoingContext(ctx), &proto.ListCategoriesRequest{})
+	if err != nil {
+		ctx.JSON(httpStatus(err), gin.H{"error": errorMessage(err)})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, categories)
+}
+
+func (ph *Handler) CreateCategory(ctx *gin.Context) {
+	var request domain.CreateCategoryRequest
+	if err := ctx.ShouldBindJSON(&request); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	category, err := ph.client.CreateCategory(outgoingContext(ctx), adapters.ToProtoCreateCategoryRequest(&request))
+	if err != nil {
+		ctx.JSON(httpStatus(err), gin.H{"error": errorMessage(err)})
+		return
+	}
+
+	ctx.JSON(http.StatusCreated, category)
+}
+
+func (ph *Handler) UpdateCategory(ctx *gin.Context) {
+	var request domain.UpdateCategoryRequest
+	if err := ctx.ShouldBindJSON(&request); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	request.ID = ctx.Param("id")
+	category, err := ph.client.UpdateCategory(outgoingContext(ctx), adapters.ToProtoUpdateCategoryRequest(&request))
+	if err != nil {
+		ctx.JSON(httpStatus(err), gin.H{"error": errorMessage(err)})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, category)
+}
+
+func (ph *Handler) DeleteCategory(ctx *gin.Context) {
+	_, err := ph.client.DeleteCategory(outgoingContext(ctx), &proto.DeleteCategoryRequest{Id: ctx.Param("id")})
+	if err != nil {
+		ctx.JSON(httpStatus(err), gin.H{"error": errorMessage(err)})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"message": "Category deleted successfully"})
+}
@@ -0,0 +1,75 @@
+package controller
+
+import (
+	"ecomm/internal/domain"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// setETag exposes the version of the resource in the response as a strong
+// entity tag.
+func setETag(ctx *gin.Context, version int64) {
+	ctx.Header("ETag", `"`+strconv.FormatInt(version, 10)+`"`)
+}
+
+// ifMatchVersion returns the version named by the If-Match header of the
+// request, or 0 when the header is absent or "*". Errors are gRPC status
+// errors, so that they are reported like the errors of the RPC the version
+// is passed to.
+func ifMatchVersion(ctx *gin.Context) (int64, error) {
+	header := strings.TrimSpace(ctx.GetHeader("If-Match"))
+	if header == "" || header == "*" {
+		return 0, nil
+	}
+
+	// If-Match compares entity tags strongly, so a weak tag never matches.
+	if strings.HasPrefix(header, "W/") {
+		return 0, errWeakIfMatch
+	}
+
+	tag, ok := strings.CutPrefix(header, `"`)
+	if ok {
+		tag, ok = strings.CutSuffix(tag, `"`)
+	}
+	version, err := strconv.ParseInt(tag, 10, 64)
+	if !ok || err != nil || version <= 0 {
+		return 0, status.Error(codes.InvalidArgument, "If-Match must be * or a single entity tag")
+	}
+	return version, nil
+}
+
+// errWeakIfMatch fails the precondition of requests whose If-Match names a
+// weak entity tag.
+var errWeakIfMatch = status.Error(codes.Aborted, "If-Match does not match weak entity tags")
+
+// conditionalStatus is httpStatus for requests that may carry If-Match: a
+// version mismatch fails the precondition, so it is reported as 412 rather
+// than as the 409 of other conflicts.
+func conditionalStatus(err error) int {
+	if isVersionMismatch(err) || errors.Is(err, errWeakIfMatch) {
+		return http.StatusPreconditionFailed
+	}
+	return httpStatus(err)
+}
+
+// isVersionMismatch reports whether err is an Aborted status error whose
+// ErrorInfo detail names domain.VersionMismatchReason.
+func isVersionMismatch(err error) bool {
+	st, ok := status.FromError(err)
+	if !ok || st.Code() != codes.Aborted {
+		return false
+	}
+	for _, detail := range st.Details() {
+		if info, ok := detail.(*errdetails.ErrorInfo); ok && info.Reason == domain.VersionMismatchReason {
+			return true
+		}
+	}
+	return false
+}
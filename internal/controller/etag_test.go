@@ -0,0 +1,90 @@
+package controller
+
+import (
+	"ecomm/internal/domain"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestIfMatchVersion(t *testing.T) {
+	tests := []struct {
+		header  string
+		version int64
+		code    codes.Code
+	}{
+		{header: "", version: 0, code: codes.OK},
+		{header: "*", version: 0, code: codes.OK},
+		{header: `"7"`, version: 7, code: codes.OK},
+		{header: `W/"7"`, code: codes.Aborted},
+		{header: `"7", "8"`, code: codes.InvalidArgument},
+		{header: `"abc"`, code: codes.InvalidArgument},
+		{header: "7", code: codes.InvalidArgument},
+		{header: `"0"`, code: codes.InvalidArgument},
+	}
+
+	for _, tt := range tests {
+		ctx, _ := gin.CreateTestContext(httptest.NewRecorder())
+		ctx.Request = httptest.NewRequest(http.MethodPut, "/", nil)
+		if tt.header != "" {
+			ctx.Request.Header.Set("If-Match", tt.header)
+		}
+
+		version, err := ifMatchVersion(ctx)
+		if code := status.Code(err); code != tt.code {
+			t.Errorf("ifMatchVersion(%q) code = %v; want %v", tt.header, code, tt.code)
+			continue
+		}
+		if version != tt.version {
+			t.Errorf("ifMatchVersion(%q) = %d; want %d", tt.header, version, tt.version)
+		}
+	}
+}
+
+func TestConditionalStatus(t *testing.T) {
+	// The service reports stale versions this way.
+	stale, err := status.New(codes.Aborted, "stale").WithDetails(&errdetails.ErrorInfo{Reason: domain.VersionMismatchReason})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name string
+		err  error
+		code int
+	}{
+		{"version mismatch", stale.Err(), http.StatusPreconditionFailed},
+		{"other abort", status.Error(codes.Aborted, "aborted"), http.StatusConflict},
+		{"failed precondition", status.Error(codes.FailedPrecondition, "product is not archived"), http.StatusConflict},
+		{"not found", status.Error(codes.NotFound, "product not found"), http.StatusNotFound},
+		{"no status", errors.New("boom"), http.StatusInternalServerError},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if code := conditionalStatus(tt.err); code != tt.code {
+				t.Errorf("conditionalStatus() = %d; want %d", code, tt.code)
+			}
+			if code := httpStatus(tt.err); code == http.StatusPreconditionFailed {
+				t.Errorf("httpStatus() = %d; want 412 only for conditional requests", code)
+			}
+		})
+	}
+}
+
+func TestWeakIfMatchFailsThePrecondition(t *testing.T) {
+	ctx, _ := gin.CreateTestContext(httptest.NewRecorder())
+	ctx.Request = httptest.NewRequest(http.MethodPut, "/", nil)
+	ctx.Request.Header.Set("If-Match", `W/"7"`)
+
+	_, err := ifMatchVersion(ctx)
+	if code := conditionalStatus(err); code != http.StatusPreconditionFailed {
+		t.Fatalf("conditionalStatus() = %d; want 412", code)
+	}
+}
@@ -0,0 +1,32 @@
+package controller
+
+import (
+	"ecomm/proto"
+	"fmt"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ExportUserData returns the caller's personal data archive, or that of the
+// user in the path for staff routes.
+func (ph *Handler) ExportUserData(ctx *gin.Context) {
+	userID := ctx.Param("id")
+	if userID == "" {
+		claims, err := ph.jwtManager.GetUserClaims(ctx)
+		if err != nil {
+			ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		userID = claims.ID
+	}
+
+	response, err := ph.client.ExportUserData(outgoingContext(ctx), &proto.ExportUserDataRequest{UserId: userID})
+	if err != nil {
+		ctx.JSON(httpStatus(err), gin.H{"error": errorMessage(err)})
+		return
+	}
+
+	ctx.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="user-%s.json"`, userID))
+	ctx.Data(http.StatusOK, "application/json", response.Archive)
+}
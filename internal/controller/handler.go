@@ -1,7 +1,6 @@
 package controller
 
 import (
-	"context"
 	"ecomm/internal/adapters"
 	"ecomm/internal/controller/auth"
 	"ecomm/internal/domain"
@@ -9,6 +8,7 @@ import (
 	"net/http"
 
 	"github.com/gin-gonic/gin"
+	"google.golang.org/protobuf/types/known/fieldmaskpb"
 )
 
 type Handler struct {
@@ -37,9 +37,9 @@ func (ph *Handler) CreateProduct(ctx *gin.Context) {
 	}
 
 	createRequest := adapters.ToProtoCreateProductRequest(&request)
-	createdProduct, err := ph.client.CreateProduct(context.Background(), createRequest)
+	createdProduct, err := ph.client.CreateProduct(outgoingContext(ctx), createRequest)
 	if err != nil {
-		ctx.JSON(500, gin.H{"error": err.Error()})
+		ctx.JSON(httpStatus(err), gin.H{"error": errorMessage(err)})
 		return
 	}
 
@@ -48,24 +48,26 @@ func (ph *Handler) CreateProduct(ctx *gin.Context) {
 
 func (ph *Handler) GetProductByID(ctx *gin.Context) {
 	id := ctx.Param("id")
-	product, err := ph.client.GetProductByID(context.Background(), &proto.GetProductByIDRequest{Id: id})
+	product, err := ph.client.GetProductByID(outgoingContext(ctx), &proto.GetProductByIDRequest{Id: id})
 	if err != nil {
-		ctx.JSON(500, gin.H{"error": err.Error()})
-		return
-	}
-
-	if product == nil {
-		ctx.JSON(404, gin.H{"error": "Product not found"})
+		ctx.JSON(httpStatus(err), gin.H{"error": errorMessage(err)})
 		return
 	}
 
+	setETag(ctx, product.Product.GetVersion())
 	ctx.JSON(200, product)
 }
 
 func (ph *Handler) ListProducts(ctx *gin.Context) {
-	products, err := ph.client.ListProducts(context.Background(), &proto.ListProductsRequest{})
+	var request domain.ListProductsRequest
+	if err := ctx.ShouldBindQuery(&request); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	products, err := ph.client.ListProducts(outgoingContext(ctx), adapters.ToProtoListProductsRequest(&request))
 	if err != nil {
-		ctx.JSON(500, gin.H{"error": err.Error()})
+		ctx.JSON(httpStatus(err), gin.H{"error": errorMessage(err)})
 		return
 	}
 
@@ -85,26 +87,99 @@ func (ph *Handler) UpdateProduct(ctx *gin.Context) {
 		return
 	}
 
+	version, err := ifMatchVersion(ctx)
+	if err != nil {
+		ctx.JSON(conditionalStatus(err), gin.H{"error": errorMessage(err)})
+		return
+	}
+
 	request.ID = productID
 	updateRequest := adapters.ToProtoUpdateProductRequest(request)
-	_, err := ph.client.UpdateProduct(context.Background(), updateRequest)
+	updateRequest.ExpectedVersion = version
+	response, err := ph.client.UpdateProduct(outgoingContext(ctx), updateRequest)
 	if err != nil {
-		ctx.JSON(500, gin.H{"error": err.Error()})
+		ctx.JSON(conditionalStatus(err), gin.H{"error": errorMessage(err)})
 		return
 	}
 
+	setETag(ctx, response.Product.GetVersion())
 	ctx.JSON(200, gin.H{"message": "Product updated successfully"})
 }
 
+// PatchProduct applies a JSON Merge Patch to a product. Only the fields in the
+// body change, so they can be set to zero values, and a null sku or
+// description clears it.
+func (ph *Handler) PatchProduct(ctx *gin.Context) {
+	if !isMergePatch(ctx) {
+		ctx.JSON(http.StatusUnsupportedMediaType, gin.H{"error": "content type must be " + mergePatchContentType})
+		return
+	}
+
+	var request domain.UpdateProductRequest
+	paths, err := bindMergePatch(ctx, &request, "sku", "description")
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	version, err := ifMatchVersion(ctx)
+	if err != nil {
+		ctx.JSON(conditionalStatus(err), gin.H{"error": errorMessage(err)})
+		return
+	}
+
+	request.ID = ctx.Param("id")
+	updateRequest := adapters.ToProtoUpdateProductRequest(&request)
+	updateRequest.UpdateMask = &fieldmaskpb.FieldMask{Paths: paths}
+	updateRequest.ExpectedVersion = version
+	response, err := ph.client.UpdateProduct(outgoingContext(ctx), updateRequest)
+	if err != nil {
+		ctx.JSON(conditionalStatus(err), gin.H{"error": errorMessage(err)})
+		return
+	}
+
+	setETag(ctx, response.Product.GetVersion())
+	ctx.JSON(http.StatusOK, response.Product)
+}
+
 func (ph *Handler) DeleteProduct(ctx *gin.Context) {
 	id := ctx.Param("id")
-	_, err := ph.client.DeleteProduct(context.Background(), &proto.DeleteProductRequest{Id: id})
+	version, err := ifMatchVersion(ctx)
 	if err != nil {
-		ctx.JSON(500, gin.H{"error": err.Error()})
+		ctx.JSON(conditionalStatus(err), gin.H{"error": errorMessage(err)})
 		return
 	}
 
-	ctx.JSON(200, gin.H{"message": "Product deleted successfully"})
+	_, err = ph.client.DeleteProduct(outgoingContext(ctx), &proto.DeleteProductRequest{
+		Id:              id,
+		ExpectedVersion: version,
+	})
+	if err != nil {
+		ctx.JSON(conditionalStatus(err), gin.H{"error": errorMessage(err)})
+		return
+	}
+
+	ctx.JSON(200, gin.H{"message": "Product archived successfully"})
+}
+
+func (ph *Handler) RestoreProduct(ctx *gin.Context) {
+	version, err := ifMatchVersion(ctx)
+	if err != nil {
+		ctx.JSON(conditionalStatus(err), gin.H{"error": errorMessage(err)})
+		return
+	}
+
+	response, err := ph.client.RestoreProduct(outgoingContext(ctx), &proto.RestoreProductRequest{
+		Id:              ctx.Param("id"),
+		ExpectedVersion: version,
+	})
+	if err != nil {
+		ctx.JSON(conditionalStatus(err), gin.H{"error": errorMessage(err)})
+		return
+	}
+
+	setETag(ctx, response.Product.GetVersion())
+	ctx.JSON(http.StatusOK, response)
 }
 
 func (ph *Handler) CreateOrder(ctx *gin.Context) {
@@ -127,7 +202,7 @@ func (ph *Handler) CreateOrder(ctx *gin.Context) {
 
 	request.UserID = claims.ID
 	createRequest := adapters.ToProtoCreateOrderRequest(&request)
-	order, err := ph.client.CreateOrder(context.Background(), createRequest)
+	order, err := ph.client.CreateOrder(outgoingContext(ctx), createRequest)
 	if err != nil {
 		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
@@ -148,7 +223,7 @@ func (ph *Handler) GetOrder(ctx *gin.Context) {
 		return
 	}
 
-	order, err := ph.client.GetOrder(context.Background(), &proto.GetOrderRequest{UserId: claims.ID})
+	order, err := ph.client.GetOrder(outgoingContext(ctx), &proto.GetOrderRequest{UserId: claims.ID})
 	if err != nil {
 		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
@@ -159,11 +234,12 @@ func (ph *Handler) GetOrder(ctx *gin.Context) {
 		return
 	}
 
+	setETag(ctx, order.Order.GetVersion())
 	ctx.JSON(200, order)
 }
 
 func (ph *Handler) ListOrders(ctx *gin.Context) {
-	orders, err := ph.client.ListOrders(context.Background(), &proto.ListOrdersRequest{})
+	orders, err := ph.client.ListOrders(outgoingContext(ctx), &proto.ListOrdersRequest{})
 	if err != nil {
 		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
@@ -174,9 +250,18 @@ func (ph *Handler) ListOrders(ctx *gin.Context) {
 
 func (ph *Handler) DeleteOrder(ctx *gin.Context) {
 	id := ctx.Param("id")
-	_, err := ph.client.DeleteOrder(context.Background(), &proto.DeleteOrderRequest{Id: id})
+	version, err := ifMatchVersion(ctx)
 	if err != nil {
-		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		ctx.JSON(conditionalStatus(err), gin.H{"error": errorMessage(err)})
+		return
+	}
+
+	_, err = ph.client.DeleteOrder(outgoingContext(ctx), &proto.DeleteOrderRequest{
+		Id:              id,
+		ExpectedVersion: version,
+	})
+	if err != nil {
+		ctx.JSON(conditionalStatus(err), gin.H{"error": errorMessage(err)})
 		return
 	}
 
@@ -191,8 +276,13 @@ func (ph *Handler) CreateUser(ctx *gin.Context) {
 		return
 	}
 
+	if request.IsAdmin != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": domain.ErrPrivilegeFieldNotAllowed.Error()})
+		return
+	}
+
 	createRequest := adapters.ToProtoCreateUserRequest(&request)
-	user, err := ph.client.CreateUser(context.Background(), createRequest)
+	user, err := ph.client.CreateUser(outgoingContext(ctx), createRequest)
 	if err != nil {
 		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
@@ -202,9 +292,15 @@ func (ph *Handler) CreateUser(ctx *gin.Context) {
 }
 
 func (ph *Handler) ListUsers(ctx *gin.Context) {
-	users, err := ph.client.ListUsers(context.Background(), &proto.ListUsersRequest{})
+	var request domain.ListUsersRequest
+	if err := ctx.ShouldBindQuery(&request); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	users, err := ph.client.ListUsers(outgoingContext(ctx), adapters.ToProtoListUsersRequest(&request))
 	if err != nil {
-		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		ctx.JSON(httpStatus(err), gin.H{"error": errorMessage(err)})
 		return
 	}
 
@@ -229,35 +325,95 @@ func (ph *Handler) UpdateUser(ctx *gin.Context) {
 		return
 	}
 
+	if request.IsAdmin != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": domain.ErrPrivilegeFieldNotAllowed.Error()})
+		return
+	}
+
+	if request.Password != nil || request.Email != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "use /account/password or /account/email to change credentials"})
+		return
+	}
+
+	version, err := ifMatchVersion(ctx)
+	if err != nil {
+		ctx.JSON(conditionalStatus(err), gin.H{"error": errorMessage(err)})
+		return
+	}
+
 	request.ID = claims.ID
 	updateRequest := adapters.ToProtoUpdateUserRequest(&request)
-	_, err = ph.client.UpdateUser(context.Background(), updateRequest)
+	updateRequest.ExpectedVersion = version
+	response, err := ph.client.UpdateUser(outgoingContext(ctx), updateRequest)
 	if err != nil {
-		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		ctx.JSON(conditionalStatus(err), gin.H{"error": errorMessage(err)})
 		return
 	}
 
+	setETag(ctx, response.User.GetVersion())
+
 	ctx.JSON(http.StatusOK, gin.H{"message": "User updated successfully"})
 }
 
-func (ph *Handler) DeleteUser(ctx *gin.Context) {
-	claims, err := ph.jwtManager.GetUserClaims(ctx)
+func (ph *Handler) SetUserAdmin(ctx *gin.Context) {
+	userID := ctx.Param("id")
+	if userID == "" {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "User ID is required"})
+		return
+	}
+
+	var request domain.SetUserAdminRequest
+	if err := ctx.ShouldBindJSON(&request); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	request.UserID = userID
+	setAdminRequest := adapters.ToProtoSetUserAdminRequest(&request)
+	response, err := ph.client.SetUserAdmin(outgoingContext(ctx), setAdminRequest)
 	if err != nil {
-		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		ctx.JSON(httpStatus(err), gin.H{"error": errorMessage(err)})
 		return
 	}
 
-	if claims == nil {
-		ctx.JSON(http.StatusUnauthorized, gin.H{"error": "claims not found"})
+	ctx.JSON(http.StatusOK, response)
+}
+
+func (ph *Handler) UnlockAccount(ctx *gin.Context) {
+	_, err := ph.client.UnlockAccount(outgoingContext(ctx), &proto.UnlockAccountRequest{UserId: ctx.Param("id")})
+	if err != nil {
+		ctx.JSON(httpStatus(err), gin.H{"error": errorMessage(err)})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"message": "Account unlocked successfully"})
+}
+
+// DeleteUser erases the user in the path, or the caller on the self-service
+// route.
+func (ph *Handler) DeleteUser(ctx *gin.Context) {
+	userID := ctx.Param("id")
+	if userID == "" {
+		claims, err := ph.jwtManager.GetUserClaims(ctx)
+		if err != nil {
+			ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		userID = claims.ID
+	}
+
+	version, err := ifMatchVersion(ctx)
+	if err != nil {
+		ctx.JSON(conditionalStatus(err), gin.H{"error": errorMessage(err)})
 		return
 	}
 
-	_, err = ph.client.DeleteUser(context.Background(), &proto.DeleteUserRequest{
-		UserId:    claims.ID,
-		SessionId: claims.RegisteredClaims.ID,
+	_, err = ph.client.DeleteUser(outgoingContext(ctx), &proto.DeleteUserRequest{
+		UserId:          userID,
+		ExpectedVersion: version,
 	})
 	if err != nil {
-		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		ctx.JSON(conditionalStatus(err), gin.H{"error": errorMessage(err)})
 		return
 	}
 
@@ -272,10 +428,11 @@ func (ph *Handler) Login(ctx *gin.Context) {
 		return
 	}
 
+	request.ClientIP = ctx.ClientIP()
 	loginRequest := adapters.ToProtoLoginUserRequest(&request)
-	loginResponse, err := ph.client.Login(context.Background(), loginRequest)
+	loginResponse, err := ph.client.Login(outgoingContext(ctx), loginRequest)
 	if err != nil {
-		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		ctx.JSON(httpStatus(err), gin.H{"error": errorMessage(err)})
 		return
 	}
 
@@ -294,7 +451,7 @@ func (ph *Handler) Logout(ctx *gin.Context) {
 		return
 	}
 
-	_, err = ph.client.Logout(context.Background(), &proto.LogoutRequest{SessionId: claims.RegisteredClaims.ID})
+	_, err = ph.client.Logout(outgoingContext(ctx), &proto.LogoutRequest{SessionId: claims.RegisteredClaims.ID})
 	if err != nil {
 		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
@@ -324,7 +481,7 @@ func (ph *Handler) RefreshAccessToken(ctx *gin.Context) {
 
 	request.SessionID = claims.RegisteredClaims.ID
 	refreshRequest := adapters.ToProtoRefreshTokenRequest(&request)
-	token, err := ph.client.RefreshToken(context.Background(), refreshRequest)
+	token, err := ph.client.RefreshToken(outgoingContext(ctx), refreshRequest)
 	if err != nil {
 		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
@@ -345,7 +502,7 @@ func (ph *Handler) RevokeSession(ctx *gin.Context) {
 		return
 	}
 
-	_, err = ph.client.RevokeSession(context.Background(), &proto.RevokeSessionRequest{SessionId: claims.RegisteredClaims.ID})
+	_, err = ph.client.RevokeSession(outgoingContext(ctx), &proto.RevokeSessionRequest{SessionId: claims.RegisteredClaims.ID})
 	if err != nil {
 		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
@@ -353,3 +510,20 @@ func (ph *Handler) RevokeSession(ctx *gin.Context) {
 
 	ctx.JSON(http.StatusOK, gin.H{"message": "Session revoked successfully"})
 }
+
+func (ph *Handler) ImpersonateUser(ctx *gin.Context) {
+	var request domain.ImpersonateUserRequest
+	if err := ctx.ShouldBindJSON(&request); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	request.UserID = ctx.Param("id")
+	response, err := ph.client.ImpersonateUser(outgoingContext(ctx), adapters.ToProtoImpersonateUserRequest(&request))
+	if err != nil {
+		ctx.JSON(httpStatus(err), gin.H{"error": errorMessage(err)})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, response)
+}
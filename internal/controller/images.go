@@ -0,0 +1,88 @@
+package controller
+
+import (
+	"ecomm/internal/domain"
+	"ecomm/proto"
+	"errors"
+	"io"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// maxUploadBytes bounds the multipart body of image uploads. The service
+// enforces the exact limit on the image itself.
+const maxUploadBytes = 6 << 20
+
+func (ph *Handler) UploadProductImage(ctx *gin.Context) {
+	ctx.Request.Body = http.MaxBytesReader(ctx.Writer, ctx.Request.Body, maxUploadBytes)
+
+	fileHeader, err := ctx.FormFile("image")
+	if err != nil {
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			ctx.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": "upload is too large"})
+			return
+		}
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "an image file is required in the \"image\" field"})
+		return
+	}
+
+	file, err := fileHeader.Open()
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	defer file.Close()
+
+	data, err := io.ReadAll(file)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	image, err := ph.client.UploadProductImage(outgoingContext(ctx), &proto.UploadProductImageRequest{
+		ProductId:   ctx.Param("id"),
+		Data:        data,
+		ContentType: fileHeader.Header.Get("Content-Type"),
+		AltText:     ctx.PostForm("alt_text"),
+	})
+	if err != nil {
+		ctx.JSON(httpStatus(err), gin.H{"error": errorMessage(err)})
+		return
+	}
+
+	ctx.JSON(http.StatusCreated, image)
+}
+
+func (ph *Handler) DeleteProductImage(ctx *gin.Context) {
+	_, err := ph.client.DeleteProductImage(outgoingContext(ctx), &proto.DeleteProductImageRequest{
+		Id:        ctx.Param("imageId"),
+		ProductId: ctx.Param("id"),
+	})
+	if err != nil {
+		ctx.JSON(httpStatus(err), gin.H{"error": errorMessage(err)})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"message": "Image deleted successfully"})
+}
+
+func (ph *Handler) ReorderProductImages(ctx *gin.Context) {
+	var request domain.ReorderProductImagesRequest
+	if err := ctx.ShouldBindJSON(&request); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	images, err := ph.client.ReorderProductImages(outgoingContext(ctx), &proto.ReorderProductImagesRequest{
+		ProductId: ctx.Param("id"),
+		ImageIds:  request.ImageIDs,
+	})
+	if err != nil {
+		ctx.JSON(httpStatus(err), gin.H{"error": errorMessage(err)})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, images)
+}
@@ -0,0 +1,85 @@
+package controller
+
+import (
+	"ecomm/internal/adapters"
+	"ecomm/internal/domain"
+	"ecomm/proto"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+func (ph *Handler) AdjustStock(ctx *gin.Context) {
+	var request domain.AdjustStockRequest
+	if err := ctx.ShouldBindJSON(&request); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	request.ProductID = ctx.Param("id")
+	movement, err := ph.client.AdjustStock(outgoingContext(ctx), adapters.ToProtoAdjustStockRequest(&request))
+	if err != nil {
+		ctx.JSON(httpStatus(err), gin.H{"error": errorMessage(err)})
+		return
+	}
+
+	ctx.JSON(http.StatusCreated, movement)
+}
+
+func (ph *Handler) ListStockMovements(ctx *gin.Context) {
+	var filter domain.InventoryMovementFilter
+	if err := ctx.ShouldBindQuery(&filter); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	filter.ProductID = ctx.Param("id")
+	movements, err := ph.client.ListStockMovements(outgoingContext(ctx), adapters.ToProtoListStockMovementsRequest(&filter))
+	if err != nil {
+		ctx.JSON(httpStatus(err), gin.H{"error": errorMessage(err)})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, movements)
+}
+
+func (ph *Handler) ReconcileStock(ctx *gin.Context) {
+	result, err := ph.client.ReconcileStock(outgoingContext(ctx), &proto.ReconcileStockRequest{
+		Apply: ctx.Query("apply") == "true",
+	})
+	if err != nil {
+		ctx.JSON(httpStatus(err), gin.H{"error": errorMessage(err)})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, result)
+}
+
+func (ph *Handler) TransferStock(ctx *gin.Context) {
+	var request domain.TransferStockRequest
+	if err := ctx.ShouldBindJSON(&request); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	request.ProductID = ctx.Param("id")
+	movements, err := ph.client.TransferStock(outgoingContext(ctx), adapters.ToProtoTransferStockRequest(&request))
+	if err != nil {
+		ctx.JSON(httpStatus(err), gin.H{"error": errorMessage(err)})
+		return
+	}
+
+	ctx.JSON(http.StatusCreated, movements)
+}
+
+func (ph *Handler) GetProductStock(ctx *gin.Context) {
+	stock, err := ph.client.GetProductStock(outgoingContext(ctx), &proto.GetProductStockRequest{
+		ProductId: ctx.Param("id"),
+	})
+	if err != nil {
+		ctx.JSON(httpStatus(err), gin.H{"error": errorMessage(err)})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, stock)
+}
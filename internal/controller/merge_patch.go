@@ -0,0 +1,66 @@
+package controller
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"mime"
+	"slices"
+	"sort"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+)
+
+const mergePatchContentType = "application/merge-patch+json"
+
+var errNotAnObject = errors.New("request body must be a JSON object")
+
+// bindMergePatch decodes a JSON Merge Patch (RFC 7396) of a flat object into
+// target and returns the names of the fields it mentions, which serve as the
+// update mask. Absent fields are left alone. null resets a field to its zero
+// value, which is only allowed for the fields listed in clearable.
+func bindMergePatch(ctx *gin.Context, target any, clearable ...string) ([]string, error) {
+	body, err := ctx.GetRawData()
+	if err != nil {
+		return nil, err
+	}
+
+	var patch map[string]json.RawMessage
+	if err := json.Unmarshal(body, &patch); err != nil || patch == nil {
+		return nil, errNotAnObject
+	}
+
+	fields := make([]string, 0, len(patch))
+	for field, value := range patch {
+		fields = append(fields, field)
+		if bytes.Equal(bytes.TrimSpace(value), []byte("null")) {
+			if !slices.Contains(clearable, field) {
+				return nil, fmt.Errorf("%s cannot be null", field)
+			}
+			delete(patch, field)
+		}
+	}
+	sort.Strings(fields)
+
+	values, err := json.Marshal(patch)
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(values, target); err != nil {
+		return nil, err
+	}
+	if err := binding.Validator.ValidateStruct(target); err != nil {
+		return nil, err
+	}
+
+	return fields, nil
+}
+
+// isMergePatch reports whether the request body is declared as a JSON Merge
+// Patch. Plain JSON is accepted too.
+func isMergePatch(ctx *gin.Context) bool {
+	mediaType, _, err := mime.ParseMediaType(ctx.GetHeader("Content-Type"))
+	return err == nil && (mediaType == mergePatchContentType || mediaType == binding.MIMEJSON)
+}
@@ -0,0 +1,49 @@
+package controller
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+type patchTarget struct {
+	Name        string  `json:"name"`
+	Description string  `json:"description"`
+	Price       float64 `json:"price" binding:"gte=0"`
+}
+
+func bindTestPatch(t *testing.T, body string) (patchTarget, []string, error) {
+	t.Helper()
+
+	ctx, _ := gin.CreateTestContext(httptest.NewRecorder())
+	ctx.Request = httptest.NewRequest(http.MethodPatch, "/", strings.NewReader(body))
+
+	target := patchTarget{Name: "unchanged"}
+	fields, err := bindMergePatch(ctx, &target, "description")
+	return target, fields, err
+}
+
+func TestBindMergePatch(t *testing.T) {
+	target, fields, err := bindTestPatch(t, `{"price": 0, "description": null}`)
+	if err != nil {
+		t.Fatalf("bindMergePatch() error = %v", err)
+	}
+	if want := []string{"description", "price"}; !reflect.DeepEqual(fields, want) {
+		t.Fatalf("fields = %v; want %v", fields, want)
+	}
+	if target.Name != "unchanged" || target.Price != 0 || target.Description != "" {
+		t.Fatalf("target = %+v", target)
+	}
+}
+
+func TestBindMergePatchRejects(t *testing.T) {
+	for _, body := range []string{`{"name": null}`, `[]`, `null`, `{"price": -1}`} {
+		if _, _, err := bindTestPatch(t, body); err == nil {
+			t.Errorf("bindMergePatch(%s) succeeded; want an error", body)
+		}
+	}
+}
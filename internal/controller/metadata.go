@@ -0,0 +1,46 @@
+package controller
+
+import (
+	"context"
+	"ecomm/internal/audit"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"google.golang.org/grpc/metadata"
+)
+
+const requestIDHeader = "X-Request-ID"
+
+// requestID makes sure every request has an ID that is echoed back to the
+// client and recorded with any audit events the request causes.
+func requestID() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		id := ctx.GetHeader(requestIDHeader)
+		if id == "" || len(id) > 128 {
+			id = uuid.NewString()
+		}
+
+		ctx.Set("request_id", id)
+		ctx.Header(requestIDHeader, id)
+		ctx.Next()
+	}
+}
+
+// outgoingContext forwards the caller's credentials to the gRPC server so
+// that it can enforce permissions on its own.
+func outgoingContext(ctx *gin.Context) context.Context {
+	outgoing := ctx.Request.Context()
+	if id := ctx.GetString("request_id"); id != "" {
+		outgoing = metadata.AppendToOutgoingContext(outgoing, audit.RequestIDKey, id)
+	}
+
+	if apiKey := ctx.GetHeader("X-API-Key"); apiKey != "" {
+		return metadata.AppendToOutgoingContext(outgoing, "x-api-key", apiKey)
+	}
+
+	authHeader := ctx.GetHeader("Authorization")
+	if authHeader == "" {
+		return outgoing
+	}
+	return metadata.AppendToOutgoingContext(outgoing, "authorization", authHeader)
+}
@@ -0,0 +1,95 @@
+package controller
+
+import (
+	"ecomm/internal/domain"
+	"ecomm/proto"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+func (ph *Handler) VerifyMFA(ctx *gin.Context) {
+	var request domain.VerifyMFARequest
+	if err := ctx.ShouldBindJSON(&request); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	loginResponse, err := ph.client.VerifyMFA(outgoingContext(ctx), &proto.VerifyMFARequest{
+		MfaToken: request.MFAToken,
+		Code:     request.Code,
+		ClientIp: ctx.ClientIP(),
+	})
+	if err != nil {
+		ctx.JSON(httpStatus(err), gin.H{"error": errorMessage(err)})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"response": loginResponse})
+}
+
+func (ph *Handler) EnrollMFA(ctx *gin.Context) {
+	claims, err := ph.jwtManager.GetUserClaims(ctx)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	response, err := ph.client.EnrollMFA(outgoingContext(ctx), &proto.EnrollMFARequest{UserId: claims.ID})
+	if err != nil {
+		ctx.JSON(httpStatus(err), gin.H{"error": errorMessage(err)})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, response)
+}
+
+func (ph *Handler) ConfirmMFA(ctx *gin.Context) {
+	claims, err := ph.jwtManager.GetUserClaims(ctx)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	var request domain.MFACodeRequest
+	if err := ctx.ShouldBindJSON(&request); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	response, err := ph.client.ConfirmMFA(outgoingContext(ctx), &proto.ConfirmMFARequest{
+		UserId: claims.ID,
+		Code:   request.Code,
+	})
+	if err != nil {
+		ctx.JSON(httpStatus(err), gin.H{"error": errorMessage(err)})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, response)
+}
+
+func (ph *Handler) DisableMFA(ctx *gin.Context) {
+	claims, err := ph.jwtManager.GetUserClaims(ctx)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	var request domain.MFACodeRequest
+	if err := ctx.ShouldBindJSON(&request); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	_, err = ph.client.DisableMFA(outgoingContext(ctx), &proto.DisableMFARequest{
+		UserId: claims.ID,
+		Code:   request.Code,
+	})
+	if err != nil {
+		ctx.JSON(httpStatus(err), gin.H{"error": errorMessage(err)})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"message": "Multi-factor authentication disabled"})
+}
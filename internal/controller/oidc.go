@@ -0,0 +1,70 @@
+package controller
+
+import (
+	"crypto/subtle"
+	"ecomm/proto"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// The state of a login is kept in a cookie so that only the browser that
+// started the login can complete it. Otherwise an attacker could send a
+// victim the callback URL of their own login and sign the victim in as the
+// attacker.
+const (
+	oidcStateCookie     = "oidc_state"
+	oidcStateCookiePath = "/auth/oidc"
+	// oidcStateCookieMaxAge matches the lifetime of the login state.
+	oidcStateCookieMaxAge = 10 * 60
+)
+
+func (ph *Handler) BeginOIDCLogin(ctx *gin.Context) {
+	response, err := ph.client.BeginOIDCLogin(outgoingContext(ctx), &proto.BeginOIDCLoginRequest{
+		Provider: ctx.Param("provider"),
+	})
+	if err != nil {
+		ctx.JSON(httpStatus(err), gin.H{"error": errorMessage(err)})
+		return
+	}
+
+	// Lax lets the cookie through on the top-level redirect back from the
+	// provider.
+	ctx.SetSameSite(http.SameSiteLaxMode)
+	ctx.SetCookie(oidcStateCookie, response.State, oidcStateCookieMaxAge, oidcStateCookiePath, "", true, true)
+	ctx.Redirect(http.StatusFound, response.AuthorizationUrl)
+}
+
+func (ph *Handler) CompleteOIDCLogin(ctx *gin.Context) {
+	if errorCode := ctx.Query("error"); errorCode != "" {
+		ctx.JSON(http.StatusUnauthorized, gin.H{"error": errorCode, "error_description": ctx.Query("error_description")})
+		return
+	}
+
+	code := ctx.Query("code")
+	state := ctx.Query("state")
+	if code == "" || state == "" {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "code and state are required"})
+		return
+	}
+
+	cookie, err := ctx.Cookie(oidcStateCookie)
+	if err != nil || subtle.ConstantTimeCompare([]byte(cookie), []byte(state)) != 1 {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "login was not started in this browser"})
+		return
+	}
+	ctx.SetSameSite(http.SameSiteLaxMode)
+	ctx.SetCookie(oidcStateCookie, "", -1, oidcStateCookiePath, "", true, true)
+
+	loginResponse, err := ph.client.CompleteOIDCLogin(outgoingContext(ctx), &proto.CompleteOIDCLoginRequest{
+		Provider: ctx.Param("provider"),
+		Code:     code,
+		State:    state,
+	})
+	if err != nil {
+		ctx.JSON(httpStatus(err), gin.H{"error": errorMessage(err)})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"response": loginResponse})
+}
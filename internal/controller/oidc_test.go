@@ -0,0 +1,77 @@
+package controller
+
+import (
+	"context"
+	"ecomm/proto"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"google.golang.org/grpc"
+)
+
+// fakeOIDCClient implements the OIDC calls of the API client. Calling any
+// other method panics on the nil embedded interface.
+type fakeOIDCClient struct {
+	proto.ApiServiceClient
+	completed bool
+}
+
+func (c *fakeOIDCClient) BeginOIDCLogin(ctx context.Context, in *proto.BeginOIDCLoginRequest, opts ...grpc.CallOption) (*proto.BeginOIDCLoginResponse, error) {
+	return &proto.BeginOIDCLoginResponse{AuthorizationUrl: "https://idp.example.com/authorize", State: "state-1"}, nil
+}
+
+func (c *fakeOIDCClient) CompleteOIDCLogin(ctx context.Context, in *proto.CompleteOIDCLoginRequest, opts ...grpc.CallOption) (*proto.LoginResponse, error) {
+	c.completed = true
+	return &proto.LoginResponse{}, nil
+}
+
+func TestBeginOIDCLoginSetsStateCookie(t *testing.T) {
+	recorder := httptest.NewRecorder()
+	ctx, _ := gin.CreateTestContext(recorder)
+	ctx.Request = httptest.NewRequest(http.MethodGet, "/auth/oidc/fake/login", nil)
+
+	(&Handler{client: &fakeOIDCClient{}}).BeginOIDCLogin(ctx)
+
+	cookies := recorder.Result().Cookies()
+	if len(cookies) != 1 || cookies[0].Name != oidcStateCookie || cookies[0].Value != "state-1" {
+		t.Fatalf("cookies = %v; want the login state", cookies)
+	}
+	if !cookies[0].HttpOnly || !cookies[0].Secure {
+		t.Fatalf("cookie = %+v; want HttpOnly and Secure", cookies[0])
+	}
+}
+
+func TestCompleteOIDCLoginRequiresStateCookie(t *testing.T) {
+	tests := []struct {
+		name   string
+		cookie string
+		want   int
+	}{
+		{"no cookie", "", http.StatusBadRequest},
+		{"other login", "state-2", http.StatusBadRequest},
+		{"same browser", "state-1", http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			recorder := httptest.NewRecorder()
+			ctx, _ := gin.CreateTestContext(recorder)
+			ctx.Request = httptest.NewRequest(http.MethodGet, "/auth/oidc/fake/callback?code=code-1&state=state-1", nil)
+			if tt.cookie != "" {
+				ctx.Request.AddCookie(&http.Cookie{Name: oidcStateCookie, Value: tt.cookie})
+			}
+
+			client := &fakeOIDCClient{}
+			(&Handler{client: client}).CompleteOIDCLogin(ctx)
+
+			if recorder.Code != tt.want {
+				t.Fatalf("status = %d; want %d", recorder.Code, tt.want)
+			}
+			if client.completed != (tt.want == http.StatusOK) {
+				t.Fatalf("login completed = %v", client.completed)
+			}
+		})
+	}
+}
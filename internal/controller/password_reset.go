@@ -0,0 +1,41 @@
+package controller
+
+import (
+	"ecomm/internal/adapters"
+	"ecomm/internal/domain"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+func (ph *Handler) RequestPasswordReset(ctx *gin.Context) {
+	var request domain.RequestPasswordResetRequest
+	if err := ctx.ShouldBindJSON(&request); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	resetRequest := adapters.ToProtoRequestPasswordResetRequest(&request)
+	if _, err := ph.client.RequestPasswordReset(outgoingContext(ctx), resetRequest); err != nil {
+		ctx.JSON(httpStatus(err), gin.H{"error": errorMessage(err)})
+		return
+	}
+
+	ctx.JSON(http.StatusAccepted, gin.H{"message": "If an account exists for this email, a password reset link has been sent"})
+}
+
+func (ph *Handler) ResetPassword(ctx *gin.Context) {
+	var request domain.ResetPasswordRequest
+	if err := ctx.ShouldBindJSON(&request); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	resetRequest := adapters.ToProtoResetPasswordRequest(&request)
+	if _, err := ph.client.ResetPassword(outgoingContext(ctx), resetRequest); err != nil {
+		ctx.JSON(httpStatus(err), gin.H{"error": errorMessage(err)})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"message": "Password reset successfully"})
+}
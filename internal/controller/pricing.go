@@ -0,0 +1,114 @@
+package controller
+
+import (
+	"ecomm/internal/adapters"
+	"ecomm/internal/domain"
+	"ecomm/proto"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+func (ph *Handler) SetProductSale(ctx *gin.Context) {
+	var request domain.SetProductSaleRequest
+	if err := ctx.ShouldBindJSON(&request); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	version, err := ifMatchVersion(ctx)
+	if err != nil {
+		ctx.JSON(conditionalStatus(err), gin.H{"error": errorMessage(err)})
+		return
+	}
+
+	request.ProductID = ctx.Param("id")
+	request.ExpectedVersion = version
+	response, err := ph.client.SetProductSale(outgoingContext(ctx), adapters.ToProtoSetProductSaleRequest(&request))
+	if err != nil {
+		ctx.JSON(conditionalStatus(err), gin.H{"error": errorMessage(err)})
+		return
+	}
+
+	setETag(ctx, response.Product.GetVersion())
+	ctx.JSON(http.StatusOK, response)
+}
+
+func (ph *Handler) ClearProductSale(ctx *gin.Context) {
+	version, err := ifMatchVersion(ctx)
+	if err != nil {
+		ctx.JSON(conditionalStatus(err), gin.H{"error": errorMessage(err)})
+		return
+	}
+
+	response, err := ph.client.ClearProductSale(outgoingContext(ctx), &proto.ClearProductSaleRequest{
+		ProductId:       ctx.Param("id"),
+		ExpectedVersion: version,
+	})
+	if err != nil {
+		ctx.JSON(conditionalStatus(err), gin.H{"error": errorMessage(err)})
+		return
+	}
+
+	setETag(ctx, response.Product.GetVersion())
+	ctx.JSON(http.StatusOK, response)
+}
+
+func (ph *Handler) ListPriceHistory(ctx *gin.Context) {
+	var filter domain.PriceHistoryFilter
+	if err := ctx.ShouldBindQuery(&filter); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	filter.ProductID = ctx.Param("id")
+	history, err := ph.client.ListPriceHistory(outgoingContext(ctx), adapters.ToProtoListPriceHistoryRequest(&filter))
+	if err != nil {
+		ctx.JSON(httpStatus(err), gin.H{"error": errorMessage(err)})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, history)
+}
+
+func (ph *Handler) SchedulePriceChange(ctx *gin.Context) {
+	var request domain.SchedulePriceChangeRequest
+	if err := ctx.ShouldBindJSON(&request); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	request.ProductID = ctx.Param("id")
+	change, err := ph.client.SchedulePriceChange(outgoingContext(ctx), adapters.ToProtoSchedulePriceChangeRequest(&request))
+	if err != nil {
+		ctx.JSON(httpStatus(err), gin.H{"error": errorMessage(err)})
+		return
+	}
+
+	ctx.JSON(http.StatusCreated, change)
+}
+
+func (ph *Handler) ListScheduledPriceChanges(ctx *gin.Context) {
+	changes, err := ph.client.ListScheduledPriceChanges(outgoingContext(ctx), &proto.ListScheduledPriceChangesRequest{
+		ProductId: ctx.Param("id"),
+	})
+	if err != nil {
+		ctx.JSON(httpStatus(err), gin.H{"error": errorMessage(err)})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, changes)
+}
+
+func (ph *Handler) CancelScheduledPriceChange(ctx *gin.Context) {
+	response, err := ph.client.CancelScheduledPriceChange(outgoingContext(ctx), &proto.CancelScheduledPriceChangeRequest{
+		ProductId: ctx.Param("id"),
+		Id:        ctx.Param("changeId"),
+	})
+	if err != nil {
+		ctx.JSON(httpStatus(err), gin.H{"error": errorMessage(err)})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, response)
+}
@@ -0,0 +1,60 @@
+package controller
+
+import (
+	"ecomm/internal/adapters"
+	"ecomm/internal/domain"
+	"ecomm/proto"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+func (ph *Handler) ListRoles(ctx *gin.Context) {
+	roles, err := ph.client.ListRoles(outgoingContext(ctx), &proto.ListRolesRequest{})
+	if err != nil {
+		ctx.JSON(httpStatus(err), gin.H{"error": errorMessage(err)})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, roles)
+}
+
+func (ph *Handler) ListUserRoles(ctx *gin.Context) {
+	roles, err := ph.client.ListUserRoles(outgoingContext(ctx), &proto.ListUserRolesRequest{UserId: ctx.Param("id")})
+	if err != nil {
+		ctx.JSON(httpStatus(err), gin.H{"error": errorMessage(err)})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, roles)
+}
+
+func (ph *Handler) AssignUserRole(ctx *gin.Context) {
+	var request domain.AssignUserRoleRequest
+	if err := ctx.ShouldBindJSON(&request); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	request.UserID = ctx.Param("id")
+	assignRequest := adapters.ToProtoAssignUserRoleRequest(&request)
+	if _, err := ph.client.AssignUserRole(outgoingContext(ctx), assignRequest); err != nil {
+		ctx.JSON(httpStatus(err), gin.H{"error": errorMessage(err)})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"message": "Role assigned successfully"})
+}
+
+func (ph *Handler) RemoveUserRole(ctx *gin.Context) {
+	_, err := ph.client.RemoveUserRole(outgoingContext(ctx), &proto.RemoveUserRoleRequest{
+		UserId: ctx.Param("id"),
+		Role:   ctx.Param("role"),
+	})
+	if err != nil {
+		ctx.JSON(httpStatus(err), gin.H{"error": errorMessage(err)})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"message": "Role removed successfully"})
+}
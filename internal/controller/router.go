@@ -2,35 +2,131 @@ package controller
 
 import (
 	"ecomm/internal/controller/auth"
+	"ecomm/internal/domain"
+	"os"
+	"strings"
 
 	"github.com/gin-gonic/gin"
 )
 
+// TrustedProxiesFromEnv returns the addresses or CIDR ranges listed in
+// TRUSTED_PROXIES, separated by commas. Only these may set the client address
+// with X-Forwarded-For, which login throttling relies on.
+func TrustedProxiesFromEnv() []string {
+	var proxies []string
+	for _, proxy := range strings.Split(os.Getenv("TRUSTED_PROXIES"), ",") {
+		if proxy = strings.TrimSpace(proxy); proxy != "" {
+			proxies = append(proxies, proxy)
+		}
+	}
+	return proxies
+}
+
+// NewRouter trusts no proxy, so the client address is the peer address until
+// SetTrustedProxies is called on the engine.
 func NewRouter(ph *Handler) *gin.Engine {
 	engine := gin.Default()
+	// Cannot fail without proxies.
+	_ = engine.SetTrustedProxies(nil)
+	engine.Use(requestID())
 
-	authMiddleware := auth.JWTAuthMiddleware(ph.jwtManager)
+	// authMiddleware only accepts user sessions. apiAuthMiddleware also
+	// accepts API keys and guards routes that integrations may call.
+	authMiddleware := auth.JWTAuthMiddleware(ph.jwtManager, nil)
+	apiAuthMiddleware := auth.JWTAuthMiddleware(ph.jwtManager, ph)
 	adminMiddleware := auth.JWTAdminMiddleware(ph.jwtManager)
+	require := auth.RequirePermission
 
-	engine.POST("/products", adminMiddleware, ph.CreateProduct)
+	engine.POST("/products", apiAuthMiddleware, require(domain.PermissionProductsCreate), ph.CreateProduct)
 	engine.GET("/products", ph.ListProducts)
+	engine.POST("/products/import", apiAuthMiddleware, require(domain.PermissionProductsImport), ph.ImportProducts)
+	engine.GET("/products/export", apiAuthMiddleware, require(domain.PermissionProductsExport), ph.ExportProducts)
 	engine.GET("/products/:id", ph.GetProductByID)
-	engine.PUT("/products/:id", adminMiddleware, ph.UpdateProduct)
-	engine.DELETE("/products/:id", adminMiddleware, ph.DeleteProduct)
+	engine.PUT("/products/:id", apiAuthMiddleware, require(domain.PermissionProductsUpdate), ph.UpdateProduct)
+	engine.PATCH("/products/:id", apiAuthMiddleware, require(domain.PermissionProductsUpdate), ph.PatchProduct)
+	engine.DELETE("/products/:id", apiAuthMiddleware, require(domain.PermissionProductsDelete), ph.DeleteProduct)
+	engine.POST("/products/:id/restore", apiAuthMiddleware, require(domain.PermissionProductsDelete), ph.RestoreProduct)
+	engine.PUT("/products/:id/options", apiAuthMiddleware, require(domain.PermissionProductsUpdate), ph.SetProductOptions)
+	engine.POST("/products/:id/variants", apiAuthMiddleware, require(domain.PermissionProductsUpdate), ph.CreateProductVariant)
+	engine.PUT("/products/:id/variants/:variantId", apiAuthMiddleware, require(domain.PermissionProductsUpdate), ph.UpdateProductVariant)
+	engine.DELETE("/products/:id/variants/:variantId", apiAuthMiddleware, require(domain.PermissionProductsUpdate), ph.DeleteProductVariant)
+	engine.POST("/products/:id/images", apiAuthMiddleware, require(domain.PermissionProductsUpdate), ph.UploadProductImage)
+	engine.PUT("/products/:id/images/order", apiAuthMiddleware, require(domain.PermissionProductsUpdate), ph.ReorderProductImages)
+	engine.DELETE("/products/:id/images/:imageId", apiAuthMiddleware, require(domain.PermissionProductsUpdate), ph.DeleteProductImage)
+	engine.PUT("/products/:id/sale", apiAuthMiddleware, require(domain.PermissionProductsUpdate), ph.SetProductSale)
+	engine.DELETE("/products/:id/sale", apiAuthMiddleware, require(domain.PermissionProductsUpdate), ph.ClearProductSale)
+	engine.GET("/products/:id/price-history", apiAuthMiddleware, require(domain.PermissionProductsUpdate), ph.ListPriceHistory)
+	engine.GET("/products/:id/price-changes", apiAuthMiddleware, require(domain.PermissionProductsUpdate), ph.ListScheduledPriceChanges)
+	engine.POST("/products/:id/price-changes", apiAuthMiddleware, require(domain.PermissionProductsUpdate), ph.SchedulePriceChange)
+	engine.DELETE("/products/:id/price-changes/:changeId", apiAuthMiddleware, require(domain.PermissionProductsUpdate), ph.CancelScheduledPriceChange)
+	engine.POST("/products/:id/stock", apiAuthMiddleware, require(domain.PermissionInventoryManage), ph.AdjustStock)
+	engine.GET("/products/:id/stock/movements", apiAuthMiddleware, require(domain.PermissionInventoryRead), ph.ListStockMovements)
+	engine.GET("/products/:id/stock", apiAuthMiddleware, require(domain.PermissionInventoryRead), ph.GetProductStock)
+	engine.POST("/products/:id/stock/transfer", apiAuthMiddleware, require(domain.PermissionInventoryManage), ph.TransferStock)
+	engine.PUT("/products/:id/stock/threshold", apiAuthMiddleware, require(domain.PermissionInventoryManage), ph.SetReorderThreshold)
+	engine.POST("/products/:id/back-in-stock", authMiddleware, ph.SubscribeBackInStock)
+	engine.POST("/inventory/reconcile", apiAuthMiddleware, require(domain.PermissionInventoryManage), ph.ReconcileStock)
+	engine.GET("/inventory/alerts", apiAuthMiddleware, require(domain.PermissionInventoryRead), ph.ListStockAlerts)
+
+	engine.GET("/warehouses", apiAuthMiddleware, require(domain.PermissionInventoryRead), ph.ListWarehouses)
+	engine.POST("/warehouses", apiAuthMiddleware, require(domain.PermissionInventoryManage), ph.CreateWarehouse)
+	engine.PUT("/warehouses/:id", apiAuthMiddleware, require(domain.PermissionInventoryManage), ph.UpdateWarehouse)
+
+	engine.GET("/categories", ph.ListCategories)
+	engine.POST("/categories", apiAuthMiddleware, require(domain.PermissionCategoriesManage), ph.CreateCategory)
+	engine.PUT("/categories/:id", apiAuthMiddleware, require(domain.PermissionCategoriesManage), ph.UpdateCategory)
+	engine.DELETE("/categories/:id", apiAuthMiddleware, require(domain.PermissionCategoriesManage), ph.DeleteCategory)
 
 	engine.POST("/orders", authMiddleware, ph.CreateOrder)
-	engine.GET("/orders", authMiddleware, ph.ListOrders)
+	engine.GET("/orders", apiAuthMiddleware, require(domain.PermissionOrdersRead), ph.ListOrders)
 	engine.GET("/orders/:id", ph.GetOrder)
-	engine.DELETE("/orders/:id", authMiddleware, ph.DeleteOrder)
+	engine.DELETE("/orders/:id", apiAuthMiddleware, require(domain.PermissionOrdersDelete), ph.DeleteOrder)
 
 	engine.POST("/users", ph.CreateUser)
-	engine.GET("/users", adminMiddleware, ph.ListUsers)
+	engine.GET("/users", apiAuthMiddleware, require(domain.PermissionUsersRead), ph.ListUsers)
 	engine.PUT("/users", authMiddleware, ph.UpdateUser)
-	engine.DELETE("/users", adminMiddleware, ph.DeleteUser)
+	engine.GET("/users/:id", apiAuthMiddleware, require(domain.PermissionUsersRead), ph.GetUserByID)
+	engine.PUT("/users/:id", apiAuthMiddleware, require(domain.PermissionUsersUpdate), ph.AdminUpdateUser)
+	engine.DELETE("/users/:id", apiAuthMiddleware, require(domain.PermissionUsersDelete), ph.DeleteUser)
+	engine.POST("/users/:id/suspend", apiAuthMiddleware, require(domain.PermissionUsersUpdate), ph.SuspendUser)
+	engine.POST("/users/:id/disable", apiAuthMiddleware, require(domain.PermissionUsersUpdate), ph.DisableUser)
+	engine.POST("/users/:id/enable", apiAuthMiddleware, require(domain.PermissionUsersUpdate), ph.EnableUser)
+	engine.PUT("/users/:id/admin", adminMiddleware, ph.SetUserAdmin)
+	engine.POST("/users/:id/unlock", apiAuthMiddleware, require(domain.PermissionUsersUpdate), ph.UnlockAccount)
+	engine.POST("/users/:id/impersonate", adminMiddleware, ph.ImpersonateUser)
+	engine.GET("/users/:id/export", apiAuthMiddleware, require(domain.PermissionUsersRead), ph.ExportUserData)
+	engine.GET("/account/export", authMiddleware, ph.ExportUserData)
+	engine.DELETE("/account", authMiddleware, ph.DeleteUser)
+	engine.POST("/account/password", authMiddleware, ph.ChangePassword)
+	engine.POST("/account/email", authMiddleware, ph.RequestEmailChange)
+	engine.POST("/account/email/confirm", ph.ConfirmEmailChange)
+	engine.GET("/account/stock-subscriptions", authMiddleware, ph.ListStockSubscriptions)
+	engine.DELETE("/account/stock-subscriptions/:id", authMiddleware, ph.UnsubscribeBackInStock)
+
+	engine.GET("/roles", apiAuthMiddleware, require(domain.PermissionRolesManage), ph.ListRoles)
+	engine.GET("/users/:id/roles", apiAuthMiddleware, require(domain.PermissionRolesManage), ph.ListUserRoles)
+	engine.POST("/users/:id/roles", apiAuthMiddleware, require(domain.PermissionRolesManage), ph.AssignUserRole)
+	engine.DELETE("/users/:id/roles/:role", apiAuthMiddleware, require(domain.PermissionRolesManage), ph.RemoveUserRole)
+
+	engine.POST("/api-keys", authMiddleware, require(domain.PermissionAPIKeysManage), ph.CreateAPIKey)
+	engine.GET("/api-keys", authMiddleware, require(domain.PermissionAPIKeysManage), ph.ListAPIKeys)
+	engine.DELETE("/api-keys/:id", authMiddleware, require(domain.PermissionAPIKeysManage), ph.RevokeAPIKey)
+
+	engine.GET("/audit-events", apiAuthMiddleware, require(domain.PermissionAuditRead), ph.ListAuditEvents)
 
 	engine.POST("/login", ph.Login)
+	engine.POST("/login/mfa", ph.VerifyMFA)
+	engine.GET("/auth/oidc/:provider/login", ph.BeginOIDCLogin)
+	engine.GET("/auth/oidc/:provider/callback", ph.CompleteOIDCLogin)
 	engine.POST("/logout", authMiddleware, ph.Logout)
 	engine.POST("/sessions/refresh", authMiddleware, ph.RefreshAccessToken)
 	engine.GET("/sessions/revoke", authMiddleware, ph.RevokeSession)
+
+	engine.POST("/mfa/enroll", authMiddleware, ph.EnrollMFA)
+	engine.POST("/mfa/confirm", authMiddleware, ph.ConfirmMFA)
+	engine.POST("/mfa/disable", authMiddleware, ph.DisableMFA)
+
+	engine.POST("/password/forgot", ph.RequestPasswordReset)
+	engine.POST("/password/reset", ph.ResetPassword)
 	return engine
 }
@@ -0,0 +1,36 @@
+package controller
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestRouterIgnoresForwardedForFromUntrustedPeers(t *testing.T) {
+	router := NewRouter(&Handler{})
+
+	var clientIP string
+	router.GET("/test/client-ip", func(ctx *gin.Context) {
+		clientIP = ctx.ClientIP()
+	})
+
+	request := httptest.NewRequest(http.MethodGet, "/test/client-ip", nil)
+	request.RemoteAddr = "203.0.113.7:1234"
+	request.Header.Set("X-Forwarded-For", "198.51.100.1")
+	router.ServeHTTP(httptest.NewRecorder(), request)
+
+	if clientIP != "203.0.113.7" {
+		t.Fatalf("ClientIP() = %q; want the peer address", clientIP)
+	}
+}
+
+func TestTrustedProxiesFromEnv(t *testing.T) {
+	t.Setenv("TRUSTED_PROXIES", " 10.0.0.0/8, ,192.168.1.1 ")
+
+	if got, want := TrustedProxiesFromEnv(), []string{"10.0.0.0/8", "192.168.1.1"}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("TrustedProxiesFromEnv() = %v; want %v", got, want)
+	}
+}
@@ -0,0 +1,104 @@
+package controller
+
+import (
+	"ecomm/internal/adapters"
+	"ecomm/internal/domain"
+	"ecomm/proto"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+func (ph *Handler) SetReorderThreshold(ctx *gin.Context) {
+	var request domain.SetReorderThresholdRequest
+	if err := ctx.ShouldBindJSON(&request); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	request.ProductID = ctx.Param("id")
+	product, err := ph.client.SetReorderThreshold(outgoingContext(ctx), adapters.ToProtoSetReorderThresholdRequest(&request))
+	if err != nil {
+		ctx.JSON(httpStatus(err), gin.H{"error": errorMessage(err)})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, product)
+}
+
+func (ph *Handler) ListStockAlerts(ctx *gin.Context) {
+	var filter domain.StockAlertFilter
+	if err := ctx.ShouldBindQuery(&filter); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	alerts, err := ph.client.ListStockAlerts(outgoingContext(ctx), adapters.ToProtoListStockAlertsRequest(&filter))
+	if err != nil {
+		ctx.JSON(httpStatus(err), gin.H{"error": errorMessage(err)})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, alerts)
+}
+
+func (ph *Handler) SubscribeBackInStock(ctx *gin.Context) {
+	claims, err := ph.jwtManager.GetUserClaims(ctx)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	var request domain.SubscribeBackInStockRequest
+	if err := ctx.ShouldBindJSON(&request); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	request.ProductID = ctx.Param("id")
+	request.UserID = claims.ID
+	subscription, err := ph.client.SubscribeBackInStock(outgoingContext(ctx), adapters.ToProtoSubscribeBackInStockRequest(&request))
+	if err != nil {
+		ctx.JSON(httpStatus(err), gin.H{"error": errorMessage(err)})
+		return
+	}
+
+	ctx.JSON(http.StatusCreated, subscription)
+}
+
+func (ph *Handler) ListStockSubscriptions(ctx *gin.Context) {
+	claims, err := ph.jwtManager.GetUserClaims(ctx)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	subscriptions, err := ph.client.ListStockSubscriptions(outgoingContext(ctx), &proto.ListStockSubscriptionsRequest{
+		UserId: claims.ID,
+	})
+	if err != nil {
+		ctx.JSON(httpStatus(err), gin.H{"error": errorMessage(err)})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, subscriptions)
+}
+
+func (ph *Handler) UnsubscribeBackInStock(ctx *gin.Context) {
+	claims, err := ph.jwtManager.GetUserClaims(ctx)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	result, err := ph.client.UnsubscribeBackInStock(outgoingContext(ctx), &proto.UnsubscribeBackInStockRequest{
+		UserId: claims.ID,
+		Id:     ctx.Param("id"),
+	})
+	if err != nil {
+		ctx.JSON(httpStatus(err), gin.H{"error": errorMessage(err)})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, result)
+}
@@ -0,0 +1,102 @@
+package controller
+
+import (
+	"ecomm/internal/adapters"
+	"ecomm/internal/domain"
+	"ecomm/proto"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+func (ph *Handler) GetUserByID(ctx *gin.Context) {
+	response, err := ph.client.GetUserByID(outgoingContext(ctx), &proto.GetUserByIDRequest{UserId: ctx.Param("id")})
+	if err != nil {
+		ctx.JSON(httpStatus(err), gin.H{"error": errorMessage(err)})
+		return
+	}
+
+	setETag(ctx, response.User.GetVersion())
+	ctx.JSON(http.StatusOK, response)
+}
+
+func (ph *Handler) AdminUpdateUser(ctx *gin.Context) {
+	var request domain.AdminUpdateUserRequest
+	if err := ctx.ShouldBindJSON(&request); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if request.IsAdmin != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": domain.ErrPrivilegeFieldNotAllowed.Error()})
+		return
+	}
+
+	version, err := ifMatchVersion(ctx)
+	if err != nil {
+		ctx.JSON(conditionalStatus(err), gin.H{"error": errorMessage(err)})
+		return
+	}
+
+	request.UserID = ctx.Param("id")
+	updateRequest := adapters.ToProtoAdminUpdateUserRequest(&request)
+	updateRequest.ExpectedVersion = version
+	response, err := ph.client.AdminUpdateUser(outgoingContext(ctx), updateRequest)
+	if err != nil {
+		ctx.JSON(conditionalStatus(err), gin.H{"error": errorMessage(err)})
+		return
+	}
+
+	setETag(ctx, response.User.GetVersion())
+
+	ctx.JSON(http.StatusOK, response)
+}
+
+func (ph *Handler) SuspendUser(ctx *gin.Context) {
+	var request domain.SuspendUserRequest
+	if err := ctx.ShouldBindJSON(&request); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	response, err := ph.client.SuspendUser(outgoingContext(ctx), &proto.SuspendUserRequest{
+		UserId: ctx.Param("id"),
+		Reason: request.Reason,
+		Until:  request.Until,
+	})
+	if err != nil {
+		ctx.JSON(httpStatus(err), gin.H{"error": errorMessage(err)})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, response)
+}
+
+func (ph *Handler) DisableUser(ctx *gin.Context) {
+	var request domain.UserStatusRequest
+	if err := ctx.ShouldBindJSON(&request); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	response, err := ph.client.DisableUser(outgoingContext(ctx), &proto.DisableUserRequest{
+		UserId: ctx.Param("id"),
+		Reason: request.Reason,
+	})
+	if err != nil {
+		ctx.JSON(httpStatus(err), gin.H{"error": errorMessage(err)})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, response)
+}
+
+func (ph *Handler) EnableUser(ctx *gin.Context) {
+	response, err := ph.client.EnableUser(outgoingContext(ctx), &proto.EnableUserRequest{UserId: ctx.Param("id")})
+	if err != nil {
+		ctx.JSON(httpStatus(err), gin.H{"error": errorMessage(err)})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, response)
+}
@@ -0,0 +1,74 @@
+package controller
+
+import (
+	"context"
+	"ecomm/proto"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"google.golang.org/grpc"
+)
+
+// fakeUsersClient records the user updates it receives. Calling any other
+// method panics on the nil embedded interface.
+type fakeUsersClient struct {
+	proto.ApiServiceClient
+	updates []*proto.AdminUpdateUserRequest
+}
+
+func (c *fakeUsersClient) AdminUpdateUser(ctx context.Context, in *proto.AdminUpdateUserRequest, opts ...grpc.CallOption) (*proto.AdminUpdateUserResponse, error) {
+	c.updates = append(c.updates, in)
+	return &proto.AdminUpdateUserResponse{User: &proto.User{Id: in.UserId, Version: 2}}, nil
+}
+
+func TestAdminUpdateUserKeepsPutSemantics(t *testing.T) {
+	tests := []struct {
+		name string
+		body string
+	}{
+		{"empty name", `{"name": ""}`},
+		{"name", `{"name": "Ada"}`},
+		{"email", `{"email": "ada@example.com"}`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := &fakeUsersClient{}
+			recorder := httptest.NewRecorder()
+			ctx, _ := gin.CreateTestContext(recorder)
+			ctx.Request = httptest.NewRequest(http.MethodPut, "/users/user-1", strings.NewReader(tt.body))
+			ctx.Request.Header.Set("Content-Type", "application/json")
+			ctx.Params = gin.Params{{Key: "id", Value: "user-1"}}
+
+			(&Handler{client: client}).AdminUpdateUser(ctx)
+
+			if recorder.Code != http.StatusOK {
+				t.Fatalf("status = %d; want 200: %s", recorder.Code, recorder.Body)
+			}
+			if len(client.updates) != 1 {
+				t.Fatalf("sent %d updates; want 1", len(client.updates))
+			}
+			// Without a mask, the service leaves empty fields unchanged.
+			if update := client.updates[0]; update.UpdateMask != nil || update.UserId != "user-1" {
+				t.Fatalf("update = %v; want no mask", update)
+			}
+		})
+	}
+}
+
+func TestAdminUpdateUserRejectsAdminFlag(t *testing.T) {
+	client := &fakeUsersClient{}
+	recorder := httptest.NewRecorder()
+	ctx, _ := gin.CreateTestContext(recorder)
+	ctx.Request = httptest.NewRequest(http.MethodPut, "/users/user-1", strings.NewReader(`{"is_admin": true}`))
+	ctx.Request.Header.Set("Content-Type", "application/json")
+
+	(&Handler{client: client}).AdminUpdateUser(ctx)
+
+	if recorder.Code != http.StatusBadRequest || len(client.updates) != 0 {
+		t.Fatalf("status = %d after %d updates; want 400 and none", recorder.Code, len(client.updates))
+	}
+}
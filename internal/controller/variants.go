@@ -0,0 +1,75 @@
+package controller
+
+import (
+	"ecomm/internal/adapters"
+	"ecomm/internal/domain"
+	"ecomm/proto"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+func (ph *Handler) SetProductOptions(ctx *gin.Context) {
+	var request domain.SetProductOptionsRequest
+	if err := ctx.ShouldBindJSON(&request); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	request.ProductID = ctx.Param("id")
+	options, err := ph.client.SetProductOptions(outgoingContext(ctx), adapters.ToProtoSetProductOptionsRequest(&request))
+	if err != nil {
+		ctx.JSON(httpStatus(err), gin.H{"error": errorMessage(err)})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, options)
+}
+
+func (ph *Handler) CreateProductVariant(ctx *gin.Context) {
+	var request domain.ProductVariantRequest
+	if err := ctx.ShouldBindJSON(&request); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	request.ProductID = ctx.Param("id")
+	variant, err := ph.client.CreateProductVariant(outgoingContext(ctx), adapters.ToProtoCreateProductVariantRequest(&request))
+	if err != nil {
+		ctx.JSON(httpStatus(err), gin.H{"error": errorMessage(err)})
+		return
+	}
+
+	ctx.JSON(http.StatusCreated, variant)
+}
+
+func (ph *Handler) UpdateProductVariant(ctx *gin.Context) {
+	var request domain.ProductVariantRequest
+	if err := ctx.ShouldBindJSON(&request); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	request.ID = ctx.Param("variantId")
+	request.ProductID = ctx.Param("id")
+	variant, err := ph.client.UpdateProductVariant(outgoingContext(ctx), adapters.ToProtoUpdateProductVariantRequest(&request))
+	if err != nil {
+		ctx.JSON(httpStatus(err), gin.H{"error": errorMessage(err)})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, variant)
+}
+
+func (ph *Handler) DeleteProductVariant(ctx *gin.Context) {
+	_, err := ph.client.DeleteProductVariant(outgoingContext(ctx), &proto.DeleteProductVariantRequest{
+		Id:        ctx.Param("variantId"),
+		ProductId: ctx.Param("id"),
+	})
+	if err != nil {
+		ctx.JSON(httpStatus(err), gin.H{"error": errorMessage(err)})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"message": "Variant deleted successfully"})
+}
@@ -0,0 +1,53 @@
+package controller
+
+import (
+	"ecomm/internal/adapters"
+	"ecomm/internal/domain"
+	"ecomm/proto"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+func (ph *Handler) CreateWarehouse(ctx *gin.Context) {
+	var request domain.CreateWarehouseRequest
+	if err := ctx.ShouldBindJSON(&request); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	warehouse, err := ph.client.CreateWarehouse(outgoingContext(ctx), adapters.ToProtoCreateWarehouseRequest(&request))
+	if err != nil {
+		ctx.JSON(httpStatus(err), gin.H{"error": errorMessage(err)})
+		return
+	}
+
+	ctx.JSON(http.StatusCreated, warehouse)
+}
+
+func (ph *Handler) ListWarehouses(ctx *gin.Context) {
+	warehouses, err := ph.client.ListWarehouses(outgoingContext(ctx), &proto.ListWarehousesRequest{})
+	if err != nil {
+		ctx.JSON(httpStatus(err), gin.H{"error": errorMessage(err)})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, warehouses)
+}
+
+func (ph *Handler) UpdateWarehouse(ctx *gin.Context) {
+	var request domain.UpdateWarehouseRequest
+	if err := ctx.ShouldBindJSON(&request); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	request.ID = ctx.Param("id")
+	warehouse, err := ph.client.UpdateWarehouse(outgoingContext(ctx), adapters.ToProtoUpdateWarehouseRequest(&request))
+	if err != nil {
+		ctx.JSON(httpStatus(err), gin.H{"error": errorMessage(err)})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, warehouse)
+}
@@ -3,8 +3,73 @@ package domain
 import "errors"
 
 var (
-	ErrProductNotFound error = errors.New("product not found")
-	ErrOrderNotFound   error = errors.New("order not found")
-	ErrUserNotFound    error = errors.New("user not found")
-	ErrSessionNotFound error = errors.New("session not found")
+	ErrProductNotFound   error = errors.New("product not found")
+	ErrOrderNotFound     error = errors.New("order not found")
+	ErrUserNotFound      error = errors.New("user not found")
+	ErrSessionNotFound   error = errors.New("session not found")
+	ErrRoleNotFound      error = errors.New("role not found")
+	ErrAPIKeyNotFound    error = errors.New("api key not found")
+	ErrCategoryNotFound  error = errors.New("category not found")
+	ErrVariantNotFound   error = errors.New("product variant not found")
+	ErrImageNotFound     error = errors.New("product image not found")
+	ErrWarehouseNotFound error = errors.New("warehouse not found")
+
+	ErrOIDCStateNotFound    error = errors.New("oidc login state not found")
+	ErrUserIdentityNotFound error = errors.New("user identity not found")
+
+	ErrPasswordResetTokenNotFound error = errors.New("password reset token not found")
+	ErrEmailChangeTokenNotFound   error = errors.New("email change token not found")
+
+	ErrEmailTaken error = errors.New("email is already in use")
+
+	ErrCategorySlugTaken error = errors.New("category slug is already in use")
+	ErrCategoryInUse     error = errors.New("category still has subcategories or products")
+
+	ErrSKUTaken           error = errors.New("sku is already in use")
+	ErrSKUArchived        error = errors.New("the product with this sku is archived, restore it first")
+	ErrProductNotArchived error = errors.New("product is not archived")
+	ErrVariantExists      error = errors.New("a variant with these options already exists")
+
+	ErrInsufficientStock   error = errors.New("not enough stock")
+	ErrNoWarehouse         error = errors.New("no active warehouse")
+	ErrWarehouseCodeTaken  error = errors.New("warehouse code is already in use")
+	ErrWarehouseInactive   error = errors.New("warehouse is inactive")
+	ErrLastActiveWarehouse error = errors.New("the last active warehouse cannot be deactivated")
+	ErrWarehouseNotEmpty   error = errors.New("warehouse still holds stock")
+
+	ErrSubscriptionNotFound error = errors.New("stock subscription not found")
+	ErrAlreadySubscribed    error = errors.New("already subscribed to this item")
+
+	ErrPriceChangeNotFound error = errors.New("scheduled price change not found")
+
+	ErrVersionMismatch error = errors.New("the resource has been modified since it was read")
+
+	ErrPrivilegeFieldNotAllowed error = errors.New("is_admin cannot be set through this endpoint")
+
+	ErrInvalidCredentials   error = errors.New("invalid credentials")
+	ErrTooManyLoginAttempts error = errors.New("too many failed login attempts, try again later")
+
+	ErrMFANotEnrolled       error = errors.New("multi-factor authentication is not enrolled")
+	ErrRecoveryCodeNotFound error = errors.New("recovery code not found")
+	ErrMFACodeAlreadyUsed   error = errors.New("multi-factor authentication code already used")
 )
+
+// VersionMismatchReason is the reason of the ErrorInfo detail attached to the
+// Aborted status errors that report ErrVersionMismatch, which tells them apart
+// from other aborted requests.
+const VersionMismatchReason = "VERSION_MISMATCH"
+
+// ProductError ties an error to the product it happened on, for writes that
+// save many products at once.
+type ProductError struct {
+	SKU string
+	Err error
+}
+
+func (e *ProductError) Error() string {
+	return "sku " + e.SKU + ": " + e.Err.Error()
+}
+
+func (e *ProductError) Unwrap() error {
+	return e.Err
+}
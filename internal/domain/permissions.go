@@ -0,0 +1,51 @@
+package domain
+
+// Permissions are granted to users through roles. Admins implicitly hold
+// PermissionAll, which satisfies every permission check.
+const (
+	PermissionAll = "*"
+
+	PermissionProductsCreate = "products:create"
+	PermissionProductsUpdate = "products:update"
+	PermissionProductsDelete = "products:delete"
+	PermissionProductsImport = "products:import"
+	PermissionProductsExport = "products:export"
+
+	PermissionCategoriesManage = "categories:manage"
+
+	PermissionInventoryRead   = "inventory:read"
+	PermissionInventoryManage = "inventory:manage"
+
+	PermissionOrdersRead   = "orders:read"
+	PermissionOrdersDelete = "orders:delete"
+
+	PermissionUsersRead   = "users:read"
+	PermissionUsersUpdate = "users:update"
+	PermissionUsersDelete = "users:delete"
+
+	PermissionRolesManage = "roles:manage"
+
+	PermissionAPIKeysManage = "api_keys:manage"
+
+	PermissionAuditRead = "audit:read"
+)
+
+// Permissions lists every permission that can be granted to a role or API key.
+var Permissions = []string{
+	PermissionProductsCreate,
+	PermissionProductsUpdate,
+	PermissionProductsDelete,
+	PermissionProductsImport,
+	PermissionProductsExport,
+	PermissionCategoriesManage,
+	PermissionInventoryRead,
+	PermissionInventoryManage,
+	PermissionOrdersRead,
+	PermissionOrdersDelete,
+	PermissionUsersRead,
+	PermissionUsersUpdate,
+	PermissionUsersDelete,
+	PermissionRolesManage,
+	PermissionAPIKeysManage,
+	PermissionAuditRead,
+}
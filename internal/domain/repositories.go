@@ -1,26 +1,128 @@
 package domain
 
+import "time"
+
 type Repository interface {
-	CreateProduct(product *Product) (*Product, error)
+	CreateProduct(product *Product, actorID string) (*Product, error)
 	GetProductByID(id string) (*Product, error)
-	ListProducts() ([]*Product, error)
-	UpdateProduct(product *Product) error
-	DeleteProduct(id string) error
+	ListProducts(filter *ProductFilter) ([]*Product, error)
+	UpdateProduct(product *Product, stock *InventoryMovement, actorID string) error
+	ArchiveProduct(id string, version int64) error
+	RestoreProduct(id string, version int64) error
+	GetProductStatusesBySKU(skus []string) (map[string]string, error)
+	UpsertProducts(products []*Product, actorID string) (created int, err error)
+	ListProductsPage(afterID string, limit int) ([]*Product, error)
+
+	SetProductOptions(productID string, options []ProductOption) error
+	CreateProductVariant(variant *ProductVariant, actorID string) (*ProductVariant, error)
+	GetProductVariant(id string) (*ProductVariant, error)
+	UpdateProductVariant(variant *ProductVariant, stock *InventoryMovement) error
+	DeleteProductVariant(id string) error
+
+	CreateProductImage(image *ProductImage) error
+	GetProductImage(id string) (*ProductImage, error)
+	DeleteProductImage(id string) error
+	ReorderProductImages(productID string, imageIDs []string) error
+
+	RecordInventoryMovement(movement *InventoryMovement) error
+	ListInventoryMovements(filter *InventoryMovementFilter) ([]*InventoryMovement, error)
+	ReconcileStock(apply bool) ([]*StockDiscrepancy, error)
+	TransferStock(from, to *InventoryMovement) error
+
+	CreateWarehouse(warehouse *Warehouse) (*Warehouse, error)
+	GetWarehouse(id string) (*Warehouse, error)
+	ListWarehouses() ([]*Warehouse, error)
+	UpdateWarehouse(warehouse *Warehouse) error
+	ListWarehouseStock(productID string) ([]*WarehouseStock, error)
+
+	SetReorderThreshold(productID string, threshold int) error
+	ListStockAlerts(filter *StockAlertFilter) ([]*StockAlert, error)
+	ClaimStockAlerts(limit int, claimTimeout time.Duration) ([]*StockAlert, error)
+	MarkStockAlertNotified(id string) error
+	CreateStockSubscription(subscription *StockSubscription) (*StockSubscription, error)
+	DeleteStockSubscription(id, userID string) error
+	ListUserStockSubscriptions(userID string) ([]*StockSubscription, error)
+	ClaimReplenishedStockSubscriptions(limit int, claimTimeout time.Duration) ([]*StockSubscription, error)
+	MarkStockSubscriptionNotified(id string) error
+
+	SetProductSale(productID string, sale *ProductSale, version int64, actorID string) error
+	ListPriceHistory(filter *PriceHistoryFilter) ([]*PriceHistoryEntry, error)
+	CreateScheduledPriceChange(change *ScheduledPriceChange) (*ScheduledPriceChange, error)
+	ListScheduledPriceChanges(productID string) ([]*ScheduledPriceChange, error)
+	DeleteScheduledPriceChange(id, productID string) (*ScheduledPriceChange, error)
+	ListDueScheduledPriceChanges(limit int) ([]*ScheduledPriceChange, error)
+	ApplyScheduledPriceChange(id string) error
+
+	CreateCategory(category *Category) (*Category, error)
+	GetCategoryByID(id string) (*Category, error)
+	ListCategories() ([]*Category, error)
+	UpdateCategory(category *Category) error
+	DeleteCategory(id string) error
 
 	CreateOrder(order *Order) (*Order, error)
 	GetOrder(userID string) (*Order, error)
 	GetOrderItems(orderID string) ([]OrderItem, error)
 	ListOrders() ([]*Order, error)
-	DeleteOrder(id string) error
+	DeleteOrder(id, actorID string, version int64) error
 
 	CreateUser(user *User) (*User, error)
 	GetUser(email string) (*User, error)
-	ListUsers() ([]*User, error)
+	GetUserByID(id string) (*User, error)
+	ListUsers(query string, limit, offset int) ([]*User, int64, error)
+	SetUserStatus(id, status, reason string, suspendedUntil uint64) error
 	UpdateUser(user *User) error
+	SetUserAdmin(id string, isAdmin bool) error
 	DeleteUser(id string) error
 
+	ListRoles() ([]*Role, error)
+	GetUserRoles(userID string) ([]*Role, error)
+	GetUserPermissions(userID string) ([]string, error)
+	AssignUserRole(userID, roleName string) error
+	RemoveUserRole(userID, roleName string) error
+
+	CreateAPIKey(key *APIKey) (*APIKey, error)
+	GetAPIKeyByPrefix(prefix string) (*APIKey, error)
+	ListAPIKeys() ([]*APIKey, error)
+	RevokeAPIKey(id string) error
+	TouchAPIKey(id string) error
+
 	CreateSession(session *Session) error
 	GetSession(id string) (*Session, error)
 	RevokeSession(id string) error
 	DeleteSession(id string) error
+	RevokeUserSessions(email string) error
+	ListUserSessions(email string) ([]*Session, error)
+	ListUserOrders(userID string) ([]*Order, error)
+	ListUserIdentities(userID string) ([]*UserIdentity, error)
+	EraseUser(userID string, version int64) error
+
+	GetLoginThrottle(key string) (*LoginThrottle, error)
+	RecordLoginFailure(key string, windowStart uint64) (*LoginThrottle, error)
+	LockLogin(key string, lockedUntil uint64) error
+	ClearLoginFailures(key string) error
+
+	GetUserMFA(userID string) (*UserMFA, error)
+	SaveMFASecret(userID, secret string) error
+	EnableMFA(userID string, recoveryCodeHashes []string) error
+	DisableMFA(userID string) error
+	UseMFAStep(userID string, step int64) error
+	UseRecoveryCode(userID, codeHash string) error
+
+	CreateOIDCLoginState(state *OIDCLoginState) error
+	ConsumeOIDCLoginState(state string) (*OIDCLoginState, error)
+	GetUserIdentity(provider, subject string) (*UserIdentity, error)
+	CreateUserIdentity(identity *UserIdentity) error
+
+	CreateAuditEvent(event *AuditEvent) error
+	ListAuditEvents(filter *AuditEventFilter) ([]*AuditEvent, error)
+
+	CreatePasswordResetToken(token *PasswordResetToken) error
+	GetPasswordResetToken(tokenHash string) (*PasswordResetToken, error)
+	ResetPassword(tokenID, userID, passwordHash string) error
+	ChangePassword(userID, passwordHash, keepSessionID string) error
+	UpdatePasswordHash(userID, oldHash, newHash string) error
+
+	CreateEmailChangeToken(token *EmailChangeToken) error
+	GetEmailChangeToken(tokenHash string) (*EmailChangeToken, error)
+	ChangeEmail(tokenID, userID, newEmail string) error
 }
@@ -1,40 +1,429 @@
 package domain
 
+// Product statuses. Drafts are only visible to staff and cannot be ordered.
+// Archived products are what deleting a product leaves behind: they are
+// hidden from listings but still resolve for the orders that reference them.
+const (
+	ProductDraft    = "draft"
+	ProductActive   = "active"
+	ProductArchived = "archived"
+)
+
 type Product struct {
-	ID              string  `json:"id"`
-	Name            string  `json:"name"`
-	Image           string  `json:"image"`
+	ID         string `json:"id"`
+	SKU        string `json:"sku"`
+	Name       string `json:"name"`
+	Image      string `json:"image"`
+	CategoryID string `json:"category_id"`
+	// Category is the name of the category, filled in when reading.
 	Category        string  `json:"category"`
 	Description     string  `json:"description"`
 	Rating          int     `json:"rating"`
 	NumberOfReviews int     `json:"number_of_reviews"`
 	Price           float64 `json:"price"`
 	CountInStock    int     `json:"count_in_stock"`
-	CreatedAt       uint64  `json:"created_at"`
-	UpdatedAt       uint64  `json:"updated_at"`
+	// Price is the regular price. Sale, if set, reduces it for a while.
+	Sale *ProductSale `json:"sale,omitempty"`
+	// ReorderThreshold is the stock at or below which the product, or any
+	// of its variants, raises a low-stock alert.
+	ReorderThreshold int    `json:"reorder_threshold"`
+	Status           string `json:"status"`
+	// DeletedAt is when the product was archived, or zero.
+	DeletedAt uint64 `json:"deleted_at"`
+	// Version is incremented by every change to the product.
+	Version   int64  `json:"version"`
+	CreatedAt uint64 `json:"created_at"`
+	UpdatedAt uint64 `json:"updated_at"`
+	// Options and Variants are only loaded for single products.
+	Options  []ProductOption   `json:"options,omitempty"`
+	Variants []*ProductVariant `json:"variants,omitempty"`
+	Images   []*ProductImage   `json:"images,omitempty"`
+}
+
+// ProductImage is an uploaded image in a product's gallery, ordered by
+// Position. The URLs are resolved from the storage keys when reading.
+type ProductImage struct {
+	ID           string `json:"id"`
+	ProductID    string `json:"product_id"`
+	StorageKey   string `json:"-"`
+	ThumbnailKey string `json:"-"`
+	MediumKey    string `json:"-"`
+	URL          string `json:"url"`
+	ThumbnailURL string `json:"thumbnail_url"`
+	MediumURL    string `json:"medium_url"`
+	ContentType  string `json:"content_type"`
+	Width        int    `json:"width"`
+	Height       int    `json:"height"`
+	SizeBytes    int64  `json:"size_bytes"`
+	AltText      string `json:"alt_text"`
+	Position     int    `json:"position"`
+	CreatedAt    uint64 `json:"created_at"`
+}
+
+type ReorderProductImagesRequest struct {
+	ProductID string   `json:"-"`
+	ImageIDs  []string `json:"image_ids" binding:"required,min=1"`
+}
+
+// ProductOption is an option type such as size or colour together with the
+// values the product is offered in.
+type ProductOption struct {
+	Name   string   `json:"name" binding:"required"`
+	Values []string `json:"values" binding:"required,min=1"`
+}
+
+// ProductVariant is a purchasable combination of option values, for example
+// {"size": "M", "colour": "red"}. A nil Price and an empty Image mean the
+// product's are used.
+type ProductVariant struct {
+	ID           string            `json:"id"`
+	ProductID    string            `json:"product_id"`
+	SKU          string            `json:"sku"`
+	Options      map[string]string `json:"options"`
+	Price        *float64          `json:"price"`
+	CountInStock int               `json:"count_in_stock"`
+	Image        string            `json:"image"`
+	CreatedAt    uint64            `json:"created_at"`
+	UpdatedAt    uint64            `json:"updated_at"`
+}
+
+// Inventory movement types. Receipts and returns add stock, sales remove it
+// and adjustments correct it in either direction. Transfers move stock
+// between warehouses and come in pairs.
+const (
+	MovementReceipt    = "receipt"
+	MovementSale       = "sale"
+	MovementReturn     = "return"
+	MovementAdjustment = "adjustment"
+	MovementTransfer   = "transfer"
+)
+
+// InventoryMovement is an entry in the stock ledger of a product, or of one
+// of its variants when VariantID is set, in one warehouse. Quantity is
+// negative for movements that remove stock and Balance is the total stock
+// across all warehouses after the movement.
+type InventoryMovement struct {
+	ID          string `json:"id"`
+	ProductID   string `json:"product_id"`
+	VariantID   string `json:"variant_id"`
+	WarehouseID string `json:"warehouse_id"`
+	Type        string `json:"type"`
+	Quantity    int    `json:"quantity"`
+	Balance     int    `json:"balance"`
+	Reason      string `json:"reason"`
+	OrderID     string `json:"order_id"`
+	ActorID     string `json:"actor_id"`
+	CreatedAt   uint64 `json:"created_at"`
+	// SetTo, if not nil, makes the movement set the stock to this value. Its
+	// Quantity is then computed from the stock when the movement is applied.
+	SetTo *int `json:"-" db:"-"`
+}
+
+// AdjustStockRequest records a manual movement. An empty WarehouseID uses the
+// default warehouse.
+type AdjustStockRequest struct {
+	ProductID   string `json:"-"`
+	VariantID   string `json:"variant_id"`
+	WarehouseID string `json:"warehouse_id"`
+	Type        string `json:"type" binding:"required,oneof=receipt return adjustment"`
+	Quantity    int    `json:"quantity" binding:"required"`
+	Reason      string `json:"reason"`
+}
+
+type TransferStockRequest struct {
+	ProductID       string `json:"-"`
+	VariantID       string `json:"variant_id"`
+	FromWarehouseID string `json:"from_warehouse_id" binding:"required"`
+	ToWarehouseID   string `json:"to_warehouse_id" binding:"required"`
+	Quantity        int    `json:"quantity" binding:"required,gte=1"`
+	Reason          string `json:"reason"`
+}
+
+// InventoryMovementFilter narrows ListInventoryMovements. Zero values match
+// everything.
+type InventoryMovementFilter struct {
+	ProductID   string `form:"-"`
+	VariantID   string `form:"variant_id"`
+	WarehouseID string `form:"warehouse_id"`
+	Type        string `form:"type"`
+	Limit       int    `form:"limit" binding:"omitempty,min=1,max=500"`
+	Offset      int    `form:"offset" binding:"omitempty,min=0"`
+}
+
+// StockDiscrepancy is a product or variant whose count_in_stock, or whose
+// stock in the warehouse when WarehouseID is set, no longer matches the sum
+// of its inventory movements.
+type StockDiscrepancy struct {
+	ProductID   string `json:"product_id"`
+	VariantID   string `json:"variant_id"`
+	WarehouseID string `json:"warehouse_id"`
+	Recorded    int    `json:"recorded"`
+	Ledger      int    `json:"ledger"`
+}
+
+// Location is a point on the earth in decimal degrees.
+type Location struct {
+	Latitude  float64 `json:"latitude" binding:"gte=-90,lte=90"`
+	Longitude float64 `json:"longitude" binding:"gte=-180,lte=180"`
+}
+
+// Warehouse holds stock. The fulfilment strategy prefers lower priorities
+// and, when the shipping location is known, closer warehouses. Inactive
+// warehouses keep their stock but are not allocated orders. Location is nil
+// if unknown.
+type Warehouse struct {
+	ID        string    `json:"id"`
+	Code      string    `json:"code"`
+	Name      string    `json:"name"`
+	Location  *Location `json:"location"`
+	Priority  int       `json:"priority"`
+	Active    bool      `json:"active"`
+	CreatedAt uint64    `json:"created_at"`
+	UpdatedAt uint64    `json:"updated_at"`
+}
+
+type CreateWarehouseRequest struct {
+	Code     string    `json:"code" binding:"required"`
+	Name     string    `json:"name" binding:"required"`
+	Location *Location `json:"location"`
+	Priority int       `json:"priority"`
+}
+
+// UpdateWarehouseRequest replaces the warehouse's attributes. The code cannot
+// be changed.
+// UpdateWarehouseRequest changes a warehouse. Location, Priority and Active
+// keep their current value when omitted.
+type UpdateWarehouseRequest struct {
+	ID       string    `json:"-"`
+	Name     string    `json:"name" binding:"required"`
+	Location *Location `json:"location"`
+	Priority *int      `json:"priority"`
+	Active   *bool     `json:"active"`
+}
+
+// WarehouseStock is the stock of a product or variant in one warehouse.
+type WarehouseStock struct {
+	WarehouseID string `json:"warehouse_id"`
+	ProductID   string `json:"product_id"`
+	VariantID   string `json:"variant_id"`
+	Quantity    int    `json:"quantity"`
+}
+
+type SetReorderThresholdRequest struct {
+	ProductID string `json:"-"`
+	Threshold int    `json:"threshold" binding:"gte=0"`
+}
+
+// StockAlert reports that the stock of a product, or of one of its variants,
+// fell to Stock, at or below the product's reorder threshold. NotifiedAt and
+// ResolvedAt are zero until the admins have been notified and the stock has
+// risen above the threshold again.
+type StockAlert struct {
+	ID          string `json:"id"`
+	ProductID   string `json:"product_id"`
+	ProductName string `json:"product_name"`
+	VariantID   string `json:"variant_id"`
+	Threshold   int    `json:"threshold"`
+	Stock       int    `json:"stock"`
+	CreatedAt   uint64 `json:"created_at"`
+	NotifiedAt  uint64 `json:"notified_at"`
+	ResolvedAt  uint64 `json:"resolved_at"`
+}
+
+type StockAlertFilter struct {
+	// OpenOnly leaves out resolved alerts.
+	OpenOnly bool `form:"open"`
+	Limit    int  `form:"limit" binding:"omitempty,min=1,max=500"`
+	Offset   int  `form:"offset" binding:"omitempty,min=0"`
+}
+
+// StockSubscription asks for an email to the user once an out-of-stock
+// product or variant is back in stock. NotifiedAt is zero until then.
+type StockSubscription struct {
+	ID         string `json:"id"`
+	UserID     string `json:"user_id"`
+	ProductID  string `json:"product_id"`
+	VariantID  string `json:"variant_id"`
+	CreatedAt  uint64 `json:"created_at"`
+	NotifiedAt uint64 `json:"notified_at"`
+	// Email and ProductName are filled in for delivering the notification.
+	Email       string `json:"-"`
+	ProductName string `json:"-"`
+}
+
+type SubscribeBackInStockRequest struct {
+	ProductID string `json:"-"`
+	VariantID string `json:"variant_id"`
+	UserID    string `json:"-"`
+}
+
+// ProductSale sells a product for Price from StartsAt until EndsAt, or
+// indefinitely when EndsAt is zero.
+type ProductSale struct {
+	Price    float64 `json:"price"`
+	StartsAt uint64  `json:"starts_at"`
+	EndsAt   uint64  `json:"ends_at"`
+}
+
+// Active reports whether the sale runs at now.
+func (s *ProductSale) Active(now uint64) bool {
+	return s != nil && now >= s.StartsAt && (s.EndsAt == 0 || now < s.EndsAt)
+}
+
+// CurrentPrice returns the price the product sells for at now and, while a
+// sale reduces it, the regular price it is compared to, or 0. A sale that
+// would not reduce the regular price is ignored.
+func (p *Product) CurrentPrice(now uint64) (price, compareAt float64) {
+	if p.Sale.Active(now) && p.Sale.Price < p.Price {
+		return p.Sale.Price, p.Price
+	}
+	return p.Price, 0
+}
+
+// Reasons for price changes, recorded in the price history.
+const (
+	PriceCreated   = "created"
+	PriceUpdated   = "updated"
+	PriceImported  = "imported"
+	PriceSale      = "sale"
+	PriceScheduled = "scheduled"
+)
+
+// PriceHistoryEntry records a change to the pricing of a product and the
+// pricing it resulted in.
+type PriceHistoryEntry struct {
+	ID        string       `json:"id"`
+	ProductID string       `json:"product_id"`
+	Price     float64      `json:"price"`
+	Sale      *ProductSale `json:"sale,omitempty"`
+	Reason    string       `json:"reason"`
+	ActorID   string       `json:"actor_id"`
+	CreatedAt uint64       `json:"created_at"`
+}
+
+type PriceHistoryFilter struct {
+	ProductID string `form:"-"`
+	Limit     int    `form:"limit" binding:"omitempty,min=1,max=500"`
+	Offset    int    `form:"offset" binding:"omitempty,min=0"`
+}
+
+// ScheduledPriceChange sets the regular price of a product to Price at
+// EffectiveAt. AppliedAt is zero until the change has been made.
+type ScheduledPriceChange struct {
+	ID          string  `json:"id"`
+	ProductID   string  `json:"product_id"`
+	Price       float64 `json:"price"`
+	EffectiveAt uint64  `json:"effective_at"`
+	ActorID     string  `json:"actor_id"`
+	CreatedAt   uint64  `json:"created_at"`
+	AppliedAt   uint64  `json:"applied_at"`
+}
+
+// SetProductSaleRequest starts a sale. A zero StartsAt starts it straight
+// away and a zero EndsAt leaves it running until it is cleared.
+type SetProductSaleRequest struct {
+	ProductID       string  `json:"-"`
+	Price           float64 `json:"price" binding:"gte=0"`
+	StartsAt        uint64  `json:"starts_at"`
+	EndsAt          uint64  `json:"ends_at"`
+	ExpectedVersion int64   `json:"-"`
+}
+
+type SchedulePriceChangeRequest struct {
+	ProductID   string  `json:"-"`
+	Price       float64 `json:"price" binding:"gte=0"`
+	EffectiveAt uint64  `json:"effective_at" binding:"required"`
+}
+
+type SetProductOptionsRequest struct {
+	ProductID string          `json:"-"`
+	Options   []ProductOption `json:"options" binding:"dive"`
+}
+
+// ProductVariantRequest is the body of the create and update variant routes.
+// A zero Price means the product's price is used.
+type ProductVariantRequest struct {
+	ID           string            `json:"-"`
+	ProductID    string            `json:"-"`
+	SKU          string            `json:"sku" binding:"required"`
+	Options      map[string]string `json:"options" binding:"required"`
+	Price        float64           `json:"price" binding:"gte=0"`
+	CountInStock int               `json:"count_in_stock" binding:"gte=0"`
+	Image        string            `json:"image"`
 }
 
 type CreateProductRequest struct {
+	SKU             string  `json:"sku"`
 	Name            string  `json:"name" binding:"required"`
 	Image           string  `json:"image" binding:"required"`
-	Category        string  `json:"category" binding:"required"`
+	CategoryID      string  `json:"category_id" binding:"required"`
 	Description     string  `json:"description" binding:"required"`
 	Rating          int     `json:"rating" binding:"required"`
 	NumberOfReviews int     `json:"number_of_reviews" binding:"required"`
 	Price           float64 `json:"price" binding:"required"`
 	CountInStock    int     `json:"count_in_stock" binding:"required"`
+	// Status defaults to active.
+	Status string `json:"status" binding:"omitempty,oneof=draft active"`
 }
 
 type UpdateProductRequest struct {
 	ID              string
+	SKU             string  `json:"sku"`
 	Name            string  `json:"name"`
 	Image           string  `json:"image"`
-	Category        string  `json:"category"`
+	CategoryID      string  `json:"category_id"`
 	Description     string  `json:"description"`
 	Rating          int     `json:"rating"`
 	NumberOfReviews int     `json:"number_of_reviews"`
 	Price           float64 `json:"price"`
 	CountInStock    int     `json:"count_in_stock"`
+	Status          string  `json:"status" binding:"omitempty,oneof=draft active"`
+}
+
+// ListProductsRequest searches the name and SKU of products. Archived
+// products are only listed when asked for by Status.
+type ListProductsRequest struct {
+	Query  string `form:"q"`
+	Status string `form:"status" binding:"omitempty,oneof=draft active archived"`
+}
+
+// ProductFilter narrows ListProducts to the products in one of Statuses
+// whose name or SKU contains Query.
+type ProductFilter struct {
+	Query    string
+	Statuses []string
+}
+
+// Category groups products. Categories form a tree through ParentID, which
+// is empty for top-level categories; siblings are ordered by Position.
+type Category struct {
+	ID       string `json:"id"`
+	ParentID string `json:"parent_id"`
+	Name     string `json:"name"`
+	Slug     string `json:"slug"`
+	Position int    `json:"position"`
+	// ProductCount only counts products directly in the category.
+	ProductCount int    `json:"product_count"`
+	CreatedAt    uint64 `json:"created_at"`
+	UpdatedAt    uint64 `json:"updated_at"`
+}
+
+type CreateCategoryRequest struct {
+	ParentID string `json:"parent_id"`
+	Name     string `json:"name" binding:"required"`
+	Slug     string `json:"slug"`
+	Position int    `json:"position"`
+}
+
+// UpdateCategoryRequest replaces the category's name. The parent and position
+// are only changed if given, and an empty ParentID moves the category to the
+// top level. An empty Slug keeps the current one.
+type UpdateCategoryRequest struct {
+	ID       string  `json:"-"`
+	ParentID *string `json:"parent_id"`
+	Name     string  `json:"name" binding:"required"`
+	Slug     string  `json:"slug"`
+	Position *int    `json:"position"`
 }
 
 type Order struct {
@@ -45,44 +434,75 @@ type Order struct {
 	TotalPrice    float64      `json:"total_price"`
 	OrderItems    []*OrderItem `json:"order_items"`
 	UserID        string       `json:"user_id"`
-	CreatedAt     uint64       `json:"created_at"`
-	UpdatedAt     uint64       `json:"updated_at"`
+	// ShippingLocation lets the closest warehouse fulfil the order.
+	ShippingLocation *Location `json:"shipping_location,omitempty"`
+	Version          int64     `json:"version"`
+	CreatedAt        uint64    `json:"created_at"`
+	UpdatedAt        uint64    `json:"updated_at"`
 }
 
+// CreateOrderRequest places an order. The total is computed by the server;
+// a total sent by the client is ignored.
 type CreateOrderRequest struct {
-	PaymentMethod string      `json:"payment_method" binding:"required"`
-	TaxPrice      float64     `json:"tax_price" binding:"required,gte=0"`
-	ShippingPrice float64     `json:"shipping_price" binding:"required,gte=0"`
-	TotalPrice    float64     `json:"total_price" binding:"required,gte=0"`
-	OrderItems    []OrderItem `json:"order_items" binding:"required,min=1"`
-	UserID        string      `json:"-"`
+	PaymentMethod    string      `json:"payment_method" binding:"required"`
+	TaxPrice         float64     `json:"tax_price" binding:"required,gte=0"`
+	ShippingPrice    float64     `json:"shipping_price" binding:"required,gte=0"`
+	TotalPrice       float64     `json:"total_price"`
+	OrderItems       []OrderItem `json:"order_items" binding:"required,min=1"`
+	ShippingLocation *Location   `json:"shipping_location"`
+	UserID           string      `json:"-"`
 }
 
+// OrderItem is a line of an order. Name, Image and Price are copied from the
+// catalog when the order is placed.
 type OrderItem struct {
 	ID        string  `json:"id"`
 	OrderID   string  `json:"order_id"`
 	ProductID string  `json:"product_id" binding:"required"`
-	Name      string  `json:"name"  binding:"required"`
+	VariantID string  `json:"variant_id"`
+	Name      string  `json:"name"`
 	Quantity  int     `json:"quantity" binding:"required,gte=1"`
-	Image     string  `json:"image" binding:"required"`
-	Price     float64 `json:"price" binding:"required,gte=0"`
+	Image     string  `json:"image"`
+	Price     float64 `json:"price"`
+	// Allocations are the warehouses the item ships from.
+	Allocations []OrderItemAllocation `json:"allocations,omitempty"`
 }
 
+type OrderItemAllocation struct {
+	WarehouseID string `json:"warehouse_id"`
+	Quantity    int    `json:"quantity"`
+}
+
+// A suspended account is blocked temporarily, for example while abuse is
+// investigated. A disabled account is closed until staff re-enable it.
+const (
+	UserStatusActive    = "active"
+	UserStatusSuspended = "suspended"
+	UserStatusDisabled  = "disabled"
+)
+
 type User struct {
-	ID        string `json:"id"`
-	Name      string `json:"name"`
-	Email     string `json:"email"`
-	Password  string `json:"password"`
-	IsAdmin   bool   `json:"is_admin"`
-	CreatedAt uint64 `json:"created_at"`
-	UpdatedAt uint64 `json:"updated_at"`
+	ID           string `json:"id"`
+	Name         string `json:"name"`
+	Email        string `json:"email"`
+	Password     string `json:"password"`
+	IsAdmin      bool   `json:"is_admin"`
+	Status       string `json:"status"`
+	StatusReason string `json:"status_reason"`
+	// SuspendedUntil is when a suspension ends. It is 0 unless the user is
+	// suspended.
+	SuspendedUntil uint64 `json:"suspended_until"`
+	Version        int64  `json:"version"`
+	CreatedAt      uint64 `json:"created_at"`
+	UpdatedAt      uint64 `json:"updated_at"`
 }
 
 type CreateUserRequest struct {
 	Name     string `json:"name" binding:"required"`
 	Email    string `json:"email" binding:"required"`
 	Password string `json:"password" binding:"required"`
-	IsAdmin  bool   `json:"is_admin"`
+	// IsAdmin is only decoded so that attempts to set it can be rejected.
+	IsAdmin *bool `json:"is_admin"`
 }
 
 type CreateUserResponse struct {
@@ -104,11 +524,48 @@ type UserInfo struct {
 }
 
 type UpdateUserRequest struct {
-	ID       string `json:"id"`
-	Name     string `json:"name"`
-	Email    string `json:"-"`
-	Password string `json:"password"`
-	IsAdmin  bool   `json:"is_admin"`
+	ID   string `json:"-"`
+	Name string `json:"name"`
+	// Password and Email are only decoded so that callers can be pointed to
+	// the dedicated change routes, which require re-authentication.
+	Password *string `json:"password"`
+	Email    *string `json:"email"`
+	// IsAdmin is only decoded so that attempts to set it can be rejected.
+	IsAdmin *bool `json:"is_admin"`
+}
+
+// ListUsersRequest is bound from the query string of GET /users.
+type ListUsersRequest struct {
+	Query  string `form:"q"`
+	Limit  int    `form:"limit" binding:"omitempty,min=1,max=200"`
+	Offset int    `form:"offset" binding:"omitempty,min=0"`
+}
+
+type AdminUpdateUserRequest struct {
+	UserID string `json:"-"`
+	Name   string `json:"name"`
+	Email  string `json:"email" binding:"omitempty,email"`
+	// IsAdmin is only decoded so that attempts to set it can be rejected.
+	IsAdmin *bool `json:"is_admin"`
+}
+
+// UserStatusRequest is the body of the disable route.
+type UserStatusRequest struct {
+	UserID string `json:"-"`
+	Reason string `json:"reason" binding:"required"`
+}
+
+// SuspendUserRequest is the body of the suspend route. Until is when the
+// suspension ends, in seconds since the epoch.
+type SuspendUserRequest struct {
+	UserID string `json:"-"`
+	Reason string `json:"reason" binding:"required"`
+	Until  uint64 `json:"until" binding:"required"`
+}
+
+type SetUserAdminRequest struct {
+	UserID  string `json:"-"`
+	IsAdmin *bool  `json:"is_admin" binding:"required"`
 }
 
 type DeleteUserRequest struct {
@@ -128,6 +585,14 @@ type Session struct {
 type LoginRequest struct {
 	Email    string `json:"email" binding:"required"`
 	Password string `json:"password" binding:"required"`
+	ClientIP string `json:"-"`
+}
+
+type LoginThrottle struct {
+	Key           string `json:"key"`
+	Failures      int    `json:"failures"`
+	LockedUntil   uint64 `json:"locked_until"`
+	LastFailureAt uint64 `json:"last_failure_at"`
 }
 
 type LoginResponse struct {
@@ -136,6 +601,25 @@ type LoginResponse struct {
 	RefreshToken string `json:"refresh_token"`
 }
 
+type UserMFA struct {
+	UserID       string `json:"user_id"`
+	Secret       string `json:"-"`
+	Enabled      bool   `json:"enabled"`
+	LastUsedStep int64  `json:"-"`
+	CreatedAt    uint64 `json:"created_at"`
+	EnabledAt    uint64 `json:"enabled_at"`
+}
+
+type MFACodeRequest struct {
+	Code string `json:"code" binding:"required"`
+}
+
+type VerifyMFARequest struct {
+	MFAToken string `json:"mfa_token" binding:"required"`
+	Code     string `json:"code" binding:"required"`
+	ClientIP string `json:"-"`
+}
+
 type LogoutRequest struct {
 	SessionID string `json:"session_id" binding:"required"`
 }
@@ -148,3 +632,169 @@ type RefreshAccessTokenRequest struct {
 type RefreshAccessTokenResponse struct {
 	AccessToken string `json:"access_token"`
 }
+
+type PasswordResetToken struct {
+	ID        string `json:"id"`
+	UserID    string `json:"user_id"`
+	TokenHash string `json:"-"`
+	ExpiresAt uint64 `json:"expires_at"`
+	UsedAt    uint64 `json:"used_at"`
+	CreatedAt uint64 `json:"created_at"`
+}
+
+type RequestPasswordResetRequest struct {
+	Email string `json:"email" binding:"required,email"`
+}
+
+type ResetPasswordRequest struct {
+	Token    string `json:"token" binding:"required"`
+	Password string `json:"password" binding:"required,min=8"`
+}
+
+type ChangePasswordRequest struct {
+	UserID          string `json:"-"`
+	SessionID       string `json:"-"`
+	CurrentPassword string `json:"current_password" binding:"required"`
+	NewPassword     string `json:"new_password" binding:"required,min=8"`
+}
+
+type EmailChangeToken struct {
+	ID        string `json:"id"`
+	UserID    string `json:"user_id"`
+	NewEmail  string `json:"new_email"`
+	TokenHash string `json:"-"`
+	ExpiresAt uint64 `json:"expires_at"`
+	UsedAt    uint64 `json:"used_at"`
+	CreatedAt uint64 `json:"created_at"`
+}
+
+type RequestEmailChangeRequest struct {
+	UserID          string `json:"-"`
+	CurrentPassword string `json:"current_password" binding:"required"`
+	NewEmail        string `json:"new_email" binding:"required,email"`
+}
+
+type ConfirmEmailChangeRequest struct {
+	Token string `json:"token" binding:"required"`
+}
+
+type Role struct {
+	ID          string   `json:"id"`
+	Name        string   `json:"name"`
+	Description string   `json:"description"`
+	Permissions []string `json:"permissions"`
+	CreatedAt   uint64   `json:"created_at"`
+}
+
+type AssignUserRoleRequest struct {
+	UserID string `json:"-"`
+	Role   string `json:"role" binding:"required"`
+}
+
+type APIKey struct {
+	ID         string   `json:"id"`
+	Name       string   `json:"name"`
+	Prefix     string   `json:"prefix"`
+	KeyHash    string   `json:"-"`
+	Scopes     []string `json:"scopes"`
+	CreatedBy  string   `json:"created_by"`
+	ExpiresAt  uint64   `json:"expires_at"`
+	LastUsedAt uint64   `json:"last_used_at"`
+	RevokedAt  uint64   `json:"revoked_at"`
+	CreatedAt  uint64   `json:"created_at"`
+}
+
+type CreateAPIKeyRequest struct {
+	Name      string   `json:"name" binding:"required"`
+	Scopes    []string `json:"scopes" binding:"required,min=1"`
+	ExpiresAt uint64   `json:"expires_at"`
+}
+
+type OIDCLoginState struct {
+	State        string `json:"state"`
+	Provider     string `json:"provider"`
+	CodeVerifier string `json:"-"`
+	Nonce        string `json:"-"`
+	ExpiresAt    uint64 `json:"expires_at"`
+	CreatedAt    uint64 `json:"created_at"`
+}
+
+type UserIdentity struct {
+	Provider  string `json:"provider"`
+	Subject   string `json:"subject"`
+	UserID    string `json:"user_id"`
+	Email     string `json:"email"`
+	CreatedAt uint64 `json:"created_at"`
+}
+
+type ImpersonateUserRequest struct {
+	UserID string `json:"-"`
+	Reason string `json:"reason" binding:"required"`
+}
+
+// PersonalAuditValue stands in for personal data in audit events.
+const PersonalAuditValue = "[PERSONAL]"
+
+// PersonalAuditFields lists the fields holding personal data for each audit
+// target type. Audit events record changes to them by field name only, and
+// erasing a user scrubs them from older events.
+var PersonalAuditFields = map[string][]string{
+	"user":  {"name", "email"},
+	"order": {"shipping_location"},
+}
+
+type AuditEvent struct {
+	ID         string         `json:"id"`
+	ActorID    string         `json:"actor_id"`
+	Action     string         `json:"action"`
+	TargetType string         `json:"target_type"`
+	TargetID   string         `json:"target_id"`
+	Before     map[string]any `json:"before"`
+	After      map[string]any `json:"after"`
+	RequestID  string         `json:"request_id"`
+	Metadata   map[string]any `json:"metadata"`
+	CreatedAt  uint64         `json:"created_at"`
+}
+
+// AuditEventFilter narrows ListAuditEvents. Zero values match everything.
+type AuditEventFilter struct {
+	ActorID    string `form:"actor_id"`
+	Action     string `form:"action"`
+	TargetType string `form:"target_type"`
+	TargetID   string `form:"target_id"`
+	RequestID  string `form:"request_id"`
+	Since      uint64 `form:"since"`
+	Until      uint64 `form:"until"`
+	Limit      int    `form:"limit" binding:"omitempty,min=1,max=500"`
+	Offset     int    `form:"offset" binding:"omitempty,min=0"`
+}
+
+// UserDataExport is the personal data archive handed to a user who asks for
+// a copy of their data.
+type UserDataExport struct {
+	ExportedAt uint64            `json:"exported_at"`
+	Profile    UserProfileExport `json:"profile"`
+	Roles      []string          `json:"roles"`
+	MFAEnabled bool              `json:"mfa_enabled"`
+	Identities []*UserIdentity   `json:"identities"`
+	Sessions   []SessionExport   `json:"sessions"`
+	Orders     []*Order          `json:"orders"`
+
+	StockSubscriptions []*StockSubscription `json:"stock_subscriptions"`
+}
+
+type UserProfileExport struct {
+	ID        string `json:"id"`
+	Name      string `json:"name"`
+	Email     string `json:"email"`
+	IsAdmin   bool   `json:"is_admin"`
+	CreatedAt uint64 `json:"created_at"`
+	UpdatedAt uint64 `json:"updated_at"`
+}
+
+type SessionExport struct {
+	ID        string `json:"id"`
+	IsRevoked bool   `json:"is_revoked"`
+	CreatedAt uint64 `json:"created_at"`
+	ExpiresAt uint64 `json:"expires_at"`
+}
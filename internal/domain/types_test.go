@@ -0,0 +1,57 @@
+package domain
+
+import "testing"
+
+func TestProductSaleActive(t *testing.T) {
+	tests := []struct {
+		name string
+		sale *ProductSale
+		now  uint64
+		want bool
+	}{
+		{"no sale", nil, 150, false},
+		{"before start", &ProductSale{StartsAt: 100, EndsAt: 200}, 99, false},
+		{"at start", &ProductSale{StartsAt: 100, EndsAt: 200}, 100, true},
+		{"running", &ProductSale{StartsAt: 100, EndsAt: 200}, 150, true},
+		{"just before end", &ProductSale{StartsAt: 100, EndsAt: 200}, 199, true},
+		{"at end", &ProductSale{StartsAt: 100, EndsAt: 200}, 200, false},
+		{"without end", &ProductSale{StartsAt: 100}, 1 << 40, true},
+		{"without end before start", &ProductSale{StartsAt: 100}, 99, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.sale.Active(tt.now); got != tt.want {
+				t.Fatalf("Active(%d) = %v; want %v", tt.now, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestProductCurrentPrice(t *testing.T) {
+	tests := []struct {
+		name      string
+		sale      *ProductSale
+		now       uint64
+		price     float64
+		compareAt float64
+	}{
+		{"no sale", nil, 150, 10, 0},
+		{"before start", &ProductSale{Price: 8, StartsAt: 100, EndsAt: 200}, 99, 10, 0},
+		{"at start", &ProductSale{Price: 8, StartsAt: 100, EndsAt: 200}, 100, 8, 10},
+		{"at end", &ProductSale{Price: 8, StartsAt: 100, EndsAt: 200}, 200, 10, 0},
+		{"without end", &ProductSale{Price: 8, StartsAt: 100}, 1 << 40, 8, 10},
+		{"sale at regular price", &ProductSale{Price: 10, StartsAt: 100}, 150, 10, 0},
+		{"sale above regular price", &ProductSale{Price: 12, StartsAt: 100}, 150, 10, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			product := &Product{Price: 10, Sale: tt.sale}
+			price, compareAt := product.CurrentPrice(tt.now)
+			if price != tt.price || compareAt != tt.compareAt {
+				t.Fatalf("CurrentPrice(%d) = %v, %v; want %v, %v", tt.now, price, compareAt, tt.price, tt.compareAt)
+			}
+		})
+	}
+}
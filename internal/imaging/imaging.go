@@ -0,0 +1,63 @@
+// Package imaging resizes images using only the standard library.
+package imaging
+
+import (
+	"image"
+	"image/draw"
+)
+
+// Fit scales img down so that it fits within maxWidth x maxHeight while
+// keeping its aspect ratio. Images that already fit are returned unchanged;
+// images are never enlarged.
+func Fit(img image.Image, maxWidth, maxHeight int) image.Image {
+	bounds := img.Bounds()
+	srcWidth, srcHeight := bounds.Dx(), bounds.Dy()
+	if srcWidth <= maxWidth && srcHeight <= maxHeight {
+		return img
+	}
+
+	width, height := maxWidth, srcHeight*maxWidth/srcWidth
+	if height > maxHeight {
+		width, height = srcWidth*maxHeight/srcHeight, maxHeight
+	}
+	return Resize(img, max(width, 1), max(height, 1))
+}
+
+// Resize scales img to width x height with a box filter: every destination
+// pixel is the average of the source pixels it covers, which gives good
+// results when shrinking.
+func Resize(img image.Image, width, height int) *image.RGBA {
+	bounds := img.Bounds()
+	src := image.NewRGBA(image.Rect(0, 0, bounds.Dx(), bounds.Dy()))
+	draw.Draw(src, src.Bounds(), img, bounds.Min, draw.Src)
+
+	srcWidth, srcHeight := src.Bounds().Dx(), src.Bounds().Dy()
+	dst := image.NewRGBA(image.Rect(0, 0, width, height))
+	for y := range height {
+		y0 := y * srcHeight / height
+		y1 := max((y+1)*srcHeight/height, y0+1)
+		for x := range width {
+			x0 := x * srcWidth / width
+			x1 := max((x+1)*srcWidth/width, x0+1)
+
+			var r, g, b, a, n uint64
+			for sy := y0; sy < y1; sy++ {
+				row := src.Pix[sy*src.Stride+x0*4 : sy*src.Stride+x1*4]
+				for i := 0; i < len(row); i += 4 {
+					r += uint64(row[i])
+					g += uint64(row[i+1])
+					b += uint64(row[i+2])
+					a += uint64(row[i+3])
+					n++
+				}
+			}
+
+			offset := y*dst.Stride + x*4
+			dst.Pix[offset] = uint8(r / n)
+			dst.Pix[offset+1] = uint8(g / n)
+			dst.Pix[offset+2] = uint8(b / n)
+			dst.Pix[offset+3] = uint8(a / n)
+		}
+	}
+	return dst
+}
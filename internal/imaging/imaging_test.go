@@ -0,0 +1,40 @@
+package imaging
+
+import (
+	"image"
+	"image/color"
+	"testing"
+)
+
+func TestFit(t *testing.T) {
+	src := image.NewRGBA(image.Rect(0, 0, 400, 200))
+	// Left half red, right half blue.
+	for y := range 200 {
+		for x := range 400 {
+			c := color.RGBA{R: 255, A: 255}
+			if x >= 200 {
+				c = color.RGBA{B: 255, A: 255}
+			}
+			src.Set(x, y, c)
+		}
+	}
+
+	dst := Fit(src, 100, 100)
+	if got := dst.Bounds().Size(); got != image.Pt(100, 50) {
+		t.Fatalf("Fit() size = %v; want (100,50)", got)
+	}
+	if got := color.RGBAModel.Convert(dst.At(10, 10)); got != (color.RGBA{R: 255, A: 255}) {
+		t.Errorf("left pixel = %v; want red", got)
+	}
+	if got := color.RGBAModel.Convert(dst.At(90, 40)); got != (color.RGBA{B: 255, A: 255}) {
+		t.Errorf("right pixel = %v; want blue", got)
+	}
+
+	if small := Fit(src, 1000, 1000); small != src {
+		t.Error("Fit() enlarged or copied an image that already fits")
+	}
+
+	if got := Fit(image.NewRGBA(image.Rect(0, 0, 50, 400)), 100, 100).Bounds().Size(); got != image.Pt(12, 100) {
+		t.Errorf("Fit() of a tall image size = %v; want (12,100)", got)
+	}
+}
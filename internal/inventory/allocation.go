@@ -0,0 +1,171 @@
+// Package inventory decides which warehouses fulfil the lines of an order.
+package inventory
+
+import (
+	"cmp"
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"slices"
+)
+
+// ErrUnfulfillable is returned when the warehouses cannot supply the requested
+// quantity under the strategy's rules.
+var ErrUnfulfillable = errors.New("not enough stock in any warehouse")
+
+// Location is a point on the earth in decimal degrees.
+type Location struct {
+	Latitude  float64
+	Longitude float64
+}
+
+const earthRadiusKm = 6371
+
+// Distance returns the great-circle distance between a and b in kilometres.
+func Distance(a, b Location) float64 {
+	lat1, lat2 := a.Latitude*math.Pi/180, b.Latitude*math.Pi/180
+	dLat := lat2 - lat1
+	dLon := (b.Longitude - a.Longitude) * math.Pi / 180
+
+	h := math.Sin(dLat/2)*math.Sin(dLat/2) + math.Cos(lat1)*math.Cos(lat2)*math.Sin(dLon/2)*math.Sin(dLon/2)
+	return 2 * earthRadiusKm * math.Asin(math.Sqrt(h))
+}
+
+// Source is a warehouse together with the stock it holds of the item being
+// allocated. Location is nil if the warehouse's position is unknown. Lower
+// priorities are preferred.
+type Source struct {
+	WarehouseID string
+	Location    *Location
+	Priority    int
+	Available   int
+}
+
+// Allocation assigns part of a line to a warehouse.
+type Allocation struct {
+	WarehouseID string
+	Quantity    int
+}
+
+// Strategy picks the warehouses that ship quantity units of an item.
+// destination is nil if the shipping address is unknown.
+type Strategy interface {
+	Allocate(quantity int, sources []Source, destination *Location) ([]Allocation, error)
+}
+
+// Strategy names accepted by StrategyByName.
+const (
+	StrategyClosest  = "closest"
+	StrategyPriority = "priority"
+	StrategySplit    = "split"
+)
+
+func StrategyByName(name string) (Strategy, error) {
+	switch name {
+	case StrategyClosest:
+		return Closest{}, nil
+	case StrategyPriority:
+		return Priority{}, nil
+	case StrategySplit:
+		return Split{}, nil
+	default:
+		return nil, fmt.Errorf("unknown fulfilment strategy %q", name)
+	}
+}
+
+// StrategyFromEnv returns the strategy named by FULFILMENT_STRATEGY, which
+// defaults to priority.
+func StrategyFromEnv() (Strategy, error) {
+	name := os.Getenv("FULFILMENT_STRATEGY")
+	if name == "" {
+		name = StrategyPriority
+	}
+	return StrategyByName(name)
+}
+
+// Priority ships every line from the most preferred warehouse that has all
+// of it in stock.
+type Priority struct{}
+
+func (Priority) Allocate(quantity int, sources []Source, destination *Location) ([]Allocation, error) {
+	return single(quantity, rankByPriority(sources))
+}
+
+// Closest ships every line from the nearest warehouse that has all of it in
+// stock. Warehouses without a location come last, and without a destination
+// it behaves like Priority.
+type Closest struct{}
+
+func (Closest) Allocate(quantity int, sources []Source, destination *Location) ([]Allocation, error) {
+	return single(quantity, rankByDistance(sources, destination))
+}
+
+// Split ships a line from the nearest warehouse that has all of it in stock
+// and otherwise splits it across warehouses, nearest first, so that orders
+// are only refused when the combined stock is too low.
+type Split struct{}
+
+func (Split) Allocate(quantity int, sources []Source, destination *Location) ([]Allocation, error) {
+	ranked := rankByDistance(sources, destination)
+	if allocations, err := single(quantity, ranked); err == nil {
+		return allocations, nil
+	}
+
+	var allocations []Allocation
+	remaining := quantity
+	for _, source := range ranked {
+		if source.Available <= 0 {
+			continue
+		}
+		take := min(source.Available, remaining)
+		allocations = append(allocations, Allocation{WarehouseID: source.WarehouseID, Quantity: take})
+		remaining -= take
+		if remaining == 0 {
+			return allocations, nil
+		}
+	}
+	return nil, ErrUnfulfillable
+}
+
+func single(quantity int, ranked []Source) ([]Allocation, error) {
+	for _, source := range ranked {
+		if source.Available >= quantity {
+			return []Allocation{{WarehouseID: source.WarehouseID, Quantity: quantity}}, nil
+		}
+	}
+	return nil, ErrUnfulfillable
+}
+
+func comparePriority(a, b Source) int {
+	return cmp.Or(cmp.Compare(a.Priority, b.Priority), cmp.Compare(a.WarehouseID, b.WarehouseID))
+}
+
+func rankByPriority(sources []Source) []Source {
+	ranked := slices.Clone(sources)
+	slices.SortFunc(ranked, comparePriority)
+	return ranked
+}
+
+func rankByDistance(sources []Source, destination *Location) []Source {
+	if destination == nil {
+		return rankByPriority(sources)
+	}
+
+	ranked := slices.Clone(sources)
+	slices.SortFunc(ranked, func(a, b Source) int {
+		if (a.Location == nil) != (b.Location == nil) {
+			if a.Location == nil {
+				return 1
+			}
+			return -1
+		}
+		if a.Location != nil {
+			if c := cmp.Compare(Distance(*a.Location, *destination), Distance(*b.Location, *destination)); c != 0 {
+				return c
+			}
+		}
+		return comparePriority(a, b)
+	})
+	return ranked
+}
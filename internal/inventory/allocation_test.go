@@ -0,0 +1,60 @@
+package inventory
+
+import (
+	"math"
+	"reflect"
+	"testing"
+)
+
+var (
+	berlin = Location{Latitude: 52.52, Longitude: 13.405}
+	paris  = Location{Latitude: 48.8566, Longitude: 2.3522}
+	madrid = Location{Latitude: 40.4168, Longitude: -3.7038}
+)
+
+func testSources(berlinStock, parisStock, madridStock int) []Source {
+	return []Source{
+		{WarehouseID: "berlin", Location: &berlin, Priority: 2, Available: berlinStock},
+		{WarehouseID: "paris", Location: &paris, Priority: 1, Available: parisStock},
+		{WarehouseID: "madrid", Location: &madrid, Priority: 3, Available: madridStock},
+	}
+}
+
+func TestDistance(t *testing.T) {
+	if d := Distance(berlin, paris); math.Abs(d-878) > 5 {
+		t.Fatalf("Distance(berlin, paris) = %.0f km; want about 878", d)
+	}
+}
+
+func TestAllocate(t *testing.T) {
+	lyon := &Location{Latitude: 45.764, Longitude: 4.8357}
+	warsaw := &Location{Latitude: 52.2297, Longitude: 21.0122}
+
+	tests := []struct {
+		name        string
+		strategy    Strategy
+		quantity    int
+		sources     []Source
+		destination *Location
+		want        []Allocation
+		wantErr     error
+	}{
+		{"priority", Priority{}, 5, testSources(10, 10, 10), warsaw, []Allocation{{"paris", 5}}, nil},
+		{"priority skips short warehouses", Priority{}, 5, testSources(10, 4, 10), nil, []Allocation{{"berlin", 5}}, nil},
+		{"closest", Closest{}, 5, testSources(10, 10, 10), warsaw, []Allocation{{"berlin", 5}}, nil},
+		{"closest without destination", Closest{}, 5, testSources(10, 10, 10), nil, []Allocation{{"paris", 5}}, nil},
+		{"closest never splits", Closest{}, 5, testSources(3, 3, 3), lyon, nil, ErrUnfulfillable},
+		{"split prefers one warehouse", Split{}, 5, testSources(3, 5, 10), lyon, []Allocation{{"paris", 5}}, nil},
+		{"split", Split{}, 8, testSources(3, 4, 5), lyon, []Allocation{{"paris", 4}, {"madrid", 4}}, nil},
+		{"split short", Split{}, 13, testSources(3, 4, 5), lyon, nil, ErrUnfulfillable},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got, err := test.strategy.Allocate(test.quantity, test.sources, test.destination)
+			if err != test.wantErr || !reflect.DeepEqual(got, test.want) {
+				t.Fatalf("Allocate() = %v, %v; want %v, %v", got, err, test.want, test.wantErr)
+			}
+		})
+	}
+}
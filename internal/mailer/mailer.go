@@ -0,0 +1,56 @@
+package mailer
+
+import (
+	"ecomm/pkg"
+	"fmt"
+	"net/smtp"
+	"strings"
+)
+
+// Mailer sends plain text emails. to may list several addresses separated by
+// commas, which then all receive the same message.
+type Mailer interface {
+	Send(to, subject, body string) error
+}
+
+// NewLogMailer returns a Mailer that writes messages to the log instead of
+// delivering them. It is used when no SMTP server is configured.
+func NewLogMailer() Mailer {
+	return &logMailer{}
+}
+
+type logMailer struct{}
+
+func (m *logMailer) Send(to, subject, body string) error {
+	pkg.Logger.Printf("mail to=%s subject=%q\n%s", to, subject, body)
+	return nil
+}
+
+func NewSMTPMailer(addr, from string, auth smtp.Auth) Mailer {
+	return &smtpMailer{
+		addr: addr,
+		from: from,
+		auth: auth,
+	}
+}
+
+type smtpMailer struct {
+	addr string
+	from string
+	auth smtp.Auth
+}
+
+func (m *smtpMailer) Send(to, subject, body string) error {
+	var msg strings.Builder
+	fmt.Fprintf(&msg, "From: %s\r\n", m.from)
+	fmt.Fprintf(&msg, "To: %s\r\n", to)
+	fmt.Fprintf(&msg, "Subject: %s\r\n", subject)
+	msg.WriteString("Content-Type: text/plain; charset=UTF-8\r\n\r\n")
+	msg.WriteString(body)
+
+	recipients := strings.Split(to, ",")
+	for i, recipient := range recipients {
+		recipients[i] = strings.TrimSpace(recipient)
+	}
+	return smtp.SendMail(m.addr, m.auth, m.from, recipients, []byte(msg.String()))
+}
@@ -0,0 +1,70 @@
+// Package notify tells admins about low stock and customers about products
+// that are back in stock.
+package notify
+
+import (
+	"ecomm/internal/domain"
+	"ecomm/internal/mailer"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// Notifier delivers stock notifications. Implementations other than email,
+// such as chat or webhooks, can be plugged into the Worker.
+type Notifier interface {
+	// LowStock tells the admins that an item has reached its reorder
+	// threshold. It either reaches all of them or fails, so that a retry
+	// does not notify anyone twice.
+	LowStock(alert *domain.StockAlert) error
+	// BackInStock tells a subscriber that the item they asked about can be
+	// ordered again.
+	BackInStock(subscription *domain.StockSubscription) error
+}
+
+// NewMailNotifier returns a Notifier that sends emails. Low-stock alerts go to
+// adminEmails in a single message and are dropped if it is empty.
+func NewMailNotifier(m mailer.Mailer, adminEmails []string) Notifier {
+	return &mailNotifier{
+		mailer:      m,
+		adminEmails: adminEmails,
+	}
+}
+
+type mailNotifier struct {
+	mailer      mailer.Mailer
+	adminEmails []string
+}
+
+func (n *mailNotifier) LowStock(alert *domain.StockAlert) error {
+	item := alert.ProductName
+	if alert.VariantID != "" {
+		item = fmt.Sprintf("%s (variant %s)", alert.ProductName, alert.VariantID)
+	}
+
+	subject := "Low stock: " + alert.ProductName
+	body := fmt.Sprintf("The stock of %s fell to %d, at or below its reorder threshold of %d.\n",
+		item, alert.Stock, alert.Threshold)
+	if len(n.adminEmails) == 0 {
+		return nil
+	}
+	return n.mailer.Send(strings.Join(n.adminEmails, ", "), subject, body)
+}
+
+func (n *mailNotifier) BackInStock(subscription *domain.StockSubscription) error {
+	subject := subscription.ProductName + " is back in stock"
+	body := fmt.Sprintf("Good news: %s, which you asked us to watch, is available again.\n", subscription.ProductName)
+	return n.mailer.Send(subscription.Email, subject, body)
+}
+
+// AdminEmailsFromEnv returns the addresses listed in STOCK_ALERT_EMAILS,
+// separated by commas.
+func AdminEmailsFromEnv() []string {
+	var emails []string
+	for _, email := range strings.Split(os.Getenv("STOCK_ALERT_EMAILS"), ",") {
+		if email = strings.TrimSpace(email); email != "" {
+			emails = append(emails, email)
+		}
+	}
+	return emails
+}
@@ -0,0 +1,98 @@
+package notify
+
+import (
+	"context"
+	"ecomm/internal/domain"
+	"ecomm/pkg"
+	"time"
+)
+
+// batchSize caps the notifications sent per kind and run.
+const batchSize = 100
+
+// claimTimeout is how long a claimed notification is left to the worker that
+// claimed it. It must exceed the time a run takes to send a batch.
+const claimTimeout = 10 * time.Minute
+
+// Store is the part of the repository the Worker needs.
+type Store interface {
+	ClaimStockAlerts(limit int, claimTimeout time.Duration) ([]*domain.StockAlert, error)
+	MarkStockAlertNotified(id string) error
+	ClaimReplenishedStockSubscriptions(limit int, claimTimeout time.Duration) ([]*domain.StockSubscription, error)
+	MarkStockSubscriptionNotified(id string) error
+}
+
+// Worker periodically sends the pending low-stock alerts and the
+// back-in-stock notifications of subscriptions whose item has been
+// replenished. Notifications are claimed before they are sent, so several
+// workers can run side by side. A notification that fails is retried once
+// its claim has expired.
+type Worker struct {
+	store    Store
+	notifier Notifier
+	interval time.Duration
+}
+
+func NewWorker(store Store, notifier Notifier, interval time.Duration) *Worker {
+	return &Worker{
+		store:    store,
+		notifier: notifier,
+		interval: interval,
+	}
+}
+
+// Run sends notifications every interval until ctx is cancelled.
+func (w *Worker) Run(ctx context.Context) {
+	ticker := time.NewTicker(w.interval)
+	defer ticker.Stop()
+
+	for {
+		w.RunOnce()
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// RunOnce sends the notifications that are due and returns how many were
+// sent.
+func (w *Worker) RunOnce() int {
+	sent := 0
+
+	alerts, err := w.store.ClaimStockAlerts(batchSize, claimTimeout)
+	if err != nil {
+		pkg.ErrorLogger.Printf("failed to list stock alerts: %v", err)
+	}
+	for _, alert := range alerts {
+		if err := w.notifier.LowStock(alert); err != nil {
+			pkg.ErrorLogger.Printf("failed to send stock alert %s: %v", alert.ID, err)
+			continue
+		}
+		if err := w.store.MarkStockAlertNotified(alert.ID); err != nil {
+			pkg.ErrorLogger.Printf("failed to mark stock alert %s as sent: %v", alert.ID, err)
+			continue
+		}
+		sent++
+	}
+
+	subscriptions, err := w.store.ClaimReplenishedStockSubscriptions(batchSize, claimTimeout)
+	if err != nil {
+		pkg.ErrorLogger.Printf("failed to list stock subscriptions: %v", err)
+	}
+	for _, subscription := range subscriptions {
+		if err := w.notifier.BackInStock(subscription); err != nil {
+			pkg.ErrorLogger.Printf("failed to send back-in-stock notification %s: %v", subscription.ID, err)
+			continue
+		}
+		if err := w.store.MarkStockSubscriptionNotified(subscription.ID); err != nil {
+			pkg.ErrorLogger.Printf("failed to mark stock subscription %s as notified: %v", subscription.ID, err)
+			continue
+		}
+		sent++
+	}
+
+	return sent
+}
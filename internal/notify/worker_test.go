@@ -0,0 +1,133 @@
+package notify
+
+import (
+	"ecomm/internal/domain"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+)
+
+type fakeStore struct {
+	alerts        []*domain.StockAlert
+	subscriptions []*domain.StockSubscription
+	notified      []string
+	// claimTimeouts holds the claim timeout of every claim.
+	claimTimeouts []time.Duration
+}
+
+func (s *fakeStore) ClaimStockAlerts(limit int, claimTimeout time.Duration) ([]*domain.StockAlert, error) {
+	s.claimTimeouts = append(s.claimTimeouts, claimTimeout)
+	return s.alerts, nil
+}
+
+func (s *fakeStore) MarkStockAlertNotified(id string) error {
+	s.notified = append(s.notified, id)
+	return nil
+}
+
+func (s *fakeStore) ClaimReplenishedStockSubscriptions(limit int, claimTimeout time.Duration) ([]*domain.StockSubscription, error) {
+	s.claimTimeouts = append(s.claimTimeouts, claimTimeout)
+	return s.subscriptions, nil
+}
+
+func (s *fakeStore) MarkStockSubscriptionNotified(id string) error {
+	s.notified = append(s.notified, id)
+	return nil
+}
+
+type fakeNotifier struct {
+	failing string
+}
+
+func (n *fakeNotifier) LowStock(alert *domain.StockAlert) error {
+	if alert.ID == n.failing {
+		return errors.New("unavailable")
+	}
+	return nil
+}
+
+func (n *fakeNotifier) BackInStock(subscription *domain.StockSubscription) error {
+	if subscription.ID == n.failing {
+		return errors.New("unavailable")
+	}
+	return nil
+}
+
+func TestRunOnceMarksOnlyDeliveredNotifications(t *testing.T) {
+	store := &fakeStore{
+		alerts:        []*domain.StockAlert{{ID: "alert-1"}, {ID: "alert-2"}},
+		subscriptions: []*domain.StockSubscription{{ID: "subscription-1"}},
+	}
+	worker := NewWorker(store, &fakeNotifier{failing: "alert-2"}, time.Minute)
+
+	if sent := worker.RunOnce(); sent != 2 {
+		t.Fatalf("RunOnce() = %d; want 2", sent)
+	}
+	if want := []string{"alert-1", "subscription-1"}; !reflect.DeepEqual(store.notified, want) {
+		t.Fatalf("notified = %v; want %v", store.notified, want)
+	}
+}
+
+func TestRunOnceClaimsForLongerThanTheInterval(t *testing.T) {
+	store := &fakeStore{}
+	NewWorker(store, &fakeNotifier{}, time.Minute).RunOnce()
+
+	if len(store.claimTimeouts) != 2 {
+		t.Fatalf("claimed %d times; want alerts and subscriptions", len(store.claimTimeouts))
+	}
+	for _, timeout := range store.claimTimeouts {
+		if timeout <= time.Minute {
+			t.Fatalf("claim timeout = %v; want more than a run", timeout)
+		}
+	}
+}
+
+type sentMail struct {
+	to, subject string
+}
+
+type fakeMailer struct {
+	sent []sentMail
+	err  error
+}
+
+func (m *fakeMailer) Send(to, subject, body string) error {
+	m.sent = append(m.sent, sentMail{to, subject})
+	return m.err
+}
+
+func TestLowStockSendsOneMessageToAllAdmins(t *testing.T) {
+	m := &fakeMailer{}
+	notifier := NewMailNotifier(m, []string{"ops@example.com", "buyer@example.com"})
+
+	if err := notifier.LowStock(&domain.StockAlert{ProductName: "Mug", Stock: 1, Threshold: 2}); err != nil {
+		t.Fatalf("LowStock() error = %v", err)
+	}
+	want := []sentMail{{"ops@example.com, buyer@example.com", "Low stock: Mug"}}
+	if !reflect.DeepEqual(m.sent, want) {
+		t.Fatalf("sent %v; want %v", m.sent, want)
+	}
+}
+
+func TestLowStockFailureSendsNothingElse(t *testing.T) {
+	m := &fakeMailer{err: errors.New("unavailable")}
+	notifier := NewMailNotifier(m, []string{"ops@example.com", "buyer@example.com"})
+
+	if err := notifier.LowStock(&domain.StockAlert{ProductName: "Mug"}); err == nil {
+		t.Fatal("LowStock() error = nil")
+	}
+	if len(m.sent) != 1 {
+		t.Fatalf("sent %d messages; want the single attempt", len(m.sent))
+	}
+}
+
+func TestLowStockWithoutAdminsSendsNothing(t *testing.T) {
+	m := &fakeMailer{}
+	if err := NewMailNotifier(m, nil).LowStock(&domain.StockAlert{ProductName: "Mug"}); err != nil {
+		t.Fatalf("LowStock() error = %v", err)
+	}
+	if len(m.sent) != 0 {
+		t.Fatalf("sent %v; want nothing", m.sent)
+	}
+}
@@ -0,0 +1,158 @@
+package oidc_test
+
+import (
+	"context"
+	"ecomm/internal/oidc"
+	"ecomm/internal/oidc/oidctest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+func newProvider(fake *oidctest.Provider) *oidc.Provider {
+	return oidc.NewProvider(oidc.Config{
+		Name:         "fake",
+		Issuer:       fake.Issuer(),
+		ClientID:     oidctest.ClientID,
+		ClientSecret: oidctest.ClientSecret,
+		RedirectURL:  "http://localhost:8080/auth/oidc/fake/callback",
+	}, fake.Client())
+}
+
+func startLogin(t *testing.T, fake *oidctest.Provider, provider *oidc.Provider) (code, verifier, nonce string) {
+	t.Helper()
+
+	state, err := oidc.RandomString()
+	if err != nil {
+		t.Fatal(err)
+	}
+	nonce, err = oidc.RandomString()
+	if err != nil {
+		t.Fatal(err)
+	}
+	verifier, err = oidc.RandomString()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	authURL, err := provider.AuthCodeURL(context.Background(), state, nonce, verifier)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !strings.HasPrefix(authURL, fake.Issuer()+"/authorize?") {
+		t.Fatalf("unexpected authorization URL %s", authURL)
+	}
+
+	return fake.Authorize(authURL), verifier, nonce
+}
+
+func TestExchange(t *testing.T) {
+	fake := oidctest.NewProvider(t)
+	provider := newProvider(fake)
+
+	code, verifier, nonce := startLogin(t, fake, provider)
+	claims, err := provider.Exchange(context.Background(), code, verifier, nonce)
+	if err != nil {
+		t.Fatalf("Exchange() error = %v", err)
+	}
+
+	if claims.Subject != fake.Subject {
+		t.Errorf("Subject = %q, want %q", claims.Subject, fake.Subject)
+	}
+	if claims.Email != fake.Email || !bool(claims.EmailVerified) {
+		t.Errorf("unexpected email claims %q verified=%v", claims.Email, claims.EmailVerified)
+	}
+}
+
+func TestExchangeRejectsWrongCodeVerifier(t *testing.T) {
+	fake := oidctest.NewProvider(t)
+	provider := newProvider(fake)
+
+	code, _, nonce := startLogin(t, fake, provider)
+	if _, err := provider.Exchange(context.Background(), code, "wrong-verifier", nonce); err == nil {
+		t.Fatal("Exchange() succeeded with the wrong code verifier")
+	}
+}
+
+func TestExchangeRejectsWrongNonce(t *testing.T) {
+	fake := oidctest.NewProvider(t)
+	provider := newProvider(fake)
+
+	code, verifier, _ := startLogin(t, fake, provider)
+	if _, err := provider.Exchange(context.Background(), code, verifier, "other-nonce"); err == nil {
+		t.Fatal("Exchange() succeeded with the wrong nonce")
+	}
+}
+
+func TestVerifyIDTokenRejectsInvalidTokens(t *testing.T) {
+	fake := oidctest.NewProvider(t)
+	provider := newProvider(fake)
+
+	tests := map[string]string{
+		"wrong audience": fake.IDToken("other-client", "nonce", time.Now().Add(time.Hour)),
+		"expired":        fake.IDToken(oidctest.ClientID, "nonce", time.Now().Add(-time.Hour)),
+		"tampered":       fake.IDToken(oidctest.ClientID, "nonce", time.Now().Add(time.Hour)) + "x",
+	}
+
+	for name, token := range tests {
+		t.Run(name, func(t *testing.T) {
+			if _, err := provider.VerifyIDToken(context.Background(), token, "nonce"); err == nil {
+				t.Fatal("VerifyIDToken() succeeded")
+			}
+		})
+	}
+}
+
+func TestVerifyIDTokenStringEmailVerified(t *testing.T) {
+	fake := oidctest.NewProvider(t)
+	provider := newProvider(fake)
+
+	token := fake.Sign(jwt.MapClaims{
+		"iss":            fake.Issuer(),
+		"sub":            fake.Subject,
+		"aud":            oidctest.ClientID,
+		"exp":            time.Now().Add(time.Hour).Unix(),
+		"nonce":          "nonce",
+		"email":          fake.Email,
+		"email_verified": "true",
+	})
+
+	claims, err := provider.VerifyIDToken(context.Background(), token, "nonce")
+	if err != nil {
+		t.Fatalf("VerifyIDToken() error = %v", err)
+	}
+	if !bool(claims.EmailVerified) {
+		t.Error("EmailVerified = false, want true")
+	}
+}
+
+func TestUnknownKeyIDDoesNotRefetchKeysEveryTime(t *testing.T) {
+	fake := oidctest.NewProvider(t)
+	provider := newProvider(fake)
+
+	valid := fake.IDToken(oidctest.ClientID, "nonce", time.Now().Add(time.Hour))
+	if _, err := provider.VerifyIDToken(context.Background(), valid, "nonce"); err != nil {
+		t.Fatalf("VerifyIDToken() error = %v", err)
+	}
+
+	claims := jwt.MapClaims{
+		"iss":   fake.Issuer(),
+		"sub":   fake.Subject,
+		"aud":   oidctest.ClientID,
+		"exp":   time.Now().Add(time.Hour).Unix(),
+		"nonce": "nonce",
+	}
+	for i := 0; i < 5; i++ {
+		token := fake.SignWithKeyID(claims, "unknown-key")
+		if _, err := provider.VerifyIDToken(context.Background(), token, "nonce"); err == nil {
+			t.Fatal("VerifyIDToken() succeeded with an unknown key")
+		}
+	}
+
+	if got := fake.JWKSRequests(); got != 1 {
+		t.Fatalf("key set fetched %d times; want 1", got)
+	}
+}
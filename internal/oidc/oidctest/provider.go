@@ -0,0 +1,200 @@
+// Package oidctest provides an in-process OpenID provider for tests.
+package oidctest
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const (
+	ClientID     = "client-id"
+	ClientSecret = "client-secret"
+	KeyID        = "test-key"
+)
+
+// Provider issues authorization codes for a single configurable user and
+// enforces PKCE and client authentication on its token endpoint.
+type Provider struct {
+	t      testing.TB
+	server *httptest.Server
+	key    *rsa.PrivateKey
+
+	Subject       string
+	Email         string
+	EmailVerified bool
+	Name          string
+
+	mu           sync.Mutex
+	codes        map[string]authorization
+	jwksRequests int
+}
+
+type authorization struct {
+	challenge string
+	nonce     string
+	audience  string
+}
+
+func NewProvider(t testing.TB) *Provider {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	p := &Provider{
+		t:             t,
+		key:           key,
+		Subject:       "user-123",
+		Email:         "jane@example.com",
+		EmailVerified: true,
+		Name:          "Jane Doe",
+		codes:         make(map[string]authorization),
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", p.handleDiscovery)
+	mux.HandleFunc("/jwks", p.handleJWKS)
+	mux.HandleFunc("/token", p.handleToken)
+	p.server = httptest.NewServer(mux)
+	t.Cleanup(p.server.Close)
+
+	return p
+}
+
+func (p *Provider) Issuer() string {
+	return p.server.URL
+}
+
+func (p *Provider) Client() *http.Client {
+	return p.server.Client()
+}
+
+// Authorize simulates the user approving the request at the authorization
+// endpoint and returns the code that would be sent to the redirect URL.
+func (p *Provider) Authorize(authURL string) string {
+	u, err := url.Parse(authURL)
+	if err != nil {
+		p.t.Fatal(err)
+	}
+
+	query := u.Query()
+	if query.Get("code_challenge_method") != "S256" {
+		p.t.Fatalf("unexpected code_challenge_method %q", query.Get("code_challenge_method"))
+	}
+
+	code := "code-" + query.Get("state")
+	p.mu.Lock()
+	p.codes[code] = authorization{
+		challenge: query.Get("code_challenge"),
+		nonce:     query.Get("nonce"),
+		audience:  query.Get("client_id"),
+	}
+	p.mu.Unlock()
+	return code
+}
+
+// IDToken returns an ID token for the configured user signed by the provider.
+func (p *Provider) IDToken(audience, nonce string, expiresAt time.Time) string {
+	return p.Sign(jwt.MapClaims{
+		"iss":            p.server.URL,
+		"sub":            p.Subject,
+		"aud":            audience,
+		"exp":            expiresAt.Unix(),
+		"iat":            time.Now().Unix(),
+		"nonce":          nonce,
+		"email":          p.Email,
+		"email_verified": p.EmailVerified,
+		"name":           p.Name,
+	})
+}
+
+// Sign signs arbitrary claims with the provider's key.
+func (p *Provider) Sign(claims jwt.MapClaims) string {
+	return p.SignWithKeyID(claims, KeyID)
+}
+
+// SignWithKeyID signs claims with the provider's key but names another key
+// in the header, as a token signed after a key rotation would.
+func (p *Provider) SignWithKeyID(claims jwt.MapClaims, kid string) string {
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+	token.Header["kid"] = kid
+
+	signed, err := token.SignedString(p.key)
+	if err != nil {
+		p.t.Fatal(err)
+	}
+	return signed
+}
+
+func (p *Provider) handleDiscovery(w http.ResponseWriter, r *http.Request) {
+	json.NewEncoder(w).Encode(map[string]string{
+		"issuer":                 p.server.URL,
+		"authorization_endpoint": p.server.URL + "/authorize",
+		"token_endpoint":         p.server.URL + "/token",
+		"jwks_uri":               p.server.URL + "/jwks",
+	})
+}
+
+// JWKSRequests returns how often the key set was requested.
+func (p *Provider) JWKSRequests() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.jwksRequests
+}
+
+func (p *Provider) handleJWKS(w http.ResponseWriter, r *http.Request) {
+	p.mu.Lock()
+	p.jwksRequests++
+	p.mu.Unlock()
+
+	json.NewEncoder(w).Encode(map[string]any{
+		"keys": []map[string]string{{
+			"kid": KeyID,
+			"kty": "RSA",
+			"use": "sig",
+			"alg": "RS256",
+			"n":   base64.RawURLEncoding.EncodeToString(p.key.N.Bytes()),
+			"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(p.key.E)).Bytes()),
+		}},
+	})
+}
+
+func (p *Provider) handleToken(w http.ResponseWriter, r *http.Request) {
+	clientID, clientSecret, ok := r.BasicAuth()
+	if !ok || clientID != ClientID || clientSecret != ClientSecret {
+		w.WriteHeader(http.StatusUnauthorized)
+		json.NewEncoder(w).Encode(map[string]string{"error": "invalid_client"})
+		return
+	}
+
+	code := r.PostFormValue("code")
+	p.mu.Lock()
+	auth, ok := p.codes[code]
+	delete(p.codes, code)
+	p.mu.Unlock()
+
+	sum := sha256.Sum256([]byte(r.PostFormValue("code_verifier")))
+	if !ok || base64.RawURLEncoding.EncodeToString(sum[:]) != auth.challenge {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": "invalid_grant"})
+		return
+	}
+
+	json.NewEncoder(w).Encode(map[string]string{
+		"access_token": "access-token",
+		"token_type":   "Bearer",
+		"id_token":     p.IDToken(auth.audience, auth.nonce, time.Now().Add(time.Hour)),
+	})
+}
@@ -0,0 +1,126 @@
+// Package password hashes and verifies user passwords and checks new
+// passwords against a strength policy.
+//
+// Hashes are stored in self-describing encodings (PHC strings for Argon2id,
+// modular crypt format for bcrypt), so the algorithm and its parameters can
+// change without invalidating existing hashes. Hashes produced with an older
+// algorithm or weaker parameters are reported by Verify so that callers can
+// upgrade them after a successful login.
+package password
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+)
+
+// ErrUnknownHash is returned for hashes no configured algorithm recognizes.
+var ErrUnknownHash = errors.New("unknown password hash format")
+
+// Algorithm is a password hashing scheme with fixed parameters.
+type Algorithm interface {
+	// Hash returns the encoded hash of password, including salt and
+	// parameters.
+	Hash(password string) (string, error)
+	// Recognizes reports whether encoded was produced by this scheme.
+	Recognizes(encoded string) bool
+	// Verify reports whether password matches encoded.
+	Verify(encoded, password string) (bool, error)
+	// NeedsRehash reports whether encoded was produced with parameters other
+	// than the current ones.
+	NeedsRehash(encoded string) bool
+}
+
+// Hasher hashes new passwords with a preferred algorithm and verifies hashes
+// produced by any of the algorithms it knows.
+type Hasher struct {
+	preferred Algorithm
+	known     []Algorithm
+}
+
+// NewHasher returns a hasher that hashes with preferred and additionally
+// accepts hashes produced by legacy.
+func NewHasher(preferred Algorithm, legacy ...Algorithm) *Hasher {
+	return &Hasher{
+		preferred: preferred,
+		known:     append([]Algorithm{preferred}, legacy...),
+	}
+}
+
+func (h *Hasher) Hash(password string) (string, error) {
+	return h.preferred.Hash(password)
+}
+
+// Verify checks password against encoded. If it matches, rehash reports
+// whether encoded should be replaced with a fresh hash from Hash.
+func (h *Hasher) Verify(encoded, password string) (ok, rehash bool, err error) {
+	for _, algorithm := range h.known {
+		if !algorithm.Recognizes(encoded) {
+			continue
+		}
+
+		ok, err := algorithm.Verify(encoded, password)
+		if err != nil || !ok {
+			return false, false, err
+		}
+
+		return true, algorithm != h.preferred || algorithm.NeedsRehash(encoded), nil
+	}
+
+	return false, false, ErrUnknownHash
+}
+
+// HasherFromEnv builds the hasher configured by PASSWORD_HASH_ALGORITHM
+// ("argon2id", the default, or "bcrypt") and the ARGON2_MEMORY_KIB,
+// ARGON2_ITERATIONS, ARGON2_PARALLELISM and BCRYPT_COST parameters. Both
+// algorithms are always accepted when verifying.
+func HasherFromEnv() (*Hasher, error) {
+	argon := DefaultArgon2id
+	var err error
+	if argon.Memory, err = envUint("ARGON2_MEMORY_KIB", argon.Memory); err != nil {
+		return nil, err
+	}
+	if argon.Iterations, err = envUint("ARGON2_ITERATIONS", argon.Iterations); err != nil {
+		return nil, err
+	}
+	parallelism, err := envUint("ARGON2_PARALLELISM", uint32(argon.Parallelism))
+	if err != nil {
+		return nil, err
+	}
+	if parallelism == 0 || parallelism > 255 {
+		return nil, fmt.Errorf("ARGON2_PARALLELISM must be between 1 and 255")
+	}
+	argon.Parallelism = uint8(parallelism)
+
+	cost, err := envUint("BCRYPT_COST", uint32(DefaultBcrypt.Cost))
+	if err != nil {
+		return nil, err
+	}
+	bcryptAlgorithm := &Bcrypt{Cost: int(cost)}
+	if err := bcryptAlgorithm.validate(); err != nil {
+		return nil, err
+	}
+
+	switch algorithm := os.Getenv("PASSWORD_HASH_ALGORITHM"); algorithm {
+	case "", "argon2id":
+		return NewHasher(&argon, bcryptAlgorithm), nil
+	case "bcrypt":
+		return NewHasher(bcryptAlgorithm, &argon), nil
+	default:
+		return nil, fmt.Errorf("unsupported PASSWORD_HASH_ALGORITHM %q", algorithm)
+	}
+}
+
+func envUint(name string, fallback uint32) (uint32, error) {
+	value := os.Getenv(name)
+	if value == "" {
+		return fallback, nil
+	}
+
+	parsed, err := strconv.ParseUint(value, 10, 32)
+	if err != nil {
+		return 0, fmt.Errorf("invalid %s: %w", name, err)
+	}
+	return uint32(parsed), nil
+}
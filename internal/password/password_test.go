@@ -0,0 +1,84 @@
+package password
+
+import (
+	"strings"
+	"testing"
+)
+
+// Cheap parameters keep the tests fast.
+var testArgon2id = &Argon2id{Memory: 1024, Iterations: 1, Parallelism: 1, SaltLength: 16, KeyLength: 32}
+
+func TestHasherVerify(t *testing.T) {
+	hasher := NewHasher(testArgon2id, &Bcrypt{Cost: 4})
+
+	encoded, err := hasher.Hash("correct horse")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(encoded, "$argon2id$v=19$m=1024,t=1,p=1$") {
+		t.Fatalf("unexpected encoding %q", encoded)
+	}
+
+	ok, rehash, err := hasher.Verify(encoded, "correct horse")
+	if err != nil || !ok || rehash {
+		t.Fatalf("Verify() = %v, %v, %v; want true, false, nil", ok, rehash, err)
+	}
+
+	ok, _, err = hasher.Verify(encoded, "wrong horse")
+	if err != nil || ok {
+		t.Fatalf("Verify() with wrong password = %v, %v; want false, nil", ok, err)
+	}
+
+	if _, _, err := hasher.Verify("plaintext", "plaintext"); err != ErrUnknownHash {
+		t.Fatalf("Verify() of unknown hash err = %v; want ErrUnknownHash", err)
+	}
+}
+
+func TestHasherRehash(t *testing.T) {
+	legacy, err := (&Bcrypt{Cost: 4}).Hash("correct horse")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	hasher := NewHasher(testArgon2id, &Bcrypt{Cost: 4})
+	ok, rehash, err := hasher.Verify(legacy, "correct horse")
+	if err != nil || !ok || !rehash {
+		t.Fatalf("Verify() of bcrypt hash = %v, %v, %v; want true, true, nil", ok, rehash, err)
+	}
+
+	weaker, err := (&Argon2id{Memory: 512, Iterations: 1, Parallelism: 1, SaltLength: 16, KeyLength: 32}).Hash("correct horse")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ok, rehash, err = hasher.Verify(weaker, "correct horse")
+	if err != nil || !ok || !rehash {
+		t.Fatalf("Verify() of weaker argon2id hash = %v, %v, %v; want true, true, nil", ok, rehash, err)
+	}
+}
+
+func TestCheckerCheck(t *testing.T) {
+	checker := NewChecker(true)
+	if err := checker.LoadBreached(strings.NewReader("F97979FF44A9A1A4105F4BAE6FE809715E0A0A84:12\n")); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		password string
+		want     error
+	}{
+		{"short", ErrTooShort},
+		{strings.Repeat("ab", 40), ErrTooLongForBcrypt},
+		{"aaaaaaaaaa", ErrTooSimple},
+		{"janedoe-2024!", ErrContainsIdentity},
+		{"Smith-rocks-42", ErrContainsIdentity},
+		{"Password123", ErrBreached},
+		{"correct-horse-battery", ErrBreached},
+		{"quiet-maple-harbor", nil},
+	}
+	for _, tt := range tests {
+		if err := checker.Check(tt.password, "janedoe@example.com", "Jane Smith"); err != tt.want {
+			t.Errorf("Check(%q) = %v; want %v", tt.password, err, tt.want)
+		}
+	}
+
+}
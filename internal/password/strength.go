@@ -0,0 +1,146 @@
+package password
+
+import (
+	"bufio"
+	"crypto/sha1"
+	_ "embed"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"unicode/utf8"
+)
+
+const (
+	MinLength = 8
+	MaxLength = 128
+	// bcryptMaxBytes is the longest input bcrypt considers; anything beyond
+	// it is silently ignored.
+	bcryptMaxBytes = 72
+)
+
+var (
+	ErrTooShort         = fmt.Errorf("password must be at least %d characters", MinLength)
+	ErrTooLong          = fmt.Errorf("password must be at most %d characters", MaxLength)
+	ErrTooLongForBcrypt = fmt.Errorf("password must be at most %d bytes", bcryptMaxBytes)
+	ErrTooSimple        = errors.New("password must contain at least 4 different characters")
+	ErrContainsIdentity = errors.New("password must not contain your email or name")
+	ErrBreached         = errors.New("password appears in a list of breached passwords")
+)
+
+//go:embed common.txt
+var commonPasswords string
+
+// Checker enforces the password policy for new passwords.
+type Checker struct {
+	// breached holds lower-cased SHA-1 hex digests of known passwords, the
+	// same format as the Have I Been Pwned downloads.
+	breached map[string]struct{}
+	// bcryptLimit rejects passwords that bcrypt would truncate.
+	bcryptLimit bool
+}
+
+// NewChecker returns a checker using the embedded list of common passwords.
+// bcryptLimit should be set while bcrypt is the preferred algorithm.
+func NewChecker(bcryptLimit bool) *Checker {
+	c := &Checker{breached: make(map[string]struct{}), bcryptLimit: bcryptLimit}
+	c.LoadBreached(strings.NewReader(commonPasswords))
+	return c
+}
+
+// CheckerFromEnv returns a checker for the hasher, additionally loading the
+// file named by BREACHED_PASSWORDS_FILE if set.
+func CheckerFromEnv(hasher *Hasher) (*Checker, error) {
+	_, bcryptPreferred := hasher.preferred.(*Bcrypt)
+	c := NewChecker(bcryptPreferred)
+
+	path := os.Getenv("BREACHED_PASSWORDS_FILE")
+	if path == "" {
+		return c, nil
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open breached passwords file: %w", err)
+	}
+	defer f.Close()
+
+	if err := c.LoadBreached(f); err != nil {
+		return nil, fmt.Errorf("failed to load breached passwords file: %w", err)
+	}
+	return c, nil
+}
+
+// LoadBreached adds passwords read from r, one per line. Lines may either be
+// plain passwords or SHA-1 digests optionally followed by ":count".
+func (c *Checker) LoadBreached(r io.Reader) error {
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+
+		if digest, _, _ := strings.Cut(line, ":"); isSHA1Hex(digest) {
+			c.breached[strings.ToLower(digest)] = struct{}{}
+			continue
+		}
+		c.breached[digestOf(line)] = struct{}{}
+	}
+	return scanner.Err()
+}
+
+// Check validates password for the account identified by the given email and
+// name.
+func (c *Checker) Check(password, email, name string) error {
+	length := utf8.RuneCountInString(password)
+	if length < MinLength {
+		return ErrTooShort
+	}
+	if length > MaxLength {
+		return ErrTooLong
+	}
+	if c.bcryptLimit && len(password) > bcryptMaxBytes {
+		return ErrTooLongForBcrypt
+	}
+
+	distinct := make(map[rune]struct{})
+	for _, r := range password {
+		distinct[r] = struct{}{}
+	}
+	if len(distinct) < 4 {
+		return ErrTooSimple
+	}
+
+	lower := strings.ToLower(password)
+	local, _, _ := strings.Cut(strings.ToLower(email), "@")
+	for _, identity := range append([]string{local}, strings.Fields(strings.ToLower(name))...) {
+		if len(identity) >= 3 && strings.Contains(lower, identity) {
+			return ErrContainsIdentity
+		}
+	}
+
+	if _, ok := c.breached[digestOf(password)]; ok {
+		return ErrBreached
+	}
+	if _, ok := c.breached[digestOf(lower)]; ok {
+		return ErrBreached
+	}
+
+	return nil
+}
+
+func digestOf(password string) string {
+	sum := sha1.Sum([]byte(password))
+	return hex.EncodeToString(sum[:])
+}
+
+func isSHA1Hex(s string) bool {
+	if len(s) != sha1.Size*2 {
+		return false
+	}
+	_, err := hex.DecodeString(s)
+	return err == nil
+}
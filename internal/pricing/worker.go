@@ -0,0 +1,72 @@
+// Package pricing applies scheduled price changes once they are due.
+package pricing
+
+import (
+	"context"
+	"ecomm/internal/domain"
+	"ecomm/pkg"
+	"errors"
+	"time"
+)
+
+// batchSize caps the price changes applied per run.
+const batchSize = 100
+
+// Store is the part of the repository the Worker needs.
+type Store interface {
+	ListDueScheduledPriceChanges(limit int) ([]*domain.ScheduledPriceChange, error)
+	ApplyScheduledPriceChange(id string) error
+}
+
+// Worker periodically applies the scheduled price changes that are due. A
+// change that fails is retried on the next run.
+type Worker struct {
+	store    Store
+	interval time.Duration
+}
+
+func NewWorker(store Store, interval time.Duration) *Worker {
+	return &Worker{
+		store:    store,
+		interval: interval,
+	}
+}
+
+// Run applies due price changes every interval until ctx is cancelled.
+func (w *Worker) Run(ctx context.Context) {
+	ticker := time.NewTicker(w.interval)
+	defer ticker.Stop()
+
+	for {
+		w.RunOnce()
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// RunOnce applies the price changes that are due and returns how many were
+// applied. Changes cancelled or applied elsewhere in the meantime are
+// skipped.
+func (w *Worker) RunOnce() int {
+	applied := 0
+
+	changes, err := w.store.ListDueScheduledPriceChanges(batchSize)
+	if err != nil {
+		pkg.ErrorLogger.Printf("failed to list scheduled price changes: %v", err)
+	}
+	for _, change := range changes {
+		if err := w.store.ApplyScheduledPriceChange(change.ID); err != nil {
+			if !errors.Is(err, domain.ErrPriceChangeNotFound) {
+				pkg.ErrorLogger.Printf("failed to apply price change %s: %v", change.ID, err)
+			}
+			continue
+		}
+		applied++
+	}
+
+	return applied
+}
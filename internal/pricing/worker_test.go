@@ -0,0 +1,150 @@
+package pricing
+
+import (
+	"context"
+	"ecomm/internal/domain"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// fakeStore keeps the pending price changes in the order they are due and
+// removes a change once it is applied.
+type fakeStore struct {
+	pending []*domain.ScheduledPriceChange
+	// failures holds how many more times applying a change fails.
+	failures map[string]int
+	listErr  error
+	limits   []int
+	applied  []string
+}
+
+func (s *fakeStore) ListDueScheduledPriceChanges(limit int) ([]*domain.ScheduledPriceChange, error) {
+	s.limits = append(s.limits, limit)
+	if s.listErr != nil {
+		return nil, s.listErr
+	}
+	return append([]*domain.ScheduledPriceChange(nil), s.pending...), nil
+}
+
+func (s *fakeStore) ApplyScheduledPriceChange(id string) error {
+	if s.failures[id] > 0 {
+		s.failures[id]--
+		return errors.New("connection reset")
+	}
+	for i, change := range s.pending {
+		if change.ID == id {
+			s.pending = append(s.pending[:i], s.pending[i+1:]...)
+			s.applied = append(s.applied, id)
+			return nil
+		}
+	}
+	return domain.ErrPriceChangeNotFound
+}
+
+func changes(ids ...string) []*domain.ScheduledPriceChange {
+	changes := make([]*domain.ScheduledPriceChange, len(ids))
+	for i, id := range ids {
+		changes[i] = &domain.ScheduledPriceChange{ID: id, Price: 9.99}
+	}
+	return changes
+}
+
+func TestRunOnceAppliesChangesInTheOrderTheyAreDue(t *testing.T) {
+	store := &fakeStore{pending: changes("change-1", "change-2", "change-3")}
+
+	if applied := NewWorker(store, time.Minute).RunOnce(); applied != 3 {
+		t.Fatalf("RunOnce() = %d; want 3", applied)
+	}
+	if want := []string{"change-1", "change-2", "change-3"}; !reflect.DeepEqual(store.applied, want) {
+		t.Fatalf("applied %v; want %v", store.applied, want)
+	}
+	if want := []int{batchSize}; !reflect.DeepEqual(store.limits, want) {
+		t.Fatalf("listed with limits %v; want %v", store.limits, want)
+	}
+}
+
+func TestRunOnceRetriesFailedChangesOnTheNextRun(t *testing.T) {
+	store := &fakeStore{
+		pending:  changes("change-1", "change-2"),
+		failures: map[string]int{"change-1": 1},
+	}
+	worker := NewWorker(store, time.Minute)
+
+	if applied := worker.RunOnce(); applied != 1 {
+		t.Fatalf("first RunOnce() = %d; want 1", applied)
+	}
+	if len(store.pending) != 1 || store.pending[0].ID != "change-1" {
+		t.Fatalf("pending = %v; want change-1 kept for a retry", store.pending)
+	}
+
+	if applied := worker.RunOnce(); applied != 1 {
+		t.Fatalf("second RunOnce() = %d; want 1", applied)
+	}
+	if want := []string{"change-2", "change-1"}; !reflect.DeepEqual(store.applied, want) {
+		t.Fatalf("applied %v; want %v", store.applied, want)
+	}
+}
+
+// cancellingStore drops a change between listing and applying it, as a
+// concurrent CancelScheduledPriceChange would.
+type cancellingStore struct {
+	*fakeStore
+	cancel string
+}
+
+func (s cancellingStore) ListDueScheduledPriceChanges(limit int) ([]*domain.ScheduledPriceChange, error) {
+	listed, err := s.fakeStore.ListDueScheduledPriceChanges(limit)
+	for i, change := range s.pending {
+		if change.ID == s.cancel {
+			s.pending = append(s.pending[:i], s.pending[i+1:]...)
+			break
+		}
+	}
+	return listed, err
+}
+
+func TestRunOnceSkipsChangesCancelledInTheMeantime(t *testing.T) {
+	store := &fakeStore{pending: changes("change-1", "change-2")}
+
+	worker := NewWorker(cancellingStore{store, "change-1"}, time.Minute)
+	if applied := worker.RunOnce(); applied != 1 {
+		t.Fatalf("RunOnce() = %d; want 1", applied)
+	}
+	if want := []string{"change-2"}; !reflect.DeepEqual(store.applied, want) {
+		t.Fatalf("applied %v; want %v", store.applied, want)
+	}
+}
+
+func TestRunOnceWithoutListAppliesNothing(t *testing.T) {
+	store := &fakeStore{pending: changes("change-1"), listErr: errors.New("connection reset")}
+
+	if applied := NewWorker(store, time.Minute).RunOnce(); applied != 0 {
+		t.Fatalf("RunOnce() = %d; want 0", applied)
+	}
+	if len(store.applied) != 0 {
+		t.Fatalf("applied %v; want nothing", store.applied)
+	}
+}
+
+func TestRunAppliesDueChangesBeforeTheFirstTick(t *testing.T) {
+	store := &fakeStore{pending: changes("change-1")}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	done := make(chan struct{})
+	go func() {
+		NewWorker(store, time.Hour).Run(ctx)
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("Run() did not return after ctx was cancelled")
+	}
+	if want := []string{"change-1"}; !reflect.DeepEqual(store.applied, want) {
+		t.Fatalf("applied %v; want %v", store.applied, want)
+	}
+}
@@ -0,0 +1,154 @@
+package repository
+
+import (
+	"context"
+	"ecomm/internal/domain"
+	"errors"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// ChangePassword stores the new password hash and revokes every session of
+// the user except the one the change was made from.
+func (r *repository) ChangePassword(userID, passwordHash, keepSessionID string) error {
+	tx, err := r.pool.Begin(context.Background())
+	if err != nil {
+		return err
+	}
+
+	defer tx.Rollback(context.Background())
+
+	var email string
+	query := `
+		UPDATE users SET password = $1, updated_at = EXTRACT (EPOCH FROM CURRENT_TIMESTAMP)
+		WHERE id = $2
+		RETURNING email
+	`
+	if err := tx.QueryRow(context.Background(), query, passwordHash, userID).Scan(&email); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return domain.ErrUserNotFound
+		}
+		return err
+	}
+
+	query = `UPDATE sessions SET is_revoked = $1 WHERE email = $2 AND id::text <> $3`
+	if _, err := tx.Exec(context.Background(), query, true, email, keepSessionID); err != nil {
+		return err
+	}
+
+	return tx.Commit(context.Background())
+}
+
+// UpdatePasswordHash replaces a hash with an equivalent one, for example after
+// the hashing parameters were raised. It does nothing if the password was
+// changed in the meantime. It leaves updated_at alone, so the version of the
+// user does not change either.
+func (r *repository) UpdatePasswordHash(userID, oldHash, newHash string) error {
+	query := `UPDATE users SET password = $1 WHERE id = $2 AND password = $3`
+	_, err := r.pool.Exec(context.Background(), query, newHash, userID, oldHash)
+	return err
+}
+
+func (r *repository) CreateEmailChangeToken(token *domain.EmailChangeToken) error {
+	tx, err := r.pool.Begin(context.Background())
+	if err != nil {
+		return err
+	}
+
+	defer tx.Rollback(context.Background())
+
+	// Only the most recently requested change for a user stays usable.
+	query := `
+		UPDATE email_change_tokens SET used_at = EXTRACT (EPOCH FROM CURRENT_TIMESTAMP)
+		WHERE user_id = $1 AND used_at IS NULL
+	`
+	if _, err := tx.Exec(context.Background(), query, token.UserID); err != nil {
+		return err
+	}
+
+	query = `
+		INSERT INTO email_change_tokens(user_id, new_email, token_hash, expires_at)
+		VALUES ($1, $2, $3, $4)
+		RETURNING id
+	`
+	if err := tx.QueryRow(context.Background(), query,
+		&token.UserID,
+		&token.NewEmail,
+		&token.TokenHash,
+		&token.ExpiresAt).Scan(&token.ID); err != nil {
+		return err
+	}
+
+	return tx.Commit(context.Background())
+}
+
+func (r *repository) GetEmailChangeToken(tokenHash string) (*domain.EmailChangeToken, error) {
+	query := `
+		SELECT id, user_id, new_email, token_hash, expires_at, COALESCE(used_at, 0), created_at
+		FROM email_change_tokens WHERE token_hash = $1
+	`
+
+	token := new(domain.EmailChangeToken)
+	if err := r.pool.QueryRow(context.Background(), query, tokenHash).Scan(
+		&token.ID,
+		&token.UserID,
+		&token.NewEmail,
+		&token.TokenHash,
+		&token.ExpiresAt,
+		&token.UsedAt,
+		&token.CreatedAt); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, domain.ErrEmailChangeTokenNotFound
+		}
+		return nil, err
+	}
+
+	return token, nil
+}
+
+// ChangeEmail consumes the token and moves the user to the new address. The
+// sessions.email foreign key cascades the change; the sessions themselves are
+// revoked because their access tokens carry the old address.
+func (r *repository) ChangeEmail(tokenID, userID, newEmail string) error {
+	tx, err := r.pool.Begin(context.Background())
+	if err != nil {
+		return err
+	}
+
+	defer tx.Rollback(context.Background())
+
+	query := `
+		UPDATE email_change_tokens SET used_at = EXTRACT (EPOCH FROM CURRENT_TIMESTAMP)
+		WHERE id = $1 AND used_at IS NULL
+	`
+	result, err := tx.Exec(context.Background(), query, tokenID)
+	if err != nil {
+		return err
+	}
+
+	if result.RowsAffected() == 0 {
+		return domain.ErrEmailChangeTokenNotFound
+	}
+
+	query = `
+		UPDATE users SET email = $1, updated_at = EXTRACT (EPOCH FROM CURRENT_TIMESTAMP)
+		WHERE id = $2
+	`
+	result, err = tx.Exec(context.Background(), query, newEmail, userID)
+	if err != nil {
+		if isUniqueViolation(err, "unique_email") {
+			return domain.ErrEmailTaken
+		}
+		return err
+	}
+
+	if result.RowsAffected() == 0 {
+		return domain.ErrUserNotFound
+	}
+
+	if err := revokeUserSessions(tx, newEmail); err != nil {
+		return err
+	}
+
+	return tx.Commit(context.Background())
+}
@@ -0,0 +1,108 @@
+package repository
+
+import (
+	"context"
+	"ecomm/internal/domain"
+	"errors"
+
+	"github.com/georgysavva/scany/v2/pgxscan"
+	"github.com/jackc/pgx/v5"
+)
+
+func (r *repository) CreateAPIKey(key *domain.APIKey) (*domain.APIKey, error) {
+	query := `
+		INSERT INTO api_keys(name, prefix, key_hash, scopes, created_by, expires_at)
+		VALUES ($1, $2, $3, $4, NULLIF($5, '')::uuid, NULLIF($6, 0))
+		RETURNING id, created_at
+	`
+
+	if err := r.pool.QueryRow(context.Background(), query,
+		&key.Name,
+		&key.Prefix,
+		&key.KeyHash,
+		&key.Scopes,
+		&key.CreatedBy,
+		&key.ExpiresAt).Scan(&key.ID, &key.CreatedAt); err != nil {
+		return nil, err
+	}
+
+	return key, nil
+}
+
+func (r *repository) GetAPIKeyByPrefix(prefix string) (*domain.APIKey, error) {
+	query := `
+		SELECT id, name, prefix, key_hash, scopes, COALESCE(created_by::text, ''),
+		COALESCE(expires_at, 0), COALESCE(last_used_at, 0), COALESCE(revoked_at, 0), created_at
+		FROM api_keys WHERE prefix = $1
+	`
+
+	key := new(domain.APIKey)
+	if err := r.pool.QueryRow(context.Background(), query, prefix).Scan(
+		&key.ID,
+		&key.Name,
+		&key.Prefix,
+		&key.KeyHash,
+		&key.Scopes,
+		&key.CreatedBy,
+		&key.ExpiresAt,
+		&key.LastUsedAt,
+		&key.RevokedAt,
+		&key.CreatedAt); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, domain.ErrAPIKeyNotFound
+		}
+		return nil, err
+	}
+
+	return key, nil
+}
+
+func (r *repository) ListAPIKeys() ([]*domain.APIKey, error) {
+	query := `
+		SELECT id, name, prefix, scopes, COALESCE(created_by::text, '') AS created_by,
+		COALESCE(expires_at, 0) AS expires_at, COALESCE(last_used_at, 0) AS last_used_at,
+		COALESCE(revoked_at, 0) AS revoked_at, created_at
+		FROM api_keys
+		ORDER BY created_at DESC
+	`
+
+	var keys []*domain.APIKey
+	if err := pgxscan.Select(context.Background(), r.pool, &keys, query); err != nil {
+		return nil, err
+	}
+
+	return keys, nil
+}
+
+func (r *repository) RevokeAPIKey(id string) error {
+	query := `
+		UPDATE api_keys SET revoked_at = EXTRACT (EPOCH FROM CURRENT_TIMESTAMP)
+		WHERE id = $1 AND revoked_at IS NULL
+	`
+
+	result, err := r.pool.Exec(context.Background(), query, id)
+	if err != nil {
+		return err
+	}
+
+	if result.RowsAffected() == 0 {
+		return domain.ErrAPIKeyNotFound
+	}
+
+	return nil
+}
+
+// TouchAPIKey records that the key was used. Updates are limited to one per
+// minute because every request through the gateway validates the key twice.
+func (r *repository) TouchAPIKey(id string) error {
+	query := `
+		UPDATE api_keys SET last_used_at = EXTRACT (EPOCH FROM CURRENT_TIMESTAMP)
+		WHERE id = $1 AND (last_used_at IS NULL OR last_used_at < EXTRACT (EPOCH FROM CURRENT_TIMESTAMP) - 60)
+	`
+
+	if _, err := r.pool.Exec(context.Background(), query, id); err != nil {
+		return err
+	}
+
+	return nil
+}
@@ -0,0 +1,64 @@
+package repository
+
+import (
+	"context"
+	"ecomm/internal/domain"
+
+	"github.com/georgysavva/scany/v2/pgxscan"
+)
+
+func (r *repository) CreateAuditEvent(event *domain.AuditEvent) error {
+	query := `
+		INSERT INTO audit_events(actor_id, action, target_type, target_id, before, after, request_id, metadata)
+		VALUES (NULLIF($1, '')::uuid, $2, $3, $4, $5, $6, NULLIF($7, ''), $8)
+	`
+
+	metadata := event.Metadata
+	if metadata == nil {
+		metadata = map[string]any{}
+	}
+
+	_, err := r.pool.Exec(context.Background(), query,
+		event.ActorID,
+		event.Action,
+		event.TargetType,
+		event.TargetID,
+		event.Before,
+		event.After,
+		event.RequestID,
+		metadata)
+	return err
+}
+
+func (r *repository) ListAuditEvents(filter *domain.AuditEventFilter) ([]*domain.AuditEvent, error) {
+	query := `
+		SELECT id, COALESCE(actor_id::text, '') AS actor_id, action, target_type, target_id,
+		before, after, COALESCE(request_id, '') AS request_id, metadata, created_at
+		FROM audit_events
+		WHERE ($1 = '' OR actor_id::text = $1)
+		AND ($2 = '' OR action = $2)
+		AND ($3 = '' OR target_type = $3)
+		AND ($4 = '' OR target_id = $4)
+		AND ($5 = '' OR request_id = $5)
+		AND ($6 = 0 OR created_at >= $6)
+		AND ($7 = 0 OR created_at < $7)
+		ORDER BY created_at DESC, id
+		LIMIT $8 OFFSET $9
+	`
+
+	var events []*domain.AuditEvent
+	if err := pgxscan.Select(context.Background(), r.pool, &events, query,
+		filter.ActorID,
+		filter.Action,
+		filter.TargetType,
+		filter.TargetID,
+		filter.RequestID,
+		filter.Since,
+		filter.Until,
+		filter.Limit,
+		filter.Offset); err != nil {
+		return nil, err
+	}
+
+	return events, nil
+}
@@ -0,0 +1,252 @@
+package repository
+
+import (
+	"context"
+	"ecomm/internal/domain"
+	"errors"
+
+	"github.com/georgysavva/scany/v2/pgxscan"
+	"github.com/jackc/pgx/v5"
+)
+
+// GetProductStatusesBySKU returns the status of the products that have one
+// of the given SKUs, keyed by SKU.
+func (r *repository) GetProductStatusesBySKU(skus []string) (map[string]string, error) {
+	query := `SELECT sku, status FROM products WHERE sku = ANY($1)`
+
+	var products []struct {
+		SKU    string
+		Status string
+	}
+	if err := pgxscan.Select(context.Background(), r.pool, &products, query, skus); err != nil {
+		return nil, err
+	}
+
+	statuses := make(map[string]string, len(products))
+	for _, product := range products {
+		statuses[product.SKU] = product.Status
+	}
+	return statuses, nil
+}
+
+// UpsertProducts creates products whose SKU is new and updates the others in
+// a single transaction. Differences between the imported and the current
+// stock are recorded as inventory movements, and new prices in the price
+// history, by actorID. It returns how many products were created, or
+// ErrSKUArchived if a SKU belongs to an archived product.
+func (r *repository) UpsertProducts(products []*domain.Product, actorID string) (int, error) {
+	tx, err := r.pool.Begin(context.Background())
+	if err != nil {
+		return 0, err
+	}
+
+	defer tx.Rollback(context.Background())
+
+	skus := make([]string, len(products))
+	for i, product := range products {
+		skus[i] = product.SKU
+	}
+
+	// The current prices tell which products the import reprices.
+	var current []struct {
+		SKU   string
+		Price float64
+	}
+	query := `SELECT sku, price FROM products WHERE sku = ANY($1) FOR UPDATE`
+	if err := pgxscan.Select(context.Background(), tx, &current, query, skus); err != nil {
+		return 0, err
+	}
+	prices := make(map[string]float64, len(current))
+	for _, product := range current {
+		prices[product.SKU] = product.Price
+	}
+
+	// xmax is only set on rows that existed before the statement, which
+	// tells inserts and updates apart. The stock is left alone here and
+	// moved through the ledger below. Archived products are not updated, so
+	// their statement returns no row.
+	query = `
+		INSERT INTO products(sku, name, image, category_id, description, rating, num_reviews, price, count_in_stock)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, 0)
+		ON CONFLICT (sku) DO UPDATE
+		SET name = EXCLUDED.name, image = EXCLUDED.image, category_id = EXCLUDED.category_id,
+		description = EXCLUDED.description, rating = EXCLUDED.rating, num_reviews = EXCLUDED.num_reviews,
+		price = EXCLUDED.price, updated_at = EXTRACT (EPOCH FROM CURRENT_TIMESTAMP)
+		WHERE products.status <> 'archived'
+		RETURNING id, xmax = 0 AS inserted, count_in_stock, price
+	`
+
+	batch := &pgx.Batch{}
+	for _, product := range products {
+		batch.Queue(query,
+			product.SKU,
+			product.Name,
+			product.Image,
+			product.CategoryID,
+			product.Description,
+			product.Rating,
+			product.NumberOfReviews,
+			product.Price)
+	}
+
+	results := tx.SendBatch(context.Background(), batch)
+
+	created := 0
+	movements := make([]*domain.InventoryMovement, 0, len(products))
+	skuOf := make(map[string]string, len(products))
+	var repriced []string
+	for _, product := range products {
+		var inserted bool
+		var stock int
+		if err := results.QueryRow().Scan(&product.ID, &inserted, &stock, &product.Price); err != nil {
+			results.Close()
+			if isForeignKeyViolation(err) {
+				return 0, domain.ErrCategoryNotFound
+			}
+			if errors.Is(err, pgx.ErrNoRows) {
+				return 0, domain.ErrSKUArchived
+			}
+			return 0, err
+		}
+
+		movementType := domain.MovementAdjustment
+		if inserted {
+			created++
+			movementType = domain.MovementReceipt
+		}
+		if inserted || prices[product.SKU] != product.Price {
+			repriced = append(repriced, product.ID)
+		}
+		if product.CountInStock != stock {
+			skuOf[product.ID] = product.SKU
+			movements = append(movements, &domain.InventoryMovement{
+				ProductID: product.ID,
+				Type:      movementType,
+				Quantity:  product.CountInStock - stock,
+				Reason:    "catalog import",
+				ActorID:   actorID,
+			})
+		}
+	}
+
+	if err := results.Close(); err != nil {
+		return 0, err
+	}
+
+	for _, movement := range movements {
+		if err := applyImportMovement(tx, movement); err != nil {
+			return 0, &domain.ProductError{SKU: skuOf[movement.ProductID], Err: err}
+		}
+	}
+
+	for _, id := range repriced {
+		if err := recordPrice(tx, id, domain.PriceImported, actorID); err != nil {
+			return 0, err
+		}
+	}
+
+	if err := tx.Commit(context.Background()); err != nil {
+		return 0, err
+	}
+
+	return created, nil
+}
+
+// applyImportMovement applies a movement of an imported product. Stock added
+// goes to the default warehouse, while stock removed is taken from the
+// warehouses that hold it, the default one first, so that a lower count is
+// not refused just because the default warehouse holds less than the
+// difference.
+func applyImportMovement(tx pgx.Tx, movement *domain.InventoryMovement) error {
+	if movement.Quantity >= 0 {
+		return applyInventoryMovement(tx, movement)
+	}
+
+	// The caller holds the lock on the product row, so the stock cannot
+	// change before it is moved.
+	var levels []warehouseLevel
+	query := `
+		SELECT s.warehouse_id, s.quantity
+		FROM warehouse_stock s
+		JOIN warehouses w ON w.id = s.warehouse_id
+		WHERE s.product_id = $1 AND s.variant_id IS NULL AND s.quantity > 0
+		ORDER BY w.active DESC, w.priority, w.created_at
+	`
+	if err := pgxscan.Select(context.Background(), tx, &levels, query, movement.ProductID); err != nil {
+		return err
+	}
+
+	parts, err := spreadReduction(movement, levels)
+	if err != nil {
+		return err
+	}
+	for _, part := range parts {
+		if err := applyInventoryMovement(tx, part); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// warehouseLevel is the stock of an item in one warehouse.
+type warehouseLevel struct {
+	WarehouseID string
+	Quantity    int
+}
+
+// spreadReduction splits a movement that removes stock into one movement per
+// warehouse, taking from levels in order. It fails with ErrInsufficientStock
+// when the warehouses hold less than the movement removes.
+func spreadReduction(movement *domain.InventoryMovement, levels []warehouseLevel) ([]*domain.InventoryMovement, error) {
+	left := -movement.Quantity
+	var parts []*domain.InventoryMovement
+	for _, level := range levels {
+		if left == 0 {
+			break
+		}
+		quantity := min(left, level.Quantity)
+		part := *movement
+		part.WarehouseID = level.WarehouseID
+		part.Quantity = -quantity
+		parts = append(parts, &part)
+		left -= quantity
+	}
+	if left > 0 {
+		return nil, domain.ErrInsufficientStock
+	}
+	return parts, nil
+}
+
+// ListProductsPage returns up to limit products ordered by ID, starting after
+// afterID. Unlike ListProducts it does not load images.
+func (r *repository) ListProductsPage(afterID string, limit int) ([]*domain.Product, error) {
+	query := `
+		SELECT ` + productColumns + `
+		FROM products p
+		JOIN categories c ON c.id = p.category_id
+		WHERE NULLIF($1, '')::uuid IS NULL OR p.id > NULLIF($1, '')::uuid
+		ORDER BY p.id
+		LIMIT $2
+	`
+
+	rows, err := r.pool.Query(context.Background(), query, afterID, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	products := make([]*domain.Product, 0, limit)
+	for rows.Next() {
+		product, err := scanProduct(rows)
+		if err != nil {
+			return nil, err
+		}
+		products = append(products, product)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return products, nil
+}
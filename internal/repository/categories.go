@@ -0,0 +1,122 @@
+package repository
+
+import (
+	"context"
+	"ecomm/internal/domain"
+	"errors"
+
+	"github.com/georgysavva/scany/v2/pgxscan"
+	"github.com/jackc/pgx/v5"
+)
+
+func (r *repository) CreateCategory(category *domain.Category) (*domain.Category, error) {
+	query := `
+		INSERT INTO categories(parent_id, name, slug, position)
+		VALUES (NULLIF($1, '')::uuid, $2, $3, $4)
+		RETURNING id, created_at, updated_at
+	`
+
+	err := r.pool.QueryRow(context.Background(), query,
+		category.ParentID,
+		category.Name,
+		category.Slug,
+		category.Position).Scan(&category.ID, &category.CreatedAt, &category.UpdatedAt)
+	if err != nil {
+		if isUniqueViolation(err, "unique_category_slug") {
+			return nil, domain.ErrCategorySlugTaken
+		}
+		if isForeignKeyViolation(err) {
+			return nil, domain.ErrCategoryNotFound
+		}
+		return nil, err
+	}
+
+	return category, nil
+}
+
+func (r *repository) GetCategoryByID(id string) (*domain.Category, error) {
+	query := `
+		SELECT c.id, COALESCE(c.parent_id::text, '') AS parent_id, c.name, c.slug, c.position,
+		(SELECT count(*) FROM products p WHERE p.category_id = c.id) AS product_count,
+		c.created_at, c.updated_at
+		FROM categories c WHERE c.id = $1
+	`
+
+	category := new(domain.Category)
+	if err := pgxscan.Get(context.Background(), r.pool, category, query, id); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, domain.ErrCategoryNotFound
+		}
+		return nil, err
+	}
+
+	return category, nil
+}
+
+func (r *repository) ListCategories() ([]*domain.Category, error) {
+	query := `
+		SELECT c.id, COALESCE(c.parent_id::text, '') AS parent_id, c.name, c.slug, c.position,
+		count(p.id) AS product_count, c.created_at, c.updated_at
+		FROM categories c
+		LEFT JOIN products p ON p.category_id = c.id
+		GROUP BY c.id
+		ORDER BY c.position, c.name
+	`
+
+	var categories []*domain.Category
+	if err := pgxscan.Select(context.Background(), r.pool, &categories, query); err != nil {
+		return nil, err
+	}
+
+	return categories, nil
+}
+
+func (r *repository) UpdateCategory(category *domain.Category) error {
+	query := `
+		UPDATE categories
+		SET parent_id = NULLIF($1, '')::uuid, name = $2, slug = $3, position = $4,
+		updated_at = EXTRACT (EPOCH FROM CURRENT_TIMESTAMP)
+		WHERE id = $5
+		RETURNING updated_at
+	`
+
+	err := r.pool.QueryRow(context.Background(), query,
+		category.ParentID,
+		category.Name,
+		category.Slug,
+		category.Position,
+		category.ID).Scan(&category.UpdatedAt)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return domain.ErrCategoryNotFound
+		}
+		if isUniqueViolation(err, "unique_category_slug") {
+			return domain.ErrCategorySlugTaken
+		}
+		if isForeignKeyViolation(err) {
+			return domain.ErrCategoryNotFound
+		}
+		return err
+	}
+
+	return nil
+}
+
+// DeleteCategory only deletes empty categories; subcategories and products
+// have to be moved elsewhere first.
+func (r *repository) DeleteCategory(id string) error {
+	query := `DELETE FROM categories WHERE id = $1`
+	result, err := r.pool.Exec(context.Background(), query, id)
+	if err != nil {
+		if isForeignKeyViolation(err) {
+			return domain.ErrCategoryInUse
+		}
+		return err
+	}
+
+	if result.RowsAffected() == 0 {
+		return domain.ErrCategoryNotFound
+	}
+
+	return nil
+}
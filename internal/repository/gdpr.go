@@ -0,0 +1,134 @@
+package repository
+
+import (
+	"context"
+	"ecomm/internal/domain"
+	"errors"
+
+	"github.com/georgysavva/scany/v2/pgxscan"
+	"github.com/jackc/pgx/v5"
+)
+
+func (r *repository) ListUserSessions(email string) ([]*domain.Session, error) {
+	query := `
+		SELECT id, email, is_revoked, created_at, expires_at
+		FROM sessions WHERE email = $1
+		ORDER BY created_at DESC
+	`
+
+	var sessions []*domain.Session
+	if err := pgxscan.Select(context.Background(), r.pool, &sessions, query, email); err != nil {
+		return nil, err
+	}
+
+	return sessions, nil
+}
+
+func (r *repository) ListUserOrders(userID string) ([]*domain.Order, error) {
+	query := `
+		SELECT ` + orderColumns + `
+		FROM orders WHERE user_id = $1
+		ORDER BY created_at DESC
+	`
+
+	return r.listOrders(query, userID)
+}
+
+func (r *repository) ListUserIdentities(userID string) ([]*domain.UserIdentity, error) {
+	query := `
+		SELECT provider, subject, user_id, email, created_at
+		FROM user_identities WHERE user_id = $1
+		ORDER BY created_at
+	`
+
+	var identities []*domain.UserIdentity
+	if err := pgxscan.Select(context.Background(), r.pool, &identities, query, userID); err != nil {
+		return nil, err
+	}
+
+	return identities, nil
+}
+
+// EraseUser deletes the user and their personal data in one transaction.
+// Orders are retained for accounting but no longer reference the user, and
+// personal data is scrubbed from the audit events of the user and their
+// orders. Roles, MFA settings, linked identities and stock subscriptions are
+// removed by cascading deletes. It fails with ErrVersionMismatch unless the
+// user is still at version.
+func (r *repository) EraseUser(userID string, version int64) error {
+	tx, err := r.pool.Begin(context.Background())
+	if err != nil {
+		return err
+	}
+
+	defer tx.Rollback(context.Background())
+
+	var email string
+	var current int64
+	query := `SELECT email, version FROM users WHERE id = $1 FOR UPDATE`
+	if err := tx.QueryRow(context.Background(), query, userID).Scan(&email, &current); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return domain.ErrUserNotFound
+		}
+		return err
+	}
+	if err := checkRowVersion(version, current); err != nil {
+		return err
+	}
+
+	// The audit log is append-only except for scrubbing personal data while
+	// a user is erased.
+	query = `SELECT set_config('audit.erasure', 'on', true)`
+	if _, err := tx.Exec(context.Background(), query); err != nil {
+		return err
+	}
+
+	if err := scrubAuditEvents(tx, "user", `$1`, userID); err != nil {
+		return err
+	}
+	if err := scrubAuditEvents(tx, "order", `SELECT id::text FROM orders WHERE user_id = $1`, userID); err != nil {
+		return err
+	}
+
+	query = `
+		UPDATE orders SET user_id = NULL, shipping_latitude = NULL, shipping_longitude = NULL,
+		updated_at = EXTRACT (EPOCH FROM CURRENT_TIMESTAMP)
+		WHERE user_id = $1
+	`
+	if _, err := tx.Exec(context.Background(), query, userID); err != nil {
+		return err
+	}
+
+	query = `DELETE FROM sessions WHERE email = $1`
+	if _, err := tx.Exec(context.Background(), query, email); err != nil {
+		return err
+	}
+
+	query = `DELETE FROM password_reset_tokens WHERE user_id = $1`
+	if _, err := tx.Exec(context.Background(), query, userID); err != nil {
+		return err
+	}
+
+	query = `DELETE FROM users WHERE id = $1`
+	if _, err := tx.Exec(context.Background(), query, userID); err != nil {
+		return err
+	}
+
+	return tx.Commit(context.Background())
+}
+
+// scrubAuditEvents replaces the personal fields of targetType in the audit
+// events whose target IDs are listed by targets, which may refer to userID as
+// $1.
+func scrubAuditEvents(tx pgx.Tx, targetType, targets, userID string) error {
+	query := `
+		UPDATE audit_events SET
+		before = scrub_audit_fields(before, $3, $4),
+		after = scrub_audit_fields(after, $3, $4)
+		WHERE target_type = $2 AND target_id IN (` + targets + `)
+	`
+
+	_, err := tx.Exec(context.Background(), query, userID, targetType,
+		domain.PersonalAuditFields[targetType], domain.PersonalAuditValue)
+	return err
+}
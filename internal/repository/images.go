@@ -0,0 +1,115 @@
+package repository
+
+import (
+	"context"
+	"ecomm/internal/domain"
+	"errors"
+
+	"github.com/georgysavva/scany/v2/pgxscan"
+	"github.com/jackc/pgx/v5"
+)
+
+const imageColumns = `
+	id, product_id, storage_key, thumbnail_key, medium_key, content_type, width, height, size_bytes,
+	alt_text, position, created_at
+`
+
+// getProductImages returns the galleries of the given products keyed by
+// product ID.
+func (r *repository) getProductImages(productIDs []string) (map[string][]*domain.ProductImage, error) {
+	query := `SELECT ` + imageColumns + ` FROM product_images WHERE product_id = ANY($1) ORDER BY position, created_at`
+
+	var images []*domain.ProductImage
+	if err := pgxscan.Select(context.Background(), r.pool, &images, query, productIDs); err != nil {
+		return nil, err
+	}
+
+	byProduct := make(map[string][]*domain.ProductImage)
+	for _, image := range images {
+		byProduct[image.ProductID] = append(byProduct[image.ProductID], image)
+	}
+	return byProduct, nil
+}
+
+// CreateProductImage appends the image to the end of the product's gallery.
+func (r *repository) CreateProductImage(image *domain.ProductImage) error {
+	query := `
+		INSERT INTO product_images(id, product_id, storage_key, thumbnail_key, medium_key, content_type,
+		width, height, size_bytes, alt_text, position)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10,
+		(SELECT COALESCE(max(position) + 1, 0) FROM product_images WHERE product_id = $2))
+		RETURNING position, created_at
+	`
+
+	err := r.pool.QueryRow(context.Background(), query,
+		image.ID,
+		image.ProductID,
+		image.StorageKey,
+		image.ThumbnailKey,
+		image.MediumKey,
+		image.ContentType,
+		image.Width,
+		image.Height,
+		image.SizeBytes,
+		image.AltText).Scan(&image.Position, &image.CreatedAt)
+	if err != nil {
+		if isForeignKeyViolation(err) {
+			return domain.ErrProductNotFound
+		}
+		return err
+	}
+
+	return nil
+}
+
+func (r *repository) GetProductImage(id string) (*domain.ProductImage, error) {
+	query := `SELECT ` + imageColumns + ` FROM product_images WHERE id = $1`
+
+	image := new(domain.ProductImage)
+	if err := pgxscan.Get(context.Background(), r.pool, image, query, id); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, domain.ErrImageNotFound
+		}
+		return nil, err
+	}
+
+	return image, nil
+}
+
+func (r *repository) DeleteProductImage(id string) error {
+	query := `DELETE FROM product_images WHERE id = $1`
+	result, err := r.pool.Exec(context.Background(), query, id)
+	if err != nil {
+		return err
+	}
+
+	if result.RowsAffected() == 0 {
+		return domain.ErrImageNotFound
+	}
+
+	return nil
+}
+
+// ReorderProductImages sets the position of every image to its index in
+// imageIDs.
+func (r *repository) ReorderProductImages(productID string, imageIDs []string) error {
+	tx, err := r.pool.Begin(context.Background())
+	if err != nil {
+		return err
+	}
+
+	defer tx.Rollback(context.Background())
+
+	query := `UPDATE product_images SET position = $1 WHERE id = $2 AND product_id = $3`
+	for i, id := range imageIDs {
+		result, err := tx.Exec(context.Background(), query, i, id, productID)
+		if err != nil {
+			return err
+		}
+		if result.RowsAffected() == 0 {
+			return domain.ErrImageNotFound
+		}
+	}
+
+	return tx.Commit(context.Background())
+}
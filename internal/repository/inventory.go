@@ -0,0 +1,370 @@
+package repository
+
+import (
+	"context"
+	"ecomm/internal/domain"
+	"errors"
+
+	"github.com/georgysavva/scany/v2/pgxscan"
+	"github.com/jackc/pgx/v5"
+)
+
+// defaultWarehouseID returns the active warehouse with the lowest priority,
+// which receives movements that do not name a warehouse. The warehouse is
+// share-locked so that it cannot be deactivated while the movement is applied.
+func defaultWarehouseID(tx pgx.Tx) (string, error) {
+	query := `SELECT id FROM warehouses WHERE active ORDER BY priority, created_at LIMIT 1 FOR SHARE`
+
+	var id string
+	if err := tx.QueryRow(context.Background(), query).Scan(&id); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return "", domain.ErrNoWarehouse
+		}
+		return "", err
+	}
+	return id, nil
+}
+
+// checkWarehouseActive rejects movements that add stock to an inactive
+// warehouse, since orders are not allocated from it. The warehouse is
+// share-locked so that it cannot be deactivated while the movement is applied.
+func checkWarehouseActive(tx pgx.Tx, movement *domain.InventoryMovement) error {
+	if movement.Quantity <= 0 {
+		return nil
+	}
+
+	var active bool
+	query := `SELECT active FROM warehouses WHERE id = $1 FOR SHARE`
+	if err := tx.QueryRow(context.Background(), query, movement.WarehouseID).Scan(&active); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return domain.ErrWarehouseNotFound
+		}
+		return err
+	}
+	if !active {
+		return domain.ErrWarehouseInactive
+	}
+	return nil
+}
+
+// balanceAfter returns the stock that results from applying movement to
+// stock. A movement with SetTo gets the quantity that reaches it.
+func balanceAfter(movement *domain.InventoryMovement, stock int) (int, error) {
+	if movement.SetTo != nil {
+		movement.Quantity = *movement.SetTo - stock
+	}
+
+	balance := stock + movement.Quantity
+	if balance < 0 {
+		return 0, domain.ErrInsufficientStock
+	}
+	return balance, nil
+}
+
+// applyInventoryMovement moves the stock of the movement's product, or of its
+// variant, by movement.Quantity both in total and in the movement's warehouse,
+// records the movement and opens or resolves the item's low-stock alert. The
+// product or variant row stays locked until the transaction ends, so
+// concurrent movements of the same item are applied one after the other and
+// every balance is exact. Nothing is recorded for a movement whose SetTo is
+// already the stock.
+func applyInventoryMovement(tx pgx.Tx, movement *domain.InventoryMovement) error {
+	threshold, err := moveStock(tx, movement)
+	if err != nil {
+		return err
+	}
+	return updateStockAlert(tx, movement.ProductID, movement.VariantID, movement.Balance, threshold)
+}
+
+// moveStock applies and records a movement like applyInventoryMovement but
+// leaves the low-stock alert to the caller. It returns the item's reorder
+// threshold.
+func moveStock(tx pgx.Tx, movement *domain.InventoryMovement) (int, error) {
+	if movement.WarehouseID == "" {
+		id, err := defaultWarehouseID(tx)
+		if err != nil {
+			return 0, err
+		}
+		movement.WarehouseID = id
+	}
+
+	var stock, threshold int
+	if movement.VariantID == "" {
+		query := `SELECT count_in_stock, reorder_threshold FROM products WHERE id = $1 FOR UPDATE`
+		if err := tx.QueryRow(context.Background(), query, movement.ProductID).Scan(&stock, &threshold); err != nil {
+			if errors.Is(err, pgx.ErrNoRows) {
+				return 0, domain.ErrProductNotFound
+			}
+			return 0, err
+		}
+	} else {
+		query := `
+			SELECT v.count_in_stock, p.reorder_threshold
+			FROM product_variants v
+			JOIN products p ON p.id = v.product_id
+			WHERE v.id = $1 AND v.product_id = $2
+			FOR UPDATE OF v
+		`
+		if err := tx.QueryRow(context.Background(), query, movement.VariantID, movement.ProductID).Scan(&stock, &threshold); err != nil {
+			if errors.Is(err, pgx.ErrNoRows) {
+				return 0, domain.ErrVariantNotFound
+			}
+			return 0, err
+		}
+	}
+
+	balance, err := balanceAfter(movement, stock)
+	if err != nil {
+		return 0, err
+	}
+	movement.Balance = balance
+	if movement.SetTo != nil && movement.Quantity == 0 {
+		return threshold, nil
+	}
+
+	if err := checkWarehouseActive(tx, movement); err != nil {
+		return 0, err
+	}
+
+	query := `
+		UPDATE products SET count_in_stock = $1, updated_at = EXTRACT (EPOCH FROM CURRENT_TIMESTAMP)
+		WHERE id = $2
+	`
+	target := movement.ProductID
+	if movement.VariantID != "" {
+		query = `
+			UPDATE product_variants SET count_in_stock = $1, updated_at = EXTRACT (EPOCH FROM CURRENT_TIMESTAMP)
+			WHERE id = $2
+		`
+		target = movement.VariantID
+	}
+	if _, err := tx.Exec(context.Background(), query, movement.Balance, target); err != nil {
+		return 0, err
+	}
+
+	if err := moveWarehouseStock(tx, movement); err != nil {
+		return 0, err
+	}
+
+	query = `
+		INSERT INTO inventory_movements(product_id, variant_id, warehouse_id, type, quantity, balance, reason, order_id, actor_id)
+		VALUES ($1, NULLIF($2, '')::uuid, $3, $4, $5, $6, $7, NULLIF($8, '')::uuid, NULLIF($9, '')::uuid)
+		RETURNING id, created_at
+	`
+	if err := tx.QueryRow(context.Background(), query,
+		movement.ProductID,
+		movement.VariantID,
+		movement.WarehouseID,
+		movement.Type,
+		movement.Quantity,
+		movement.Balance,
+		movement.Reason,
+		movement.OrderID,
+		movement.ActorID).Scan(&movement.ID, &movement.CreatedAt); err != nil {
+		return 0, err
+	}
+	return threshold, nil
+}
+
+// moveWarehouseStock applies a movement to the stock of its warehouse. It
+// relies on the caller holding the lock on the product or variant row.
+func moveWarehouseStock(tx pgx.Tx, movement *domain.InventoryMovement) error {
+	query := `
+		UPDATE warehouse_stock SET quantity = quantity + $4
+		WHERE warehouse_id = $1 AND product_id = $2 AND variant_id IS NOT DISTINCT FROM NULLIF($3, '')::uuid
+		RETURNING quantity
+	`
+
+	var quantity int
+	err := tx.QueryRow(context.Background(), query,
+		movement.WarehouseID,
+		movement.ProductID,
+		movement.VariantID,
+		movement.Quantity).Scan(&quantity)
+	switch {
+	case err == nil:
+		return nil
+	case !errors.Is(err, pgx.ErrNoRows):
+		if isCheckViolation(err, "warehouse_stock_quantity") {
+			return domain.ErrInsufficientStock
+		}
+		return err
+	case movement.Quantity < 0:
+		return domain.ErrInsufficientStock
+	}
+
+	query = `
+		INSERT INTO warehouse_stock(warehouse_id, product_id, variant_id, quantity)
+		VALUES ($1, $2, NULLIF($3, '')::uuid, $4)
+	`
+	if _, err := tx.Exec(context.Background(), query,
+		movement.WarehouseID,
+		movement.ProductID,
+		movement.VariantID,
+		movement.Quantity); err != nil {
+		if isForeignKeyViolation(err) {
+			return domain.ErrWarehouseNotFound
+		}
+		return err
+	}
+	return nil
+}
+
+func (r *repository) RecordInventoryMovement(movement *domain.InventoryMovement) error {
+	tx, err := r.pool.Begin(context.Background())
+	if err != nil {
+		return err
+	}
+
+	defer tx.Rollback(context.Background())
+
+	if err := applyInventoryMovement(tx, movement); err != nil {
+		return err
+	}
+
+	return tx.Commit(context.Background())
+}
+
+func (r *repository) ListInventoryMovements(filter *domain.InventoryMovementFilter) ([]*domain.InventoryMovement, error) {
+	query := `
+		SELECT id, product_id, COALESCE(variant_id::text, '') AS variant_id, warehouse_id, type, quantity,
+		balance, reason, COALESCE(order_id::text, '') AS order_id, COALESCE(actor_id::text, '') AS actor_id,
+		created_at
+		FROM inventory_movements
+		WHERE product_id = $1
+		AND ($2 = '' OR variant_id::text = $2)
+		AND ($3 = '' OR warehouse_id::text = $3)
+		AND ($4 = '' OR type = $4)
+		ORDER BY created_at DESC, id
+		LIMIT $5 OFFSET $6
+	`
+
+	var movements []*domain.InventoryMovement
+	if err := pgxscan.Select(context.Background(), r.pool, &movements, query,
+		filter.ProductID,
+		filter.VariantID,
+		filter.WarehouseID,
+		filter.Type,
+		filter.Limit,
+		filter.Offset); err != nil {
+		return nil, err
+	}
+
+	return movements, nil
+}
+
+// TransferStock moves stock between warehouses by applying a pair of
+// transfer movements in one transaction. The total stock is the same before
+// and after, so the low-stock alert is only evaluated once both are applied.
+func (r *repository) TransferStock(from, to *domain.InventoryMovement) error {
+	tx, err := r.pool.Begin(context.Background())
+	if err != nil {
+		return err
+	}
+
+	defer tx.Rollback(context.Background())
+
+	if _, err := moveStock(tx, from); err != nil {
+		return err
+	}
+	threshold, err := moveStock(tx, to)
+	if err != nil {
+		return err
+	}
+	if err := updateStockAlert(tx, to.ProductID, to.VariantID, to.Balance, threshold); err != nil {
+		return err
+	}
+
+	return tx.Commit(context.Background())
+}
+
+// ReconcileStock compares the stock of every product and variant, in total
+// and per warehouse, with the sum of its movements. With apply set, the
+// stock is reset to the ledger total, which is treated as the source of
+// truth.
+func (r *repository) ReconcileStock(apply bool) ([]*domain.StockDiscrepancy, error) {
+	// A report is a single statement and so sees a consistent snapshot
+	// without blocking movements.
+	if !apply {
+		discrepancies := make([]*domain.StockDiscrepancy, 0)
+		if err := pgxscan.Select(context.Background(), r.pool, &discrepancies, stockDiscrepanciesQuery); err != nil {
+			return nil, err
+		}
+		return discrepancies, nil
+	}
+
+	tx, err := r.pool.Begin(context.Background())
+	if err != nil {
+		return nil, err
+	}
+
+	defer tx.Rollback(context.Background())
+
+	// Lock the stock tables against concurrent movements so the totals
+	// compared and written are consistent.
+	query := `LOCK TABLE products, product_variants, warehouse_stock IN SHARE ROW EXCLUSIVE MODE`
+	if _, err := tx.Exec(context.Background(), query); err != nil {
+		return nil, err
+	}
+
+	discrepancies := make([]*domain.StockDiscrepancy, 0)
+	if err := pgxscan.Select(context.Background(), tx, &discrepancies, stockDiscrepanciesQuery); err != nil {
+		return nil, err
+	}
+
+	for _, discrepancy := range discrepancies {
+		query, args := resetStockQuery(discrepancy)
+		if _, err := tx.Exec(context.Background(), query, args...); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := tx.Commit(context.Background()); err != nil {
+		return nil, err
+	}
+
+	return discrepancies, nil
+}
+
+// stockDiscrepanciesQuery lists the totals and warehouse levels that differ
+// from the sum of their movements.
+const stockDiscrepanciesQuery = `
+	SELECT p.id AS product_id, '' AS variant_id, '' AS warehouse_id, p.count_in_stock AS recorded,
+	COALESCE(SUM(m.quantity), 0) AS ledger
+	FROM products p
+	LEFT JOIN inventory_movements m ON m.product_id = p.id AND m.variant_id IS NULL
+	GROUP BY p.id
+	HAVING p.count_in_stock <> COALESCE(SUM(m.quantity), 0)
+	UNION ALL
+	SELECT v.product_id, v.id::text, '', v.count_in_stock, COALESCE(SUM(m.quantity), 0)
+	FROM product_variants v
+	LEFT JOIN inventory_movements m ON m.variant_id = v.id
+	GROUP BY v.id
+	HAVING v.count_in_stock <> COALESCE(SUM(m.quantity), 0)
+	UNION ALL
+	SELECT s.product_id, COALESCE(s.variant_id::text, ''), s.warehouse_id::text, s.quantity,
+	COALESCE(SUM(m.quantity), 0)
+	FROM warehouse_stock s
+	LEFT JOIN inventory_movements m ON m.product_id = s.product_id
+	AND m.variant_id IS NOT DISTINCT FROM s.variant_id AND m.warehouse_id = s.warehouse_id
+	GROUP BY s.product_id, s.variant_id, s.warehouse_id, s.quantity
+	HAVING s.quantity <> COALESCE(SUM(m.quantity), 0)
+	ORDER BY product_id, variant_id, warehouse_id
+`
+
+// resetStockQuery returns the statement that sets the stock of a discrepancy
+// to its ledger total.
+func resetStockQuery(discrepancy *domain.StockDiscrepancy) (string, []any) {
+	switch {
+	case discrepancy.WarehouseID != "":
+		query := `
+			UPDATE warehouse_stock SET quantity = $1
+			WHERE warehouse_id = $2 AND product_id = $3 AND variant_id IS NOT DISTINCT FROM NULLIF($4, '')::uuid
+		`
+		return query, []any{discrepancy.Ledger, discrepancy.WarehouseID, discrepancy.ProductID, discrepancy.VariantID}
+	case discrepancy.VariantID != "":
+		return `UPDATE product_variants SET count_in_stock = $1 WHERE id = $2`, []any{discrepancy.Ledger, discrepancy.VariantID}
+	default:
+		return `UPDATE products SET count_in_stock = $1 WHERE id = $2`, []any{discrepancy.Ledger, discrepancy.ProductID}
+	}
+}
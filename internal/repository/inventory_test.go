@@ -0,0 +1,126 @@
+package repository
+
+import (
+	"ecomm/internal/domain"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestBalanceAfter(t *testing.T) {
+	target := func(n int) *int { return &n }
+
+	tests := []struct {
+		name     string
+		movement domain.InventoryMovement
+		stock    int
+		balance  int
+		quantity int
+		err      error
+	}{
+		{"receipt", domain.InventoryMovement{Quantity: 5}, 3, 8, 5, nil},
+		{"sale", domain.InventoryMovement{Quantity: -3}, 3, 0, -3, nil},
+		{"oversold", domain.InventoryMovement{Quantity: -4}, 3, 0, -4, domain.ErrInsufficientStock},
+		{"set up", domain.InventoryMovement{SetTo: target(10)}, 4, 10, 6, nil},
+		{"set down", domain.InventoryMovement{SetTo: target(1)}, 4, 1, -3, nil},
+		{"set to stock", domain.InventoryMovement{SetTo: target(4)}, 4, 4, 0, nil},
+		{"set ignores quantity", domain.InventoryMovement{Quantity: 7, SetTo: target(2)}, 4, 2, -2, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			movement := tt.movement
+			balance, err := balanceAfter(&movement, tt.stock)
+			if !errors.Is(err, tt.err) {
+				t.Fatalf("balanceAfter() error = %v; want %v", err, tt.err)
+			}
+			if err == nil && balance != tt.balance {
+				t.Errorf("balance = %d; want %d", balance, tt.balance)
+			}
+			if movement.Quantity != tt.quantity {
+				t.Errorf("quantity = %d; want %d", movement.Quantity, tt.quantity)
+			}
+		})
+	}
+}
+
+func TestResetStockQuery(t *testing.T) {
+	tests := []struct {
+		name        string
+		discrepancy domain.StockDiscrepancy
+		table       string
+		args        []any
+	}{
+		{
+			"product",
+			domain.StockDiscrepancy{ProductID: "product-1", Ledger: 4},
+			"UPDATE products",
+			[]any{4, "product-1"},
+		},
+		{
+			"variant",
+			domain.StockDiscrepancy{ProductID: "product-1", VariantID: "variant-1", Ledger: 2},
+			"UPDATE product_variants",
+			[]any{2, "variant-1"},
+		},
+		{
+			"warehouse",
+			domain.StockDiscrepancy{ProductID: "product-1", VariantID: "variant-1", WarehouseID: "warehouse-1", Ledger: 1},
+			"UPDATE warehouse_stock",
+			[]any{1, "warehouse-1", "product-1", "variant-1"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			query, args := resetStockQuery(&tt.discrepancy)
+			if !strings.Contains(query, tt.table) {
+				t.Errorf("query = %q; want %s", query, tt.table)
+			}
+			if !reflect.DeepEqual(args, tt.args) {
+				t.Errorf("args = %v; want %v", args, tt.args)
+			}
+		})
+	}
+}
+
+func TestSpreadReduction(t *testing.T) {
+	levels := []warehouseLevel{{"default", 2}, {"north", 5}, {"south", 4}}
+
+	tests := []struct {
+		name       string
+		quantity   int
+		warehouses []string
+		quantities []int
+		err        error
+	}{
+		{"default warehouse holds enough", -2, []string{"default"}, []int{-2}, nil},
+		{"spills into the next warehouse", -4, []string{"default", "north"}, []int{-2, -2}, nil},
+		{"empties every warehouse", -11, []string{"default", "north", "south"}, []int{-2, -5, -4}, nil},
+		{"more than the warehouses hold", -12, nil, nil, domain.ErrInsufficientStock},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			movement := &domain.InventoryMovement{ProductID: "product-1", Quantity: tt.quantity, Reason: "catalog import"}
+			parts, err := spreadReduction(movement, levels)
+			if !errors.Is(err, tt.err) {
+				t.Fatalf("spreadReduction() error = %v; want %v", err, tt.err)
+			}
+
+			var warehouses []string
+			var quantities []int
+			for _, part := range parts {
+				if part.ProductID != "product-1" || part.Reason != "catalog import" {
+					t.Errorf("part = %+v; want the movement's product and reason", part)
+				}
+				warehouses = append(warehouses, part.WarehouseID)
+				quantities = append(quantities, part.Quantity)
+			}
+			if !reflect.DeepEqual(warehouses, tt.warehouses) || !reflect.DeepEqual(quantities, tt.quantities) {
+				t.Errorf("parts = %v %v; want %v %v", warehouses, quantities, tt.warehouses, tt.quantities)
+			}
+		})
+	}
+}
@@ -0,0 +1,74 @@
+package repository
+
+import (
+	"context"
+	"ecomm/internal/domain"
+	"errors"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// GetLoginThrottle returns an empty throttle for keys without recorded
+// failures.
+func (r *repository) GetLoginThrottle(key string) (*domain.LoginThrottle, error) {
+	query := `
+		SELECT key, failures, locked_until, last_failure_at
+		FROM login_throttles WHERE key = $1
+	`
+
+	throttle := new(domain.LoginThrottle)
+	if err := r.pool.QueryRow(context.Background(), query, key).Scan(
+		&throttle.Key,
+		&throttle.Failures,
+		&throttle.LockedUntil,
+		&throttle.LastFailureAt); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return &domain.LoginThrottle{Key: key}, nil
+		}
+		return nil, err
+	}
+
+	return throttle, nil
+}
+
+// RecordLoginFailure increments the failure counter for key. Failures recorded
+// before windowStart are forgotten and the counter starts over.
+func (r *repository) RecordLoginFailure(key string, windowStart uint64) (*domain.LoginThrottle, error) {
+	query := `
+		INSERT INTO login_throttles(key, failures)
+		VALUES ($1, 1)
+		ON CONFLICT (key) DO UPDATE SET
+			failures = CASE WHEN login_throttles.last_failure_at < $2 THEN 1 ELSE login_throttles.failures + 1 END,
+			last_failure_at = EXTRACT (EPOCH FROM CURRENT_TIMESTAMP)
+		RETURNING key, failures, locked_until, last_failure_at
+	`
+
+	throttle := new(domain.LoginThrottle)
+	if err := r.pool.QueryRow(context.Background(), query, key, windowStart).Scan(
+		&throttle.Key,
+		&throttle.Failures,
+		&throttle.LockedUntil,
+		&throttle.LastFailureAt); err != nil {
+		return nil, err
+	}
+
+	return throttle, nil
+}
+
+func (r *repository) LockLogin(key string, lockedUntil uint64) error {
+	query := `UPDATE login_throttles SET locked_until = $1 WHERE key = $2`
+	if _, err := r.pool.Exec(context.Background(), query, lockedUntil, key); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (r *repository) ClearLoginFailures(key string) error {
+	query := `DELETE FROM login_throttles WHERE key = $1`
+	if _, err := r.pool.Exec(context.Background(), query, key); err != nil {
+		return err
+	}
+
+	return nil
+}
@@ -0,0 +1,148 @@
+package repository
+
+import (
+	"context"
+	"ecomm/internal/domain"
+	"errors"
+
+	"github.com/jackc/pgx/v5"
+)
+
+func (r *repository) GetUserMFA(userID string) (*domain.UserMFA, error) {
+	query := `
+		SELECT user_id, secret, enabled, last_used_step, created_at, COALESCE(enabled_at, 0)
+		FROM user_mfa WHERE user_id = $1
+	`
+
+	mfa := new(domain.UserMFA)
+	if err := r.pool.QueryRow(context.Background(), query, userID).Scan(
+		&mfa.UserID,
+		&mfa.Secret,
+		&mfa.Enabled,
+		&mfa.LastUsedStep,
+		&mfa.CreatedAt,
+		&mfa.EnabledAt); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, domain.ErrMFANotEnrolled
+		}
+		return nil, err
+	}
+
+	return mfa, nil
+}
+
+// SaveMFASecret stores a pending secret for the user. A secret that has
+// already been confirmed is never replaced.
+func (r *repository) SaveMFASecret(userID, secret string) error {
+	query := `
+		INSERT INTO user_mfa(user_id, secret)
+		VALUES ($1, $2)
+		ON CONFLICT (user_id) DO UPDATE SET
+			secret = EXCLUDED.secret,
+			last_used_step = 0,
+			created_at = EXTRACT (EPOCH FROM CURRENT_TIMESTAMP)
+		WHERE user_mfa.enabled = FALSE
+	`
+
+	if _, err := r.pool.Exec(context.Background(), query, userID, secret); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (r *repository) EnableMFA(userID string, recoveryCodeHashes []string) error {
+	tx, err := r.pool.Begin(context.Background())
+	if err != nil {
+		return err
+	}
+
+	defer tx.Rollback(context.Background())
+
+	query := `
+		UPDATE user_mfa SET enabled = TRUE, enabled_at = EXTRACT (EPOCH FROM CURRENT_TIMESTAMP)
+		WHERE user_id = $1
+	`
+	result, err := tx.Exec(context.Background(), query, userID)
+	if err != nil {
+		return err
+	}
+
+	if result.RowsAffected() == 0 {
+		return domain.ErrMFANotEnrolled
+	}
+
+	query = `DELETE FROM mfa_recovery_codes WHERE user_id = $1`
+	if _, err := tx.Exec(context.Background(), query, userID); err != nil {
+		return err
+	}
+
+	query = `INSERT INTO mfa_recovery_codes(user_id, code_hash) VALUES ($1, $2)`
+	for _, codeHash := range recoveryCodeHashes {
+		if _, err := tx.Exec(context.Background(), query, userID, codeHash); err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit(context.Background())
+}
+
+func (r *repository) DisableMFA(userID string) error {
+	tx, err := r.pool.Begin(context.Background())
+	if err != nil {
+		return err
+	}
+
+	defer tx.Rollback(context.Background())
+
+	query := `DELETE FROM mfa_recovery_codes WHERE user_id = $1`
+	if _, err := tx.Exec(context.Background(), query, userID); err != nil {
+		return err
+	}
+
+	query = `DELETE FROM user_mfa WHERE user_id = $1`
+	result, err := tx.Exec(context.Background(), query, userID)
+	if err != nil {
+		return err
+	}
+
+	if result.RowsAffected() == 0 {
+		return domain.ErrMFANotEnrolled
+	}
+
+	return tx.Commit(context.Background())
+}
+
+// UseMFAStep records the time step of an accepted TOTP code. It fails if the
+// same or a later step was already used, which prevents code replay.
+func (r *repository) UseMFAStep(userID string, step int64) error {
+	query := `UPDATE user_mfa SET last_used_step = $1 WHERE user_id = $2 AND last_used_step < $1`
+	result, err := r.pool.Exec(context.Background(), query, step, userID)
+	if err != nil {
+		return err
+	}
+
+	if result.RowsAffected() == 0 {
+		return domain.ErrMFACodeAlreadyUsed
+	}
+
+	return nil
+}
+
+func (r *repository) UseRecoveryCode(userID, codeHash string) error {
+	query := `
+		UPDATE mfa_recovery_codes SET used_at = EXTRACT (EPOCH FROM CURRENT_TIMESTAMP)
+		WHERE user_id = $1 AND code_hash = $2 AND used_at IS NULL
+	`
+
+	result, err := r.pool.Exec(context.Background(), query, userID, codeHash)
+	if err != nil {
+		return err
+	}
+
+	if result.RowsAffected() == 0 {
+		return domain.ErrRecoveryCodeNotFound
+	}
+
+	return nil
+}
@@ -0,0 +1,97 @@
+package repository
+
+import (
+	"context"
+	"ecomm/internal/domain"
+	"errors"
+
+	"github.com/jackc/pgx/v5"
+)
+
+func (r *repository) CreateOIDCLoginState(state *domain.OIDCLoginState) error {
+	query := `
+		INSERT INTO oidc_login_states(state, provider, code_verifier, nonce, expires_at)
+		VALUES ($1, $2, $3, $4, $5)
+	`
+
+	if _, err := r.pool.Exec(context.Background(), query,
+		&state.State,
+		&state.Provider,
+		&state.CodeVerifier,
+		&state.Nonce,
+		&state.ExpiresAt); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// ConsumeOIDCLoginState deletes and returns the state so that each
+// authorization response can only be redeemed once. Expired states are
+// cleaned up along the way.
+func (r *repository) ConsumeOIDCLoginState(state string) (*domain.OIDCLoginState, error) {
+	query := `DELETE FROM oidc_login_states WHERE expires_at < EXTRACT (EPOCH FROM CURRENT_TIMESTAMP)`
+	if _, err := r.pool.Exec(context.Background(), query); err != nil {
+		return nil, err
+	}
+
+	query = `
+		DELETE FROM oidc_login_states WHERE state = $1
+		RETURNING state, provider, code_verifier, nonce, expires_at, created_at
+	`
+
+	loginState := new(domain.OIDCLoginState)
+	if err := r.pool.QueryRow(context.Background(), query, state).Scan(
+		&loginState.State,
+		&loginState.Provider,
+		&loginState.CodeVerifier,
+		&loginState.Nonce,
+		&loginState.ExpiresAt,
+		&loginState.CreatedAt); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, domain.ErrOIDCStateNotFound
+		}
+		return nil, err
+	}
+
+	return loginState, nil
+}
+
+func (r *repository) GetUserIdentity(provider, subject string) (*domain.UserIdentity, error) {
+	query := `
+		SELECT provider, subject, user_id, email, created_at
+		FROM user_identities WHERE provider = $1 AND subject = $2
+	`
+
+	identity := new(domain.UserIdentity)
+	if err := r.pool.QueryRow(context.Background(), query, provider, subject).Scan(
+		&identity.Provider,
+		&identity.Subject,
+		&identity.UserID,
+		&identity.Email,
+		&identity.CreatedAt); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, domain.ErrUserIdentityNotFound
+		}
+		return nil, err
+	}
+
+	return identity, nil
+}
+
+func (r *repository) CreateUserIdentity(identity *domain.UserIdentity) error {
+	query := `
+		INSERT INTO user_identities(provider, subject, user_id, email)
+		VALUES ($1, $2, $3, $4)
+	`
+
+	if _, err := r.pool.Exec(context.Background(), query,
+		&identity.Provider,
+		&identity.Subject,
+		&identity.UserID,
+		&identity.Email); err != nil {
+		return err
+	}
+
+	return nil
+}
@@ -0,0 +1,105 @@
+package repository
+
+import (
+	"context"
+	"ecomm/internal/domain"
+	"errors"
+
+	"github.com/jackc/pgx/v5"
+)
+
+func (r *repository) CreatePasswordResetToken(token *domain.PasswordResetToken) error {
+	tx, err := r.pool.Begin(context.Background())
+	if err != nil {
+		return err
+	}
+
+	defer tx.Rollback(context.Background())
+
+	// Only the most recently issued token for a user stays usable.
+	query := `
+		UPDATE password_reset_tokens SET used_at = EXTRACT (EPOCH FROM CURRENT_TIMESTAMP)
+		WHERE user_id = $1 AND used_at IS NULL
+	`
+	if _, err := tx.Exec(context.Background(), query, token.UserID); err != nil {
+		return err
+	}
+
+	query = `
+		INSERT INTO password_reset_tokens(user_id, token_hash, expires_at)
+		VALUES ($1, $2, $3)
+		RETURNING id
+	`
+	if err := tx.QueryRow(context.Background(), query,
+		&token.UserID,
+		&token.TokenHash,
+		&token.ExpiresAt).Scan(&token.ID); err != nil {
+		return err
+	}
+
+	return tx.Commit(context.Background())
+}
+
+func (r *repository) GetPasswordResetToken(tokenHash string) (*domain.PasswordResetToken, error) {
+	query := `
+		SELECT id, user_id, token_hash, expires_at, COALESCE(used_at, 0), created_at
+		FROM password_reset_tokens WHERE token_hash = $1
+	`
+
+	token := new(domain.PasswordResetToken)
+	if err := r.pool.QueryRow(context.Background(), query, tokenHash).Scan(
+		&token.ID,
+		&token.UserID,
+		&token.TokenHash,
+		&token.ExpiresAt,
+		&token.UsedAt,
+		&token.CreatedAt); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, domain.ErrPasswordResetTokenNotFound
+		}
+		return nil, err
+	}
+
+	return token, nil
+}
+
+func (r *repository) ResetPassword(tokenID, userID, passwordHash string) error {
+	tx, err := r.pool.Begin(context.Background())
+	if err != nil {
+		return err
+	}
+
+	defer tx.Rollback(context.Background())
+
+	query := `
+		UPDATE password_reset_tokens SET used_at = EXTRACT (EPOCH FROM CURRENT_TIMESTAMP)
+		WHERE id = $1 AND used_at IS NULL
+	`
+	result, err := tx.Exec(context.Background(), query, tokenID)
+	if err != nil {
+		return err
+	}
+
+	if result.RowsAffected() == 0 {
+		return domain.ErrPasswordResetTokenNotFound
+	}
+
+	var email string
+	query = `
+		UPDATE users SET password = $1, updated_at = EXTRACT (EPOCH FROM CURRENT_TIMESTAMP)
+		WHERE id = $2
+		RETURNING email
+	`
+	if err := tx.QueryRow(context.Background(), query, passwordHash, userID).Scan(&email); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return domain.ErrUserNotFound
+		}
+		return err
+	}
+
+	if err := revokeUserSessions(tx, email); err != nil {
+		return err
+	}
+
+	return tx.Commit(context.Background())
+}
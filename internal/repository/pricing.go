@@ -0,0 +1,240 @@
+package repository
+
+import (
+	"context"
+	"ecomm/internal/domain"
+	"errors"
+
+	"github.com/georgysavva/scany/v2/pgxscan"
+	"github.com/jackc/pgx/v5"
+)
+
+// recordPrice adds the current pricing of a product to its price history,
+// attributing the change to actorID.
+func recordPrice(tx pgx.Tx, productID, reason, actorID string) error {
+	query := `
+		INSERT INTO price_history(product_id, price, sale_price, sale_starts_at, sale_ends_at, reason, actor_id)
+		SELECT id, price, sale_price, sale_starts_at, sale_ends_at, $2, NULLIF($3, '')::uuid
+		FROM products WHERE id = $1
+	`
+	_, err := tx.Exec(context.Background(), query, productID, reason, actorID)
+	return err
+}
+
+// productSale builds the sale of a product from its nullable sale columns.
+func productSale(price *float64, startsAt, endsAt uint64) *domain.ProductSale {
+	if price == nil {
+		return nil
+	}
+	return &domain.ProductSale{Price: *price, StartsAt: startsAt, EndsAt: endsAt}
+}
+
+// SetProductSale replaces the sale of a product, or ends it when sale is nil,
+// on behalf of actorID. It fails with ErrVersionMismatch unless the product is
+// still at version.
+func (r *repository) SetProductSale(productID string, sale *domain.ProductSale, version int64, actorID string) error {
+	tx, err := r.pool.Begin(context.Background())
+	if err != nil {
+		return err
+	}
+
+	defer tx.Rollback(context.Background())
+
+	var price *float64
+	var startsAt, endsAt *uint64
+	if sale != nil {
+		price, startsAt = &sale.Price, &sale.StartsAt
+		if sale.EndsAt != 0 {
+			endsAt = &sale.EndsAt
+		}
+	}
+
+	query := `
+		UPDATE products SET sale_price = $1, sale_starts_at = $2, sale_ends_at = $3,
+		updated_at = EXTRACT (EPOCH FROM CURRENT_TIMESTAMP)
+		WHERE id = $4 AND version = $5
+	`
+	result, err := tx.Exec(context.Background(), query, price, startsAt, endsAt, productID, version)
+	if err != nil {
+		return err
+	}
+
+	if result.RowsAffected() == 0 {
+		return r.versionError("products", productID, domain.ErrProductNotFound)
+	}
+
+	if err := recordPrice(tx, productID, domain.PriceSale, actorID); err != nil {
+		return err
+	}
+
+	return tx.Commit(context.Background())
+}
+
+func (r *repository) ListPriceHistory(filter *domain.PriceHistoryFilter) ([]*domain.PriceHistoryEntry, error) {
+	query := `
+		SELECT id, product_id, price, sale_price, COALESCE(sale_starts_at, 0), COALESCE(sale_ends_at, 0), reason,
+		COALESCE(actor_id::text, ''), created_at
+		FROM price_history
+		WHERE product_id = $1
+		ORDER BY created_at DESC, id
+		LIMIT $2 OFFSET $3
+	`
+
+	rows, err := r.pool.Query(context.Background(), query, filter.ProductID, filter.Limit, filter.Offset)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	entries := make([]*domain.PriceHistoryEntry, 0)
+	for rows.Next() {
+		entry := new(domain.PriceHistoryEntry)
+		var salePrice *float64
+		var saleStartsAt, saleEndsAt uint64
+		if err := rows.Scan(
+			&entry.ID,
+			&entry.ProductID,
+			&entry.Price,
+			&salePrice,
+			&saleStartsAt,
+			&saleEndsAt,
+			&entry.Reason,
+			&entry.ActorID,
+			&entry.CreatedAt); err != nil {
+			return nil, err
+		}
+		entry.Sale = productSale(salePrice, saleStartsAt, saleEndsAt)
+		entries = append(entries, entry)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return entries, nil
+}
+
+const scheduledPriceChangeColumns = `
+	id, product_id, price, effective_at, COALESCE(actor_id::text, '') AS actor_id, created_at,
+	COALESCE(applied_at, 0) AS applied_at
+`
+
+func (r *repository) CreateScheduledPriceChange(change *domain.ScheduledPriceChange) (*domain.ScheduledPriceChange, error) {
+	query := `
+		INSERT INTO scheduled_price_changes(product_id, price, effective_at, actor_id)
+		VALUES ($1, $2, $3, NULLIF($4, '')::uuid)
+		RETURNING id, created_at
+	`
+
+	if err := r.pool.QueryRow(context.Background(), query,
+		change.ProductID,
+		change.Price,
+		change.EffectiveAt,
+		change.ActorID).Scan(&change.ID, &change.CreatedAt); err != nil {
+		if isForeignKeyViolation(err) {
+			return nil, domain.ErrProductNotFound
+		}
+		return nil, err
+	}
+
+	return change, nil
+}
+
+// ListScheduledPriceChanges returns the pending and applied price changes of
+// a product in the order they take effect.
+func (r *repository) ListScheduledPriceChanges(productID string) ([]*domain.ScheduledPriceChange, error) {
+	query := `
+		SELECT ` + scheduledPriceChangeColumns + `
+		FROM scheduled_price_changes
+		WHERE product_id = $1
+		ORDER BY effective_at, created_at
+	`
+
+	changes := make([]*domain.ScheduledPriceChange, 0)
+	if err := pgxscan.Select(context.Background(), r.pool, &changes, query, productID); err != nil {
+		return nil, err
+	}
+
+	return changes, nil
+}
+
+// DeleteScheduledPriceChange cancels a price change of the product that has
+// not been applied yet and returns it.
+func (r *repository) DeleteScheduledPriceChange(id, productID string) (*domain.ScheduledPriceChange, error) {
+	query := `
+		DELETE FROM scheduled_price_changes
+		WHERE id = $1 AND product_id = $2 AND applied_at IS NULL
+		RETURNING ` + scheduledPriceChangeColumns
+
+	change := new(domain.ScheduledPriceChange)
+	if err := pgxscan.Get(context.Background(), r.pool, change, query, id, productID); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, domain.ErrPriceChangeNotFound
+		}
+		return nil, err
+	}
+
+	return change, nil
+}
+
+// ListDueScheduledPriceChanges returns the pending price changes whose time
+// has come, oldest first.
+func (r *repository) ListDueScheduledPriceChanges(limit int) ([]*domain.ScheduledPriceChange, error) {
+	query := `
+		SELECT ` + scheduledPriceChangeColumns + `
+		FROM scheduled_price_changes
+		WHERE applied_at IS NULL AND effective_at <= EXTRACT (EPOCH FROM CURRENT_TIMESTAMP)
+		ORDER BY effective_at, created_at
+		LIMIT $1
+	`
+
+	var changes []*domain.ScheduledPriceChange
+	if err := pgxscan.Select(context.Background(), r.pool, &changes, query, limit); err != nil {
+		return nil, err
+	}
+
+	return changes, nil
+}
+
+// ApplyScheduledPriceChange sets the regular price of the product to the
+// price of the change and records it in the price history on behalf of
+// whoever scheduled it. A change that has been applied or cancelled in the
+// meantime is reported as ErrPriceChangeNotFound.
+func (r *repository) ApplyScheduledPriceChange(id string) error {
+	tx, err := r.pool.Begin(context.Background())
+	if err != nil {
+		return err
+	}
+
+	defer tx.Rollback(context.Background())
+
+	var change domain.ScheduledPriceChange
+	query := `
+		SELECT product_id, price, COALESCE(actor_id::text, '')
+		FROM scheduled_price_changes
+		WHERE id = $1 AND applied_at IS NULL
+		FOR UPDATE
+	`
+	if err := tx.QueryRow(context.Background(), query, id).Scan(&change.ProductID, &change.Price, &change.ActorID); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return domain.ErrPriceChangeNotFound
+		}
+		return err
+	}
+
+	query = `UPDATE products SET price = $1, updated_at = EXTRACT (EPOCH FROM CURRENT_TIMESTAMP) WHERE id = $2`
+	if _, err := tx.Exec(context.Background(), query, change.Price, change.ProductID); err != nil {
+		return err
+	}
+
+	if err := recordPrice(tx, change.ProductID, domain.PriceScheduled, change.ActorID); err != nil {
+		return err
+	}
+
+	query = `UPDATE scheduled_price_changes SET applied_at = EXTRACT (EPOCH FROM CURRENT_TIMESTAMP) WHERE id = $1`
+	if _, err := tx.Exec(context.Background(), query, id); err != nil {
+		return err
+	}
+
+	return tx.Commit(context.Background())
+}
@@ -3,8 +3,11 @@ package repository
 import (
 	"context"
 	"ecomm/internal/domain"
+	"errors"
 
 	"github.com/georgysavva/scany/v2/pgxscan"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
 	"github.com/jackc/pgx/v5/pgxpool"
 )
 
@@ -12,131 +15,354 @@ type repository struct {
 	pool *pgxpool.Pool
 }
 
+// execer is satisfied by both the pool and transactions, so statements can be
+// shared between standalone writes and larger transactions.
+type execer interface {
+	Exec(ctx context.Context, sql string, arguments ...any) (pgconn.CommandTag, error)
+}
+
 func NewRepository(pool *pgxpool.Pool) domain.Repository {
 	return &repository{pool: pool}
 }
 
-func (r *repository) CreateProduct(product *domain.Product) (*domain.Product, error) {
+// versionError explains why a write conditional on the version of the row
+// with id in table matched nothing: either the row no longer exists, in which
+// case notFound is returned, or it has moved on to another version.
+func (r *repository) versionError(table, id string, notFound error) error {
+	var exists bool
+	query := `SELECT EXISTS (SELECT 1 FROM ` + table + ` WHERE id = $1)`
+	if err := r.pool.QueryRow(context.Background(), query, id).Scan(&exists); err != nil {
+		return err
+	}
+
+	if !exists {
+		return notFound
+	}
+	return domain.ErrVersionMismatch
+}
+
+// checkRowVersion fails with ErrVersionMismatch unless a row locked at
+// current is at the expected version. An expected version of 0 skips the
+// check.
+func checkRowVersion(expected, current int64) error {
+	if expected != 0 && expected != current {
+		return domain.ErrVersionMismatch
+	}
+	return nil
+}
+
+// CreateProduct creates the product and records its initial price and its
+// initial stock, as a receipt, by actorID.
+func (r *repository) CreateProduct(product *domain.Product, actorID string) (*domain.Product, error) {
+	tx, err := r.pool.Begin(context.Background())
+	if err != nil {
+		return nil, err
+	}
+
+	defer tx.Rollback(context.Background())
+
 	query := `
         INSERT INTO 
-        products(name, image, category, description, rating, num_reviews, price, count_in_stock)
-        VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+        products(name, image, category_id, description, rating, num_reviews, price, count_in_stock, sku, status)
+        VALUES ($1, $2, $3, $4, $5, $6, $7, 0, NULLIF($8, ''), $9)
 		RETURNING id
     `
 
-	err := r.pool.QueryRow(context.Background(), query,
+	err = tx.QueryRow(context.Background(), query,
 		&product.Name,
 		&product.Image,
-		&product.Category,
+		&product.CategoryID,
 		&product.Description,
 		&product.Rating,
 		&product.NumberOfReviews,
 		&product.Price,
-		&product.CountInStock).Scan(&product.ID)
+		&product.SKU,
+		&product.Status).Scan(&product.ID)
 	if err != nil {
+		if isUniqueViolation(err, "unique_product_sku") {
+			return nil, domain.ErrSKUTaken
+		}
+		if isForeignKeyViolation(err) {
+			return nil, domain.ErrCategoryNotFound
+		}
+		return nil, err
+	}
+
+	if err := recordPrice(tx, product.ID, domain.PriceCreated, actorID); err != nil {
+		return nil, err
+	}
+
+	if product.CountInStock != 0 {
+		if err := applyInventoryMovement(tx, &domain.InventoryMovement{
+			ProductID: product.ID,
+			Type:      domain.MovementReceipt,
+			Quantity:  product.CountInStock,
+			Reason:    "initial stock",
+			ActorID:   actorID,
+		}); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := tx.Commit(context.Background()); err != nil {
 		return nil, err
 	}
+
 	return product, nil
 }
 
-func (r *repository) GetProductByID(id string) (*domain.Product, error) {
-	query := `
-		SELECT id, name, image, category, description, rating, num_reviews, price, count_in_stock, created_at, updated_at
-		FROM products WHERE id = $1
-	`
+// productColumns are read by scanProduct.
+const productColumns = `
+	p.id, COALESCE(p.sku, ''), p.name, p.image, p.category_id, c.name, p.description, p.rating,
+	p.num_reviews, p.price, p.sale_price, COALESCE(p.sale_starts_at, 0), COALESCE(p.sale_ends_at, 0),
+	p.count_in_stock, p.reorder_threshold, p.status, COALESCE(p.deleted_at, 0), p.version, p.created_at,
+	p.updated_at
+`
 
+func scanProduct(row pgx.Row) (*domain.Product, error) {
 	product := new(domain.Product)
-	if err := r.pool.QueryRow(context.Background(), query, id).Scan(
+	var salePrice *float64
+	var saleStartsAt, saleEndsAt uint64
+	err := row.Scan(
 		&product.ID,
+		&product.SKU,
 		&product.Name,
 		&product.Image,
+		&product.CategoryID,
 		&product.Category,
 		&product.Description,
 		&product.Rating,
 		&product.NumberOfReviews,
 		&product.Price,
+		&salePrice,
+		&saleStartsAt,
+		&saleEndsAt,
 		&product.CountInStock,
+		&product.ReorderThreshold,
+		&product.Status,
+		&product.DeletedAt,
+		&product.Version,
 		&product.CreatedAt,
-		&product.UpdatedAt); err != nil {
+		&product.UpdatedAt)
+	if err != nil {
 		return nil, err
 	}
+	product.Sale = productSale(salePrice, saleStartsAt, saleEndsAt)
+	return product, nil
+}
+
+func (r *repository) GetProductByID(id string) (*domain.Product, error) {
+	query := `
+		SELECT ` + productColumns + `
+		FROM products p
+		JOIN categories c ON c.id = p.category_id
+		WHERE p.id = $1
+	`
+
+	product, err := scanProduct(r.pool.QueryRow(context.Background(), query, id))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, domain.ErrProductNotFound
+		}
+		return nil, err
+	}
+
+	options, err := r.getProductOptions(product.ID)
+	if err != nil {
+		return nil, err
+	}
+	product.Options = options
+
+	variants, err := r.getProductVariants(product.ID)
+	if err != nil {
+		return nil, err
+	}
+	product.Variants = variants
+
+	images, err := r.getProductImages([]string{product.ID})
+	if err != nil {
+		return nil, err
+	}
+	product.Images = images[product.ID]
 
 	return product, nil
 }
 
-func (r *repository) ListProducts() ([]*domain.Product, error) {
+func (r *repository) ListProducts(filter *domain.ProductFilter) ([]*domain.Product, error) {
 	query := `
-		SELECT id, name, image, category, description, rating, num_reviews, price, count_in_stock, created_at, updated_at
-		FROM products
+		SELECT ` + productColumns + `
+		FROM products p
+		JOIN categories c ON c.id = p.category_id
+		WHERE p.status = ANY($1) AND (p.name ILIKE $2 OR p.sku ILIKE $2)
+		ORDER BY p.created_at DESC, p.id
 	`
 
+	pattern := "%" + likeEscaper.Replace(filter.Query) + "%"
 	products := make([]*domain.Product, 0)
-	rows, err := r.pool.Query(context.Background(), query)
+	rows, err := r.pool.Query(context.Background(), query, filter.Statuses, pattern)
 	if err != nil {
 		return nil, err
 	}
+	defer rows.Close()
 
 	for rows.Next() {
-		product := new(domain.Product)
-		err := rows.Scan(
-			&product.ID,
-			&product.Name,
-			&product.Image,
-			&product.Category,
-			&product.Description,
-			&product.Rating,
-			&product.NumberOfReviews,
-			&product.Price,
-			&product.CountInStock,
-			&product.CreatedAt,
-			&product.UpdatedAt)
+		product, err := scanProduct(rows)
 		if err != nil {
 			return nil, err
 		}
 		products = append(products, product)
 	}
 
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	productIDs := make([]string, len(products))
+	for i, product := range products {
+		productIDs[i] = product.ID
+	}
+
+	images, err := r.getProductImages(productIDs)
+	if err != nil {
+		return nil, err
+	}
+	for _, product := range products {
+		product.Images = images[product.ID]
+	}
+
 	return products, nil
 }
 
-func (r *repository) UpdateProduct(product *domain.Product) error {
-	query := `
+// UpdateProduct saves everything but the stock and the sale. The stock only
+// changes through inventory movements; if stock is not nil, it is applied in
+// the same transaction and product.CountInStock is set to its balance. A new
+// price is recorded in the price history by actorID. It fails with
+// ErrVersionMismatch unless the product is still at product.Version, which is
+// then set to the new version.
+func (r *repository) UpdateProduct(product *domain.Product, stock *domain.InventoryMovement, actorID string) error {
+	tx, err := r.pool.Begin(context.Background())
+	if err != nil {
+		return err
+	}
+
+	defer tx.Rollback(context.Background())
+
+	var price float64
+	query := `SELECT price FROM products WHERE id = $1 AND version = $2 FOR UPDATE`
+	if err := tx.QueryRow(context.Background(), query, product.ID, product.Version).Scan(&price); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return r.versionError("products", product.ID, domain.ErrProductNotFound)
+		}
+		return err
+	}
+
+	query = `
 		UPDATE products
-		SET name = $1, image = $2, category = $3, description = $4,
-		rating = $5, num_reviews = $6, price = $7, count_in_stock = $8
-		WHERE id = $9
+		SET name = $1, image = $2, category_id = $3, description = $4,
+		rating = $5, num_reviews = $6, price = $7, sku = NULLIF($8, ''), status = $9,
+		updated_at = EXTRACT (EPOCH FROM CURRENT_TIMESTAMP)
+		WHERE id = $10
+		RETURNING price, version, updated_at
 	`
 
-	if _, err := r.pool.Exec(context.Background(), query,
+	if err := tx.QueryRow(context.Background(), query,
 		&product.Name,
 		&product.Image,
-		&product.Category,
+		&product.CategoryID,
 		&product.Description,
 		&product.Rating,
 		&product.NumberOfReviews,
 		&product.Price,
-		&product.CountInStock,
-		&product.ID); err != nil {
+		&product.SKU,
+		&product.Status,
+		&product.ID).Scan(&product.Price, &product.Version, &product.UpdatedAt); err != nil {
+		if isUniqueViolation(err, "unique_product_sku") {
+			return domain.ErrSKUTaken
+		}
+		if isForeignKeyViolation(err) {
+			return domain.ErrCategoryNotFound
+		}
 		return err
 	}
 
-	return nil
+	if product.Price != price {
+		if err := recordPrice(tx, product.ID, domain.PriceUpdated, actorID); err != nil {
+			return err
+		}
+	}
+
+	if stock != nil {
+		if err := applyInventoryMovement(tx, stock); err != nil {
+			return err
+		}
+		product.CountInStock = stock.Balance
+	}
+
+	return tx.Commit(context.Background())
 }
 
-func (r *repository) DeleteProduct(id string) error {
-	query := `DELETE FROM products where id = $1`
-	result, err := r.pool.Exec(context.Background(), query, id)
+// ArchiveProduct hides a product from listings and ordering while keeping it
+// for the orders that reference it. Archiving an archived product keeps its
+// original deleted_at. It fails with ErrVersionMismatch unless the product is
+// still at version.
+func (r *repository) ArchiveProduct(id string, version int64) error {
+	query := `
+		UPDATE products
+		SET status = 'archived', deleted_at = COALESCE(deleted_at, EXTRACT (EPOCH FROM CURRENT_TIMESTAMP)),
+		updated_at = EXTRACT (EPOCH FROM CURRENT_TIMESTAMP)
+		WHERE id = $1 AND version = $2
+	`
+	result, err := r.pool.Exec(context.Background(), query, id, version)
 	if err != nil {
 		return err
 	}
 
 	if result.RowsAffected() == 0 {
-		return domain.ErrProductNotFound
+		return r.versionError("products", id, domain.ErrProductNotFound)
 	}
 
 	return nil
 }
 
+// RestoreProduct makes an archived product active again. It fails with
+// ErrProductNotArchived if the product is not archived and, unless version is 0,
+// with ErrVersionMismatch if the product is no longer at that version.
+func (r *repository) RestoreProduct(id string, version int64) error {
+	tx, err := r.pool.Begin(context.Background())
+	if err != nil {
+		return err
+	}
+
+	defer tx.Rollback(context.Background())
+
+	var productStatus string
+	var current int64
+	query := `SELECT status, version FROM products WHERE id = $1 FOR UPDATE`
+	if err := tx.QueryRow(context.Background(), query, id).Scan(&productStatus, &current); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return domain.ErrProductNotFound
+		}
+		return err
+	}
+	if err := checkRowVersion(version, current); err != nil {
+		return err
+	}
+	if productStatus != domain.ProductArchived {
+		return domain.ErrProductNotArchived
+	}
+
+	query = `
+		UPDATE products
+		SET status = 'active', deleted_at = NULL, updated_at = EXTRACT (EPOCH FROM CURRENT_TIMESTAMP)
+		WHERE id = $1
+	`
+	if _, err := tx.Exec(context.Background(), query, id); err != nil {
+		return err
+	}
+
+	return tx.Commit(context.Background())
+}
+
 func (r *repository) CreateOrder(order *domain.Order) (*domain.Order, error) {
 	tx, err := r.pool.Begin(context.Background())
 	if err != nil {
@@ -146,24 +372,28 @@ func (r *repository) CreateOrder(order *domain.Order) (*domain.Order, error) {
 	defer tx.Rollback(context.Background())
 
 	query := `
-		INSERT INTO orders(payment_method, tax_price, shipping_price, total_price, user_id)
-		VALUES ($1, $2, $3, $4, $5)
-		RETURNING id
+		INSERT INTO orders(payment_method, tax_price, shipping_price, total_price, user_id,
+		shipping_latitude, shipping_longitude)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		RETURNING id, version, created_at, updated_at
 	`
 
+	latitude, longitude := locationColumns(order.ShippingLocation)
 	err = tx.QueryRow(context.Background(), query,
 		&order.PaymentMethod,
 		&order.TaxPrice,
 		&order.ShippingPrice,
 		&order.TotalPrice,
-		&order.UserID).Scan(&order.ID)
+		&order.UserID,
+		latitude,
+		longitude).Scan(&order.ID, &order.Version, &order.CreatedAt, &order.UpdatedAt)
 	if err != nil {
 		return nil, err
 	}
 
 	query = `
-		INSERT INTO order_items(order_id, product_id, name, quantity, image, price)
-		VALUES ($1, $2, $3, $4, $5, $6)
+		INSERT INTO order_items(order_id, product_id, variant_id, name, quantity, image, price)
+		VALUES ($1, $2, NULLIF($3, '')::uuid, $4, $5, $6, $7)
 		RETURNING id
 	`
 
@@ -171,6 +401,7 @@ func (r *repository) CreateOrder(order *domain.Order) (*domain.Order, error) {
 		err := tx.QueryRow(context.Background(), query,
 			&order.ID,
 			&orderItem.ProductID,
+			&orderItem.VariantID,
 			&orderItem.Name,
 			&orderItem.Quantity,
 			&orderItem.Image,
@@ -178,6 +409,36 @@ func (r *repository) CreateOrder(order *domain.Order) (*domain.Order, error) {
 		if err != nil {
 			return nil, err
 		}
+
+		if len(orderItem.Allocations) == 0 {
+			// Without an allocation the item ships from the default
+			// warehouse.
+			orderItem.Allocations = []domain.OrderItemAllocation{{Quantity: orderItem.Quantity}}
+		}
+
+		for i, allocation := range orderItem.Allocations {
+			movement := &domain.InventoryMovement{
+				ProductID:   orderItem.ProductID,
+				VariantID:   orderItem.VariantID,
+				WarehouseID: allocation.WarehouseID,
+				Type:        domain.MovementSale,
+				Quantity:    -allocation.Quantity,
+				OrderID:     order.ID,
+				ActorID:     order.UserID,
+			}
+			if err := applyInventoryMovement(tx, movement); err != nil {
+				return nil, err
+			}
+			orderItem.Allocations[i].WarehouseID = movement.WarehouseID
+
+			query := `
+				INSERT INTO order_item_allocations(order_item_id, warehouse_id, quantity)
+				VALUES ($1, $2, $3)
+			`
+			if _, err := tx.Exec(context.Background(), query, orderItem.ID, movement.WarehouseID, allocation.Quantity); err != nil {
+				return nil, err
+			}
+		}
 	}
 
 	if err := tx.Commit(context.Background()); err != nil {
@@ -187,56 +448,95 @@ func (r *repository) CreateOrder(order *domain.Order) (*domain.Order, error) {
 	return order, nil
 }
 
-func (r *repository) GetOrder(userID string) (*domain.Order, error) {
-	query := `
-		SELECT id, payment_method, tax_price, shipping_price, total_price, created_at, updated_at
-		FROM orders WHERE user_id = $1
-	`
+// orderColumns are read by scanOrder.
+const orderColumns = `
+	id, payment_method, tax_price, shipping_price, total_price, COALESCE(user_id::text, ''),
+	shipping_latitude, shipping_longitude, version, created_at, updated_at
+`
 
+func scanOrder(row pgx.Row) (*domain.Order, error) {
 	order := new(domain.Order)
-	if err := r.pool.QueryRow(context.Background(), query, userID).Scan(
+	var latitude, longitude *float64
+	if err := row.Scan(
 		&order.ID,
 		&order.PaymentMethod,
 		&order.TaxPrice,
 		&order.ShippingPrice,
 		&order.TotalPrice,
+		&order.UserID,
+		&latitude,
+		&longitude,
+		&order.Version,
 		&order.CreatedAt,
 		&order.UpdatedAt); err != nil {
 		return nil, err
 	}
 
+	if latitude != nil && longitude != nil {
+		order.ShippingLocation = &domain.Location{Latitude: *latitude, Longitude: *longitude}
+	}
 	return order, nil
 }
 
-func (r *repository) ListOrders() ([]*domain.Order, error) {
-	query := `
-		SELECT id, payment_method, tax_price, shipping_price, total_price, created_at, updated_at
-		FROM orders
-	`
-
-	var orders []*domain.Order
-	if err := pgxscan.Select(context.Background(), r.pool, &orders, query); err != nil {
+// listOrders runs a query selecting orderColumns and loads the items of the
+// orders it returns.
+func (r *repository) listOrders(query string, args ...any) ([]*domain.Order, error) {
+	rows, err := r.pool.Query(context.Background(), query, args...)
+	if err != nil {
 		return nil, err
 	}
+	defer rows.Close()
 
-	for i := range orders {
-		query := `
-			SELECT id, order_id, product_id, name, quantity, image, price
-			FROM order_items WHERE order_id = $1
-		`
+	orders := make([]*domain.Order, 0)
+	for rows.Next() {
+		order, err := scanOrder(rows)
+		if err != nil {
+			return nil, err
+		}
+		orders = append(orders, order)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	rows.Close()
 
-		var orderItems []*domain.OrderItem
-		if err := pgxscan.Select(context.Background(), r.pool, &orderItems, query, orders[i].ID); err != nil {
+	for _, order := range orders {
+		orderItems, err := r.GetOrderItems(order.ID)
+		if err != nil {
 			return nil, err
 		}
 
-		orders[i].OrderItems = orderItems
+		order.OrderItems = make([]*domain.OrderItem, len(orderItems))
+		for i := range orderItems {
+			order.OrderItems[i] = &orderItems[i]
+		}
 	}
 
 	return orders, nil
 }
 
-func (r *repository) DeleteOrder(id string) error {
+func (r *repository) GetOrder(userID string) (*domain.Order, error) {
+	query := `SELECT ` + orderColumns + ` FROM orders WHERE user_id = $1`
+
+	order, err := scanOrder(r.pool.QueryRow(context.Background(), query, userID))
+	if err != nil {
+		return nil, err
+	}
+
+	return order, nil
+}
+
+func (r *repository) ListOrders() ([]*domain.Order, error) {
+	query := `SELECT ` + orderColumns + ` FROM orders`
+
+	return r.listOrders(query)
+}
+
+// DeleteOrder deletes the order and returns its items to stock on behalf of
+// actorID. Unless version is 0, it fails with ErrVersionMismatch if the order
+// is no longer at that version.
+func (r *repository) DeleteOrder(id, actorID string, version int64) error {
 	tx, err := r.pool.Begin(context.Background())
 	if err != nil {
 		return err
@@ -244,11 +544,67 @@ func (r *repository) DeleteOrder(id string) error {
 
 	defer tx.Rollback(context.Background())
 
-	query := `DELETE FROM order_items where order_id = $1`
+	var current int64
+	query := `SELECT version FROM orders WHERE id = $1 FOR UPDATE`
+	if err := tx.QueryRow(context.Background(), query, id).Scan(&current); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return domain.ErrOrderNotFound
+		}
+		return err
+	}
+	if err := checkRowVersion(version, current); err != nil {
+		return err
+	}
+
+	// Items go back to the warehouses they were allocated from. Items
+	// without allocations, or from a warehouse that has since been
+	// deactivated, go back to the default warehouse.
+	query = `
+		SELECT i.product_id, COALESCE(i.variant_id::text, ''), COALESCE(w.id::text, ''),
+		COALESCE(a.quantity, i.quantity)
+		FROM order_items i
+		LEFT JOIN order_item_allocations a ON a.order_item_id = i.id
+		LEFT JOIN warehouses w ON w.id = a.warehouse_id AND w.active
+		WHERE i.order_id = $1
+	`
+	rows, err := tx.Query(context.Background(), query, id)
+	if err != nil {
+		return err
+	}
+
+	var returns []*domain.InventoryMovement
+	for rows.Next() {
+		movement := &domain.InventoryMovement{
+			Type:    domain.MovementReturn,
+			Reason:  "order deleted",
+			OrderID: id,
+			ActorID: actorID,
+		}
+		if err := rows.Scan(&movement.ProductID, &movement.VariantID, &movement.WarehouseID, &movement.Quantity); err != nil {
+			rows.Close()
+			return err
+		}
+		returns = append(returns, movement)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	query = `DELETE FROM order_items where order_id = $1`
 	if _, err := tx.Exec(context.Background(), query, id); err != nil {
 		return err
 	}
 
+	for _, movement := range returns {
+		if movement.Quantity <= 0 {
+			continue
+		}
+		if err := applyInventoryMovement(tx, movement); err != nil {
+			return err
+		}
+	}
+
 	query = `DELETE FROM orders where id = $1`
 	result, err := tx.Exec(context.Background(), query, id)
 	if err != nil {
@@ -268,7 +624,7 @@ func (r *repository) DeleteOrder(id string) error {
 
 func (r *repository) GetOrderItems(orderID string) ([]domain.OrderItem, error) {
 	query := `
-		SELECT id, order_id, product_id, name, quantity, image, price
+		SELECT id, order_id, product_id, COALESCE(variant_id::text, '') AS variant_id, name, quantity, image, price
 		FROM order_items WHERE order_id = $1
 	`
 
@@ -277,6 +633,37 @@ func (r *repository) GetOrderItems(orderID string) ([]domain.OrderItem, error) {
 		return nil, err
 	}
 
+	query = `
+		SELECT a.order_item_id, a.warehouse_id, a.quantity
+		FROM order_item_allocations a
+		JOIN order_items i ON i.id = a.order_item_id
+		WHERE i.order_id = $1
+		ORDER BY a.quantity DESC, a.warehouse_id
+	`
+	rows, err := r.pool.Query(context.Background(), query, orderID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	allocations := make(map[string][]domain.OrderItemAllocation)
+	for rows.Next() {
+		var orderItemID string
+		var allocation domain.OrderItemAllocation
+		if err := rows.Scan(&orderItemID, &allocation.WarehouseID, &allocation.Quantity); err != nil {
+			return nil, err
+		}
+		allocations[orderItemID] = append(allocations[orderItemID], allocation)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	for i := range orderItems {
+		orderItems[i].Allocations = allocations[orderItems[i].ID]
+	}
+
 	return orderItems, nil
 }
 
@@ -284,14 +671,17 @@ func (r *repository) CreateUser(user *domain.User) (*domain.User, error) {
 	query := `
 		INSERT INTO users(name, email, password, is_admin)
 		VALUES ($1, $2, $3, $4)
-		RETURNING id
+		RETURNING id, status, version, created_at, updated_at
 	`
 
 	if err := r.pool.QueryRow(context.Background(), query,
 		&user.Name,
 		&user.Email,
 		&user.Password,
-		&user.IsAdmin).Scan(&user.ID); err != nil {
+		&user.IsAdmin).Scan(&user.ID, &user.Status, &user.Version, &user.CreatedAt, &user.UpdatedAt); err != nil {
+		if isUniqueViolation(err, "unique_email") {
+			return nil, domain.ErrEmailTaken
+		}
 		return nil, err
 	}
 
@@ -300,7 +690,8 @@ func (r *repository) CreateUser(user *domain.User) (*domain.User, error) {
 
 func (r *repository) GetUser(email string) (*domain.User, error) {
 	query := `
-		SELECT id, name, email, password, is_admin, created_at, updated_at
+		SELECT id, name, email, password, is_admin, status, COALESCE(status_reason, ''),
+		COALESCE(suspended_until, 0), version, created_at, updated_at
 		FROM users WHERE email = $1
 	`
 
@@ -311,46 +702,143 @@ func (r *repository) GetUser(email string) (*domain.User, error) {
 		&user.Email,
 		&user.Password,
 		&user.IsAdmin,
+		&user.Status,
+		&user.StatusReason,
+		&user.SuspendedUntil,
+		&user.Version,
 		&user.CreatedAt,
 		&user.UpdatedAt); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, domain.ErrUserNotFound
+		}
 		return nil, err
 	}
 
 	return user, nil
 }
 
-func (r *repository) ListUsers() ([]*domain.User, error) {
+func (r *repository) GetUserByID(id string) (*domain.User, error) {
 	query := `
-		SELECT id, name, email, is_admin, created_at, updated_at
-		FROM users
+		SELECT id, name, email, password, is_admin, status, COALESCE(status_reason, ''),
+		COALESCE(suspended_until, 0), version, created_at, updated_at
+		FROM users WHERE id = $1
 	`
 
-	var users []*domain.User
-	if err := pgxscan.Select(context.Background(), r.pool, &users, query); err != nil {
+	user := new(domain.User)
+	if err := r.pool.QueryRow(context.Background(), query, id).Scan(
+		&user.ID,
+		&user.Name,
+		&user.Email,
+		&user.Password,
+		&user.IsAdmin,
+		&user.Status,
+		&user.StatusReason,
+		&user.SuspendedUntil,
+		&user.Version,
+		&user.CreatedAt,
+		&user.UpdatedAt); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, domain.ErrUserNotFound
+		}
 		return nil, err
 	}
 
-	return users, nil
+	return user, nil
+}
+
+// ListUsers returns a page of users whose name or email contains query,
+// together with the number of matching users.
+func (r *repository) ListUsers(query string, limit, offset int) ([]*domain.User, int64, error) {
+	pattern := "%" + likeEscaper.Replace(query) + "%"
+
+	var total int64
+	countQuery := `SELECT COUNT(*) FROM users WHERE name ILIKE $1 OR email ILIKE $1`
+	if err := r.pool.QueryRow(context.Background(), countQuery, pattern).Scan(&total); err != nil {
+		return nil, 0, err
+	}
+
+	listQuery := `
+		SELECT id, name, email, is_admin, status, COALESCE(status_reason, '') AS status_reason,
+		COALESCE(suspended_until, 0) AS suspended_until, version, created_at, updated_at
+		FROM users WHERE name ILIKE $1 OR email ILIKE $1
+		ORDER BY created_at DESC, id
+		LIMIT $2 OFFSET $3
+	`
+
+	var users []*domain.User
+	if err := pgxscan.Select(context.Background(), r.pool, &users, listQuery, pattern, limit, offset); err != nil {
+		return nil, 0, err
+	}
+
+	return users, total, nil
 }
 
+// UpdateUser fails with ErrVersionMismatch unless the user is still at
+// user.Version, which is then set to the new version.
 func (r *repository) UpdateUser(user *domain.User) error {
 	query := `
-		UPDATE users SET name = $1, email = $2, password = $3, is_admin = $4
-		WHERE id = $5
+		UPDATE users SET name = $1, email = $2, password = $3, is_admin = $4,
+		updated_at = EXTRACT (EPOCH FROM CURRENT_TIMESTAMP)
+		WHERE id = $5 AND version = $6
+		RETURNING version, updated_at
 	`
 
-	if _, err := r.pool.Exec(context.Background(), query,
+	if err := r.pool.QueryRow(context.Background(), query,
 		&user.Name,
 		&user.Email,
 		&user.Password,
 		&user.IsAdmin,
-		&user.ID); err != nil {
+		&user.ID,
+		&user.Version).Scan(&user.Version, &user.UpdatedAt); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return r.versionError("users", user.ID, domain.ErrUserNotFound)
+		}
+		if isUniqueViolation(err, "unique_email") {
+			return domain.ErrEmailTaken
+		}
 		return err
 	}
 
 	return nil
 }
 
+func (r *repository) SetUserAdmin(id string, isAdmin bool) error {
+	query := `
+		UPDATE users SET is_admin = $1, updated_at = EXTRACT (EPOCH FROM CURRENT_TIMESTAMP)
+		WHERE id = $2
+	`
+
+	result, err := r.pool.Exec(context.Background(), query, isAdmin, id)
+	if err != nil {
+		return err
+	}
+
+	if result.RowsAffected() == 0 {
+		return domain.ErrUserNotFound
+	}
+
+	return nil
+}
+
+func (r *repository) SetUserStatus(id, status, reason string, suspendedUntil uint64) error {
+	query := `
+		UPDATE users SET status = $1, status_reason = NULLIF($2, ''), suspended_until = NULLIF($3, 0),
+		updated_at = EXTRACT (EPOCH FROM CURRENT_TIMESTAMP)
+		WHERE id = $4
+	`
+
+	result, err := r.pool.Exec(context.Background(), query, status, reason, suspendedUntil, id)
+	if err != nil {
+		return err
+	}
+
+	if result.RowsAffected() == 0 {
+		return domain.ErrUserNotFound
+	}
+
+	return nil
+}
+
 func (r *repository) DeleteUser(id string) error {
 	query := `DELETE FROM users where id = $1`
 	result, err := r.pool.Exec(context.Background(), query, id)
@@ -428,3 +916,14 @@ func (r *repository) DeleteSession(id string) error {
 
 	return nil
 }
+
+func (r *repository) RevokeUserSessions(email string) error {
+	return revokeUserSessions(r.pool, email)
+}
+
+// revokeUserSessions revokes every session of the user with the given email.
+func revokeUserSessions(db execer, email string) error {
+	query := `UPDATE sessions SET is_revoked = $1 WHERE email = $2`
+	_, err := db.Exec(context.Background(), query, true, email)
+	return err
+}
@@ -1,6 +1,8 @@
 package repository
 
 import (
+	"ecomm/internal/domain"
+	"errors"
 	"testing"
 )
 
@@ -89,3 +91,21 @@ func TestCreateProduct_Error(t *testing.T) {
 			})
 	*/
 }
+
+func TestCheckRowVersion(t *testing.T) {
+	tests := []struct {
+		expected, current int64
+		err               error
+	}{
+		{0, 3, nil},
+		{3, 3, nil},
+		{2, 3, domain.ErrVersionMismatch},
+		{4, 3, domain.ErrVersionMismatch},
+	}
+
+	for _, tt := range tests {
+		if err := checkRowVersion(tt.expected, tt.current); !errors.Is(err, tt.err) {
+			t.Errorf("checkRowVersion(%d, %d) error = %v; want %v", tt.expected, tt.current, err, tt.err)
+		}
+	}
+}
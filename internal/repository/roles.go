@@ -0,0 +1,105 @@
+package repository
+
+import (
+	"context"
+	"ecomm/internal/domain"
+	"errors"
+
+	"github.com/georgysavva/scany/v2/pgxscan"
+	"github.com/jackc/pgx/v5"
+)
+
+func (r *repository) ListRoles() ([]*domain.Role, error) {
+	query := `
+		SELECT r.id, r.name, r.description, r.created_at,
+		COALESCE(array_agg(rp.permission ORDER BY rp.permission) FILTER (WHERE rp.permission IS NOT NULL), '{}') AS permissions
+		FROM roles r
+		LEFT JOIN role_permissions rp ON rp.role_id = r.id
+		GROUP BY r.id
+		ORDER BY r.name
+	`
+
+	var roles []*domain.Role
+	if err := pgxscan.Select(context.Background(), r.pool, &roles, query); err != nil {
+		return nil, err
+	}
+
+	return roles, nil
+}
+
+func (r *repository) GetUserRoles(userID string) ([]*domain.Role, error) {
+	query := `
+		SELECT r.id, r.name, r.description, r.created_at,
+		COALESCE(array_agg(rp.permission ORDER BY rp.permission) FILTER (WHERE rp.permission IS NOT NULL), '{}') AS permissions
+		FROM user_roles ur
+		JOIN roles r ON r.id = ur.role_id
+		LEFT JOIN role_permissions rp ON rp.role_id = r.id
+		WHERE ur.user_id = $1
+		GROUP BY r.id
+		ORDER BY r.name
+	`
+
+	var roles []*domain.Role
+	if err := pgxscan.Select(context.Background(), r.pool, &roles, query, userID); err != nil {
+		return nil, err
+	}
+
+	return roles, nil
+}
+
+func (r *repository) GetUserPermissions(userID string) ([]string, error) {
+	query := `
+		SELECT DISTINCT rp.permission
+		FROM user_roles ur
+		JOIN role_permissions rp ON rp.role_id = ur.role_id
+		WHERE ur.user_id = $1
+		ORDER BY rp.permission
+	`
+
+	var permissions []string
+	if err := pgxscan.Select(context.Background(), r.pool, &permissions, query, userID); err != nil {
+		return nil, err
+	}
+
+	return permissions, nil
+}
+
+func (r *repository) AssignUserRole(userID, roleName string) error {
+	var roleID string
+	query := `SELECT id FROM roles WHERE name = $1`
+	if err := r.pool.QueryRow(context.Background(), query, roleName).Scan(&roleID); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return domain.ErrRoleNotFound
+		}
+		return err
+	}
+
+	query = `
+		INSERT INTO user_roles(user_id, role_id)
+		VALUES ($1, $2)
+		ON CONFLICT DO NOTHING
+	`
+	if _, err := r.pool.Exec(context.Background(), query, userID, roleID); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (r *repository) RemoveUserRole(userID, roleName string) error {
+	query := `
+		DELETE FROM user_roles
+		WHERE user_id = $1 AND role_id = (SELECT id FROM roles WHERE name = $2)
+	`
+
+	result, err := r.pool.Exec(context.Background(), query, userID, roleName)
+	if err != nil {
+		return err
+	}
+
+	if result.RowsAffected() == 0 {
+		return domain.ErrRoleNotFound
+	}
+
+	return nil
+}
@@ -0,0 +1,238 @@
+package repository
+
+import (
+	"context"
+	"ecomm/internal/domain"
+	"errors"
+	"time"
+
+	"github.com/georgysavva/scany/v2/pgxscan"
+	"github.com/jackc/pgx/v5"
+)
+
+// updateStockAlert opens a low-stock alert for a product or variant whose
+// stock is at or below the reorder threshold, unless one is already open, and
+// resolves the open alert once the stock is above the threshold again. The
+// caller holds the lock on the product or variant row.
+func updateStockAlert(tx pgx.Tx, productID, variantID string, stock, threshold int) error {
+	if stock <= threshold {
+		query := `
+			INSERT INTO stock_alerts(product_id, variant_id, threshold, stock)
+			VALUES ($1, NULLIF($2, '')::uuid, $3, $4)
+			ON CONFLICT DO NOTHING
+		`
+		_, err := tx.Exec(context.Background(), query, productID, variantID, threshold, stock)
+		return err
+	}
+
+	query := `
+		UPDATE stock_alerts SET resolved_at = EXTRACT (EPOCH FROM CURRENT_TIMESTAMP)
+		WHERE product_id = $1 AND variant_id IS NOT DISTINCT FROM NULLIF($2, '')::uuid AND resolved_at IS NULL
+	`
+	_, err := tx.Exec(context.Background(), query, productID, variantID)
+	return err
+}
+
+// SetReorderThreshold changes the reorder threshold of a product and
+// re-evaluates the alerts of the product, or of its variants if it has any,
+// against it.
+func (r *repository) SetReorderThreshold(productID string, threshold int) error {
+	tx, err := r.pool.Begin(context.Background())
+	if err != nil {
+		return err
+	}
+
+	defer tx.Rollback(context.Background())
+
+	var stock int
+	query := `
+		UPDATE products SET reorder_threshold = $1, updated_at = EXTRACT (EPOCH FROM CURRENT_TIMESTAMP)
+		WHERE id = $2
+		RETURNING count_in_stock
+	`
+	if err := tx.QueryRow(context.Background(), query, threshold, productID).Scan(&stock); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return domain.ErrProductNotFound
+		}
+		return err
+	}
+
+	var variants []struct {
+		ID           string
+		CountInStock int
+	}
+	query = `SELECT id, count_in_stock FROM product_variants WHERE product_id = $1 FOR UPDATE`
+	if err := pgxscan.Select(context.Background(), tx, &variants, query, productID); err != nil {
+		return err
+	}
+
+	if len(variants) == 0 {
+		if err := updateStockAlert(tx, productID, "", stock, threshold); err != nil {
+			return err
+		}
+	}
+	for _, variant := range variants {
+		if err := updateStockAlert(tx, productID, variant.ID, variant.CountInStock, threshold); err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit(context.Background())
+}
+
+const stockAlertColumns = `
+	a.id, a.product_id, p.name AS product_name, COALESCE(a.variant_id::text, '') AS variant_id, a.threshold,
+	a.stock, a.created_at, COALESCE(a.notified_at, 0) AS notified_at, COALESCE(a.resolved_at, 0) AS resolved_at
+`
+
+func (r *repository) ListStockAlerts(filter *domain.StockAlertFilter) ([]*domain.StockAlert, error) {
+	query := `
+		SELECT ` + stockAlertColumns + `
+		FROM stock_alerts a
+		JOIN products p ON p.id = a.product_id
+		WHERE NOT $1 OR a.resolved_at IS NULL
+		ORDER BY a.created_at DESC, a.id
+		LIMIT $2 OFFSET $3
+	`
+
+	alerts := make([]*domain.StockAlert, 0)
+	if err := pgxscan.Select(context.Background(), r.pool, &alerts, query, filter.OpenOnly, filter.Limit, filter.Offset); err != nil {
+		return nil, err
+	}
+
+	return alerts, nil
+}
+
+// ClaimStockAlerts claims the oldest open alerts the admins have not been
+// notified of yet. A claimed alert is not returned again until claimTimeout
+// has passed, so that workers running side by side do not send it twice.
+func (r *repository) ClaimStockAlerts(limit int, claimTimeout time.Duration) ([]*domain.StockAlert, error) {
+	query := `
+		WITH claimed AS (
+			UPDATE stock_alerts SET claimed_at = EXTRACT (EPOCH FROM CURRENT_TIMESTAMP)
+			WHERE id IN (
+				SELECT a.id
+				FROM stock_alerts a
+				JOIN products p ON p.id = a.product_id
+				WHERE a.notified_at IS NULL AND a.resolved_at IS NULL AND p.status <> 'archived'
+				AND (a.claimed_at IS NULL OR a.claimed_at <= EXTRACT (EPOCH FROM CURRENT_TIMESTAMP) - $2)
+				ORDER BY a.created_at, a.id
+				LIMIT $1
+				FOR UPDATE OF a SKIP LOCKED
+			)
+			RETURNING *
+		)
+		SELECT ` + stockAlertColumns + `
+		FROM claimed a
+		JOIN products p ON p.id = a.product_id
+		ORDER BY a.created_at, a.id
+	`
+
+	var alerts []*domain.StockAlert
+	if err := pgxscan.Select(context.Background(), r.pool, &alerts, query, limit, int64(claimTimeout.Seconds())); err != nil {
+		return nil, err
+	}
+
+	return alerts, nil
+}
+
+func (r *repository) MarkStockAlertNotified(id string) error {
+	query := `UPDATE stock_alerts SET notified_at = EXTRACT (EPOCH FROM CURRENT_TIMESTAMP) WHERE id = $1`
+	_, err := r.pool.Exec(context.Background(), query, id)
+	return err
+}
+
+func (r *repository) CreateStockSubscription(subscription *domain.StockSubscription) (*domain.StockSubscription, error) {
+	query := `
+		INSERT INTO stock_subscriptions(user_id, product_id, variant_id)
+		VALUES ($1, $2, NULLIF($3, '')::uuid)
+		RETURNING id, created_at
+	`
+
+	err := r.pool.QueryRow(context.Background(), query,
+		subscription.UserID,
+		subscription.ProductID,
+		subscription.VariantID).Scan(&subscription.ID, &subscription.CreatedAt)
+	if err != nil {
+		if isUniqueViolation(err, "stock_subscriptions_pending_idx") {
+			return nil, domain.ErrAlreadySubscribed
+		}
+		return nil, err
+	}
+
+	return subscription, nil
+}
+
+func (r *repository) DeleteStockSubscription(id, userID string) error {
+	query := `DELETE FROM stock_subscriptions WHERE id = $1 AND user_id = $2`
+
+	tag, err := r.pool.Exec(context.Background(), query, id, userID)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return domain.ErrSubscriptionNotFound
+	}
+
+	return nil
+}
+
+func (r *repository) ListUserStockSubscriptions(userID string) ([]*domain.StockSubscription, error) {
+	query := `
+		SELECT id, user_id, product_id, COALESCE(variant_id::text, '') AS variant_id, created_at,
+		COALESCE(notified_at, 0) AS notified_at
+		FROM stock_subscriptions
+		WHERE user_id = $1
+		ORDER BY created_at DESC
+	`
+
+	subscriptions := make([]*domain.StockSubscription, 0)
+	if err := pgxscan.Select(context.Background(), r.pool, &subscriptions, query, userID); err != nil {
+		return nil, err
+	}
+
+	return subscriptions, nil
+}
+
+// ClaimReplenishedStockSubscriptions claims the oldest pending subscriptions
+// whose product or variant is back in stock and returns them with the
+// subscriber's email and the product's name. Claims expire like those of
+// ClaimStockAlerts.
+func (r *repository) ClaimReplenishedStockSubscriptions(limit int, claimTimeout time.Duration) ([]*domain.StockSubscription, error) {
+	query := `
+		WITH claimed AS (
+			UPDATE stock_subscriptions SET claimed_at = EXTRACT (EPOCH FROM CURRENT_TIMESTAMP)
+			WHERE id IN (
+				SELECT s.id
+				FROM stock_subscriptions s
+				JOIN products p ON p.id = s.product_id
+				LEFT JOIN product_variants v ON v.id = s.variant_id
+				WHERE s.notified_at IS NULL AND p.status = 'active' AND COALESCE(v.count_in_stock, p.count_in_stock) > 0
+				AND (s.claimed_at IS NULL OR s.claimed_at <= EXTRACT (EPOCH FROM CURRENT_TIMESTAMP) - $2)
+				ORDER BY s.created_at, s.id
+				LIMIT $1
+				FOR UPDATE OF s SKIP LOCKED
+			)
+			RETURNING *
+		)
+		SELECT s.id, s.user_id, s.product_id, COALESCE(s.variant_id::text, '') AS variant_id, s.created_at,
+		0 AS notified_at, u.email, p.name AS product_name
+		FROM claimed s
+		JOIN users u ON u.id = s.user_id
+		JOIN products p ON p.id = s.product_id
+		ORDER BY s.created_at, s.id
+	`
+
+	var subscriptions []*domain.StockSubscription
+	if err := pgxscan.Select(context.Background(), r.pool, &subscriptions, query, limit, int64(claimTimeout.Seconds())); err != nil {
+		return nil, err
+	}
+
+	return subscriptions, nil
+}
+
+func (r *repository) MarkStockSubscriptionNotified(id string) error {
+	query := `UPDATE stock_subscriptions SET notified_at = EXTRACT (EPOCH FROM CURRENT_TIMESTAMP) WHERE id = $1`
+	_, err := r.pool.Exec(context.Background(), query, id)
+	return err
+}
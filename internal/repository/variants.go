@@ -0,0 +1,199 @@
+package repository
+
+import (
+	"context"
+	"ecomm/internal/domain"
+	"errors"
+
+	"github.com/georgysavva/scany/v2/pgxscan"
+	"github.com/jackc/pgx/v5"
+)
+
+const variantColumns = `
+	id, product_id, sku, options, price, count_in_stock, COALESCE(image, '') AS image, created_at, updated_at
+`
+
+func variantError(err error) error {
+	switch {
+	case isUniqueViolation(err, "unique_variant_sku"):
+		return domain.ErrSKUTaken
+	case isUniqueViolation(err, "unique_variant_options"):
+		return domain.ErrVariantExists
+	case isForeignKeyViolation(err):
+		return domain.ErrProductNotFound
+	default:
+		return err
+	}
+}
+
+func (r *repository) getProductOptions(productID string) ([]domain.ProductOption, error) {
+	query := `
+		SELECT name, option_values AS values FROM product_options
+		WHERE product_id = $1
+		ORDER BY position
+	`
+
+	var options []domain.ProductOption
+	if err := pgxscan.Select(context.Background(), r.pool, &options, query, productID); err != nil {
+		return nil, err
+	}
+
+	return options, nil
+}
+
+func (r *repository) getProductVariants(productID string) ([]*domain.ProductVariant, error) {
+	query := `SELECT ` + variantColumns + ` FROM product_variants WHERE product_id = $1 ORDER BY created_at, sku`
+
+	var variants []*domain.ProductVariant
+	if err := pgxscan.Select(context.Background(), r.pool, &variants, query, productID); err != nil {
+		return nil, err
+	}
+
+	return variants, nil
+}
+
+// SetProductOptions replaces the option types of a product.
+func (r *repository) SetProductOptions(productID string, options []domain.ProductOption) error {
+	tx, err := r.pool.Begin(context.Background())
+	if err != nil {
+		return err
+	}
+
+	defer tx.Rollback(context.Background())
+
+	query := `DELETE FROM product_options WHERE product_id = $1`
+	if _, err := tx.Exec(context.Background(), query, productID); err != nil {
+		return err
+	}
+
+	query = `
+		INSERT INTO product_options(product_id, name, option_values, position)
+		VALUES ($1, $2, $3, $4)
+	`
+	for i, option := range options {
+		if _, err := tx.Exec(context.Background(), query, productID, option.Name, option.Values, i); err != nil {
+			if isForeignKeyViolation(err) {
+				return domain.ErrProductNotFound
+			}
+			return err
+		}
+	}
+
+	return tx.Commit(context.Background())
+}
+
+// CreateProductVariant creates the variant and records its initial stock as
+// a receipt by actorID.
+func (r *repository) CreateProductVariant(variant *domain.ProductVariant, actorID string) (*domain.ProductVariant, error) {
+	tx, err := r.pool.Begin(context.Background())
+	if err != nil {
+		return nil, err
+	}
+
+	defer tx.Rollback(context.Background())
+
+	query := `
+		INSERT INTO product_variants(product_id, sku, options, price, count_in_stock, image)
+		VALUES ($1, $2, $3, $4, 0, NULLIF($5, ''))
+		RETURNING id, created_at, updated_at
+	`
+
+	err = tx.QueryRow(context.Background(), query,
+		variant.ProductID,
+		variant.SKU,
+		variant.Options,
+		variant.Price,
+		variant.Image).Scan(&variant.ID, &variant.CreatedAt, &variant.UpdatedAt)
+	if err != nil {
+		return nil, variantError(err)
+	}
+
+	if variant.CountInStock != 0 {
+		if err := applyInventoryMovement(tx, &domain.InventoryMovement{
+			ProductID: variant.ProductID,
+			VariantID: variant.ID,
+			Type:      domain.MovementReceipt,
+			Quantity:  variant.CountInStock,
+			Reason:    "initial stock",
+			ActorID:   actorID,
+		}); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := tx.Commit(context.Background()); err != nil {
+		return nil, err
+	}
+
+	return variant, nil
+}
+
+func (r *repository) GetProductVariant(id string) (*domain.ProductVariant, error) {
+	query := `SELECT ` + variantColumns + ` FROM product_variants WHERE id = $1`
+
+	variant := new(domain.ProductVariant)
+	if err := pgxscan.Get(context.Background(), r.pool, variant, query, id); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, domain.ErrVariantNotFound
+		}
+		return nil, err
+	}
+
+	return variant, nil
+}
+
+// UpdateProductVariant saves everything but the stock, which only changes
+// through inventory movements. If stock is not nil, it is applied in the same
+// transaction and variant.CountInStock is set to its balance.
+func (r *repository) UpdateProductVariant(variant *domain.ProductVariant, stock *domain.InventoryMovement) error {
+	tx, err := r.pool.Begin(context.Background())
+	if err != nil {
+		return err
+	}
+
+	defer tx.Rollback(context.Background())
+
+	query := `
+		UPDATE product_variants
+		SET sku = $1, options = $2, price = $3, image = NULLIF($4, ''),
+		updated_at = EXTRACT (EPOCH FROM CURRENT_TIMESTAMP)
+		WHERE id = $5
+		RETURNING updated_at
+	`
+
+	err = tx.QueryRow(context.Background(), query,
+		variant.SKU,
+		variant.Options,
+		variant.Price,
+		variant.Image,
+		variant.ID).Scan(&variant.UpdatedAt)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return domain.ErrVariantNotFound
+		}
+		return variantError(err)
+	}
+
+	if stock != nil {
+		if err := applyInventoryMovement(tx, stock); err != nil {
+			return err
+		}
+		variant.CountInStock = stock.Balance
+	}
+
+	return tx.Commit(context.Background())
+}
+
+func (r *repository) DeleteProductVariant(id string) error {
+	query := `DELETE FROM product_variants WHERE id = $1`
+	result, err := r.pool.Exec(context.Background(), query, id)
+	if err != nil {
+		return err
+	}
+
+	if result.RowsAffected() == 0 {
+		return domain.ErrVariantNotFound
+	}
+
+	return nil
+}
@@ -0,0 +1,209 @@
+package repository
+
+import (
+	"context"
+	"ecomm/internal/domain"
+	"errors"
+
+	"github.com/georgysavva/scany/v2/pgxscan"
+	"github.com/jackc/pgx/v5"
+)
+
+const warehouseColumns = `id, code, name, latitude, longitude, priority, active, created_at, updated_at`
+
+func scanWarehouse(row pgx.Row) (*domain.Warehouse, error) {
+	warehouse := new(domain.Warehouse)
+	var latitude, longitude *float64
+	if err := row.Scan(
+		&warehouse.ID,
+		&warehouse.Code,
+		&warehouse.Name,
+		&latitude,
+		&longitude,
+		&warehouse.Priority,
+		&warehouse.Active,
+		&warehouse.CreatedAt,
+		&warehouse.UpdatedAt); err != nil {
+		return nil, err
+	}
+
+	if latitude != nil && longitude != nil {
+		warehouse.Location = &domain.Location{Latitude: *latitude, Longitude: *longitude}
+	}
+	return warehouse, nil
+}
+
+// locationColumns splits a location into the nullable latitude and longitude
+// columns.
+func locationColumns(location *domain.Location) (latitude, longitude *float64) {
+	if location == nil {
+		return nil, nil
+	}
+	return &location.Latitude, &location.Longitude
+}
+
+func (r *repository) CreateWarehouse(warehouse *domain.Warehouse) (*domain.Warehouse, error) {
+	query := `
+		INSERT INTO warehouses(code, name, latitude, longitude, priority, active)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		RETURNING id, created_at, updated_at
+	`
+
+	latitude, longitude := locationColumns(warehouse.Location)
+	err := r.pool.QueryRow(context.Background(), query,
+		warehouse.Code,
+		warehouse.Name,
+		latitude,
+		longitude,
+		warehouse.Priority,
+		warehouse.Active).Scan(&warehouse.ID, &warehouse.CreatedAt, &warehouse.UpdatedAt)
+	if err != nil {
+		if isUniqueViolation(err, "unique_warehouse_code") {
+			return nil, domain.ErrWarehouseCodeTaken
+		}
+		return nil, err
+	}
+
+	return warehouse, nil
+}
+
+func (r *repository) GetWarehouse(id string) (*domain.Warehouse, error) {
+	query := `SELECT ` + warehouseColumns + ` FROM warehouses WHERE id = $1`
+
+	warehouse, err := scanWarehouse(r.pool.QueryRow(context.Background(), query, id))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, domain.ErrWarehouseNotFound
+		}
+		return nil, err
+	}
+
+	return warehouse, nil
+}
+
+func (r *repository) ListWarehouses() ([]*domain.Warehouse, error) {
+	query := `SELECT ` + warehouseColumns + ` FROM warehouses ORDER BY priority, created_at`
+
+	rows, err := r.pool.Query(context.Background(), query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	warehouses := make([]*domain.Warehouse, 0)
+	for rows.Next() {
+		warehouse, err := scanWarehouse(rows)
+		if err != nil {
+			return nil, err
+		}
+		warehouses = append(warehouses, warehouse)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return warehouses, nil
+}
+
+// UpdateWarehouse saves a warehouse. A warehouse can only be deactivated
+// while it holds no stock and another warehouse is active, since all stock
+// must be available to orders and movements need a default warehouse.
+func (r *repository) UpdateWarehouse(warehouse *domain.Warehouse) error {
+	tx, err := r.pool.Begin(context.Background())
+	if err != nil {
+		return err
+	}
+
+	defer tx.Rollback(context.Background())
+
+	if !warehouse.Active {
+		if err := checkDeactivation(tx, warehouse.ID); err != nil {
+			return err
+		}
+	}
+
+	query := `
+		UPDATE warehouses
+		SET name = $1, latitude = $2, longitude = $3, priority = $4, active = $5,
+		updated_at = EXTRACT (EPOCH FROM CURRENT_TIMESTAMP)
+		WHERE id = $6
+		RETURNING updated_at
+	`
+
+	latitude, longitude := locationColumns(warehouse.Location)
+	err = tx.QueryRow(context.Background(), query,
+		warehouse.Name,
+		latitude,
+		longitude,
+		warehouse.Priority,
+		warehouse.Active,
+		warehouse.ID).Scan(&warehouse.UpdatedAt)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return domain.ErrWarehouseNotFound
+		}
+		return err
+	}
+
+	return tx.Commit(context.Background())
+}
+
+// checkDeactivation fails unless the warehouse may be set inactive. It locks
+// the warehouses against concurrent deactivations, and the warehouse row
+// against movements that add stock to it.
+func checkDeactivation(tx pgx.Tx, id string) error {
+	query := `LOCK TABLE warehouses IN SHARE ROW EXCLUSIVE MODE`
+	if _, err := tx.Exec(context.Background(), query); err != nil {
+		return err
+	}
+
+	var active bool
+	query = `SELECT active FROM warehouses WHERE id = $1 FOR UPDATE`
+	if err := tx.QueryRow(context.Background(), query, id).Scan(&active); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return domain.ErrWarehouseNotFound
+		}
+		return err
+	}
+	if !active {
+		return nil
+	}
+
+	var others int
+	query = `SELECT COUNT(*) FROM warehouses WHERE active AND id <> $1`
+	if err := tx.QueryRow(context.Background(), query, id).Scan(&others); err != nil {
+		return err
+	}
+	if others == 0 {
+		return domain.ErrLastActiveWarehouse
+	}
+
+	var stocked bool
+	query = `SELECT EXISTS (SELECT 1 FROM warehouse_stock WHERE warehouse_id = $1 AND quantity <> 0)`
+	if err := tx.QueryRow(context.Background(), query, id).Scan(&stocked); err != nil {
+		return err
+	}
+	if stocked {
+		return domain.ErrWarehouseNotEmpty
+	}
+	return nil
+}
+
+// ListWarehouseStock returns the stock of a product and its variants in every
+// warehouse that has held any of it.
+func (r *repository) ListWarehouseStock(productID string) ([]*domain.WarehouseStock, error) {
+	query := `
+		SELECT warehouse_id, product_id, COALESCE(variant_id::text, '') AS variant_id, quantity
+		FROM warehouse_stock
+		WHERE product_id = $1
+		ORDER BY variant_id NULLS FIRST, warehouse_id
+	`
+
+	stock := make([]*domain.WarehouseStock, 0)
+	if err := pgxscan.Select(context.Background(), r.pool, &stock, query, productID); err != nil {
+		return nil, err
+	}
+
+	return stock, nil
+}
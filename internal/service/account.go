@@ -0,0 +1,184 @@
+package service
+
+import (
+	"context"
+	"ecomm/internal/audit"
+	"ecomm/internal/controller/auth"
+	"ecomm/internal/domain"
+	"ecomm/pkg"
+	"ecomm/proto"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const emailChangeTokenTTL = 24 * time.Hour
+
+const emailTakenBody = "Someone asked to move another account to this email address, but it is already in use. If this was you, sign in to your existing account instead. Otherwise, you can ignore this email."
+
+// authorizeSelf only lets callers act on their own account.
+func authorizeSelf(ctx context.Context, userID string) error {
+	claims := auth.ClaimsFromContext(ctx)
+	if claims == nil || claims.ID == "" {
+		return status.Error(codes.Unauthenticated, "missing credentials")
+	}
+	if claims.ID != userID {
+		return status.Error(codes.PermissionDenied, "can only be done for your own account")
+	}
+	return nil
+}
+
+// reauthenticate checks the current password of a signed-in user. Failures
+// count towards the same lockout as failed logins.
+func (s *service) reauthenticate(user *domain.User, password string) error {
+	throttles := []loginThrottle{{key: accountThrottleKey(user.Email), policy: accountThrottlePolicy}}
+	if err := s.checkLoginThrottles(throttles); err != nil {
+		return err
+	}
+
+	if !s.verifyPassword(user, password) {
+		s.recordLoginFailure(throttles)
+		return status.Error(codes.PermissionDenied, "current password is incorrect")
+	}
+
+	return nil
+}
+
+// ChangePassword replaces the caller's password and signs out every other
+// session.
+func (s *service) ChangePassword(ctx context.Context, req *proto.ChangePasswordRequest) (*proto.ChangePasswordResponse, error) {
+	if err := authorizeSelf(ctx, req.UserId); err != nil {
+		return nil, err
+	}
+
+	user, err := s.getUserByID(req.UserId)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.reauthenticate(user, req.CurrentPassword); err != nil {
+		return nil, err
+	}
+
+	if err := s.checkPasswordStrength(req.NewPassword, user.Email, user.Name); err != nil {
+		return nil, err
+	}
+
+	hashed, err := s.hashPassword(req.NewPassword)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.repo.ChangePassword(user.ID, hashed, req.SessionId); err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to change password: %v", err)
+	}
+
+	audit.Record(ctx, "user", user.ID, nil, nil)
+	return &proto.ChangePasswordResponse{}, nil
+}
+
+// RequestEmailChange sends a confirmation link to the new address. The email
+// is only changed once the link is used, which proves the caller controls the
+// new address. If the new address already belongs to an account, it is told
+// so instead, and the response is the same so that it does not reveal which
+// addresses are registered.
+func (s *service) RequestEmailChange(ctx context.Context, req *proto.RequestEmailChangeRequest) (*proto.RequestEmailChangeResponse, error) {
+	if err := authorizeSelf(ctx, req.UserId); err != nil {
+		return nil, err
+	}
+
+	newEmail := strings.TrimSpace(req.NewEmail)
+	if newEmail == "" {
+		return nil, status.Error(codes.InvalidArgument, "new email is required")
+	}
+
+	user, err := s.getUserByID(req.UserId)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.reauthenticate(user, req.CurrentPassword); err != nil {
+		return nil, err
+	}
+
+	if strings.EqualFold(newEmail, user.Email) {
+		return nil, status.Error(codes.InvalidArgument, "new email is the same as the current one")
+	}
+
+	if _, err := s.repo.GetUser(newEmail); err == nil {
+		s.sendMail(newEmail, "Confirm your new email address", emailTakenBody)
+		return &proto.RequestEmailChangeResponse{}, nil
+	} else if !errors.Is(err, domain.ErrUserNotFound) {
+		return nil, status.Errorf(codes.Internal, "failed to get user: %v", err)
+	}
+
+	token, tokenHash, err := generateToken()
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to generate token: %v", err)
+	}
+
+	if err := s.repo.CreateEmailChangeToken(&domain.EmailChangeToken{
+		UserID:    user.ID,
+		NewEmail:  newEmail,
+		TokenHash: tokenHash,
+		ExpiresAt: uint64(time.Now().Add(emailChangeTokenTTL).Unix()),
+	}); err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to store token: %v", err)
+	}
+
+	s.sendMail(newEmail, "Confirm your new email address", emailChangeBody(token))
+
+	return &proto.RequestEmailChangeResponse{}, nil
+}
+
+// ConfirmEmailChange applies a requested email change and signs the user out
+// everywhere. The old address is told about the change so that an account
+// takeover does not go unnoticed.
+func (s *service) ConfirmEmailChange(ctx context.Context, req *proto.ConfirmEmailChangeRequest) (*proto.ConfirmEmailChangeResponse, error) {
+	token, err := s.repo.GetEmailChangeToken(hashToken(req.Token))
+	if err != nil {
+		if errors.Is(err, domain.ErrEmailChangeTokenNotFound) {
+			return nil, status.Error(codes.InvalidArgument, "invalid or expired token")
+		}
+		return nil, status.Errorf(codes.Internal, "failed to get token: %v", err)
+	}
+
+	if token.UsedAt != 0 || token.ExpiresAt <= uint64(time.Now().Unix()) {
+		return nil, status.Error(codes.InvalidArgument, "invalid or expired token")
+	}
+
+	user, err := s.getUserByID(token.UserID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.repo.ChangeEmail(token.ID, user.ID, token.NewEmail); err != nil {
+		switch {
+		case errors.Is(err, domain.ErrEmailChangeTokenNotFound):
+			return nil, status.Error(codes.InvalidArgument, "invalid or expired token")
+		case errors.Is(err, domain.ErrEmailTaken):
+			return nil, status.Error(codes.AlreadyExists, err.Error())
+		}
+		return nil, status.Errorf(codes.Internal, "failed to change email: %v", err)
+	}
+
+	body := fmt.Sprintf("The email address of your account was changed to %s. If you did not make this change, contact support immediately.", token.NewEmail)
+	if err := s.mailer.Send(user.Email, "Your email address was changed", body); err != nil {
+		pkg.ErrorLogger.Printf("failed to send email change notice: %v", err)
+	}
+
+	audit.Record(ctx, "user", user.ID, map[string]any{"email": user.Email}, map[string]any{"email": token.NewEmail})
+	return &proto.ConfirmEmailChangeResponse{}, nil
+}
+
+func emailChangeBody(token string) string {
+	if confirmURL := os.Getenv("EMAIL_CHANGE_URL"); confirmURL != "" {
+		return fmt.Sprintf("Follow this link to confirm your new email address:\n\n%s?token=%s\n\nThe link expires in 24 hours. If you did not request this change, you can ignore this email.", confirmURL, token)
+	}
+	return fmt.Sprintf("Use this token to confirm your new email address:\n\n%s\n\nThe token expires in 24 hours. If you did not request this change, you can ignore this email.", token)
+}
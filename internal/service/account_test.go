@@ -0,0 +1,213 @@
+package service
+
+import (
+	"context"
+	"ecomm/internal/controller/auth"
+	"ecomm/internal/domain"
+	"ecomm/internal/password"
+	"ecomm/proto"
+	"strings"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func newAccountService(t *testing.T, users ...*domain.User) (*service, *fakeRepo, *fakeMailer) {
+	t.Helper()
+	t.Setenv("EMAIL_CHANGE_URL", "")
+
+	hasher := password.NewHasher(&password.Bcrypt{Cost: 4})
+	hashed, err := hasher.Hash("current password")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, user := range users {
+		user.Password = hashed
+	}
+
+	repo := newFakeRepo(users...)
+	mailer := newFakeMailer()
+	return &service{repo: repo, mailer: mailer, hasher: hasher, passwordChecker: password.NewChecker(true)}, repo, mailer
+}
+
+func receiveMail(t *testing.T, mailer *fakeMailer) sentMail {
+	t.Helper()
+	select {
+	case mail := <-mailer.sent:
+		return mail
+	case <-time.After(time.Second):
+		t.Fatal("no email sent")
+		return sentMail{}
+	}
+}
+
+func TestRequestEmailChangeMailsTokenToNewAddress(t *testing.T) {
+	s, repo, mailer := newAccountService(t, &domain.User{ID: "user-1", Email: "ada@example.com"})
+	ctx := auth.ContextWithClaims(context.Background(), &auth.Claims{ID: "user-1"})
+
+	_, err := s.RequestEmailChange(ctx, &proto.RequestEmailChangeRequest{
+		UserId:          "user-1",
+		NewEmail:        " lovelace@example.com ",
+		CurrentPassword: "current password",
+	})
+	if err != nil {
+		t.Fatalf("RequestEmailChange() error = %v", err)
+	}
+
+	mail := receiveMail(t, mailer)
+	if mail.to != "lovelace@example.com" {
+		t.Fatalf("sent to %q; want lovelace@example.com", mail.to)
+	}
+	if len(repo.emailChangeTokens) != 1 {
+		t.Fatalf("stored %d tokens; want 1", len(repo.emailChangeTokens))
+	}
+	stored := repo.emailChangeTokens[0]
+	if stored.UserID != "user-1" || stored.NewEmail != "lovelace@example.com" {
+		t.Fatalf("token = %+v", stored)
+	}
+
+	token := strings.Fields(strings.SplitN(mail.body, "\n\n", 2)[1])[0]
+	if hashToken(token) != stored.TokenHash {
+		t.Fatal("mailed token does not match the stored hash")
+	}
+}
+
+func TestRequestEmailChangeToTakenAddressLooksTheSame(t *testing.T) {
+	s, repo, mailer := newAccountService(t,
+		&domain.User{ID: "user-1", Email: "ada@example.com"},
+		&domain.User{ID: "user-2", Email: "taken@example.com"},
+	)
+	ctx := auth.ContextWithClaims(context.Background(), &auth.Claims{ID: "user-1"})
+
+	_, err := s.RequestEmailChange(ctx, &proto.RequestEmailChangeRequest{
+		UserId:          "user-1",
+		NewEmail:        "taken@example.com",
+		CurrentPassword: "current password",
+	})
+	if err != nil {
+		t.Fatalf("RequestEmailChange() error = %v; want the same response as for a free address", err)
+	}
+
+	mail := receiveMail(t, mailer)
+	if mail.to != "taken@example.com" || mail.body != emailTakenBody {
+		t.Fatalf("sent %+v; want the address-in-use notice", mail)
+	}
+	if len(repo.emailChangeTokens) != 0 {
+		t.Fatalf("stored %d tokens; want none", len(repo.emailChangeTokens))
+	}
+}
+
+func TestRequestEmailChangeRejections(t *testing.T) {
+	tests := []struct {
+		name string
+		req  *proto.RequestEmailChangeRequest
+		code codes.Code
+	}{
+		{"other user", &proto.RequestEmailChangeRequest{UserId: "user-2", NewEmail: "new@example.com", CurrentPassword: "current password"}, codes.PermissionDenied},
+		{"wrong password", &proto.RequestEmailChangeRequest{UserId: "user-1", NewEmail: "new@example.com", CurrentPassword: "wrong"}, codes.PermissionDenied},
+		{"no email", &proto.RequestEmailChangeRequest{UserId: "user-1", NewEmail: " ", CurrentPassword: "current password"}, codes.InvalidArgument},
+		{"same email", &proto.RequestEmailChangeRequest{UserId: "user-1", NewEmail: "ADA@example.com", CurrentPassword: "current password"}, codes.InvalidArgument},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, repo, _ := newAccountService(t, &domain.User{ID: "user-1", Email: "ada@example.com"})
+			ctx := auth.ContextWithClaims(context.Background(), &auth.Claims{ID: "user-1"})
+
+			if _, err := s.RequestEmailChange(ctx, tt.req); status.Code(err) != tt.code {
+				t.Fatalf("RequestEmailChange() error = %v; want %v", err, tt.code)
+			}
+			if len(repo.emailChangeTokens) != 0 {
+				t.Fatalf("stored %d tokens; want none", len(repo.emailChangeTokens))
+			}
+		})
+	}
+}
+
+func TestConfirmEmailChangeNotifiesOldAddress(t *testing.T) {
+	s, repo, mailer := newAccountService(t, &domain.User{ID: "user-1", Email: "ada@example.com"})
+	repo.emailChangeTokens = []*domain.EmailChangeToken{{
+		ID:        "token-1",
+		UserID:    "user-1",
+		NewEmail:  "lovelace@example.com",
+		TokenHash: hashToken("secret"),
+		ExpiresAt: uint64(time.Now().Add(time.Hour).Unix()),
+	}}
+
+	if _, err := s.ConfirmEmailChange(context.Background(), &proto.ConfirmEmailChangeRequest{Token: "secret"}); err != nil {
+		t.Fatalf("ConfirmEmailChange() error = %v", err)
+	}
+	if email := repo.users["user-1"].Email; email != "lovelace@example.com" {
+		t.Fatalf("email = %q; want lovelace@example.com", email)
+	}
+	if mail := receiveMail(t, mailer); mail.to != "ada@example.com" {
+		t.Fatalf("notice sent to %q; want the old address", mail.to)
+	}
+
+	_, err := s.ConfirmEmailChange(context.Background(), &proto.ConfirmEmailChangeRequest{Token: "secret"})
+	if status.Code(err) != codes.InvalidArgument {
+		t.Fatalf("reusing the token: error = %v; want InvalidArgument", err)
+	}
+}
+
+func TestConfirmEmailChangeRejectsExpiredToken(t *testing.T) {
+	s, repo, _ := newAccountService(t, &domain.User{ID: "user-1", Email: "ada@example.com"})
+	repo.emailChangeTokens = []*domain.EmailChangeToken{{
+		ID:        "token-1",
+		UserID:    "user-1",
+		NewEmail:  "lovelace@example.com",
+		TokenHash: hashToken("secret"),
+		ExpiresAt: uint64(time.Now().Add(-time.Minute).Unix()),
+	}}
+
+	_, err := s.ConfirmEmailChange(context.Background(), &proto.ConfirmEmailChangeRequest{Token: "secret"})
+	if status.Code(err) != codes.InvalidArgument {
+		t.Fatalf("ConfirmEmailChange() error = %v; want InvalidArgument", err)
+	}
+	if email := repo.users["user-1"].Email; email != "ada@example.com" {
+		t.Fatalf("email changed to %q", email)
+	}
+}
+
+func TestChangePasswordKeepsCurrentSession(t *testing.T) {
+	s, repo, _ := newAccountService(t, &domain.User{ID: "user-1", Email: "ada@example.com"})
+	ctx := auth.ContextWithClaims(context.Background(), &auth.Claims{ID: "user-1"})
+
+	_, err := s.ChangePassword(ctx, &proto.ChangePasswordRequest{
+		UserId:          "user-1",
+		CurrentPassword: "current password",
+		NewPassword:     "a much longer passphrase",
+		SessionId:       "session-1",
+	})
+	if err != nil {
+		t.Fatalf("ChangePassword() error = %v", err)
+	}
+	if repo.changedPasswords["user-1"] != "session-1" {
+		t.Fatalf("kept session %q; want session-1", repo.changedPasswords["user-1"])
+	}
+	if ok, _, _ := s.hasher.Verify(repo.users["user-1"].Password, "a much longer passphrase"); !ok {
+		t.Fatal("new password was not stored")
+	}
+}
+
+func TestChangePasswordWithWrongPasswordCountsAsFailure(t *testing.T) {
+	s, repo, _ := newAccountService(t, &domain.User{ID: "user-1", Email: "ada@example.com"})
+	ctx := auth.ContextWithClaims(context.Background(), &auth.Claims{ID: "user-1"})
+
+	_, err := s.ChangePassword(ctx, &proto.ChangePasswordRequest{
+		UserId:          "user-1",
+		CurrentPassword: "wrong",
+		NewPassword:     "a much longer passphrase",
+	})
+	if status.Code(err) != codes.PermissionDenied {
+		t.Fatalf("ChangePassword() error = %v; want PermissionDenied", err)
+	}
+	if throttle := repo.loginThrottles[accountThrottleKey("ada@example.com")]; throttle == nil || throttle.Failures != 1 {
+		t.Fatalf("throttle = %+v; want one recorded failure", throttle)
+	}
+	if _, ok := repo.changedPasswords["user-1"]; ok {
+		t.Fatal("password was changed")
+	}
+}
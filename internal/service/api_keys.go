@@ -0,0 +1,192 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"ecomm/internal/adapters"
+	"ecomm/internal/audit"
+	"ecomm/internal/controller/auth"
+	"ecomm/internal/domain"
+	"ecomm/pkg"
+	"ecomm/proto"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// API keys look like ek_<prefix>_<secret>. The prefix is stored in plain text
+// so keys can be identified in logs and listings; only a hash of the full key
+// is stored.
+const apiKeyPrefix = "ek"
+
+func generateAPIKey() (key, prefix string, err error) {
+	prefixBytes := make([]byte, 4)
+	if _, err := rand.Read(prefixBytes); err != nil {
+		return "", "", err
+	}
+
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return "", "", err
+	}
+
+	prefix = hex.EncodeToString(prefixBytes)
+	key = fmt.Sprintf("%s_%s_%s", apiKeyPrefix, prefix, base64.RawURLEncoding.EncodeToString(secret))
+	return key, prefix, nil
+}
+
+// authenticateAPIKey returns the stored key matching the plaintext key if it
+// is neither revoked nor expired.
+func authenticateAPIKey(repo domain.Repository, key string) (*domain.APIKey, error) {
+	parts := strings.SplitN(key, "_", 3)
+	if len(parts) != 3 || parts[0] != apiKeyPrefix {
+		return nil, domain.ErrAPIKeyNotFound
+	}
+
+	apiKey, err := repo.GetAPIKeyByPrefix(parts[1])
+	if err != nil {
+		return nil, err
+	}
+
+	if subtle.ConstantTimeCompare([]byte(apiKey.KeyHash), []byte(hashToken(key))) != 1 {
+		return nil, domain.ErrAPIKeyNotFound
+	}
+
+	if apiKey.RevokedAt != 0 {
+		return nil, domain.ErrAPIKeyNotFound
+	}
+
+	if apiKey.ExpiresAt != 0 && apiKey.ExpiresAt <= uint64(time.Now().Unix()) {
+		return nil, domain.ErrAPIKeyNotFound
+	}
+
+	if err := repo.TouchAPIKey(apiKey.ID); err != nil {
+		pkg.ErrorLogger.Printf("failed to record api key usage: %v", err)
+	}
+
+	return apiKey, nil
+}
+
+func apiKeyClaims(apiKey *domain.APIKey) *auth.Claims {
+	claims := &auth.Claims{
+		Permissions: apiKey.Scopes,
+		APIKeyID:    apiKey.ID,
+	}
+	claims.Subject = "apikey:" + apiKey.ID
+	return claims
+}
+
+type apiKeyValidator struct {
+	repo domain.Repository
+}
+
+// NewAPIKeyValidator returns the validator used by the gRPC interceptor to
+// authenticate calls made with an API key.
+func NewAPIKeyValidator(repo domain.Repository) auth.APIKeyValidator {
+	return &apiKeyValidator{repo: repo}
+}
+
+func (v *apiKeyValidator) ValidateAPIKey(ctx context.Context, key string) (*auth.Claims, error) {
+	apiKey, err := authenticateAPIKey(v.repo, key)
+	if err != nil {
+		return nil, err
+	}
+	return apiKeyClaims(apiKey), nil
+}
+
+// CreateAPIKey issues a key on behalf of the caller. Keys can only be scoped
+// to permissions the caller holds, so creating one never escalates privileges.
+func (s *service) CreateAPIKey(ctx context.Context, req *proto.CreateAPIKeyRequest) (*proto.CreateAPIKeyResponse, error) {
+	claims := auth.ClaimsFromContext(ctx)
+	if claims == nil {
+		return nil, status.Error(codes.Unauthenticated, "missing credentials")
+	}
+
+	if len(req.Scopes) == 0 {
+		return nil, status.Error(codes.InvalidArgument, "at least one scope is required")
+	}
+
+	for _, scope := range req.Scopes {
+		if !slices.Contains(domain.Permissions, scope) {
+			return nil, status.Errorf(codes.InvalidArgument, "unknown scope %q", scope)
+		}
+		if !claims.HasPermission(scope) {
+			return nil, status.Errorf(codes.PermissionDenied, "cannot grant scope %q you do not hold", scope)
+		}
+	}
+
+	if req.ExpiresAt != 0 && req.ExpiresAt <= uint64(time.Now().Unix()) {
+		return nil, status.Error(codes.InvalidArgument, "expires_at must be in the future")
+	}
+
+	key, prefix, err := generateAPIKey()
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to generate api key: %v", err)
+	}
+
+	apiKey, err := s.repo.CreateAPIKey(&domain.APIKey{
+		Name:      req.Name,
+		Prefix:    prefix,
+		KeyHash:   hashToken(key),
+		Scopes:    req.Scopes,
+		CreatedBy: claims.ID,
+		ExpiresAt: req.ExpiresAt,
+	})
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to create api key: %v", err)
+	}
+
+	audit.Record(ctx, "api_key", apiKey.ID, nil, apiKey)
+
+	return &proto.CreateAPIKeyResponse{
+		ApiKey: adapters.ToProtoAPIKey(*apiKey),
+		Key:    key,
+	}, nil
+}
+
+func (s *service) ListAPIKeys(ctx context.Context, req *proto.ListAPIKeysRequest) (*proto.ListAPIKeysResponse, error) {
+	keys, err := s.repo.ListAPIKeys()
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to list api keys: %v", err)
+	}
+
+	return &proto.ListAPIKeysResponse{
+		ApiKeys: adapters.ToProtoAPIKeys(keys),
+	}, nil
+}
+
+func (s *service) RevokeAPIKey(ctx context.Context, req *proto.RevokeAPIKeyRequest) (*proto.RevokeAPIKeyResponse, error) {
+	if err := s.repo.RevokeAPIKey(req.Id); err != nil {
+		if errors.Is(err, domain.ErrAPIKeyNotFound) {
+			return nil, status.Error(codes.NotFound, err.Error())
+		}
+		return nil, status.Errorf(codes.Internal, "failed to revoke api key: %v", err)
+	}
+
+	audit.Record(ctx, "api_key", req.Id, nil, nil)
+
+	return &proto.RevokeAPIKeyResponse{}, nil
+}
+
+func (s *service) AuthenticateAPIKey(ctx context.Context, req *proto.AuthenticateAPIKeyRequest) (*proto.AuthenticateAPIKeyResponse, error) {
+	apiKey, err := authenticateAPIKey(s.repo, req.Key)
+	if err != nil {
+		if errors.Is(err, domain.ErrAPIKeyNotFound) {
+			return nil, status.Error(codes.Unauthenticated, "invalid api key")
+		}
+		return nil, status.Errorf(codes.Internal, "failed to authenticate api key: %v", err)
+	}
+
+	return &proto.AuthenticateAPIKeyResponse{
+		ApiKeyId: apiKey.ID,
+		Scopes:   apiKey.Scopes,
+	}, nil
+}
@@ -0,0 +1,67 @@
+package service
+
+import (
+	"context"
+	"ecomm/internal/controller/auth"
+	"ecomm/internal/domain"
+	"ecomm/proto"
+	"testing"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestCreateAPIKeyRejectsScopesTheCallerLacks(t *testing.T) {
+	repo := newFakeRepo()
+	s := &service{repo: repo}
+
+	ctx := auth.ContextWithClaims(context.Background(), &auth.Claims{
+		ID:          "user-1",
+		Permissions: []string{domain.PermissionAPIKeysManage, domain.PermissionOrdersRead},
+	})
+	_, err := s.CreateAPIKey(ctx, &proto.CreateAPIKeyRequest{
+		Name:   "escalation",
+		Scopes: []string{domain.PermissionOrdersRead, domain.PermissionRolesManage},
+	})
+	if status.Code(err) != codes.PermissionDenied {
+		t.Fatalf("CreateAPIKey() error = %v; want PermissionDenied", err)
+	}
+	if len(repo.apiKeys) != 0 {
+		t.Fatalf("created %d keys; want none", len(repo.apiKeys))
+	}
+}
+
+func TestCreateAPIKeyRecordsCallerAsCreator(t *testing.T) {
+	repo := newFakeRepo()
+	s := &service{repo: repo}
+
+	ctx := auth.ContextWithClaims(context.Background(), &auth.Claims{
+		ID:          "user-1",
+		Permissions: []string{domain.PermissionAll},
+	})
+	response, err := s.CreateAPIKey(ctx, &proto.CreateAPIKeyRequest{
+		Name:   "exporter",
+		Scopes: []string{domain.PermissionProductsExport},
+	})
+	if err != nil {
+		t.Fatalf("CreateAPIKey() error = %v", err)
+	}
+	if response.Key == "" {
+		t.Fatal("plaintext key not returned")
+	}
+	if len(repo.apiKeys) != 1 || repo.apiKeys[0].CreatedBy != "user-1" {
+		t.Fatalf("created keys = %+v; want one created by user-1", repo.apiKeys)
+	}
+	if repo.apiKeys[0].KeyHash != hashToken(response.Key) {
+		t.Fatal("stored hash does not match the returned key")
+	}
+}
+
+func TestCreateAPIKeyRequiresCredentials(t *testing.T) {
+	s := &service{repo: newFakeRepo()}
+
+	_, err := s.CreateAPIKey(context.Background(), &proto.CreateAPIKeyRequest{Scopes: []string{domain.PermissionOrdersRead}})
+	if status.Code(err) != codes.Unauthenticated {
+		t.Fatalf("CreateAPIKey() error = %v; want Unauthenticated", err)
+	}
+}
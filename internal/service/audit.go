@@ -0,0 +1,108 @@
+package service
+
+import (
+	"context"
+	"ecomm/internal/adapters"
+	"ecomm/internal/domain"
+	"ecomm/proto"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	defaultAuditEventLimit = 50
+	maxAuditEventLimit     = 500
+)
+
+// AuditedMethods maps the RPCs that change state to the action recorded in
+// the audit log. ImpersonateUser is absent because it writes its own event
+// and must fail if that event cannot be written.
+var AuditedMethods = map[string]string{
+	proto.ApiService_CreateProduct_FullMethodName:  "product.create",
+	proto.ApiService_UpdateProduct_FullMethodName:  "product.update",
+	proto.ApiService_DeleteProduct_FullMethodName:  "product.delete",
+	proto.ApiService_RestoreProduct_FullMethodName: "product.restore",
+	proto.ApiService_ImportProducts_FullMethodName: "product.import",
+
+	proto.ApiService_SetProductOptions_FullMethodName:    "product.set_options",
+	proto.ApiService_CreateProductVariant_FullMethodName: "product_variant.create",
+	proto.ApiService_UpdateProductVariant_FullMethodName: "product_variant.update",
+	proto.ApiService_DeleteProductVariant_FullMethodName: "product_variant.delete",
+	proto.ApiService_UploadProductImage_FullMethodName:   "product_image.upload",
+	proto.ApiService_DeleteProductImage_FullMethodName:   "product_image.delete",
+	proto.ApiService_ReorderProductImages_FullMethodName: "product.reorder_images",
+
+	proto.ApiService_SetProductSale_FullMethodName:             "product.set_sale",
+	proto.ApiService_ClearProductSale_FullMethodName:           "product.clear_sale",
+	proto.ApiService_SchedulePriceChange_FullMethodName:        "product.schedule_price",
+	proto.ApiService_CancelScheduledPriceChange_FullMethodName: "product.cancel_price_change",
+
+	proto.ApiService_AdjustStock_FullMethodName:         "inventory.adjust",
+	proto.ApiService_ReconcileStock_FullMethodName:      "inventory.reconcile",
+	proto.ApiService_TransferStock_FullMethodName:       "inventory.transfer",
+	proto.ApiService_SetReorderThreshold_FullMethodName: "inventory.set_threshold",
+	proto.ApiService_CreateWarehouse_FullMethodName:     "warehouse.create",
+	proto.ApiService_UpdateWarehouse_FullMethodName:     "warehouse.update",
+
+	proto.ApiService_CreateCategory_FullMethodName: "category.create",
+	proto.ApiService_UpdateCategory_FullMethodName: "category.update",
+	proto.ApiService_DeleteCategory_FullMethodName: "category.delete",
+
+	proto.ApiService_CreateOrder_FullMethodName: "order.create",
+	proto.ApiService_DeleteOrder_FullMethodName: "order.delete",
+
+	proto.ApiService_CreateUser_FullMethodName:         "user.create",
+	proto.ApiService_UpdateUser_FullMethodName:         "user.update",
+	proto.ApiService_DeleteUser_FullMethodName:         "user.delete",
+	proto.ApiService_SetUserAdmin_FullMethodName:       "user.set_admin",
+	proto.ApiService_AdminUpdateUser_FullMethodName:    "user.update",
+	proto.ApiService_SuspendUser_FullMethodName:        "user.suspend",
+	proto.ApiService_DisableUser_FullMethodName:        "user.disable",
+	proto.ApiService_EnableUser_FullMethodName:         "user.enable",
+	proto.ApiService_UnlockAccount_FullMethodName:      "user.unlock",
+	proto.ApiService_ResetPassword_FullMethodName:      "user.reset_password",
+	proto.ApiService_ChangePassword_FullMethodName:     "user.change_password",
+	proto.ApiService_ConfirmEmailChange_FullMethodName: "user.change_email",
+	proto.ApiService_ConfirmMFA_FullMethodName:         "user.enable_mfa",
+	proto.ApiService_DisableMFA_FullMethodName:         "user.disable_mfa",
+
+	proto.ApiService_AssignUserRole_FullMethodName: "user.assign_role",
+	proto.ApiService_RemoveUserRole_FullMethodName: "user.remove_role",
+
+	proto.ApiService_CreateAPIKey_FullMethodName: "api_key.create",
+	proto.ApiService_RevokeAPIKey_FullMethodName: "api_key.revoke",
+}
+
+func (s *service) ListAuditEvents(ctx context.Context, req *proto.ListAuditEventsRequest) (*proto.ListAuditEventsResponse, error) {
+	limit := int(req.Limit)
+	if limit <= 0 {
+		limit = defaultAuditEventLimit
+	}
+	if limit > maxAuditEventLimit {
+		limit = maxAuditEventLimit
+	}
+
+	if req.Offset < 0 {
+		return nil, status.Error(codes.InvalidArgument, "offset must not be negative")
+	}
+
+	events, err := s.repo.ListAuditEvents(&domain.AuditEventFilter{
+		ActorID:    req.ActorId,
+		Action:     req.Action,
+		TargetType: req.TargetType,
+		TargetID:   req.TargetId,
+		RequestID:  req.RequestId,
+		Since:      req.Since,
+		Until:      req.Until,
+		Limit:      limit,
+		Offset:     int(req.Offset),
+	})
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to list audit events: %v", err)
+	}
+
+	return &proto.ListAuditEventsResponse{
+		Events: adapters.ToProtoAuditEvents(events),
+	}, nil
+}
@@ -0,0 +1,228 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"ecomm/internal/adapters"
+	"ecomm/internal/audit"
+	"ecomm/internal/catalog"
+	"ecomm/internal/domain"
+	"ecomm/pkg"
+	"ecomm/proto"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/google/uuid"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	// maxImportBytes must stay below MaxMessageBytes.
+	maxImportBytes  = 5 << 20
+	maxImportRows   = 10_000
+	importBatchSize = 500
+
+	defaultExportLimit = 500
+	maxExportLimit     = 1000
+)
+
+// ImportProducts creates or updates products from a catalog file, matching
+// them by SKU. Rows are validated individually and invalid rows are reported
+// without stopping the import; valid rows are saved in batches. Rows whose SKU
+// belongs to an archived product are reported rather than reviving it.
+func (s *service) ImportProducts(ctx context.Context, req *proto.ImportProductsRequest) (*proto.ImportProductsResponse, error) {
+	if len(req.Data) > maxImportBytes {
+		return nil, status.Errorf(codes.InvalidArgument, "file must be at most %d MiB", maxImportBytes>>20)
+	}
+
+	rows, rowErrors, err := catalog.Decode(strings.ToLower(req.Format), bytes.NewReader(req.Data))
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	total := len(rows) + len(rowErrors)
+	if total > maxImportRows {
+		return nil, status.Errorf(codes.InvalidArgument, "file must contain at most %d products", maxImportRows)
+	}
+
+	categories, err := s.repo.ListCategories()
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to list categories: %v", err)
+	}
+
+	categoryIDs := make(map[string]string, len(categories))
+	for _, category := range categories {
+		categoryIDs[category.Slug] = category.ID
+	}
+
+	importErrors := make([]*proto.ImportRowError, 0, len(rowErrors))
+	for _, rowError := range rowErrors {
+		importErrors = append(importErrors, &proto.ImportRowError{
+			Line:     int32(rowError.Line),
+			Messages: []string{rowError.Err.Error()},
+		})
+	}
+
+	skus := make([]string, len(rows))
+	for i := range rows {
+		rows[i].SKU = strings.TrimSpace(rows[i].SKU)
+		skus[i] = rows[i].SKU
+	}
+	statuses, err := s.repo.GetProductStatusesBySKU(skus)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to look up products: %v", err)
+	}
+
+	products := make([]*domain.Product, 0, len(rows))
+	lines := make([]int, 0, len(rows))
+	seen := make(map[string]int, len(rows))
+	for _, row := range rows {
+		row.Category = strings.TrimSpace(row.Category)
+
+		messages := pkg.ValidateStruct(row)
+		if statuses[row.SKU] == domain.ProductArchived {
+			messages = append(messages, domain.ErrSKUArchived.Error())
+		}
+		categoryID, ok := categoryIDs[row.Category]
+		if row.Category != "" && !ok {
+			messages = append(messages, fmt.Sprintf("category %q does not exist", row.Category))
+		}
+		if line, ok := seen[row.SKU]; ok && row.SKU != "" {
+			messages = append(messages, fmt.Sprintf("sku also appears on line %d", line))
+		}
+
+		if len(messages) > 0 {
+			importErrors = append(importErrors, &proto.ImportRowError{
+				Line:     int32(row.Line),
+				Sku:      row.SKU,
+				Messages: messages,
+			})
+			continue
+		}
+
+		seen[row.SKU] = row.Line
+		lines = append(lines, row.Line)
+		products = append(products, &domain.Product{
+			SKU:             row.SKU,
+			Name:            row.Name,
+			Image:           row.Image,
+			CategoryID:      categoryID,
+			Description:     row.Description,
+			Rating:          row.Rating,
+			NumberOfReviews: row.NumberOfReviews,
+			Price:           row.Price,
+			CountInStock:    row.CountInStock,
+		})
+	}
+
+	created := 0
+	saved := len(products)
+	if req.DryRun {
+		for _, product := range products {
+			if _, ok := statuses[product.SKU]; !ok {
+				created++
+			}
+		}
+	} else {
+		for start := 0; start < len(products); start += importBatchSize {
+			end := min(start+importBatchSize, len(products))
+
+			batchCreated, err := s.repo.UpsertProducts(products[start:end], actorID(ctx))
+			if err != nil {
+				// The batch was rolled back, so every row in it failed. When
+				// the error names a product, its row gets the cause and the
+				// others point to it.
+				message := fmt.Sprintf("failed to save product: %v", err)
+				if errors.Is(err, domain.ErrCategoryNotFound) || errors.Is(err, domain.ErrSKUArchived) {
+					message = err.Error()
+				}
+				var productErr *domain.ProductError
+				failedSKU := ""
+				if errors.As(err, &productErr) {
+					failedSKU = productErr.SKU
+					message = importFailure(productErr.Err)
+				}
+				for i, product := range products[start:end] {
+					rowMessage := message
+					if failedSKU != "" && product.SKU != failedSKU {
+						rowMessage = fmt.Sprintf("not saved because sku %q in the same batch failed", failedSKU)
+					}
+					importErrors = append(importErrors, &proto.ImportRowError{
+						Line:     int32(lines[start+i]),
+						Sku:      product.SKU,
+						Messages: []string{rowMessage},
+					})
+				}
+				saved -= end - start
+				continue
+			}
+			created += batchCreated
+		}
+	}
+
+	slices.SortFunc(importErrors, func(a, b *proto.ImportRowError) int {
+		return int(a.Line - b.Line)
+	})
+
+	audit.AddMetadata(ctx, "dry_run", req.DryRun)
+	audit.AddMetadata(ctx, "created", created)
+	audit.AddMetadata(ctx, "updated", saved-created)
+	audit.AddMetadata(ctx, "failed", len(importErrors))
+
+	return &proto.ImportProductsResponse{
+		Total:   int32(total),
+		Created: int32(created),
+		Updated: int32(saved - created),
+		Failed:  int32(len(importErrors)),
+		Errors:  importErrors,
+		DryRun:  req.DryRun,
+	}, nil
+}
+
+// importFailure describes why saving an imported product failed.
+func importFailure(err error) string {
+	switch {
+	case errors.Is(err, domain.ErrInsufficientStock):
+		return "count_in_stock is lower than the warehouses can give up, reconcile the stock first"
+	case errors.Is(err, domain.ErrNoWarehouse):
+		return "count_in_stock cannot be set without an active warehouse"
+	default:
+		return fmt.Sprintf("failed to save product: %v", err)
+	}
+}
+
+// ExportProducts returns the catalog one page at a time, ordered by product
+// ID.
+func (s *service) ExportProducts(ctx context.Context, req *proto.ExportProductsRequest) (*proto.ExportProductsResponse, error) {
+	limit := int(req.Limit)
+	if limit <= 0 {
+		limit = defaultExportLimit
+	}
+	if limit > maxExportLimit {
+		limit = maxExportLimit
+	}
+
+	if req.AfterId != "" {
+		if _, err := uuid.Parse(req.AfterId); err != nil {
+			return nil, status.Error(codes.InvalidArgument, "after_id must be a product id")
+		}
+	}
+
+	products, err := s.repo.ListProductsPage(req.AfterId, limit)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to list products: %v", err)
+	}
+
+	nextAfterID := ""
+	if len(products) == limit {
+		nextAfterID = products[len(products)-1].ID
+	}
+
+	return &proto.ExportProductsResponse{
+		Products:    adapters.ToProtoProducts(products),
+		NextAfterId: nextAfterID,
+	}, nil
+}
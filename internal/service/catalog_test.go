@@ -0,0 +1,287 @@
+package service
+
+import (
+	"context"
+	"ecomm/internal/domain"
+	"ecomm/proto"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// importRepo returns a repository with one category and one product to
+// import against.
+func importRepo() *fakeRepo {
+	repo := newFakeRepo()
+	repo.categories = []*domain.Category{{ID: "category-1", Slug: "mugs"}}
+	repo.products = map[string]*domain.Product{
+		"product-1": {ID: "product-1", SKU: "MUG-1", Name: "Mug", CategoryID: "category-1", Status: domain.ProductActive},
+	}
+	return repo
+}
+
+// importRows encodes rows as an import file in JSON. Fields that a row leaves
+// out get valid values.
+func importRows(t *testing.T, rows ...map[string]any) []byte {
+	t.Helper()
+
+	for _, row := range rows {
+		for field, value := range map[string]any{"name": "Mug", "category": "mugs", "image": "mug.png"} {
+			if _, ok := row[field]; !ok {
+				row[field] = value
+			}
+		}
+	}
+	data, err := json.Marshal(rows)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return data
+}
+
+func TestImportProducts(t *testing.T) {
+	tests := []struct {
+		name    string
+		rows    []map[string]any
+		dryRun  bool
+		created int32
+		updated int32
+		errors  map[int32][]string
+	}{
+		{
+			name: "creates and updates",
+			rows: []map[string]any{
+				{"sku": "MUG-1", "price": 12.5},
+				{"sku": " MUG-2 ", "count_in_stock": 3},
+			},
+			created: 1,
+			updated: 1,
+		},
+		{
+			name: "dry run counts without saving",
+			rows: []map[string]any{
+				{"sku": "MUG-1"},
+				{"sku": "MUG-2"},
+				{"sku": "MUG-3"},
+			},
+			dryRun:  true,
+			created: 2,
+			updated: 1,
+		},
+		{
+			name: "validation messages",
+			rows: []map[string]any{
+				{"sku": "", "name": "", "price": -1},
+				{"sku": "MUG-2", "image": "", "rating": 6, "count_in_stock": -2},
+				{"sku": strings.Repeat("x", 65)},
+			},
+			errors: map[int32][]string{
+				1: {"SKU field is required.", "Name field is required.", "Price field must be at least 0."},
+				2: {"Image field is required.", "CountInStock field must be at least 0.", "Rating field must be at most 5."},
+				3: {"SKU field must be at most 64 characters long."},
+			},
+		},
+		{
+			name: "unknown category",
+			rows: []map[string]any{
+				{"sku": "MUG-2", "category": "plates"},
+				{"sku": "MUG-3", "category": " mugs "},
+			},
+			created: 1,
+			errors: map[int32][]string{
+				1: {`category "plates" does not exist`},
+			},
+		},
+		{
+			name: "duplicate sku",
+			rows: []map[string]any{
+				{"sku": "MUG-2"},
+				{"sku": "MUG-3"},
+				{"sku": "MUG-2 "},
+			},
+			created: 2,
+			errors: map[int32][]string{
+				3: {"sku also appears on line 1"},
+			},
+		},
+		{
+			name: "invalid rows are not duplicates",
+			rows: []map[string]any{
+				{"sku": "MUG-2", "category": "plates"},
+				{"sku": "MUG-2"},
+			},
+			created: 1,
+			errors: map[int32][]string{
+				1: {`category "plates" does not exist`},
+			},
+		},
+		{
+			name: "dry run reports the same errors",
+			rows: []map[string]any{
+				{"sku": "MUG-2", "category": "plates"},
+				{"sku": "MUG-1"},
+				{"sku": "MUG-1"},
+			},
+			dryRun:  true,
+			updated: 1,
+			errors: map[int32][]string{
+				1: {`category "plates" does not exist`},
+				3: {"sku also appears on line 2"},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := importRepo()
+			s := &service{repo: repo}
+
+			res, err := s.ImportProducts(context.Background(), &proto.ImportProductsRequest{
+				Format: "json",
+				Data:   importRows(t, tt.rows...),
+				DryRun: tt.dryRun,
+			})
+			if err != nil {
+				t.Fatalf("ImportProducts() error = %v", err)
+			}
+
+			if res.Total != int32(len(tt.rows)) || res.Created != tt.created || res.Updated != tt.updated || res.Failed != int32(len(tt.errors)) {
+				t.Errorf("total %d, created %d, updated %d, failed %d; want %d, %d, %d, %d",
+					res.Total, res.Created, res.Updated, res.Failed, len(tt.rows), tt.created, tt.updated, len(tt.errors))
+			}
+			errors := make(map[int32][]string, len(res.Errors))
+			for _, rowError := range res.Errors {
+				errors[rowError.Line] = rowError.Messages
+			}
+			if len(tt.errors) == 0 {
+				tt.errors = map[int32][]string{}
+			}
+			if !reflect.DeepEqual(errors, tt.errors) {
+				t.Errorf("errors = %v; want %v", errors, tt.errors)
+			}
+
+			want := 1
+			if !tt.dryRun {
+				want += int(tt.created)
+			}
+			if len(repo.products) != want {
+				t.Errorf("%d products saved; want %d", len(repo.products), want)
+			}
+		})
+	}
+}
+
+func TestImportProductsLimits(t *testing.T) {
+	rows := make([]map[string]any, maxImportRows+1)
+	for i := range rows {
+		rows[i] = map[string]any{"sku": fmt.Sprintf("MUG-%d", i)}
+	}
+
+	tests := []struct {
+		name    string
+		data    []byte
+		message string
+	}{
+		{"too many rows", importRows(t, rows...), "file must contain at most 10000 products"},
+		{"too many bytes", make([]byte, maxImportBytes+1), "file must be at most 5 MiB"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := importRepo()
+			s := &service{repo: repo}
+
+			_, err := s.ImportProducts(context.Background(), &proto.ImportProductsRequest{Format: "json", Data: tt.data})
+			if status.Code(err) != codes.InvalidArgument || status.Convert(err).Message() != tt.message {
+				t.Fatalf("ImportProducts() error = %v; want InvalidArgument %q", err, tt.message)
+			}
+			if len(repo.products) != 1 {
+				t.Fatal("products were saved")
+			}
+		})
+	}
+}
+
+// failingUpsertRepo fails to save any batch that holds sku.
+type failingUpsertRepo struct {
+	*fakeRepo
+	sku string
+	err error
+}
+
+func (r failingUpsertRepo) UpsertProducts(products []*domain.Product, actorID string) (int, error) {
+	if slices.ContainsFunc(products, func(product *domain.Product) bool { return product.SKU == r.sku }) {
+		return 0, r.err
+	}
+	return r.fakeRepo.UpsertProducts(products, actorID)
+}
+
+func TestImportProductsReportsFailedBatches(t *testing.T) {
+	rows := make([]map[string]any, importBatchSize+100)
+	for i := range rows {
+		rows[i] = map[string]any{"sku": fmt.Sprintf("SKU-%d", i+1)}
+	}
+
+	tests := []struct {
+		name   string
+		err    error
+		failed string
+		others string
+	}{
+		{
+			"error without a product",
+			errors.New("connection reset"),
+			"failed to save product: connection reset",
+			"failed to save product: connection reset",
+		},
+		{
+			"category removed meanwhile",
+			domain.ErrCategoryNotFound,
+			domain.ErrCategoryNotFound.Error(),
+			domain.ErrCategoryNotFound.Error(),
+		},
+		{
+			"stock shortfall on one product",
+			&domain.ProductError{SKU: "SKU-550", Err: domain.ErrInsufficientStock},
+			"count_in_stock is lower than the warehouses can give up, reconcile the stock first",
+			`not saved because sku "SKU-550" in the same batch failed`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := importRepo()
+			s := &service{repo: failingUpsertRepo{repo, "SKU-550", tt.err}}
+
+			res, err := s.ImportProducts(context.Background(), &proto.ImportProductsRequest{Format: "json", Data: importRows(t, rows...)})
+			if err != nil {
+				t.Fatalf("ImportProducts() error = %v", err)
+			}
+
+			// Only the second batch, lines 501 to 600, fails.
+			if res.Created != importBatchSize || res.Updated != 0 || res.Failed != 100 {
+				t.Fatalf("created %d, updated %d, failed %d; want %d, 0, 100", res.Created, res.Updated, res.Failed, importBatchSize)
+			}
+			for i, rowError := range res.Errors {
+				line := int32(importBatchSize + i + 1)
+				want := tt.others
+				if rowError.Sku == "SKU-550" {
+					want = tt.failed
+				}
+				if rowError.Line != line || rowError.Sku != fmt.Sprintf("SKU-%d", line) || !reflect.DeepEqual(rowError.Messages, []string{want}) {
+					t.Fatalf("error %d = %+v; want line %d with %q", i, rowError, line, want)
+				}
+			}
+			if len(repo.products) != importBatchSize+1 {
+				t.Fatalf("%d products saved; want %d", len(repo.products), importBatchSize+1)
+			}
+		})
+	}
+}
@@ -0,0 +1,234 @@
+package service
+
+import (
+	"context"
+	"crypto/md5"
+	"ecomm/internal/adapters"
+	"ecomm/internal/audit"
+	"ecomm/internal/domain"
+	"ecomm/proto"
+	"encoding/hex"
+	"errors"
+	"regexp"
+	"strings"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// Slugs are made of lowercase letters and digits of any script, so that names
+// in other alphabets keep a readable slug.
+var (
+	slugPattern    = regexp.MustCompile(`^[\p{Ll}\p{Lm}\p{Lo}\p{M}\p{N}]+(-[\p{Ll}\p{Lm}\p{Lo}\p{M}\p{N}]+)*$`)
+	slugSeparators = regexp.MustCompile(`[^\p{L}\p{M}\p{N}]+`)
+)
+
+// slugify derives a URL slug from a category name. Names without any letter or
+// digit get a slug derived from a hash of the name, so that they do not all
+// collide.
+func slugify(name string) string {
+	lower := strings.ToLower(strings.TrimSpace(name))
+	if slug := strings.Trim(slugSeparators.ReplaceAllString(lower, "-"), "-"); slug != "" {
+		return slug
+	}
+	sum := md5.Sum([]byte(lower))
+	return "category-" + hex.EncodeToString(sum[:])[:8]
+}
+
+// categorySlug returns the requested slug, or one derived from the name if
+// none was given.
+func categorySlug(slug, name string) (string, error) {
+	if slug == "" {
+		slug = slugify(name)
+	}
+	if !slugPattern.MatchString(slug) {
+		return "", status.Error(codes.InvalidArgument, "slug may only contain lowercase letters, digits and single dashes")
+	}
+	return slug, nil
+}
+
+func categoryError(err error) error {
+	switch {
+	case errors.Is(err, domain.ErrCategoryNotFound):
+		return status.Error(codes.NotFound, err.Error())
+	case errors.Is(err, domain.ErrCategorySlugTaken):
+		return status.Error(codes.AlreadyExists, err.Error())
+	case errors.Is(err, domain.ErrCategoryInUse):
+		return status.Error(codes.FailedPrecondition, err.Error())
+	default:
+		return status.Errorf(codes.Internal, "failed to save category: %v", err)
+	}
+}
+
+// checkCategoryParent verifies that parentID exists and, when moving an
+// existing category, that it is not the category itself or one of its
+// descendants.
+func (s *service) checkCategoryParent(categoryID, parentID string) error {
+	if parentID == "" {
+		return nil
+	}
+
+	categories, err := s.repo.ListCategories()
+	if err != nil {
+		return status.Errorf(codes.Internal, "failed to list categories: %v", err)
+	}
+
+	parents := make(map[string]string, len(categories))
+	for _, category := range categories {
+		parents[category.ID] = category.ParentID
+	}
+
+	if _, ok := parents[parentID]; !ok {
+		return status.Error(codes.InvalidArgument, "parent category not found")
+	}
+
+	for id := parentID; id != ""; id = parents[id] {
+		if id == categoryID {
+			return status.Error(codes.InvalidArgument, "a category cannot be moved below itself")
+		}
+	}
+
+	return nil
+}
+
+// categoryTree nests categories below their parents and adds the product
+// counts of subcategories to their ancestors.
+func categoryTree(categories []*domain.Category) []*proto.Category {
+	nodes := make(map[string]*proto.Category, len(categories))
+	for _, category := range categories {
+		nodes[category.ID] = adapters.ToProtoCategory(*category)
+	}
+
+	var roots []*proto.Category
+	for _, category := range categories {
+		node := nodes[category.ID]
+		parent, ok := nodes[category.ParentID]
+		if !ok {
+			roots = append(roots, node)
+			continue
+		}
+		parent.Children = append(parent.Children, node)
+	}
+
+	var count func(node *proto.Category) int32
+	count = func(node *proto.Category) int32 {
+		for _, child := range node.Children {
+			node.ProductCount += count(child)
+		}
+		return node.ProductCount
+	}
+	for _, root := range roots {
+		count(root)
+	}
+
+	return roots
+}
+
+func (s *service) ListCategories(ctx context.Context, req *proto.ListCategoriesRequest) (*proto.ListCategoriesResponse, error) {
+	categories, err := s.repo.ListCategories()
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to list categories: %v", err)
+	}
+
+	return &proto.ListCategoriesResponse{
+		Categories: categoryTree(categories),
+	}, nil
+}
+
+func (s *service) CreateCategory(ctx context.Context, req *proto.CreateCategoryRequest) (*proto.CreateCategoryResponse, error) {
+	name := strings.TrimSpace(req.Name)
+	if name == "" {
+		return nil, status.Error(codes.InvalidArgument, "name is required")
+	}
+
+	slug, err := categorySlug(req.Slug, name)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.checkCategoryParent("", req.ParentId); err != nil {
+		return nil, err
+	}
+
+	category, err := s.repo.CreateCategory(&domain.Category{
+		ParentID: req.ParentId,
+		Name:     name,
+		Slug:     slug,
+		Position: int(req.Position),
+	})
+	if err != nil {
+		return nil, categoryError(err)
+	}
+
+	audit.Record(ctx, "category", category.ID, nil, category)
+
+	return &proto.CreateCategoryResponse{
+		Category: adapters.ToProtoCategory(*category),
+	}, nil
+}
+
+// UpdateCategory changes the fields in the update mask. An empty slug in the
+// mask derives the slug from the name, and an empty parent moves the category
+// to the top level.
+func (s *service) UpdateCategory(ctx context.Context, req *proto.UpdateCategoryRequest) (*proto.UpdateCategoryResponse, error) {
+	paths, err := updatePaths(req.UpdateMask, req, categoryUpdateFields)
+	if err != nil {
+		return nil, err
+	}
+
+	category, err := s.repo.GetCategoryByID(req.Id)
+	if err != nil {
+		return nil, categoryError(err)
+	}
+	before := *category
+
+	if paths["name"] {
+		if category.Name = strings.TrimSpace(req.Name); category.Name == "" {
+			return nil, status.Error(codes.InvalidArgument, "name is required")
+		}
+	}
+
+	if paths["slug"] {
+		if category.Slug, err = categorySlug(req.Slug, category.Name); err != nil {
+			return nil, err
+		}
+	}
+
+	if paths["parent_id"] {
+		if err := s.checkCategoryParent(category.ID, req.ParentId); err != nil {
+			return nil, err
+		}
+		category.ParentID = req.ParentId
+	}
+
+	if paths["position"] {
+		category.Position = int(req.Position)
+	}
+
+	if err := s.repo.UpdateCategory(category); err != nil {
+		return nil, categoryError(err)
+	}
+
+	audit.Record(ctx, "category", category.ID, before, category)
+
+	return &proto.UpdateCategoryResponse{
+		Category: adapters.ToProtoCategory(*category),
+	}, nil
+}
+
+func (s *service) DeleteCategory(ctx context.Context, req *proto.DeleteCategoryRequest) (*proto.DeleteCategoryResponse, error) {
+	category, err := s.repo.GetCategoryByID(req.Id)
+	if err != nil {
+		return nil, categoryError(err)
+	}
+
+	if err := s.repo.DeleteCategory(category.ID); err != nil {
+		return nil, categoryError(err)
+	}
+
+	audit.Record(ctx, "category", category.ID, category, nil)
+
+	return &proto.DeleteCategoryResponse{
+		Id: category.ID,
+	}, nil
+}
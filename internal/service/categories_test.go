@@ -0,0 +1,169 @@
+package service
+
+import (
+	"context"
+	"ecomm/internal/domain"
+	"ecomm/proto"
+	"strings"
+	"testing"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/fieldmaskpb"
+)
+
+func TestSlugify(t *testing.T) {
+	tests := []struct {
+		name string
+		want string
+	}{
+		{"Home & Garden", "home-garden"},
+		{"  Kids' Toys  ", "kids-toys"},
+		{"Électronique", "électronique"},
+		{"Электроника", "электроника"},
+		{"家具", "家具"},
+	}
+
+	for _, tt := range tests {
+		if got := slugify(tt.name); got != tt.want {
+			t.Errorf("slugify(%q) = %q; want %q", tt.name, got, tt.want)
+		}
+		if _, err := categorySlug("", tt.name); err != nil {
+			t.Errorf("categorySlug(%q) error = %v", tt.name, err)
+		}
+	}
+}
+
+func TestSlugifyWithoutLettersDoesNotCollide(t *testing.T) {
+	first, second := slugify("★★★"), slugify("!!!")
+	if first == second {
+		t.Fatalf("slugify gave %q for both names", first)
+	}
+	for _, slug := range []string{first, second} {
+		if !strings.HasPrefix(slug, "category-") || !slugPattern.MatchString(slug) {
+			t.Errorf("slug %q; want a valid category- slug", slug)
+		}
+	}
+	if slugify("★★★") != first {
+		t.Error("slugify is not deterministic")
+	}
+}
+
+func TestCategorySlugRejectsInvalidSlugs(t *testing.T) {
+	for _, slug := range []string{"Home", "home--garden", "-home", "home garden"} {
+		if _, err := categorySlug(slug, "Home"); status.Code(err) != codes.InvalidArgument {
+			t.Errorf("categorySlug(%q) error = %v; want InvalidArgument", slug, err)
+		}
+	}
+}
+
+func categoryRepo() *fakeRepo {
+	repo := newFakeRepo()
+	repo.categories = []*domain.Category{
+		{ID: "home", Name: "Home", Slug: "home", ProductCount: 1},
+		{ID: "garden", ParentID: "home", Name: "Garden", Slug: "garden", Position: 2, ProductCount: 2},
+		{ID: "tools", ParentID: "garden", Name: "Tools", Slug: "tools", ProductCount: 4},
+		{ID: "books", Name: "Books", Slug: "books", ProductCount: 8},
+	}
+	return repo
+}
+
+func TestCheckCategoryParent(t *testing.T) {
+	s := &service{repo: categoryRepo()}
+
+	tests := []struct {
+		name       string
+		categoryID string
+		parentID   string
+		code       codes.Code
+	}{
+		{"top level", "garden", "", codes.OK},
+		{"new category", "", "tools", codes.OK},
+		{"other branch", "garden", "books", codes.OK},
+		{"unknown parent", "garden", "missing", codes.InvalidArgument},
+		{"itself", "garden", "garden", codes.InvalidArgument},
+		{"descendant", "home", "tools", codes.InvalidArgument},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := s.checkCategoryParent(tt.categoryID, tt.parentID); status.Code(err) != tt.code {
+				t.Fatalf("checkCategoryParent(%q, %q) error = %v; want %v", tt.categoryID, tt.parentID, err, tt.code)
+			}
+		})
+	}
+}
+
+func TestCategoryTreeNestsAndCountsProducts(t *testing.T) {
+	roots := categoryTree(categoryRepo().categories)
+
+	if len(roots) != 2 || roots[0].Id != "home" || roots[1].Id != "books" {
+		t.Fatalf("roots = %v; want home and books", roots)
+	}
+
+	home := roots[0]
+	if home.ProductCount != 7 {
+		t.Errorf("home product count = %d; want 7", home.ProductCount)
+	}
+	if len(home.Children) != 1 || home.Children[0].Id != "garden" {
+		t.Fatalf("home children = %v; want garden", home.Children)
+	}
+
+	garden := home.Children[0]
+	if garden.ProductCount != 6 || len(garden.Children) != 1 || garden.Children[0].ProductCount != 4 {
+		t.Errorf("garden = %v; want 6 products with tools below it", garden)
+	}
+	if roots[1].ProductCount != 8 {
+		t.Errorf("books product count = %d; want 8", roots[1].ProductCount)
+	}
+}
+
+func TestUpdateCategoryKeepsFieldsOutsideMask(t *testing.T) {
+	repo := categoryRepo()
+	s := &service{repo: repo}
+
+	response, err := s.UpdateCategory(context.Background(), &proto.UpdateCategoryRequest{
+		Id:         "garden",
+		Name:       "Garden & Patio",
+		UpdateMask: &fieldmaskpb.FieldMask{Paths: []string{"name"}},
+	})
+	if err != nil {
+		t.Fatalf("UpdateCategory() error = %v", err)
+	}
+
+	category := response.Category
+	if category.Name != "Garden & Patio" || category.Slug != "garden" || category.ParentId != "home" || category.Position != 2 {
+		t.Fatalf("category = %v; want only the name changed", category)
+	}
+}
+
+func TestUpdateCategoryMovesToTopLevelAndFirstPosition(t *testing.T) {
+	repo := categoryRepo()
+	s := &service{repo: repo}
+
+	response, err := s.UpdateCategory(context.Background(), &proto.UpdateCategoryRequest{
+		Id:         "garden",
+		Name:       "Garden",
+		UpdateMask: &fieldmaskpb.FieldMask{Paths: []string{"name", "parent_id", "position"}},
+	})
+	if err != nil {
+		t.Fatalf("UpdateCategory() error = %v", err)
+	}
+	if response.Category.ParentId != "" || response.Category.Position != 0 {
+		t.Fatalf("category = %v; want a top-level category at position 0", response.Category)
+	}
+}
+
+func TestUpdateCategoryRejectsMoveBelowDescendant(t *testing.T) {
+	s := &service{repo: categoryRepo()}
+
+	_, err := s.UpdateCategory(context.Background(), &proto.UpdateCategoryRequest{
+		Id:         "home",
+		Name:       "Home",
+		ParentId:   "tools",
+		UpdateMask: &fieldmaskpb.FieldMask{Paths: []string{"parent_id"}},
+	})
+	if status.Code(err) != codes.InvalidArgument {
+		t.Fatalf("UpdateCategory() error = %v; want InvalidArgument", err)
+	}
+}
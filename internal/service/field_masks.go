@@ -0,0 +1,49 @@
+package service
+
+import (
+	"slices"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/known/fieldmaskpb"
+)
+
+// Fields that the update RPCs accept in their update masks.
+var (
+	productUpdateFields = []string{
+		"sku", "name", "image", "category_id", "description", "rating", "number_of_reviews", "price",
+		"count_in_stock", "status",
+	}
+	userUpdateFields      = []string{"name"}
+	adminUserUpdateFields = []string{"name", "email"}
+	categoryUpdateFields  = []string{"parent_id", "name", "slug", "position"}
+	warehouseUpdateFields = []string{"name", "location", "priority", "active"}
+)
+
+// updatePaths returns the set of fields an update request changes. With a
+// mask these are its paths, which must all be in allowed. Without one they are
+// the allowed fields set to a non-zero value, as clients that predate field
+// masks expect.
+func updatePaths(mask *fieldmaskpb.FieldMask, req protoreflect.ProtoMessage, allowed []string) (map[string]bool, error) {
+	paths := make(map[string]bool)
+
+	if mask == nil {
+		message := req.ProtoReflect()
+		fields := message.Descriptor().Fields()
+		for _, name := range allowed {
+			if field := fields.ByName(protoreflect.Name(name)); field != nil && message.Has(field) {
+				paths[name] = true
+			}
+		}
+		return paths, nil
+	}
+
+	for _, path := range mask.GetPaths() {
+		if !slices.Contains(allowed, path) {
+			return nil, status.Errorf(codes.InvalidArgument, "field %q cannot be updated", path)
+		}
+		paths[path] = true
+	}
+	return paths, nil
+}
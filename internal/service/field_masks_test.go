@@ -0,0 +1,67 @@
+package service
+
+import (
+	"ecomm/proto"
+	"reflect"
+	"testing"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/fieldmaskpb"
+)
+
+func TestUpdatePaths(t *testing.T) {
+	tests := []struct {
+		name string
+		mask *fieldmaskpb.FieldMask
+		req  *proto.UpdateProductRequest
+		want map[string]bool
+	}{
+		{
+			"no mask uses non-zero fields",
+			nil,
+			&proto.UpdateProductRequest{Id: "product-1", Name: "Mug", Price: 0, Description: ""},
+			map[string]bool{"name": true},
+		},
+		{
+			"no mask and no fields",
+			nil,
+			&proto.UpdateProductRequest{Id: "product-1"},
+			map[string]bool{},
+		},
+		{
+			"mask allows zero values",
+			&fieldmaskpb.FieldMask{Paths: []string{"price", "description"}},
+			&proto.UpdateProductRequest{Id: "product-1", Name: "Mug"},
+			map[string]bool{"price": true, "description": true},
+		},
+		{
+			"empty mask updates nothing",
+			&fieldmaskpb.FieldMask{},
+			&proto.UpdateProductRequest{Id: "product-1", Name: "Mug"},
+			map[string]bool{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			paths, err := updatePaths(tt.mask, tt.req, productUpdateFields)
+			if err != nil {
+				t.Fatalf("updatePaths() error = %v", err)
+			}
+			if !reflect.DeepEqual(paths, tt.want) {
+				t.Fatalf("updatePaths() = %v; want %v", paths, tt.want)
+			}
+		})
+	}
+}
+
+func TestUpdatePathsRejectsFieldsOutsideAllowed(t *testing.T) {
+	for _, path := range []string{"id", "version", "email", "unknown"} {
+		mask := &fieldmaskpb.FieldMask{Paths: []string{"name", path}}
+		_, err := updatePaths(mask, &proto.UpdateUserRequest{}, userUpdateFields)
+		if status.Code(err) != codes.InvalidArgument {
+			t.Errorf("updatePaths(%q) error = %v; want InvalidArgument", path, err)
+		}
+	}
+}
@@ -0,0 +1,155 @@
+package service
+
+import (
+	"context"
+	"ecomm/internal/audit"
+	"ecomm/internal/controller/auth"
+	"ecomm/internal/domain"
+	"ecomm/pkg"
+	"ecomm/proto"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// authorizeUserAccess allows callers to act on their own account, and staff
+// holding permission to act on any account.
+func authorizeUserAccess(ctx context.Context, userID, permission string) error {
+	claims := auth.ClaimsFromContext(ctx)
+	if claims == nil {
+		return status.Error(codes.Unauthenticated, "missing credentials")
+	}
+
+	if claims.ID != "" && claims.ID == userID {
+		return nil
+	}
+
+	if !claims.HasPermission(permission) {
+		return status.Errorf(codes.PermissionDenied, "missing permission %s", permission)
+	}
+
+	return nil
+}
+
+func (s *service) getUserByID(id string) (*domain.User, error) {
+	user, err := s.repo.GetUserByID(id)
+	if err != nil {
+		if errors.Is(err, domain.ErrUserNotFound) {
+			return nil, status.Error(codes.NotFound, err.Error())
+		}
+		return nil, status.Errorf(codes.Internal, "failed to get user: %v", err)
+	}
+	return user, nil
+}
+
+// ExportUserData returns a JSON archive of the personal data held about a
+// user. Credentials such as password hashes, refresh tokens and MFA secrets
+// are left out.
+func (s *service) ExportUserData(ctx context.Context, req *proto.ExportUserDataRequest) (*proto.ExportUserDataResponse, error) {
+	if err := authorizeUserAccess(ctx, req.UserId, domain.PermissionUsersRead); err != nil {
+		return nil, err
+	}
+
+	user, err := s.getUserByID(req.UserId)
+	if err != nil {
+		return nil, err
+	}
+
+	export := domain.UserDataExport{
+		ExportedAt: uint64(time.Now().Unix()),
+		Profile: domain.UserProfileExport{
+			ID:        user.ID,
+			Name:      user.Name,
+			Email:     user.Email,
+			IsAdmin:   user.IsAdmin,
+			CreatedAt: user.CreatedAt,
+			UpdatedAt: user.UpdatedAt,
+		},
+	}
+
+	roles, err := s.repo.GetUserRoles(user.ID)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to get roles: %v", err)
+	}
+	for _, role := range roles {
+		export.Roles = append(export.Roles, role.Name)
+	}
+
+	if export.MFAEnabled, err = s.mfaEnabled(user.ID); err != nil {
+		return nil, err
+	}
+
+	if export.Identities, err = s.repo.ListUserIdentities(user.ID); err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to get identities: %v", err)
+	}
+
+	sessions, err := s.repo.ListUserSessions(user.Email)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to get sessions: %v", err)
+	}
+	for _, session := range sessions {
+		export.Sessions = append(export.Sessions, domain.SessionExport{
+			ID:        session.ID,
+			IsRevoked: session.IsRevoked,
+			CreatedAt: session.CreatedAt,
+			ExpiresAt: session.ExpiresAt,
+		})
+	}
+
+	if export.Orders, err = s.repo.ListUserOrders(user.ID); err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to get orders: %v", err)
+	}
+
+	if export.StockSubscriptions, err = s.repo.ListUserStockSubscriptions(user.ID); err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to get stock subscriptions: %v", err)
+	}
+
+	archive, err := json.MarshalIndent(export, "", "  ")
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to encode archive: %v", err)
+	}
+
+	return &proto.ExportUserDataResponse{
+		Archive: archive,
+	}, nil
+}
+
+// DeleteUser erases the account and its personal data. Orders are kept for
+// accounting but are no longer linked to the user. The audit event carries no
+// snapshot of the user so that the erased data does not live on in the
+// append-only audit log.
+func (s *service) DeleteUser(ctx context.Context, req *proto.DeleteUserRequest) (*proto.DeleteUserResponse, error) {
+	if err := authorizeUserAccess(ctx, req.UserId, domain.PermissionUsersDelete); err != nil {
+		return nil, err
+	}
+
+	user, err := s.getUserByID(req.UserId)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkVersion(req.ExpectedVersion, user.Version); err != nil {
+		return nil, err
+	}
+
+	if err := s.repo.EraseUser(user.ID, user.Version); err != nil {
+		if errors.Is(err, domain.ErrVersionMismatch) {
+			return nil, versionConflict(err.Error())
+		}
+		if errors.Is(err, domain.ErrUserNotFound) {
+			return nil, status.Error(codes.NotFound, err.Error())
+		}
+		return nil, status.Errorf(codes.Internal, "failed to erase user: %v", err)
+	}
+
+	for _, key := range []string{accountThrottleKey(user.Email), mfaThrottleKey(user.ID)} {
+		if err := s.repo.ClearLoginFailures(key); err != nil {
+			pkg.ErrorLogger.Printf("failed to clear login failures: %v", err)
+		}
+	}
+
+	audit.Record(ctx, "user", user.ID, nil, nil)
+	return &proto.DeleteUserResponse{}, nil
+}
@@ -0,0 +1,91 @@
+package service
+
+import (
+	"context"
+	"ecomm/internal/audit"
+	"ecomm/internal/controller/auth"
+	"ecomm/internal/domain"
+	"ecomm/proto"
+	"testing"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestDeleteUserErasesAccountWithoutAuditSnapshot(t *testing.T) {
+	repo := newFakeRepo(&domain.User{ID: "user-1", Name: "Ada", Email: "ada@example.com", Version: 3})
+	s := &service{repo: repo}
+
+	interceptor := audit.UnaryServerInterceptor(repo, AuditedMethods)
+	info := &grpc.UnaryServerInfo{FullMethod: proto.ApiService_DeleteUser_FullMethodName}
+	handler := func(ctx context.Context, req any) (any, error) {
+		return s.DeleteUser(ctx, req.(*proto.DeleteUserRequest))
+	}
+
+	ctx := auth.ContextWithClaims(context.Background(), &auth.Claims{ID: "user-1"})
+	if _, err := interceptor(ctx, &proto.DeleteUserRequest{UserId: "user-1", ExpectedVersion: 3}, info, handler); err != nil {
+		t.Fatalf("DeleteUser() error = %v", err)
+	}
+
+	if _, ok := repo.users["user-1"]; ok {
+		t.Fatal("user was not erased")
+	}
+	if len(repo.clearedThrottles) != 2 {
+		t.Errorf("cleared throttles = %v; want the account and MFA keys", repo.clearedThrottles)
+	}
+
+	if len(repo.auditEvents) != 1 {
+		t.Fatalf("recorded %d audit events; want 1", len(repo.auditEvents))
+	}
+	event := repo.auditEvents[0]
+	if event.Action != "user.delete" || event.TargetID != "user-1" || event.Before != nil || event.After != nil {
+		t.Fatalf("audit event = %+v; want user.delete without snapshots", event)
+	}
+}
+
+func TestDeleteUserRejections(t *testing.T) {
+	tests := []struct {
+		name    string
+		claims  *auth.Claims
+		version int64
+		code    codes.Code
+	}{
+		{"unauthenticated", nil, 0, codes.Unauthenticated},
+		{"other user", &auth.Claims{ID: "user-2"}, 0, codes.PermissionDenied},
+		{"stale version", &auth.Claims{ID: "user-1"}, 2, codes.Aborted},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := newFakeRepo(&domain.User{ID: "user-1", Email: "ada@example.com", Version: 3})
+			s := &service{repo: repo}
+
+			ctx := context.Background()
+			if tt.claims != nil {
+				ctx = auth.ContextWithClaims(ctx, tt.claims)
+			}
+
+			_, err := s.DeleteUser(ctx, &proto.DeleteUserRequest{UserId: "user-1", ExpectedVersion: tt.version})
+			if status.Code(err) != tt.code {
+				t.Fatalf("DeleteUser() error = %v; want %v", err, tt.code)
+			}
+			if _, ok := repo.users["user-1"]; !ok {
+				t.Fatal("user was erased")
+			}
+		})
+	}
+}
+
+func TestDeleteUserByStaffRequiresPermission(t *testing.T) {
+	repo := newFakeRepo(&domain.User{ID: "user-1", Email: "ada@example.com", Version: 1})
+	s := &service{repo: repo}
+
+	ctx := auth.ContextWithClaims(context.Background(), &auth.Claims{ID: "staff-1", Permissions: []string{domain.PermissionUsersDelete}})
+	if _, err := s.DeleteUser(ctx, &proto.DeleteUserRequest{UserId: "user-1"}); err != nil {
+		t.Fatalf("DeleteUser() error = %v", err)
+	}
+	if _, ok := repo.users["user-1"]; ok {
+		t.Fatal("user was not erased")
+	}
+}
@@ -0,0 +1,248 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"ecomm/internal/adapters"
+	"ecomm/internal/audit"
+	"ecomm/internal/domain"
+	"ecomm/internal/imaging"
+	"ecomm/pkg"
+	"ecomm/proto"
+	"errors"
+	"image"
+	_ "image/gif" // registers the GIF decoder
+	"image/jpeg"
+	"image/png"
+	"io"
+	"net/http"
+	"slices"
+
+	"github.com/google/uuid"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	maxImageBytes = 5 << 20
+	// maxImagePixels guards against small files that decode to huge images.
+	maxImagePixels = 40_000_000
+
+	thumbnailSize = 320
+	mediumSize    = 1024
+)
+
+// MaxMessageBytes is the largest request the gRPC server has to accept, which
+// is an image upload of the maximum size plus its other fields.
+const MaxMessageBytes = maxImageBytes + 1<<20
+
+type imageFormat struct {
+	extension string
+	// encode writes the resized renditions. GIFs are resized to PNG so that
+	// they keep their transparency without being reduced to a palette.
+	encode           func(w io.Writer, img image.Image) error
+	renditionExt     string
+	renditionContent string
+}
+
+var imageFormats = map[string]imageFormat{
+	"image/jpeg": {
+		extension: ".jpg",
+		encode: func(w io.Writer, img image.Image) error {
+			return jpeg.Encode(w, img, &jpeg.Options{Quality: 85})
+		},
+		renditionExt:     ".jpg",
+		renditionContent: "image/jpeg",
+	},
+	"image/png": {
+		extension:        ".png",
+		encode:           png.Encode,
+		renditionExt:     ".png",
+		renditionContent: "image/png",
+	},
+	"image/gif": {
+		extension:        ".gif",
+		encode:           png.Encode,
+		renditionExt:     ".png",
+		renditionContent: "image/png",
+	},
+}
+
+func imageError(err error) error {
+	switch {
+	case errors.Is(err, domain.ErrProductNotFound), errors.Is(err, domain.ErrImageNotFound):
+		return status.Error(codes.NotFound, err.Error())
+	default:
+		return status.Errorf(codes.Internal, "failed to save product image: %v", err)
+	}
+}
+
+// resolveImageURLs fills in the download URLs of the images.
+func (s *service) resolveImageURLs(images []*domain.ProductImage) {
+	for _, image := range images {
+		image.URL = s.storage.URL(image.StorageKey)
+		image.ThumbnailURL = s.storage.URL(image.ThumbnailKey)
+		image.MediumURL = s.storage.URL(image.MediumKey)
+	}
+}
+
+// deleteImageFiles removes the stored files of deleted images. Failures only
+// leave orphaned files behind, so they are logged.
+func (s *service) deleteImageFiles(ctx context.Context, images ...*domain.ProductImage) {
+	for _, image := range images {
+		for _, key := range []string{image.StorageKey, image.ThumbnailKey, image.MediumKey} {
+			if err := s.storage.Delete(ctx, key); err != nil {
+				pkg.ErrorLogger.Printf("failed to delete image file %s: %v", key, err)
+			}
+		}
+	}
+}
+
+// UploadProductImage validates the uploaded image, stores it together with a
+// thumbnail and a medium-sized rendition, and appends it to the product's
+// gallery.
+func (s *service) UploadProductImage(ctx context.Context, req *proto.UploadProductImageRequest) (*proto.UploadProductImageResponse, error) {
+	if len(req.Data) == 0 {
+		return nil, status.Error(codes.InvalidArgument, "image is empty")
+	}
+	if len(req.Data) > maxImageBytes {
+		return nil, status.Errorf(codes.InvalidArgument, "image must be at most %d MiB", maxImageBytes>>20)
+	}
+
+	contentType := http.DetectContentType(req.Data)
+	format, ok := imageFormats[contentType]
+	if !ok {
+		return nil, status.Error(codes.InvalidArgument, "image must be a JPEG, PNG or GIF")
+	}
+	if declared := req.ContentType; declared != "" && declared != "application/octet-stream" && declared != contentType {
+		return nil, status.Errorf(codes.InvalidArgument, "declared content type %s does not match the image data", req.ContentType)
+	}
+
+	config, _, err := image.DecodeConfig(bytes.NewReader(req.Data))
+	if err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "invalid image: %v", err)
+	}
+	if config.Width*config.Height > maxImagePixels {
+		return nil, status.Error(codes.InvalidArgument, "image dimensions are too large")
+	}
+
+	decoded, _, err := image.Decode(bytes.NewReader(req.Data))
+	if err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "invalid image: %v", err)
+	}
+
+	product, err := s.getProduct(req.ProductId)
+	if err != nil {
+		return nil, err
+	}
+
+	imageID := uuid.New().String()
+	prefix := "products/" + product.ID + "/" + imageID + "/"
+	productImage := &domain.ProductImage{
+		ID:           imageID,
+		ProductID:    product.ID,
+		StorageKey:   prefix + "original" + format.extension,
+		ThumbnailKey: prefix + "thumbnail" + format.renditionExt,
+		MediumKey:    prefix + "medium" + format.renditionExt,
+		ContentType:  contentType,
+		Width:        config.Width,
+		Height:       config.Height,
+		SizeBytes:    int64(len(req.Data)),
+		AltText:      req.AltText,
+	}
+
+	files := map[string][]byte{productImage.StorageKey: req.Data}
+	for key, size := range map[string]int{productImage.ThumbnailKey: thumbnailSize, productImage.MediumKey: mediumSize} {
+		var buf bytes.Buffer
+		if err := format.encode(&buf, imaging.Fit(decoded, size, size)); err != nil {
+			return nil, status.Errorf(codes.Internal, "failed to resize image: %v", err)
+		}
+		files[key] = buf.Bytes()
+	}
+
+	for key, data := range files {
+		fileType := format.renditionContent
+		if key == productImage.StorageKey {
+			fileType = contentType
+		}
+		if err := s.storage.Put(ctx, key, bytes.NewReader(data), fileType); err != nil {
+			s.deleteImageFiles(ctx, productImage)
+			return nil, status.Errorf(codes.Internal, "failed to store image: %v", err)
+		}
+	}
+
+	if err := s.repo.CreateProductImage(productImage); err != nil {
+		s.deleteImageFiles(ctx, productImage)
+		return nil, imageError(err)
+	}
+
+	s.resolveImageURLs([]*domain.ProductImage{productImage})
+	audit.Record(ctx, "product_image", productImage.ID, nil, productImage)
+
+	return &proto.UploadProductImageResponse{
+		Image: adapters.ToProtoProductImage(*productImage),
+	}, nil
+}
+
+func (s *service) DeleteProductImage(ctx context.Context, req *proto.DeleteProductImageRequest) (*proto.DeleteProductImageResponse, error) {
+	productImage, err := s.repo.GetProductImage(req.Id)
+	if err != nil || productImage.ProductID != req.ProductId {
+		if err == nil || errors.Is(err, domain.ErrImageNotFound) {
+			return nil, status.Error(codes.NotFound, domain.ErrImageNotFound.Error())
+		}
+		return nil, status.Errorf(codes.Internal, "failed to get product image: %v", err)
+	}
+
+	if err := s.repo.DeleteProductImage(productImage.ID); err != nil {
+		return nil, imageError(err)
+	}
+
+	s.deleteImageFiles(ctx, productImage)
+	audit.Record(ctx, "product_image", productImage.ID, productImage, nil)
+
+	return &proto.DeleteProductImageResponse{
+		Id: productImage.ID,
+	}, nil
+}
+
+func (s *service) ReorderProductImages(ctx context.Context, req *proto.ReorderProductImagesRequest) (*proto.ReorderProductImagesResponse, error) {
+	product, err := s.getProduct(req.ProductId)
+	if err != nil {
+		return nil, err
+	}
+
+	current := make([]string, len(product.Images))
+	for i, image := range product.Images {
+		current[i] = image.ID
+	}
+
+	sortedCurrent := slices.Sorted(slices.Values(current))
+	sortedRequested := slices.Sorted(slices.Values(req.ImageIds))
+	if !slices.Equal(sortedCurrent, sortedRequested) {
+		return nil, status.Error(codes.InvalidArgument, "image_ids must list every image of the product exactly once")
+	}
+
+	if err := s.repo.ReorderProductImages(product.ID, req.ImageIds); err != nil {
+		return nil, imageError(err)
+	}
+
+	byID := make(map[string]*domain.ProductImage, len(product.Images))
+	for _, image := range product.Images {
+		byID[image.ID] = image
+	}
+
+	images := make([]*domain.ProductImage, len(req.ImageIds))
+	for i, id := range req.ImageIds {
+		images[i] = byID[id]
+		images[i].Position = i
+	}
+	s.resolveImageURLs(images)
+
+	audit.Record(ctx, "product", product.ID,
+		map[string]any{"image_ids": current},
+		map[string]any{"image_ids": req.ImageIds})
+
+	return &proto.ReorderProductImagesResponse{
+		Images: adapters.ToProtoProductImages(images),
+	}, nil
+}
@@ -0,0 +1,106 @@
+package service
+
+import (
+	"context"
+	"ecomm/internal/audit"
+	"ecomm/internal/controller/auth"
+	"ecomm/internal/domain"
+	"ecomm/proto"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const impersonationTokenTTL = 15 * time.Minute
+
+// ImpersonationBlockedMethods lists the RPCs that cannot be called with an
+// impersonation token because they change the user's credentials, sessions or
+// account.
+var ImpersonationBlockedMethods = map[string]bool{
+	proto.ApiService_UpdateUser_FullMethodName:         true,
+	proto.ApiService_ChangePassword_FullMethodName:     true,
+	proto.ApiService_RequestEmailChange_FullMethodName: true,
+	proto.ApiService_DeleteUser_FullMethodName:         true,
+	proto.ApiService_ExportUserData_FullMethodName:     true,
+	proto.ApiService_RefreshToken_FullMethodName:       true,
+	proto.ApiService_RevokeSession_FullMethodName:      true,
+	proto.ApiService_EnrollMFA_FullMethodName:          true,
+	proto.ApiService_ConfirmMFA_FullMethodName:         true,
+	proto.ApiService_DisableMFA_FullMethodName:         true,
+	proto.ApiService_CreateAPIKey_FullMethodName:       true,
+	proto.ApiService_RevokeAPIKey_FullMethodName:       true,
+	proto.ApiService_ImpersonateUser_FullMethodName:    true,
+}
+
+// ImpersonateUser issues a short-lived access token that lets an admin act as
+// a customer. The token cannot be refreshed and every issuance is recorded in
+// the audit trail.
+func (s *service) ImpersonateUser(ctx context.Context, req *proto.ImpersonateUserRequest) (*proto.ImpersonateUserResponse, error) {
+	actor := auth.ClaimsFromContext(ctx)
+	if actor == nil || actor.ID == "" || actor.Impersonating() {
+		return nil, status.Error(codes.PermissionDenied, "impersonation requires an admin user session")
+	}
+
+	if req.Reason == "" {
+		return nil, status.Error(codes.InvalidArgument, "a reason is required")
+	}
+
+	if req.UserId == actor.ID {
+		return nil, status.Error(codes.InvalidArgument, "cannot impersonate yourself")
+	}
+
+	user, err := s.repo.GetUserByID(req.UserId)
+	if err != nil {
+		if errors.Is(err, domain.ErrUserNotFound) {
+			return nil, status.Error(codes.NotFound, err.Error())
+		}
+		return nil, status.Errorf(codes.Internal, "failed to get user: %v", err)
+	}
+
+	// Impersonating a privileged user would let the actor borrow rights they
+	// were not granted, so only customers can be impersonated.
+	permissions, err := s.userPermissions(user)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to get permissions: %v", err)
+	}
+	if len(permissions) > 0 {
+		return nil, status.Error(codes.PermissionDenied, "users with admin rights or roles cannot be impersonated")
+	}
+
+	tokenID, err := uuid.NewUUID()
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to generate token id: %v", err)
+	}
+
+	expiresAt := time.Now().Add(impersonationTokenTTL)
+	accessToken, _, err := s.jwtManager.GenerateImpersonationToken(user.Email, user.ID, tokenID.String(), nil, auth.Actor{
+		ID:    actor.ID,
+		Email: actor.Email,
+	}, expiresAt)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to generate token: %v", err)
+	}
+
+	if err := s.repo.CreateAuditEvent(&domain.AuditEvent{
+		ActorID:    actor.ID,
+		Action:     "user.impersonate",
+		TargetType: "user",
+		TargetID:   user.ID,
+		RequestID:  audit.RequestID(ctx),
+		Metadata: map[string]any{
+			"reason":     req.Reason,
+			"token_id":   tokenID.String(),
+			"expires_at": expiresAt.Unix(),
+		},
+	}); err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to record impersonation: %v", err)
+	}
+
+	return &proto.ImpersonateUserResponse{
+		AccessToken: accessToken,
+		ExpiresAt:   uint64(expiresAt.Unix()),
+	}, nil
+}
@@ -0,0 +1,111 @@
+package service
+
+import (
+	"context"
+	"ecomm/internal/controller/auth"
+	"ecomm/internal/domain"
+	"ecomm/proto"
+	"testing"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func newImpersonationService(t *testing.T) (*service, *fakeRepo) {
+	t.Helper()
+	t.Setenv("JWT_KEY", "test-key")
+
+	jwtManager, err := auth.NewTokenGenerator()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	repo := newFakeRepo(
+		&domain.User{ID: "admin-1", Email: "admin@example.com", IsAdmin: true},
+		&domain.User{ID: "customer-1", Email: "jane@example.com"},
+		&domain.User{ID: "staff-1", Email: "staff@example.com"},
+	)
+	repo.permissions = map[string][]string{"staff-1": {domain.PermissionOrdersRead}}
+	return &service{repo: repo, jwtManager: jwtManager}, repo
+}
+
+func TestImpersonateUserIssuesTokenForCustomer(t *testing.T) {
+	s, repo := newImpersonationService(t)
+
+	ctx := auth.ContextWithClaims(context.Background(), &auth.Claims{ID: "admin-1", Email: "admin@example.com"})
+	response, err := s.ImpersonateUser(ctx, &proto.ImpersonateUserRequest{UserId: "customer-1", Reason: "ticket 42"})
+	if err != nil {
+		t.Fatalf("ImpersonateUser() error = %v", err)
+	}
+
+	claims, err := s.jwtManager.ValidateToken(response.AccessToken)
+	if err != nil {
+		t.Fatalf("ValidateToken() error = %v", err)
+	}
+	if claims.ID != "customer-1" || claims.IsAdmin || len(claims.Permissions) != 0 {
+		t.Fatalf("claims = %+v; want an unprivileged token for customer-1", claims)
+	}
+	if claims.Act == nil || claims.Act.ID != "admin-1" {
+		t.Fatalf("act = %+v; want admin-1", claims.Act)
+	}
+
+	if len(repo.auditEvents) != 1 {
+		t.Fatalf("recorded %d audit events; want 1", len(repo.auditEvents))
+	}
+	event := repo.auditEvents[0]
+	if event.Action != "user.impersonate" || event.ActorID != "admin-1" || event.TargetID != "customer-1" || event.Metadata["reason"] != "ticket 42" {
+		t.Fatalf("audit event = %+v", event)
+	}
+}
+
+func TestImpersonateUserRejections(t *testing.T) {
+	admin := &auth.Claims{ID: "admin-1", Email: "admin@example.com"}
+	impersonating := &auth.Claims{ID: "customer-1", Act: &auth.Actor{ID: "admin-1"}}
+
+	tests := []struct {
+		name   string
+		claims *auth.Claims
+		req    *proto.ImpersonateUserRequest
+		want   codes.Code
+	}{
+		{"anonymous", nil, &proto.ImpersonateUserRequest{UserId: "customer-1", Reason: "r"}, codes.PermissionDenied},
+		{"api key", &auth.Claims{APIKeyID: "key-1"}, &proto.ImpersonateUserRequest{UserId: "customer-1", Reason: "r"}, codes.PermissionDenied},
+		{"already impersonating", impersonating, &proto.ImpersonateUserRequest{UserId: "staff-1", Reason: "r"}, codes.PermissionDenied},
+		{"no reason", admin, &proto.ImpersonateUserRequest{UserId: "customer-1"}, codes.InvalidArgument},
+		{"self", admin, &proto.ImpersonateUserRequest{UserId: "admin-1", Reason: "r"}, codes.InvalidArgument},
+		{"unknown user", admin, &proto.ImpersonateUserRequest{UserId: "missing", Reason: "r"}, codes.NotFound},
+		{"user with a role", admin, &proto.ImpersonateUserRequest{UserId: "staff-1", Reason: "r"}, codes.PermissionDenied},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, repo := newImpersonationService(t)
+
+			ctx := context.Background()
+			if tt.claims != nil {
+				ctx = auth.ContextWithClaims(ctx, tt.claims)
+			}
+			if _, err := s.ImpersonateUser(ctx, tt.req); status.Code(err) != tt.want {
+				t.Fatalf("ImpersonateUser() error = %v; want %v", err, tt.want)
+			}
+			if len(repo.auditEvents) != 0 {
+				t.Fatalf("recorded %d audit events; want none", len(repo.auditEvents))
+			}
+		})
+	}
+}
+
+func TestImpersonationTokensCannotCallBlockedMethods(t *testing.T) {
+	interceptor := auth.UnaryImpersonationInterceptor(ImpersonationBlockedMethods)
+	ctx := auth.ContextWithClaims(context.Background(), &auth.Claims{ID: "customer-1", Act: &auth.Actor{ID: "admin-1"}})
+
+	for method := range ImpersonationBlockedMethods {
+		_, err := interceptor(ctx, nil, &grpc.UnaryServerInfo{FullMethod: method}, func(ctx context.Context, req any) (any, error) {
+			return nil, nil
+		})
+		if status.Code(err) != codes.PermissionDenied {
+			t.Errorf("%s error = %v; want PermissionDenied", method, err)
+		}
+	}
+}
@@ -0,0 +1,153 @@
+package service
+
+import (
+	"context"
+	"ecomm/internal/adapters"
+	"ecomm/internal/audit"
+	"ecomm/internal/controller/auth"
+	"ecomm/internal/domain"
+	"ecomm/proto"
+	"errors"
+	"strings"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	defaultStockMovementLimit = 50
+	maxStockMovementLimit     = 500
+)
+
+func inventoryError(err error) error {
+	switch {
+	case errors.Is(err, domain.ErrProductNotFound), errors.Is(err, domain.ErrVariantNotFound):
+		return status.Error(codes.NotFound, err.Error())
+	case errors.Is(err, domain.ErrWarehouseNotFound):
+		return status.Error(codes.NotFound, err.Error())
+	case errors.Is(err, domain.ErrInsufficientStock), errors.Is(err, domain.ErrNoWarehouse),
+		errors.Is(err, domain.ErrWarehouseInactive):
+		return status.Error(codes.FailedPrecondition, err.Error())
+	default:
+		return status.Errorf(codes.Internal, "failed to record stock movement: %v", err)
+	}
+}
+
+// actorID returns the user making the call, which is the admin rather than
+// the impersonated user during impersonation. It is empty for API keys.
+func actorID(ctx context.Context) string {
+	claims := auth.ClaimsFromContext(ctx)
+	if claims == nil {
+		return ""
+	}
+	if claims.Impersonating() {
+		return claims.Act.ID
+	}
+	return claims.ID
+}
+
+// stockTarget returns the adjustment that sets the stock of a product or
+// variant to target. The repository computes its quantity from the stock
+// when it applies it.
+func stockTarget(ctx context.Context, productID, variantID string, target int, reason string) *domain.InventoryMovement {
+	return &domain.InventoryMovement{
+		ProductID: productID,
+		VariantID: variantID,
+		Type:      domain.MovementAdjustment,
+		Reason:    reason,
+		ActorID:   actorID(ctx),
+		SetTo:     &target,
+	}
+}
+
+// AdjustStock records a manual stock movement. Receipts and returns add
+// stock; adjustments correct it in either direction and need a reason.
+func (s *service) AdjustStock(ctx context.Context, req *proto.AdjustStockRequest) (*proto.AdjustStockResponse, error) {
+	reason := strings.TrimSpace(req.Reason)
+
+	switch req.Type {
+	case domain.MovementReceipt, domain.MovementReturn:
+		if req.Quantity <= 0 {
+			return nil, status.Errorf(codes.InvalidArgument, "quantity of a %s must be positive", req.Type)
+		}
+	case domain.MovementAdjustment:
+		if req.Quantity == 0 {
+			return nil, status.Error(codes.InvalidArgument, "quantity must not be zero")
+		}
+		if reason == "" {
+			return nil, status.Error(codes.InvalidArgument, "adjustments require a reason")
+		}
+	default:
+		return nil, status.Error(codes.InvalidArgument, "type must be receipt, return or adjustment")
+	}
+
+	movement := &domain.InventoryMovement{
+		ProductID:   req.ProductId,
+		VariantID:   req.VariantId,
+		WarehouseID: req.WarehouseId,
+		Type:        req.Type,
+		Quantity:    int(req.Quantity),
+		Reason:      reason,
+		ActorID:     actorID(ctx),
+	}
+	if err := s.repo.RecordInventoryMovement(movement); err != nil {
+		return nil, inventoryError(err)
+	}
+
+	audit.Record(ctx, "inventory_movement", movement.ID, nil, movement)
+
+	return &proto.AdjustStockResponse{
+		Movement: adapters.ToProtoInventoryMovement(*movement),
+	}, nil
+}
+
+func (s *service) ListStockMovements(ctx context.Context, req *proto.ListStockMovementsRequest) (*proto.ListStockMovementsResponse, error) {
+	limit := int(req.Limit)
+	if limit <= 0 {
+		limit = defaultStockMovementLimit
+	}
+	if limit > maxStockMovementLimit {
+		limit = maxStockMovementLimit
+	}
+
+	if req.Offset < 0 {
+		return nil, status.Error(codes.InvalidArgument, "offset must not be negative")
+	}
+
+	if _, err := s.getProduct(req.ProductId); err != nil {
+		return nil, err
+	}
+
+	movements, err := s.repo.ListInventoryMovements(&domain.InventoryMovementFilter{
+		ProductID:   req.ProductId,
+		VariantID:   req.VariantId,
+		WarehouseID: req.WarehouseId,
+		Type:        req.Type,
+		Limit:       limit,
+		Offset:      int(req.Offset),
+	})
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to list stock movements: %v", err)
+	}
+
+	return &proto.ListStockMovementsResponse{
+		Movements: adapters.ToProtoInventoryMovements(movements),
+	}, nil
+}
+
+// ReconcileStock reports products and variants whose stock has drifted from
+// their ledger and, if asked to, resets them to the ledger total.
+func (s *service) ReconcileStock(ctx context.Context, req *proto.ReconcileStockRequest) (*proto.ReconcileStockResponse, error) {
+	discrepancies, err := s.repo.ReconcileStock(req.Apply)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to reconcile stock: %v", err)
+	}
+
+	audit.AddMetadata(ctx, "applied", req.Apply)
+	audit.AddMetadata(ctx, "discrepancies", len(discrepancies))
+
+	return &proto.ReconcileStockResponse{
+		Discrepancies: adapters.ToProtoStockDiscrepancies(discrepancies),
+		Applied:       req.Apply,
+	}, nil
+}
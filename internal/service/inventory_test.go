@@ -0,0 +1,137 @@
+package service
+
+import (
+	"context"
+	"ecomm/internal/domain"
+	"ecomm/proto"
+	"testing"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/fieldmaskpb"
+)
+
+func inventoryRepo() *fakeRepo {
+	repo := newFakeRepo()
+	repo.products = map[string]*domain.Product{
+		"product-1": {ID: "product-1", Name: "Mug", CountInStock: 4, Status: domain.ProductActive, Version: 1},
+	}
+	return repo
+}
+
+func TestUpdateProductSetsStockWithTheUpdate(t *testing.T) {
+	repo := inventoryRepo()
+	s := &service{repo: repo}
+
+	res, err := s.UpdateProduct(context.Background(), &proto.UpdateProductRequest{
+		Id:           "product-1",
+		CountInStock: 7,
+		UpdateMask:   &fieldmaskpb.FieldMask{Paths: []string{"count_in_stock"}},
+	})
+	if err != nil {
+		t.Fatalf("UpdateProduct() error = %v", err)
+	}
+	if res.Product.CountInStock != 7 {
+		t.Fatalf("count_in_stock = %d; want 7", res.Product.CountInStock)
+	}
+
+	if len(repo.stockMovements) != 1 {
+		t.Fatalf("recorded %d movements; want 1", len(repo.stockMovements))
+	}
+	movement := repo.stockMovements[0]
+	if movement.Type != domain.MovementAdjustment || movement.SetTo == nil || *movement.SetTo != 7 {
+		t.Fatalf("movement = %+v; want an adjustment to 7", movement)
+	}
+}
+
+func TestUpdateProductWithoutStockRecordsNoMovement(t *testing.T) {
+	repo := inventoryRepo()
+	s := &service{repo: repo}
+
+	_, err := s.UpdateProduct(context.Background(), &proto.UpdateProductRequest{
+		Id:         "product-1",
+		Name:       "Cup",
+		UpdateMask: &fieldmaskpb.FieldMask{Paths: []string{"name"}},
+	})
+	if err != nil {
+		t.Fatalf("UpdateProduct() error = %v", err)
+	}
+	if len(repo.stockMovements) != 0 {
+		t.Fatalf("recorded %d movements; want none", len(repo.stockMovements))
+	}
+	if stock := repo.products["product-1"].CountInStock; stock != 4 {
+		t.Fatalf("count_in_stock = %d; want 4", stock)
+	}
+}
+
+func TestUpdateProductRejectsNegativeStock(t *testing.T) {
+	repo := inventoryRepo()
+	s := &service{repo: repo}
+
+	_, err := s.UpdateProduct(context.Background(), &proto.UpdateProductRequest{
+		Id:           "product-1",
+		CountInStock: -1,
+		UpdateMask:   &fieldmaskpb.FieldMask{Paths: []string{"count_in_stock"}},
+	})
+	if status.Code(err) != codes.InvalidArgument {
+		t.Fatalf("UpdateProduct() error = %v; want InvalidArgument", err)
+	}
+	if repo.products["product-1"].Version != 1 {
+		t.Fatal("product was updated")
+	}
+}
+
+func TestAdjustStock(t *testing.T) {
+	tests := []struct {
+		name    string
+		req     *proto.AdjustStockRequest
+		code    codes.Code
+		balance int
+	}{
+		{"receipt", &proto.AdjustStockRequest{Type: domain.MovementReceipt, Quantity: 3}, codes.OK, 7},
+		{"adjustment down", &proto.AdjustStockRequest{Type: domain.MovementAdjustment, Quantity: -4, Reason: "damaged"}, codes.OK, 0},
+		{"negative receipt", &proto.AdjustStockRequest{Type: domain.MovementReceipt, Quantity: -1}, codes.InvalidArgument, 4},
+		{"adjustment without reason", &proto.AdjustStockRequest{Type: domain.MovementAdjustment, Quantity: 1, Reason: " "}, codes.InvalidArgument, 4},
+		{"zero adjustment", &proto.AdjustStockRequest{Type: domain.MovementAdjustment, Reason: "count"}, codes.InvalidArgument, 4},
+		{"sale", &proto.AdjustStockRequest{Type: domain.MovementSale, Quantity: -1}, codes.InvalidArgument, 4},
+		{"below zero", &proto.AdjustStockRequest{Type: domain.MovementAdjustment, Quantity: -5, Reason: "lost"}, codes.FailedPrecondition, 4},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := inventoryRepo()
+			s := &service{repo: repo}
+
+			tt.req.ProductId = "product-1"
+			res, err := s.AdjustStock(context.Background(), tt.req)
+			if status.Code(err) != tt.code {
+				t.Fatalf("AdjustStock() error = %v; want %v", err, tt.code)
+			}
+			if err == nil && res.Movement.Balance != int32(tt.balance) {
+				t.Errorf("balance = %d; want %d", res.Movement.Balance, tt.balance)
+			}
+			if stock := repo.products["product-1"].CountInStock; stock != tt.balance {
+				t.Errorf("count_in_stock = %d; want %d", stock, tt.balance)
+			}
+		})
+	}
+}
+
+func TestReconcileStockPassesApply(t *testing.T) {
+	for _, apply := range []bool{false, true} {
+		repo := inventoryRepo()
+		repo.discrepancies = []*domain.StockDiscrepancy{{ProductID: "product-1", Recorded: 4, Ledger: 3}}
+		s := &service{repo: repo}
+
+		res, err := s.ReconcileStock(context.Background(), &proto.ReconcileStockRequest{Apply: apply})
+		if err != nil {
+			t.Fatalf("ReconcileStock(%v) error = %v", apply, err)
+		}
+		if len(repo.reconcileApplied) != 1 || repo.reconcileApplied[0] != apply {
+			t.Fatalf("repository called with %v; want [%v]", repo.reconcileApplied, apply)
+		}
+		if res.Applied != apply || len(res.Discrepancies) != 1 || res.Discrepancies[0].Ledger != 3 {
+			t.Fatalf("response = %+v", res)
+		}
+	}
+}
@@ -0,0 +1,161 @@
+package service
+
+import (
+	"context"
+	"ecomm/internal/audit"
+	"ecomm/internal/domain"
+	"ecomm/pkg"
+	"ecomm/proto"
+	"errors"
+	"strings"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// loginFailureWindow is how long a failed attempt counts against an account or
+// address before the counter starts over.
+const loginFailureWindow = 15 * time.Minute
+
+type throttlePolicy struct {
+	// freeAttempts failures are allowed before any delay is enforced.
+	freeAttempts int
+	// lockoutThreshold failures lock the key for lockoutDuration.
+	lockoutThreshold int
+	lockoutDuration  time.Duration
+}
+
+// Addresses get a more generous policy than accounts because many users can
+// share one address behind a NAT or proxy.
+var (
+	accountThrottlePolicy = throttlePolicy{freeAttempts: 3, lockoutThreshold: 10, lockoutDuration: 15 * time.Minute}
+	ipThrottlePolicy      = throttlePolicy{freeAttempts: 10, lockoutThreshold: 50, lockoutDuration: 15 * time.Minute}
+)
+
+// backoff returns how long the key stays locked after the given number of
+// consecutive failures. The delay doubles with every failure past the free
+// attempts until the lockout threshold is reached.
+func (p throttlePolicy) backoff(failures int) time.Duration {
+	if failures < p.freeAttempts {
+		return 0
+	}
+	if failures >= p.lockoutThreshold {
+		return p.lockoutDuration
+	}
+
+	// Shifting by more than this would overflow long before reaching any
+	// sensible lockout duration.
+	shift := failures - p.freeAttempts
+	if shift > 20 {
+		return p.lockoutDuration
+	}
+	return min(time.Second<<shift, p.lockoutDuration)
+}
+
+func accountThrottleKey(email string) string {
+	return "account:" + strings.ToLower(strings.TrimSpace(email))
+}
+
+func mfaThrottleKey(userID string) string {
+	return "mfa:" + userID
+}
+
+func ipThrottleKey(ip string) string {
+	return "ip:" + ip
+}
+
+type loginThrottle struct {
+	key    string
+	policy throttlePolicy
+}
+
+func loginThrottles(email, clientIP string) []loginThrottle {
+	throttles := []loginThrottle{{key: accountThrottleKey(email), policy: accountThrottlePolicy}}
+	if clientIP != "" {
+		throttles = append(throttles, loginThrottle{key: ipThrottleKey(clientIP), policy: ipThrottlePolicy})
+	}
+	return throttles
+}
+
+// checkLoginThrottles rejects the attempt if any of the keys is locked.
+func (s *service) checkLoginThrottles(throttles []loginThrottle) error {
+	now := uint64(time.Now().Unix())
+	for _, throttle := range throttles {
+		state, err := s.repo.GetLoginThrottle(throttle.key)
+		if err != nil {
+			return status.Errorf(codes.Internal, "failed to check login attempts: %v", err)
+		}
+		if state.LockedUntil > now {
+			return status.Error(codes.ResourceExhausted, domain.ErrTooManyLoginAttempts.Error())
+		}
+	}
+	return nil
+}
+
+func (s *service) recordLoginFailure(throttles []loginThrottle) {
+	now := time.Now()
+	windowStart := uint64(now.Add(-loginFailureWindow).Unix())
+	for _, throttle := range throttles {
+		state, err := s.repo.RecordLoginFailure(throttle.key, windowStart)
+		if err != nil {
+			pkg.ErrorLogger.Printf("failed to record login failure: %v", err)
+			continue
+		}
+
+		if delay := throttle.policy.backoff(state.Failures); delay > 0 {
+			if err := s.repo.LockLogin(throttle.key, uint64(now.Add(delay).Unix())); err != nil {
+				pkg.ErrorLogger.Printf("failed to lock login: %v", err)
+			}
+		}
+	}
+}
+
+// authenticate verifies the credentials, enforcing the per-account and
+// per-address throttles. Unknown emails and wrong passwords produce the same
+// error.
+func (s *service) authenticate(email, password, clientIP string) (*domain.User, error) {
+	throttles := loginThrottles(email, clientIP)
+	if err := s.checkLoginThrottles(throttles); err != nil {
+		return nil, err
+	}
+
+	user, err := s.repo.GetUser(email)
+	if err != nil {
+		if !errors.Is(err, domain.ErrUserNotFound) {
+			return nil, status.Errorf(codes.Internal, "failed to get user: %v", err)
+		}
+		s.compareDummyHash(password)
+		s.recordLoginFailure(throttles)
+		return nil, status.Error(codes.Unauthenticated, domain.ErrInvalidCredentials.Error())
+	}
+
+	if !s.verifyPassword(user, password) {
+		s.recordLoginFailure(throttles)
+		return nil, status.Error(codes.Unauthenticated, domain.ErrInvalidCredentials.Error())
+	}
+
+	if err := s.repo.ClearLoginFailures(accountThrottleKey(email)); err != nil {
+		pkg.ErrorLogger.Printf("failed to clear login failures: %v", err)
+	}
+
+	return user, nil
+}
+
+func (s *service) UnlockAccount(ctx context.Context, req *proto.UnlockAccountRequest) (*proto.UnlockAccountResponse, error) {
+	user, err := s.repo.GetUserByID(req.UserId)
+	if err != nil {
+		if errors.Is(err, domain.ErrUserNotFound) {
+			return nil, status.Error(codes.NotFound, err.Error())
+		}
+		return nil, status.Errorf(codes.Internal, "failed to get user: %v", err)
+	}
+
+	if err := s.repo.ClearLoginFailures(accountThrottleKey(user.Email)); err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to unlock account: %v", err)
+	}
+
+	audit.Record(ctx, "user", user.ID, nil, nil)
+
+	return &proto.UnlockAccountResponse{}, nil
+}
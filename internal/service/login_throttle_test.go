@@ -0,0 +1,39 @@
+package service
+
+import (
+	"testing"
+	"time"
+)
+
+func TestThrottlePolicyBackoff(t *testing.T) {
+	policy := throttlePolicy{freeAttempts: 3, lockoutThreshold: 10, lockoutDuration: 15 * time.Minute}
+
+	tests := []struct {
+		failures int
+		want     time.Duration
+	}{
+		{0, 0},
+		{2, 0},
+		{3, time.Second},
+		{4, 2 * time.Second},
+		{9, 64 * time.Second},
+		{10, 15 * time.Minute},
+		{100, 15 * time.Minute},
+	}
+
+	for _, tt := range tests {
+		if got := policy.backoff(tt.failures); got != tt.want {
+			t.Errorf("backoff(%d) = %v; want %v", tt.failures, got, tt.want)
+		}
+	}
+}
+
+func TestThrottlePolicyBackoffIsCappedByLockout(t *testing.T) {
+	policy := throttlePolicy{freeAttempts: 0, lockoutThreshold: 1000, lockoutDuration: time.Minute}
+
+	for _, failures := range []int{6, 7, 21, 500} {
+		if got := policy.backoff(failures); got != time.Minute {
+			t.Errorf("backoff(%d) = %v; want %v", failures, got, time.Minute)
+		}
+	}
+}
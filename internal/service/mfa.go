@@ -0,0 +1,260 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"ecomm/internal/audit"
+	"ecomm/internal/controller/auth"
+	"ecomm/internal/domain"
+	"ecomm/proto"
+	"errors"
+	"strings"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	mfaIssuer         = "ecomm"
+	mfaChallengeTTL   = 5 * time.Minute
+	recoveryCodeCount = 10
+)
+
+func (s *service) mfaEnabled(userID string) (bool, error) {
+	mfa, err := s.repo.GetUserMFA(userID)
+	if err != nil {
+		if errors.Is(err, domain.ErrMFANotEnrolled) {
+			return false, nil
+		}
+		return false, status.Errorf(codes.Internal, "failed to get mfa settings: %v", err)
+	}
+	return mfa.Enabled, nil
+}
+
+// tokenGrants returns the privileges to embed in the user's tokens. When MFA
+// is required for privileged users, privileges are withheld until the user
+// has enrolled and passed the second factor; enrollmentRequired reports that
+// this happened.
+func (s *service) tokenGrants(user *domain.User, mfaVerified bool) (isAdmin bool, permissions []string, enrollmentRequired bool, err error) {
+	permissions, err = s.userPermissions(user)
+	if err != nil {
+		return false, nil, false, err
+	}
+
+	privileged := user.IsAdmin || len(permissions) > 0
+	if s.requireAdminMFA && privileged && !mfaVerified {
+		return false, nil, true, nil
+	}
+
+	return user.IsAdmin, permissions, false, nil
+}
+
+func (s *service) VerifyMFA(ctx context.Context, req *proto.VerifyMFARequest) (*proto.LoginResponse, error) {
+	userID, err := s.jwtManager.ValidateMFAChallenge(req.MfaToken)
+	if err != nil {
+		return nil, status.Error(codes.Unauthenticated, "invalid or expired mfa token")
+	}
+
+	throttles := []loginThrottle{{key: mfaThrottleKey(userID), policy: accountThrottlePolicy}}
+	if req.ClientIp != "" {
+		throttles = append(throttles, loginThrottle{key: ipThrottleKey(req.ClientIp), policy: ipThrottlePolicy})
+	}
+	if err := s.checkLoginThrottles(throttles); err != nil {
+		return nil, err
+	}
+
+	if err := s.verifySecondFactor(userID, req.Code); err != nil {
+		if status.Code(err) == codes.Unauthenticated {
+			s.recordLoginFailure(throttles)
+		}
+		return nil, err
+	}
+
+	user, err := s.repo.GetUserByID(userID)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to get user: %v", err)
+	}
+
+	return s.issueSession(user, true)
+}
+
+func (s *service) EnrollMFA(ctx context.Context, req *proto.EnrollMFARequest) (*proto.EnrollMFAResponse, error) {
+	if err := authorizeSelf(ctx, req.UserId); err != nil {
+		return nil, err
+	}
+
+	user, err := s.repo.GetUserByID(req.UserId)
+	if err != nil {
+		if errors.Is(err, domain.ErrUserNotFound) {
+			return nil, status.Error(codes.NotFound, err.Error())
+		}
+		return nil, status.Errorf(codes.Internal, "failed to get user: %v", err)
+	}
+
+	enabled, err := s.mfaEnabled(user.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	if enabled {
+		return nil, status.Error(codes.AlreadyExists, "multi-factor authentication is already enabled")
+	}
+
+	secret, err := auth.GenerateTOTPSecret()
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to generate secret: %v", err)
+	}
+
+	if err := s.repo.SaveMFASecret(user.ID, secret); err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to save secret: %v", err)
+	}
+
+	return &proto.EnrollMFAResponse{
+		Secret:     secret,
+		OtpauthUri: auth.TOTPURI(mfaIssuer, user.Email, secret),
+	}, nil
+}
+
+// ConfirmMFA enables MFA once the user proves their authenticator produces
+// valid codes, and returns single-use recovery codes. The codes are only
+// shown once.
+func (s *service) ConfirmMFA(ctx context.Context, req *proto.ConfirmMFARequest) (*proto.ConfirmMFAResponse, error) {
+	if err := authorizeSelf(ctx, req.UserId); err != nil {
+		return nil, err
+	}
+
+	mfa, err := s.repo.GetUserMFA(req.UserId)
+	if err != nil {
+		if errors.Is(err, domain.ErrMFANotEnrolled) {
+			return nil, status.Error(codes.FailedPrecondition, err.Error())
+		}
+		return nil, status.Errorf(codes.Internal, "failed to get mfa settings: %v", err)
+	}
+
+	if mfa.Enabled {
+		return nil, status.Error(codes.AlreadyExists, "multi-factor authentication is already enabled")
+	}
+
+	step, ok := auth.ValidateTOTP(mfa.Secret, req.Code, time.Now())
+	if !ok {
+		return nil, status.Error(codes.InvalidArgument, "invalid code")
+	}
+
+	if err := s.repo.UseMFAStep(mfa.UserID, step); err != nil {
+		return nil, status.Error(codes.InvalidArgument, "invalid code")
+	}
+
+	recoveryCodes, hashes, err := generateRecoveryCodes()
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to generate recovery codes: %v", err)
+	}
+
+	if err := s.repo.EnableMFA(mfa.UserID, hashes); err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to enable mfa: %v", err)
+	}
+
+	audit.Record(ctx, "user", mfa.UserID, nil, nil)
+
+	return &proto.ConfirmMFAResponse{
+		RecoveryCodes: recoveryCodes,
+	}, nil
+}
+
+func (s *service) DisableMFA(ctx context.Context, req *proto.DisableMFARequest) (*proto.DisableMFAResponse, error) {
+	if err := authorizeSelf(ctx, req.UserId); err != nil {
+		return nil, err
+	}
+
+	user, err := s.repo.GetUserByID(req.UserId)
+	if err != nil {
+		if errors.Is(err, domain.ErrUserNotFound) {
+			return nil, status.Error(codes.NotFound, err.Error())
+		}
+		return nil, status.Errorf(codes.Internal, "failed to get user: %v", err)
+	}
+
+	if s.requireAdminMFA {
+		permissions, err := s.userPermissions(user)
+		if err != nil {
+			return nil, err
+		}
+		if user.IsAdmin || len(permissions) > 0 {
+			return nil, status.Error(codes.FailedPrecondition, "multi-factor authentication is required for privileged accounts")
+		}
+	}
+
+	if err := s.verifySecondFactor(user.ID, req.Code); err != nil {
+		return nil, err
+	}
+
+	if err := s.repo.DisableMFA(user.ID); err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to disable mfa: %v", err)
+	}
+
+	audit.Record(ctx, "user", user.ID, nil, nil)
+
+	return &proto.DisableMFAResponse{}, nil
+}
+
+// verifySecondFactor accepts either a current TOTP code or an unused recovery
+// code.
+func (s *service) verifySecondFactor(userID, code string) error {
+	mfa, err := s.repo.GetUserMFA(userID)
+	if err != nil {
+		if errors.Is(err, domain.ErrMFANotEnrolled) {
+			return status.Error(codes.FailedPrecondition, err.Error())
+		}
+		return status.Errorf(codes.Internal, "failed to get mfa settings: %v", err)
+	}
+
+	if !mfa.Enabled {
+		return status.Error(codes.FailedPrecondition, domain.ErrMFANotEnrolled.Error())
+	}
+
+	if step, ok := auth.ValidateTOTP(mfa.Secret, code, time.Now()); ok {
+		if err := s.repo.UseMFAStep(userID, step); err != nil {
+			if errors.Is(err, domain.ErrMFACodeAlreadyUsed) {
+				return status.Error(codes.Unauthenticated, err.Error())
+			}
+			return status.Errorf(codes.Internal, "failed to record mfa code: %v", err)
+		}
+		return nil
+	}
+
+	if err := s.repo.UseRecoveryCode(userID, hashToken(normalizeRecoveryCode(code))); err != nil {
+		if errors.Is(err, domain.ErrRecoveryCodeNotFound) {
+			return status.Error(codes.Unauthenticated, "invalid code")
+		}
+		return status.Errorf(codes.Internal, "failed to use recovery code: %v", err)
+	}
+
+	return nil
+}
+
+const recoveryCodeAlphabet = "ABCDEFGHJKLMNPQRSTUVWXYZ23456789"
+
+// generateRecoveryCodes returns codes formatted as XXXXX-XXXXX together with
+// the hashes that are stored in their place.
+func generateRecoveryCodes() ([]string, []string, error) {
+	recoveryCodes := make([]string, recoveryCodeCount)
+	hashes := make([]string, recoveryCodeCount)
+	for i := range recoveryCodes {
+		buf := make([]byte, 10)
+		if _, err := rand.Read(buf); err != nil {
+			return nil, nil, err
+		}
+
+		for j, b := range buf {
+			buf[j] = recoveryCodeAlphabet[int(b)%len(recoveryCodeAlphabet)]
+		}
+
+		recoveryCodes[i] = string(buf[:5]) + "-" + string(buf[5:])
+		hashes[i] = hashToken(normalizeRecoveryCode(recoveryCodes[i]))
+	}
+	return recoveryCodes, hashes, nil
+}
+
+func normalizeRecoveryCode(code string) string {
+	return strings.ToUpper(strings.ReplaceAll(strings.TrimSpace(code), "-", ""))
+}
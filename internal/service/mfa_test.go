@@ -0,0 +1,66 @@
+package service
+
+import (
+	"context"
+	"ecomm/internal/controller/auth"
+	"ecomm/internal/domain"
+	"ecomm/proto"
+	"testing"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestMFAMethodsRejectOtherUsers(t *testing.T) {
+	repo := newFakeRepo(
+		&domain.User{ID: "user-1", Email: "jane@example.com"},
+		&domain.User{ID: "user-2", Email: "john@example.com"},
+	)
+	s := &service{repo: repo}
+
+	calls := map[string]func(ctx context.Context) error{
+		"EnrollMFA": func(ctx context.Context) error {
+			_, err := s.EnrollMFA(ctx, &proto.EnrollMFARequest{UserId: "user-2"})
+			return err
+		},
+		"ConfirmMFA": func(ctx context.Context) error {
+			_, err := s.ConfirmMFA(ctx, &proto.ConfirmMFARequest{UserId: "user-2", Code: "123456"})
+			return err
+		},
+		"DisableMFA": func(ctx context.Context) error {
+			_, err := s.DisableMFA(ctx, &proto.DisableMFARequest{UserId: "user-2", Code: "123456"})
+			return err
+		},
+	}
+
+	for name, call := range calls {
+		t.Run(name, func(t *testing.T) {
+			if err := call(context.Background()); status.Code(err) != codes.Unauthenticated {
+				t.Errorf("anonymous error = %v; want Unauthenticated", err)
+			}
+
+			ctx := auth.ContextWithClaims(context.Background(), &auth.Claims{ID: "user-1"})
+			if err := call(ctx); status.Code(err) != codes.PermissionDenied {
+				t.Errorf("other user error = %v; want PermissionDenied", err)
+			}
+		})
+	}
+
+	if len(repo.mfa) != 0 {
+		t.Fatalf("mfa settings changed: %v", repo.mfa)
+	}
+}
+
+func TestEnrollMFAForOwnAccount(t *testing.T) {
+	repo := newFakeRepo(&domain.User{ID: "user-1", Email: "jane@example.com"})
+	s := &service{repo: repo}
+
+	ctx := auth.ContextWithClaims(context.Background(), &auth.Claims{ID: "user-1"})
+	response, err := s.EnrollMFA(ctx, &proto.EnrollMFARequest{UserId: "user-1"})
+	if err != nil {
+		t.Fatalf("EnrollMFA() error = %v", err)
+	}
+	if mfa := repo.mfa["user-1"]; mfa == nil || mfa.Secret != response.Secret {
+		t.Fatalf("stored mfa = %+v; want secret %q", mfa, response.Secret)
+	}
+}
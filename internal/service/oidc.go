@@ -0,0 +1,161 @@
+package service
+
+import (
+	"context"
+	"ecomm/internal/domain"
+	"ecomm/internal/oidc"
+	"ecomm/proto"
+	"errors"
+	"strings"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const oidcLoginStateTTL = 10 * time.Minute
+
+func (s *service) oidcProvider(name string) (*oidc.Provider, error) {
+	provider, ok := s.oidcProviders[name]
+	if !ok {
+		return nil, status.Errorf(codes.NotFound, "unknown identity provider %q", name)
+	}
+	return provider, nil
+}
+
+// BeginOIDCLogin stores a fresh state, nonce and PKCE verifier and returns the
+// provider URL the user should be redirected to.
+func (s *service) BeginOIDCLogin(ctx context.Context, req *proto.BeginOIDCLoginRequest) (*proto.BeginOIDCLoginResponse, error) {
+	provider, err := s.oidcProvider(req.Provider)
+	if err != nil {
+		return nil, err
+	}
+
+	var values [3]string
+	for i := range values {
+		if values[i], err = oidc.RandomString(); err != nil {
+			return nil, status.Errorf(codes.Internal, "failed to generate login state: %v", err)
+		}
+	}
+	state, nonce, codeVerifier := values[0], values[1], values[2]
+
+	authURL, err := provider.AuthCodeURL(ctx, state, nonce, codeVerifier)
+	if err != nil {
+		return nil, status.Errorf(codes.Unavailable, "identity provider unavailable: %v", err)
+	}
+
+	if err := s.repo.CreateOIDCLoginState(&domain.OIDCLoginState{
+		State:        state,
+		Provider:     provider.Name(),
+		CodeVerifier: codeVerifier,
+		Nonce:        nonce,
+		ExpiresAt:    uint64(time.Now().Add(oidcLoginStateTTL).Unix()),
+	}); err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to store login state: %v", err)
+	}
+
+	return &proto.BeginOIDCLoginResponse{
+		AuthorizationUrl: authURL,
+		State:            state,
+	}, nil
+}
+
+// CompleteOIDCLogin redeems the authorization code, resolves the local user
+// and logs them in exactly like a password login would.
+func (s *service) CompleteOIDCLogin(ctx context.Context, req *proto.CompleteOIDCLoginRequest) (*proto.LoginResponse, error) {
+	provider, err := s.oidcProvider(req.Provider)
+	if err != nil {
+		return nil, err
+	}
+
+	loginState, err := s.repo.ConsumeOIDCLoginState(req.State)
+	if err != nil {
+		if errors.Is(err, domain.ErrOIDCStateNotFound) {
+			return nil, status.Error(codes.InvalidArgument, "invalid or expired login state")
+		}
+		return nil, status.Errorf(codes.Internal, "failed to load login state: %v", err)
+	}
+
+	if loginState.Provider != provider.Name() || loginState.ExpiresAt <= uint64(time.Now().Unix()) {
+		return nil, status.Error(codes.InvalidArgument, "invalid or expired login state")
+	}
+
+	claims, err := provider.Exchange(ctx, req.Code, loginState.CodeVerifier, loginState.Nonce)
+	if err != nil {
+		return nil, status.Errorf(codes.Unauthenticated, "identity provider login failed: %v", err)
+	}
+
+	user, err := s.oidcUser(provider.Name(), claims)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.completeLogin(user)
+}
+
+// oidcUser returns the user linked to the provider identity. Unknown
+// identities are linked to the existing user with the same email, or to a new
+// user, but only if the provider verified the email address.
+func (s *service) oidcUser(provider string, claims *oidc.IDTokenClaims) (*domain.User, error) {
+	identity, err := s.repo.GetUserIdentity(provider, claims.Subject)
+	if err == nil {
+		user, err := s.repo.GetUserByID(identity.UserID)
+		if err != nil {
+			return nil, status.Errorf(codes.Internal, "failed to get user: %v", err)
+		}
+		return user, nil
+	}
+
+	if !errors.Is(err, domain.ErrUserIdentityNotFound) {
+		return nil, status.Errorf(codes.Internal, "failed to get identity: %v", err)
+	}
+
+	if claims.Email == "" || !bool(claims.EmailVerified) {
+		return nil, status.Error(codes.PermissionDenied, "the identity provider did not verify the email address")
+	}
+
+	user, err := s.repo.GetUser(claims.Email)
+	if errors.Is(err, domain.ErrUserNotFound) {
+		user, err = s.createOIDCUser(claims)
+	}
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to resolve user: %v", err)
+	}
+
+	if err := s.repo.CreateUserIdentity(&domain.UserIdentity{
+		Provider: provider,
+		Subject:  claims.Subject,
+		UserID:   user.ID,
+		Email:    claims.Email,
+	}); err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to link identity: %v", err)
+	}
+
+	return user, nil
+}
+
+// createOIDCUser creates an account for a first-time provider login. The
+// account gets a random password that nobody knows; a password can be set
+// later through the reset flow.
+func (s *service) createOIDCUser(claims *oidc.IDTokenClaims) (*domain.User, error) {
+	password, err := oidc.RandomString()
+	if err != nil {
+		return nil, err
+	}
+
+	hashed, err := s.hasher.Hash(password)
+	if err != nil {
+		return nil, err
+	}
+
+	name := claims.Name
+	if name == "" {
+		name, _, _ = strings.Cut(claims.Email, "@")
+	}
+
+	return s.repo.CreateUser(&domain.User{
+		Name:     name,
+		Email:    claims.Email,
+		Password: hashed,
+	})
+}
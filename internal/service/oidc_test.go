@@ -0,0 +1,87 @@
+package service
+
+import (
+	"ecomm/internal/domain"
+	"ecomm/internal/oidc"
+	"ecomm/internal/password"
+	"testing"
+
+	"github.com/golang-jwt/jwt/v5"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func idTokenClaims(subject, email string, verified bool) *oidc.IDTokenClaims {
+	return &oidc.IDTokenClaims{
+		Email:            email,
+		EmailVerified:    oidc.Bool(verified),
+		RegisteredClaims: jwt.RegisteredClaims{Subject: subject},
+	}
+}
+
+func TestOIDCUserReturnsLinkedUser(t *testing.T) {
+	repo := newFakeRepo(&domain.User{ID: "user-1", Email: "jane@example.com"})
+	repo.identities = []*domain.UserIdentity{{Provider: "google", Subject: "sub-1", UserID: "user-1"}}
+	s := &service{repo: repo}
+
+	// The identity decides, even if the provider no longer vouches for the
+	// email.
+	user, err := s.oidcUser("google", idTokenClaims("sub-1", "other@example.com", false))
+	if err != nil {
+		t.Fatalf("oidcUser() error = %v", err)
+	}
+	if user.ID != "user-1" {
+		t.Fatalf("user = %q; want user-1", user.ID)
+	}
+}
+
+func TestOIDCUserLinksVerifiedEmailToExistingUser(t *testing.T) {
+	repo := newFakeRepo(&domain.User{ID: "user-1", Email: "jane@example.com"})
+	s := &service{repo: repo}
+
+	user, err := s.oidcUser("google", idTokenClaims("sub-1", "jane@example.com", true))
+	if err != nil {
+		t.Fatalf("oidcUser() error = %v", err)
+	}
+	if user.ID != "user-1" {
+		t.Fatalf("user = %q; want user-1", user.ID)
+	}
+	if len(repo.identities) != 1 || repo.identities[0].UserID != "user-1" || repo.identities[0].Subject != "sub-1" {
+		t.Fatalf("identities = %+v; want sub-1 linked to user-1", repo.identities)
+	}
+}
+
+func TestOIDCUserRejectsUnverifiedEmail(t *testing.T) {
+	repo := newFakeRepo(&domain.User{ID: "user-1", Email: "jane@example.com"})
+	s := &service{repo: repo}
+
+	for _, claims := range []*oidc.IDTokenClaims{
+		idTokenClaims("sub-1", "jane@example.com", false),
+		idTokenClaims("sub-1", "", true),
+	} {
+		_, err := s.oidcUser("google", claims)
+		if status.Code(err) != codes.PermissionDenied {
+			t.Fatalf("oidcUser(%q, verified=%v) error = %v; want PermissionDenied", claims.Email, claims.EmailVerified, err)
+		}
+	}
+	if len(repo.identities) != 0 {
+		t.Fatalf("identities = %+v; want none linked", repo.identities)
+	}
+}
+
+func TestOIDCUserCreatesUserForNewEmail(t *testing.T) {
+	repo := newFakeRepo()
+	s := &service{repo: repo, hasher: password.NewHasher(&password.Bcrypt{Cost: 4})}
+
+	claims := idTokenClaims("sub-1", "new@example.com", true)
+	user, err := s.oidcUser("google", claims)
+	if err != nil {
+		t.Fatalf("oidcUser() error = %v", err)
+	}
+	if user.Email != "new@example.com" || user.Name != "new" || user.Password == "" {
+		t.Fatalf("user = %+v; want a new account named after the email", user)
+	}
+	if len(repo.identities) != 1 || repo.identities[0].UserID != user.ID {
+		t.Fatalf("identities = %+v; want sub-1 linked to %s", repo.identities, user.ID)
+	}
+}
@@ -0,0 +1,114 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"ecomm/internal/audit"
+	"ecomm/internal/domain"
+	"ecomm/proto"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const passwordResetTokenTTL = time.Hour
+
+// RequestPasswordReset always succeeds for well-formed requests so that the
+// response never reveals whether an account exists for the given email. The
+// email is sent in the background so the response time does not reveal it
+// either.
+func (s *service) RequestPasswordReset(ctx context.Context, req *proto.RequestPasswordResetRequest) (*proto.RequestPasswordResetResponse, error) {
+	user, err := s.repo.GetUser(req.Email)
+	if err != nil {
+		if errors.Is(err, domain.ErrUserNotFound) {
+			return &proto.RequestPasswordResetResponse{}, nil
+		}
+		return nil, status.Errorf(codes.Internal, "failed to request password reset: %v", err)
+	}
+
+	token, tokenHash, err := generateToken()
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to generate reset token: %v", err)
+	}
+
+	if err := s.repo.CreatePasswordResetToken(&domain.PasswordResetToken{
+		UserID:    user.ID,
+		TokenHash: tokenHash,
+		ExpiresAt: uint64(time.Now().Add(passwordResetTokenTTL).Unix()),
+	}); err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to store reset token: %v", err)
+	}
+
+	s.sendMail(user.Email, "Reset your password", passwordResetBody(token))
+
+	return &proto.RequestPasswordResetResponse{}, nil
+}
+
+func (s *service) ResetPassword(ctx context.Context, req *proto.ResetPasswordRequest) (*proto.ResetPasswordResponse, error) {
+	token, err := s.repo.GetPasswordResetToken(hashToken(req.Token))
+	if err != nil {
+		if errors.Is(err, domain.ErrPasswordResetTokenNotFound) {
+			return nil, status.Error(codes.InvalidArgument, "invalid or expired reset token")
+		}
+		return nil, status.Errorf(codes.Internal, "failed to reset password: %v", err)
+	}
+
+	if token.UsedAt != 0 || token.ExpiresAt <= uint64(time.Now().Unix()) {
+		return nil, status.Error(codes.InvalidArgument, "invalid or expired reset token")
+	}
+
+	user, err := s.getUserByID(token.UserID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.checkPasswordStrength(req.Password, user.Email, user.Name); err != nil {
+		return nil, err
+	}
+
+	hashed, err := s.hashPassword(req.Password)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.repo.ResetPassword(token.ID, token.UserID, hashed); err != nil {
+		if errors.Is(err, domain.ErrPasswordResetTokenNotFound) {
+			return nil, status.Error(codes.InvalidArgument, "invalid or expired reset token")
+		}
+		return nil, status.Errorf(codes.Internal, "failed to reset password: %v", err)
+	}
+
+	audit.Record(ctx, "user", token.UserID, nil, nil)
+	return &proto.ResetPasswordResponse{}, nil
+}
+
+// generateToken returns a random URL-safe token and the hash that is stored
+// in place of it.
+func generateToken() (string, string, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", "", err
+	}
+
+	token := base64.RawURLEncoding.EncodeToString(buf)
+	return token, hashToken(token), nil
+}
+
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+func passwordResetBody(token string) string {
+	if resetURL := os.Getenv("PASSWORD_RESET_URL"); resetURL != "" {
+		return fmt.Sprintf("Follow this link to reset your password:\n\n%s?token=%s\n\nThe link expires in one hour. If you did not request a reset, you can ignore this email.", resetURL, token)
+	}
+	return fmt.Sprintf("Use this token to reset your password:\n\n%s\n\nThe token expires in one hour. If you did not request a reset, you can ignore this email.", token)
+}
@@ -0,0 +1,65 @@
+package service
+
+import (
+	"context"
+	"ecomm/internal/domain"
+	"ecomm/proto"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRequestPasswordResetForUnknownEmailSendsNothing(t *testing.T) {
+	repo := newFakeRepo()
+	mailer := newFakeMailer()
+	s := &service{repo: repo, mailer: mailer}
+
+	if _, err := s.RequestPasswordReset(context.Background(), &proto.RequestPasswordResetRequest{Email: "nobody@example.com"}); err != nil {
+		t.Fatalf("RequestPasswordReset() error = %v", err)
+	}
+
+	select {
+	case mail := <-mailer.sent:
+		t.Fatalf("sent %+v; want no email", mail)
+	case <-time.After(50 * time.Millisecond):
+	}
+	if len(repo.resetTokens) != 0 {
+		t.Fatalf("stored %d tokens; want none", len(repo.resetTokens))
+	}
+}
+
+func TestRequestPasswordResetMailsTokenMatchingStoredHash(t *testing.T) {
+	t.Setenv("PASSWORD_RESET_URL", "")
+
+	repo := newFakeRepo(&domain.User{ID: "user-1", Email: "jane@example.com"})
+	mailer := newFakeMailer()
+	s := &service{repo: repo, mailer: mailer}
+
+	if _, err := s.RequestPasswordReset(context.Background(), &proto.RequestPasswordResetRequest{Email: "jane@example.com"}); err != nil {
+		t.Fatalf("RequestPasswordReset() error = %v", err)
+	}
+
+	var mail sentMail
+	select {
+	case mail = <-mailer.sent:
+	case <-time.After(time.Second):
+		t.Fatal("no email sent")
+	}
+	if mail.to != "jane@example.com" {
+		t.Fatalf("sent to %q; want jane@example.com", mail.to)
+	}
+
+	if len(repo.resetTokens) != 1 {
+		t.Fatalf("stored %d tokens; want 1", len(repo.resetTokens))
+	}
+	stored := repo.resetTokens[0]
+	if stored.UserID != "user-1" {
+		t.Fatalf("token user = %q; want user-1", stored.UserID)
+	}
+
+	// The body carries the token itself; only its hash is stored.
+	token := strings.Fields(strings.SplitN(mail.body, "\n\n", 3)[1])[0]
+	if hashToken(token) != stored.TokenHash {
+		t.Fatal("mailed token does not match the stored hash")
+	}
+}
@@ -0,0 +1,55 @@
+package service
+
+import (
+	"ecomm/internal/domain"
+	"ecomm/pkg"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func (s *service) hashPassword(password string) (string, error) {
+	hashed, err := s.hasher.Hash(password)
+	if err != nil {
+		return "", status.Errorf(codes.Internal, "failed to hash password: %v", err)
+	}
+	return hashed, nil
+}
+
+func (s *service) checkPasswordStrength(password, email, name string) error {
+	if err := s.passwordChecker.Check(password, email, name); err != nil {
+		return status.Error(codes.InvalidArgument, err.Error())
+	}
+	return nil
+}
+
+// verifyPassword checks the user's password and, on success, upgrades hashes
+// produced with an older algorithm or weaker parameters.
+func (s *service) verifyPassword(user *domain.User, password string) bool {
+	ok, rehash, err := s.hasher.Verify(user.Password, password)
+	if err != nil {
+		pkg.ErrorLogger.Printf("failed to verify password of user %s: %v", user.ID, err)
+		return false
+	}
+	if !ok || !rehash {
+		return ok
+	}
+
+	hashed, err := s.hasher.Hash(password)
+	if err != nil {
+		pkg.ErrorLogger.Printf("failed to rehash password: %v", err)
+		return true
+	}
+	if err := s.repo.UpdatePasswordHash(user.ID, user.Password, hashed); err != nil {
+		pkg.ErrorLogger.Printf("failed to store rehashed password: %v", err)
+		return true
+	}
+	user.Password = hashed
+	return true
+}
+
+// compareDummyHash spends the same time as a real password check so that
+// response times do not reveal whether an account exists.
+func (s *service) compareDummyHash(password string) {
+	s.hasher.Verify(s.dummyHash, password)
+}
@@ -0,0 +1,171 @@
+package service
+
+import (
+	"context"
+	"ecomm/internal/controller/auth"
+	"ecomm/internal/domain"
+	"ecomm/internal/password"
+	"ecomm/proto"
+	"strings"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// testArgon2id keeps the preferred algorithm cheap enough for tests.
+var testArgon2id = &password.Argon2id{Memory: 64, Iterations: 1, Parallelism: 1, SaltLength: 16, KeyLength: 32}
+
+// newPasswordService returns a service that prefers Argon2id and still
+// verifies bcrypt hashes, with a user whose password is stored as a bcrypt
+// hash.
+func newPasswordService(t *testing.T) (*service, *fakeRepo) {
+	t.Helper()
+
+	legacy := &password.Bcrypt{Cost: 4}
+	hashed, err := legacy.Hash("current password")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	repo := newFakeRepo(&domain.User{ID: "user-1", Name: "Ada Lovelace", Email: "ada@example.com", Password: hashed})
+	return &service{
+		repo:            repo,
+		hasher:          password.NewHasher(testArgon2id, legacy),
+		passwordChecker: password.NewChecker(false),
+	}, repo
+}
+
+func TestLoginRehashesLegacyHash(t *testing.T) {
+	s, repo := newPasswordService(t)
+
+	if _, err := s.authenticate("ada@example.com", "current password", "192.0.2.1"); err != nil {
+		t.Fatalf("authenticate() error = %v", err)
+	}
+
+	stored := repo.users["user-1"].Password
+	if !strings.HasPrefix(stored, "$argon2id$") {
+		t.Fatalf("stored hash = %q; want an Argon2id hash", stored)
+	}
+	if ok, rehash, err := s.hasher.Verify(stored, "current password"); !ok || rehash || err != nil {
+		t.Fatalf("Verify() = %v, %v, %v; want the new hash to match without a rehash", ok, rehash, err)
+	}
+
+	if _, err := s.authenticate("ada@example.com", "current password", "192.0.2.1"); err != nil {
+		t.Fatalf("second authenticate() error = %v", err)
+	}
+	if repo.rehashes != 1 {
+		t.Fatalf("rehashed %d times; want once", repo.rehashes)
+	}
+}
+
+func TestFailedLoginDoesNotRehash(t *testing.T) {
+	s, repo := newPasswordService(t)
+	before := repo.users["user-1"].Password
+
+	if _, err := s.authenticate("ada@example.com", "wrong password", "192.0.2.1"); status.Code(err) != codes.Unauthenticated {
+		t.Fatalf("authenticate() error = %v; want Unauthenticated", err)
+	}
+	if repo.rehashes != 0 || repo.users["user-1"].Password != before {
+		t.Fatal("password was rehashed")
+	}
+}
+
+// passwordChangingRepo changes the password of a user right after it is
+// read, as a concurrent password change would.
+type passwordChangingRepo struct {
+	*fakeRepo
+}
+
+func (r passwordChangingRepo) GetUser(email string) (*domain.User, error) {
+	user, err := r.fakeRepo.GetUser(email)
+	if err == nil {
+		r.mu.Lock()
+		r.users[user.ID].Password = "changed concurrently"
+		r.mu.Unlock()
+	}
+	return user, err
+}
+
+func TestLoginRehashSkippedWhenPasswordChangedConcurrently(t *testing.T) {
+	s, repo := newPasswordService(t)
+	s.repo = passwordChangingRepo{repo}
+
+	if _, err := s.authenticate("ada@example.com", "current password", "192.0.2.1"); err != nil {
+		t.Fatalf("authenticate() error = %v", err)
+	}
+	if repo.rehashes != 1 {
+		t.Fatalf("rehashed %d times; want one attempt", repo.rehashes)
+	}
+	if stored := repo.users["user-1"].Password; stored != "changed concurrently" {
+		t.Fatalf("stored hash = %q; want the concurrent change kept", stored)
+	}
+}
+
+func TestNewPasswordsMustBeStrong(t *testing.T) {
+	weak := []struct {
+		name     string
+		password string
+	}{
+		{"too short", "abc123"},
+		{"too simple", "aaaaaaaaaaaa"},
+		{"contains name", "lovelace-rules"},
+		{"breached", "password"},
+	}
+
+	entryPoints := []struct {
+		name   string
+		change func(s *service, password string) error
+	}{
+		{"create user", func(s *service, password string) error {
+			_, err := s.CreateUser(context.Background(), &proto.CreateUserRequest{
+				Name: "Ada Lovelace", Email: "ada.new@example.com", Password: password,
+			})
+			return err
+		}},
+		{"reset password", func(s *service, password string) error {
+			repo := s.repo.(*fakeRepo)
+			repo.resetTokens = []*domain.PasswordResetToken{{
+				ID: "token-1", UserID: "user-1", TokenHash: hashToken("secret"),
+				ExpiresAt: uint64(time.Now().Add(time.Hour).Unix()),
+			}}
+			_, err := s.ResetPassword(context.Background(), &proto.ResetPasswordRequest{Token: "secret", Password: password})
+			return err
+		}},
+		{"change password", func(s *service, password string) error {
+			ctx := auth.ContextWithClaims(context.Background(), &auth.Claims{ID: "user-1"})
+			_, err := s.ChangePassword(ctx, &proto.ChangePasswordRequest{
+				UserId: "user-1", CurrentPassword: "current password", NewPassword: password,
+			})
+			return err
+		}},
+	}
+
+	for _, entry := range entryPoints {
+		for _, tt := range weak {
+			t.Run(entry.name+"/"+tt.name, func(t *testing.T) {
+				s, repo := newPasswordService(t)
+
+				if err := entry.change(s, tt.password); status.Code(err) != codes.InvalidArgument {
+					t.Fatalf("error = %v; want InvalidArgument", err)
+				}
+				if len(repo.users) != 1 {
+					t.Fatal("user was created")
+				}
+				// Reauthenticating may rehash the current password, but
+				// it must still be the one that matches.
+				if ok, _, _ := s.hasher.Verify(repo.users["user-1"].Password, "current password"); !ok {
+					t.Fatal("password was changed")
+				}
+			})
+		}
+
+		t.Run(entry.name+"/strong", func(t *testing.T) {
+			s, _ := newPasswordService(t)
+			if err := entry.change(s, "a much longer passphrase"); err != nil {
+				t.Fatalf("error = %v", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,198 @@
+package service
+
+import (
+	"context"
+	"ecomm/internal/adapters"
+	"ecomm/internal/audit"
+	"ecomm/internal/domain"
+	"ecomm/proto"
+	"errors"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	defaultPriceHistoryLimit = 50
+	maxPriceHistoryLimit     = 500
+)
+
+func pricingError(err error, action string) error {
+	switch {
+	case errors.Is(err, domain.ErrProductNotFound), errors.Is(err, domain.ErrPriceChangeNotFound):
+		return status.Error(codes.NotFound, err.Error())
+	case errors.Is(err, domain.ErrVersionMismatch):
+		return versionConflict(err.Error())
+	default:
+		return status.Errorf(codes.Internal, "failed to %s: %v", action, err)
+	}
+}
+
+// SetProductSale puts a product on sale at a reduced price between two
+// times, replacing any sale it already has. A sale without a start time
+// starts straight away and one without an end time runs until cleared.
+func (s *service) SetProductSale(ctx context.Context, req *proto.SetProductSaleRequest) (*proto.SetProductSaleResponse, error) {
+	sale := adapters.FromProtoProductSale(req.Sale)
+	if sale == nil {
+		return nil, status.Error(codes.InvalidArgument, "sale is required")
+	}
+	if sale.Price < 0 {
+		return nil, status.Error(codes.InvalidArgument, "sale price must not be negative")
+	}
+
+	now := uint64(time.Now().Unix())
+	if sale.StartsAt == 0 {
+		sale.StartsAt = now
+	}
+	if sale.EndsAt != 0 && (sale.EndsAt <= sale.StartsAt || sale.EndsAt <= now) {
+		return nil, status.Error(codes.InvalidArgument, "sale must end in the future and after it starts")
+	}
+
+	before, err := s.getProduct(req.ProductId)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkVersion(req.ExpectedVersion, before.Version); err != nil {
+		return nil, err
+	}
+	if sale.Price >= before.Price {
+		return nil, status.Error(codes.InvalidArgument, "sale price must be below the regular price")
+	}
+
+	if err := s.repo.SetProductSale(req.ProductId, sale, before.Version, actorID(ctx)); err != nil {
+		return nil, pricingError(err, "set sale")
+	}
+
+	product, err := s.getProduct(req.ProductId)
+	if err != nil {
+		return nil, err
+	}
+
+	audit.Record(ctx, "product", product.ID,
+		map[string]*domain.ProductSale{"sale": before.Sale},
+		map[string]*domain.ProductSale{"sale": product.Sale})
+
+	return &proto.SetProductSaleResponse{
+		Product: adapters.ToProtoProduct(*product),
+	}, nil
+}
+
+func (s *service) ClearProductSale(ctx context.Context, req *proto.ClearProductSaleRequest) (*proto.ClearProductSaleResponse, error) {
+	before, err := s.getProduct(req.ProductId)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkVersion(req.ExpectedVersion, before.Version); err != nil {
+		return nil, err
+	}
+
+	if err := s.repo.SetProductSale(req.ProductId, nil, before.Version, actorID(ctx)); err != nil {
+		return nil, pricingError(err, "clear sale")
+	}
+
+	product, err := s.getProduct(req.ProductId)
+	if err != nil {
+		return nil, err
+	}
+
+	audit.Record(ctx, "product", product.ID,
+		map[string]*domain.ProductSale{"sale": before.Sale},
+		map[string]*domain.ProductSale{"sale": nil})
+
+	return &proto.ClearProductSaleResponse{
+		Product: adapters.ToProtoProduct(*product),
+	}, nil
+}
+
+func (s *service) ListPriceHistory(ctx context.Context, req *proto.ListPriceHistoryRequest) (*proto.ListPriceHistoryResponse, error) {
+	limit := int(req.Limit)
+	if limit <= 0 {
+		limit = defaultPriceHistoryLimit
+	}
+	if limit > maxPriceHistoryLimit {
+		limit = maxPriceHistoryLimit
+	}
+
+	if req.Offset < 0 {
+		return nil, status.Error(codes.InvalidArgument, "offset must not be negative")
+	}
+
+	if _, err := s.getProduct(req.ProductId); err != nil {
+		return nil, err
+	}
+
+	entries, err := s.repo.ListPriceHistory(&domain.PriceHistoryFilter{
+		ProductID: req.ProductId,
+		Limit:     limit,
+		Offset:    int(req.Offset),
+	})
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to list price history: %v", err)
+	}
+
+	return &proto.ListPriceHistoryResponse{
+		Entries: adapters.ToProtoPriceHistory(entries),
+	}, nil
+}
+
+// SchedulePriceChange sets the regular price of a product at a later time.
+// The pricing worker applies it once it is due.
+func (s *service) SchedulePriceChange(ctx context.Context, req *proto.SchedulePriceChangeRequest) (*proto.SchedulePriceChangeResponse, error) {
+	if req.Price < 0 {
+		return nil, status.Error(codes.InvalidArgument, "price must not be negative")
+	}
+	if req.EffectiveAt <= uint64(time.Now().Unix()) {
+		return nil, status.Error(codes.InvalidArgument, "effective_at must be in the future")
+	}
+
+	if _, err := s.getProduct(req.ProductId); err != nil {
+		return nil, err
+	}
+
+	change, err := s.repo.CreateScheduledPriceChange(&domain.ScheduledPriceChange{
+		ProductID:   req.ProductId,
+		Price:       req.Price,
+		EffectiveAt: req.EffectiveAt,
+		ActorID:     actorID(ctx),
+	})
+	if err != nil {
+		return nil, pricingError(err, "schedule price change")
+	}
+
+	audit.Record(ctx, "scheduled_price_change", change.ID, nil, change)
+
+	return &proto.SchedulePriceChangeResponse{
+		Change: adapters.ToProtoScheduledPriceChange(*change),
+	}, nil
+}
+
+func (s *service) ListScheduledPriceChanges(ctx context.Context, req *proto.ListScheduledPriceChangesRequest) (*proto.ListScheduledPriceChangesResponse, error) {
+	if _, err := s.getProduct(req.ProductId); err != nil {
+		return nil, err
+	}
+
+	changes, err := s.repo.ListScheduledPriceChanges(req.ProductId)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to list scheduled price changes: %v", err)
+	}
+
+	return &proto.ListScheduledPriceChangesResponse{
+		Changes: adapters.ToProtoScheduledPriceChanges(changes),
+	}, nil
+}
+
+// CancelScheduledPriceChange drops a price change that has not been applied
+// yet.
+func (s *service) CancelScheduledPriceChange(ctx context.Context, req *proto.CancelScheduledPriceChangeRequest) (*proto.CancelScheduledPriceChangeResponse, error) {
+	change, err := s.repo.DeleteScheduledPriceChange(req.Id, req.ProductId)
+	if err != nil {
+		return nil, pricingError(err, "cancel price change")
+	}
+
+	audit.Record(ctx, "scheduled_price_change", change.ID, change, nil)
+
+	return &proto.CancelScheduledPriceChangeResponse{
+		Id: req.Id,
+	}, nil
+}
@@ -0,0 +1,92 @@
+package service
+
+import (
+	"context"
+	"ecomm/internal/audit"
+	"ecomm/internal/domain"
+	"ecomm/proto"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestSetProductSale(t *testing.T) {
+	tests := []struct {
+		name    string
+		price   float64
+		version int64
+		code    codes.Code
+	}{
+		{"any version", 3, 0, codes.OK},
+		{"current version", 3, 1, codes.OK},
+		{"stale version", 3, 2, codes.Aborted},
+		{"at regular price", 4.99, 1, codes.InvalidArgument},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := inventoryRepo()
+			repo.products["product-1"].Price = 4.99
+			s := &service{repo: repo}
+
+			_, err := s.SetProductSale(context.Background(), &proto.SetProductSaleRequest{
+				ProductId:       "product-1",
+				Sale:            &proto.ProductSale{Price: tt.price},
+				ExpectedVersion: tt.version,
+			})
+			if status.Code(err) != tt.code {
+				t.Fatalf("SetProductSale() error = %v; want %v", err, tt.code)
+			}
+			if tt.code == codes.Aborted {
+				wantVersionConflict(t, err)
+			}
+			if onSale := repo.products["product-1"].Sale != nil; onSale != (tt.code == codes.OK) {
+				t.Fatalf("sale = %+v", repo.products["product-1"].Sale)
+			}
+		})
+	}
+}
+
+func TestClearProductSaleWithStaleVersion(t *testing.T) {
+	repo := inventoryRepo()
+	sale := &domain.ProductSale{Price: 2, StartsAt: uint64(time.Now().Unix())}
+	repo.products["product-1"].Sale = sale
+	s := &service{repo: repo}
+
+	_, err := s.ClearProductSale(context.Background(), &proto.ClearProductSaleRequest{ProductId: "product-1", ExpectedVersion: 2})
+	wantVersionConflict(t, err)
+	if repo.products["product-1"].Sale != sale {
+		t.Fatal("sale was cleared")
+	}
+}
+
+func TestCancelScheduledPriceChangeAuditsTheChange(t *testing.T) {
+	repo := newFakeRepo()
+	repo.priceChanges = []*domain.ScheduledPriceChange{{ID: "change-1", ProductID: "product-1", Price: 9.99, EffectiveAt: 2000000000}}
+	s := &service{repo: repo}
+
+	interceptor := audit.UnaryServerInterceptor(repo, AuditedMethods)
+	info := &grpc.UnaryServerInfo{FullMethod: proto.ApiService_CancelScheduledPriceChange_FullMethodName}
+	handler := func(ctx context.Context, req any) (any, error) {
+		return s.CancelScheduledPriceChange(ctx, req.(*proto.CancelScheduledPriceChangeRequest))
+	}
+
+	req := &proto.CancelScheduledPriceChangeRequest{ProductId: "product-1", Id: "change-1"}
+	if _, err := interceptor(context.Background(), req, info, handler); err != nil {
+		t.Fatalf("CancelScheduledPriceChange() error = %v", err)
+	}
+
+	if len(repo.priceChanges) != 0 {
+		t.Fatal("price change was not cancelled")
+	}
+	if len(repo.auditEvents) != 1 {
+		t.Fatalf("recorded %d audit events; want 1", len(repo.auditEvents))
+	}
+	event := repo.auditEvents[0]
+	if event.TargetID != "change-1" || event.Before["price"] != 9.99 || event.After != nil {
+		t.Fatalf("audit event = %+v; want the cancelled change as before", event)
+	}
+}
@@ -0,0 +1,104 @@
+package service
+
+import (
+	"context"
+	"ecomm/internal/adapters"
+	"ecomm/internal/audit"
+	"ecomm/internal/controller/auth"
+	"ecomm/internal/domain"
+	"ecomm/proto"
+	"errors"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// isCatalogStaff reports whether the caller may see draft products.
+func isCatalogStaff(ctx context.Context) bool {
+	claims := auth.ClaimsFromContext(ctx)
+	return claims != nil && claims.HasPermission(domain.PermissionProductsUpdate)
+}
+
+// productStatus validates the status requested when creating or updating a
+// product. Products are archived by deleting them, not by setting the status.
+func productStatus(requested, current string) (string, error) {
+	switch requested {
+	case "":
+		return current, nil
+	case domain.ProductDraft, domain.ProductActive:
+		if current == domain.ProductArchived {
+			return "", status.Error(codes.FailedPrecondition, "archived products must be restored first")
+		}
+		return requested, nil
+	default:
+		return "", status.Error(codes.InvalidArgument, "status must be draft or active")
+	}
+}
+
+// listedStatuses returns the statuses ListProducts shows to the caller.
+func listedStatuses(ctx context.Context, requested string) ([]string, error) {
+	staff := isCatalogStaff(ctx)
+
+	switch requested {
+	case "":
+		if staff {
+			return []string{domain.ProductDraft, domain.ProductActive}, nil
+		}
+		return []string{domain.ProductActive}, nil
+	case domain.ProductActive:
+		return []string{domain.ProductActive}, nil
+	case domain.ProductDraft, domain.ProductArchived:
+		if !staff {
+			return nil, status.Errorf(codes.PermissionDenied, "missing permission %s", domain.PermissionProductsUpdate)
+		}
+		return []string{requested}, nil
+	default:
+		return nil, status.Error(codes.InvalidArgument, "status must be draft, active or archived")
+	}
+}
+
+// checkProductAvailable rejects products that cannot be ordered.
+func checkProductAvailable(product *domain.Product) error {
+	if product.Status != domain.ProductActive {
+		return status.Errorf(codes.FailedPrecondition, "product %s is not available", product.ID)
+	}
+	return nil
+}
+
+// RestoreProduct makes an archived product active again.
+func (s *service) RestoreProduct(ctx context.Context, req *proto.RestoreProductRequest) (*proto.RestoreProductResponse, error) {
+	before, err := s.getProduct(req.Id)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkVersion(req.ExpectedVersion, before.Version); err != nil {
+		return nil, err
+	}
+	if before.Status != domain.ProductArchived {
+		return nil, status.Error(codes.FailedPrecondition, domain.ErrProductNotArchived.Error())
+	}
+
+	if err := s.repo.RestoreProduct(req.Id, before.Version); err != nil {
+		if errors.Is(err, domain.ErrVersionMismatch) {
+			return nil, versionConflict(err.Error())
+		}
+		if errors.Is(err, domain.ErrProductNotArchived) {
+			return nil, status.Error(codes.FailedPrecondition, err.Error())
+		}
+		if errors.Is(err, domain.ErrProductNotFound) {
+			return nil, status.Error(codes.NotFound, err.Error())
+		}
+		return nil, status.Errorf(codes.Internal, "failed to restore product: %v", err)
+	}
+
+	product, err := s.getProduct(req.Id)
+	if err != nil {
+		return nil, err
+	}
+
+	audit.Record(ctx, "product", product.ID, before, product)
+
+	return &proto.RestoreProductResponse{
+		Product: adapters.ToProtoProduct(*product),
+	}, nil
+}
@@ -0,0 +1,123 @@
+package service
+
+import (
+	"context"
+	"ecomm/internal/controller/auth"
+	"ecomm/internal/domain"
+	"ecomm/proto"
+	"reflect"
+	"testing"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestProductStatus(t *testing.T) {
+	tests := []struct {
+		name      string
+		requested string
+		current   string
+		want      string
+		code      codes.Code
+	}{
+		{"unset keeps current", "", domain.ProductDraft, domain.ProductDraft, codes.OK},
+		{"publish draft", domain.ProductActive, domain.ProductDraft, domain.ProductActive, codes.OK},
+		{"unpublish", domain.ProductDraft, domain.ProductActive, domain.ProductDraft, codes.OK},
+		{"archive", domain.ProductArchived, domain.ProductActive, "", codes.InvalidArgument},
+		{"unknown", "hidden", domain.ProductActive, "", codes.InvalidArgument},
+		{"activate archived", domain.ProductActive, domain.ProductArchived, "", codes.FailedPrecondition},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := productStatus(tt.requested, tt.current)
+			if status.Code(err) != tt.code {
+				t.Fatalf("productStatus() error = %v; want %v", err, tt.code)
+			}
+			if got != tt.want {
+				t.Fatalf("productStatus() = %q; want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestListedStatuses(t *testing.T) {
+	staff := &auth.Claims{ID: "staff-1", Permissions: []string{domain.PermissionProductsUpdate}}
+
+	tests := []struct {
+		name      string
+		claims    *auth.Claims
+		requested string
+		want      []string
+		code      codes.Code
+	}{
+		{"customer default", nil, "", []string{domain.ProductActive}, codes.OK},
+		{"staff default", staff, "", []string{domain.ProductDraft, domain.ProductActive}, codes.OK},
+		{"customer active", nil, domain.ProductActive, []string{domain.ProductActive}, codes.OK},
+		{"customer drafts", &auth.Claims{ID: "user-1"}, domain.ProductDraft, nil, codes.PermissionDenied},
+		{"customer archived", nil, domain.ProductArchived, nil, codes.PermissionDenied},
+		{"staff archived", staff, domain.ProductArchived, []string{domain.ProductArchived}, codes.OK},
+		{"unknown", staff, "hidden", nil, codes.InvalidArgument},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			if tt.claims != nil {
+				ctx = auth.ContextWithClaims(ctx, tt.claims)
+			}
+
+			got, err := listedStatuses(ctx, tt.requested)
+			if status.Code(err) != tt.code {
+				t.Fatalf("listedStatuses() error = %v; want %v", err, tt.code)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("listedStatuses() = %v; want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCheckProductAvailable(t *testing.T) {
+	for _, productStatus := range []string{domain.ProductDraft, domain.ProductActive, domain.ProductArchived} {
+		err := checkProductAvailable(&domain.Product{ID: "product-1", Status: productStatus})
+		if productStatus == domain.ProductActive && err != nil {
+			t.Errorf("%s: checkProductAvailable() error = %v", productStatus, err)
+		}
+		if productStatus != domain.ProductActive && status.Code(err) != codes.FailedPrecondition {
+			t.Errorf("%s: checkProductAvailable() error = %v; want FailedPrecondition", productStatus, err)
+		}
+	}
+}
+
+func TestImportProductsReportsArchivedSKUs(t *testing.T) {
+	for _, dryRun := range []bool{true, false} {
+		repo := newFakeRepo()
+		repo.categories = []*domain.Category{{ID: "category-1", Slug: "mugs"}}
+		repo.products = map[string]*domain.Product{
+			"product-1": {ID: "product-1", SKU: "MUG-1", Name: "Old mug", Status: domain.ProductArchived},
+			"product-2": {ID: "product-2", SKU: "MUG-2", Name: "Mug", Status: domain.ProductActive},
+		}
+		s := &service{repo: repo}
+
+		data := `[
+			{"sku": "MUG-1", "name": "Revived mug", "category": "mugs", "image": "mug.png"},
+			{"sku": "MUG-2", "name": "Mug", "category": "mugs", "image": "mug.png"},
+			{"sku": "MUG-3", "name": "New mug", "category": "mugs", "image": "mug.png"}
+		]`
+		res, err := s.ImportProducts(context.Background(), &proto.ImportProductsRequest{Format: "json", Data: []byte(data), DryRun: dryRun})
+		if err != nil {
+			t.Fatalf("ImportProducts(dry run %v) error = %v", dryRun, err)
+		}
+
+		if res.Created != 1 || res.Updated != 1 || res.Failed != 1 {
+			t.Fatalf("dry run %v: created %d, updated %d, failed %d; want 1 each", dryRun, res.Created, res.Updated, res.Failed)
+		}
+		if rowError := res.Errors[0]; rowError.Line != 1 || rowError.Sku != "MUG-1" {
+			t.Fatalf("dry run %v: error = %+v; want line 1", dryRun, rowError)
+		}
+		if name := repo.products["product-1"].Name; name != "Old mug" {
+			t.Fatalf("dry run %v: archived product renamed to %q", dryRun, name)
+		}
+	}
+}
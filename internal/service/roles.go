@@ -0,0 +1,144 @@
+package service
+
+import (
+	"context"
+	"ecomm/internal/adapters"
+	"ecomm/internal/audit"
+	"ecomm/internal/domain"
+	"ecomm/proto"
+	"errors"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// MethodPermissions maps RPCs to the permission a caller must hold. RPCs that
+// are not listed are either public or only require an authenticated caller.
+var MethodPermissions = map[string]string{
+	proto.ApiService_CreateProduct_FullMethodName:  domain.PermissionProductsCreate,
+	proto.ApiService_UpdateProduct_FullMethodName:  domain.PermissionProductsUpdate,
+	proto.ApiService_DeleteProduct_FullMethodName:  domain.PermissionProductsDelete,
+	proto.ApiService_RestoreProduct_FullMethodName: domain.PermissionProductsDelete,
+	proto.ApiService_ImportProducts_FullMethodName: domain.PermissionProductsImport,
+	proto.ApiService_ExportProducts_FullMethodName: domain.PermissionProductsExport,
+
+	proto.ApiService_SetProductOptions_FullMethodName:    domain.PermissionProductsUpdate,
+	proto.ApiService_CreateProductVariant_FullMethodName: domain.PermissionProductsUpdate,
+	proto.ApiService_UpdateProductVariant_FullMethodName: domain.PermissionProductsUpdate,
+	proto.ApiService_DeleteProductVariant_FullMethodName: domain.PermissionProductsUpdate,
+	proto.ApiService_UploadProductImage_FullMethodName:   domain.PermissionProductsUpdate,
+	proto.ApiService_DeleteProductImage_FullMethodName:   domain.PermissionProductsUpdate,
+	proto.ApiService_ReorderProductImages_FullMethodName: domain.PermissionProductsUpdate,
+
+	proto.ApiService_SetProductSale_FullMethodName:             domain.PermissionProductsUpdate,
+	proto.ApiService_ClearProductSale_FullMethodName:           domain.PermissionProductsUpdate,
+	proto.ApiService_ListPriceHistory_FullMethodName:           domain.PermissionProductsUpdate,
+	proto.ApiService_SchedulePriceChange_FullMethodName:        domain.PermissionProductsUpdate,
+	proto.ApiService_ListScheduledPriceChanges_FullMethodName:  domain.PermissionProductsUpdate,
+	proto.ApiService_CancelScheduledPriceChange_FullMethodName: domain.PermissionProductsUpdate,
+
+	proto.ApiService_AdjustStock_FullMethodName:         domain.PermissionInventoryManage,
+	proto.ApiService_ListStockMovements_FullMethodName:  domain.PermissionInventoryRead,
+	proto.ApiService_ReconcileStock_FullMethodName:      domain.PermissionInventoryManage,
+	proto.ApiService_TransferStock_FullMethodName:       domain.PermissionInventoryManage,
+	proto.ApiService_GetProductStock_FullMethodName:     domain.PermissionInventoryRead,
+	proto.ApiService_SetReorderThreshold_FullMethodName: domain.PermissionInventoryManage,
+	proto.ApiService_ListStockAlerts_FullMethodName:     domain.PermissionInventoryRead,
+	proto.ApiService_ListWarehouses_FullMethodName:      domain.PermissionInventoryRead,
+	proto.ApiService_CreateWarehouse_FullMethodName:     domain.PermissionInventoryManage,
+	proto.ApiService_UpdateWarehouse_FullMethodName:     domain.PermissionInventoryManage,
+
+	proto.ApiService_CreateCategory_FullMethodName: domain.PermissionCategoriesManage,
+	proto.ApiService_UpdateCategory_FullMethodName: domain.PermissionCategoriesManage,
+	proto.ApiService_DeleteCategory_FullMethodName: domain.PermissionCategoriesManage,
+
+	proto.ApiService_ListOrders_FullMethodName:  domain.PermissionOrdersRead,
+	proto.ApiService_DeleteOrder_FullMethodName: domain.PermissionOrdersDelete,
+
+	proto.ApiService_GetUser_FullMethodName:         domain.PermissionUsersRead,
+	proto.ApiService_ListUsers_FullMethodName:       domain.PermissionUsersRead,
+	proto.ApiService_GetUserByID_FullMethodName:     domain.PermissionUsersRead,
+	proto.ApiService_AdminUpdateUser_FullMethodName: domain.PermissionUsersUpdate,
+	proto.ApiService_SuspendUser_FullMethodName:     domain.PermissionUsersUpdate,
+	proto.ApiService_DisableUser_FullMethodName:     domain.PermissionUsersUpdate,
+	proto.ApiService_EnableUser_FullMethodName:      domain.PermissionUsersUpdate,
+	proto.ApiService_SetUserAdmin_FullMethodName:    domain.PermissionAll,
+	proto.ApiService_UnlockAccount_FullMethodName:   domain.PermissionUsersUpdate,
+	proto.ApiService_ImpersonateUser_FullMethodName: domain.PermissionAll,
+
+	proto.ApiService_ListRoles_FullMethodName:      domain.PermissionRolesManage,
+	proto.ApiService_ListUserRoles_FullMethodName:  domain.PermissionRolesManage,
+	proto.ApiService_AssignUserRole_FullMethodName: domain.PermissionRolesManage,
+	proto.ApiService_RemoveUserRole_FullMethodName: domain.PermissionRolesManage,
+
+	proto.ApiService_CreateAPIKey_FullMethodName: domain.PermissionAPIKeysManage,
+	proto.ApiService_ListAPIKeys_FullMethodName:  domain.PermissionAPIKeysManage,
+	proto.ApiService_RevokeAPIKey_FullMethodName: domain.PermissionAPIKeysManage,
+
+	proto.ApiService_ListAuditEvents_FullMethodName: domain.PermissionAuditRead,
+}
+
+// userPermissions returns the permissions embedded in the user's tokens.
+func (s *service) userPermissions(user *domain.User) ([]string, error) {
+	if user.IsAdmin {
+		return []string{domain.PermissionAll}, nil
+	}
+	return s.repo.GetUserPermissions(user.ID)
+}
+
+func (s *service) ListRoles(ctx context.Context, req *proto.ListRolesRequest) (*proto.ListRolesResponse, error) {
+	roles, err := s.repo.ListRoles()
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to list roles: %v", err)
+	}
+
+	return &proto.ListRolesResponse{
+		Roles: adapters.ToProtoRoles(roles),
+	}, nil
+}
+
+func (s *service) ListUserRoles(ctx context.Context, req *proto.ListUserRolesRequest) (*proto.ListUserRolesResponse, error) {
+	roles, err := s.repo.GetUserRoles(req.UserId)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to list user roles: %v", err)
+	}
+
+	return &proto.ListUserRolesResponse{
+		Roles: adapters.ToProtoRoles(roles),
+	}, nil
+}
+
+func (s *service) AssignUserRole(ctx context.Context, req *proto.AssignUserRoleRequest) (*proto.AssignUserRoleResponse, error) {
+	if _, err := s.repo.GetUserByID(req.UserId); err != nil {
+		if errors.Is(err, domain.ErrUserNotFound) {
+			return nil, status.Error(codes.NotFound, err.Error())
+		}
+		return nil, status.Errorf(codes.Internal, "failed to get user: %v", err)
+	}
+
+	if err := s.repo.AssignUserRole(req.UserId, req.Role); err != nil {
+		if errors.Is(err, domain.ErrRoleNotFound) {
+			return nil, status.Error(codes.NotFound, err.Error())
+		}
+		return nil, status.Errorf(codes.Internal, "failed to assign role: %v", err)
+	}
+
+	audit.Record(ctx, "user", req.UserId, nil, nil)
+	audit.AddMetadata(ctx, "role", req.Role)
+
+	return &proto.AssignUserRoleResponse{}, nil
+}
+
+func (s *service) RemoveUserRole(ctx context.Context, req *proto.RemoveUserRoleRequest) (*proto.RemoveUserRoleResponse, error) {
+	if err := s.repo.RemoveUserRole(req.UserId, req.Role); err != nil {
+		if errors.Is(err, domain.ErrRoleNotFound) {
+			return nil, status.Error(codes.NotFound, err.Error())
+		}
+		return nil, status.Errorf(codes.Internal, "failed to remove role: %v", err)
+	}
+
+	audit.Record(ctx, "user", req.UserId, nil, nil)
+	audit.AddMetadata(ctx, "role", req.Role)
+
+	return &proto.RemoveUserRoleResponse{}, nil
+}
@@ -3,14 +3,24 @@ package service
 import (
 	"context"
 	"ecomm/internal/adapters"
+	"ecomm/internal/audit"
 	"ecomm/internal/controller/auth"
 	"ecomm/internal/domain"
+	"ecomm/internal/inventory"
+	"ecomm/internal/mailer"
+	"ecomm/internal/oidc"
+	"ecomm/internal/password"
+	"ecomm/internal/storage"
+	"ecomm/pkg"
 	"ecomm/proto"
+	"errors"
 	"fmt"
+	"math"
+	"os"
+	"strings"
 	"time"
 
 	"github.com/google/uuid"
-	"golang.org/x/crypto/bcrypt"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
 )
@@ -18,25 +28,67 @@ import (
 type service struct {
 	repo       domain.Repository
 	jwtManager *auth.JWTManager
+	mailer     mailer.Mailer
+	// requireAdminMFA withholds privileges from users who have not enrolled
+	// in multi-factor authentication.
+	requireAdminMFA bool
+	oidcProviders   map[string]*oidc.Provider
+	storage         storage.Storage
+	hasher          *password.Hasher
+	passwordChecker *password.Checker
+	// dummyHash is verified against when a login names an unknown account.
+	dummyHash string
+	// strategy picks the warehouses that fulfil new orders.
+	strategy inventory.Strategy
 	proto.UnimplementedApiServiceServer
 }
 
-func NewService(repo domain.Repository) proto.ApiServiceServer {
+func NewService(repo domain.Repository, mailer mailer.Mailer, oidcProviders map[string]*oidc.Provider, storage storage.Storage) proto.ApiServiceServer {
 	jwtManager, err := auth.NewTokenGenerator()
 	if err != nil {
 		panic(err)
 	}
+
+	hasher, err := password.HasherFromEnv()
+	if err != nil {
+		panic(err)
+	}
+
+	passwordChecker, err := password.CheckerFromEnv(hasher)
+	if err != nil {
+		panic(err)
+	}
+
+	dummyHash, err := hasher.Hash("dummy-password")
+	if err != nil {
+		panic(err)
+	}
+
+	strategy, err := inventory.StrategyFromEnv()
+	if err != nil {
+		panic(err)
+	}
+
 	return &service{
-		repo:       repo,
-		jwtManager: jwtManager,
+		repo:            repo,
+		jwtManager:      jwtManager,
+		mailer:          mailer,
+		requireAdminMFA: os.Getenv("MFA_REQUIRED_FOR_ADMINS") == "true",
+		oidcProviders:   oidcProviders,
+		storage:         storage,
+		hasher:          hasher,
+		passwordChecker: passwordChecker,
+		dummyHash:       dummyHash,
+		strategy:        strategy,
 	}
 }
 
 func (s *service) CreateProduct(ctx context.Context, req *proto.CreateProductRequest) (*proto.CreateProductResponse, error) {
 	product := &domain.Product{
+		SKU:             strings.TrimSpace(req.Sku),
 		Name:            req.Name,
 		Image:           req.Image,
-		Category:        req.Category,
+		CategoryID:      req.CategoryId,
 		Description:     req.Description,
 		Rating:          int(req.Rating),
 		NumberOfReviews: int(req.NumberOfReviews),
@@ -44,19 +96,41 @@ func (s *service) CreateProduct(ctx context.Context, req *proto.CreateProductReq
 		CountInStock:    int(req.CountInStock),
 	}
 
-	product, err := s.repo.CreateProduct(product)
+	if product.CountInStock < 0 {
+		return nil, status.Error(codes.InvalidArgument, "count_in_stock must not be negative")
+	}
+
+	var err error
+	if product.Status, err = productStatus(req.Status, domain.ProductActive); err != nil {
+		return nil, err
+	}
+
+	product, err = s.repo.CreateProduct(product, actorID(ctx))
 	if err != nil {
+		if errors.Is(err, domain.ErrCategoryNotFound) {
+			return nil, status.Error(codes.InvalidArgument, err.Error())
+		}
+		if errors.Is(err, domain.ErrSKUTaken) {
+			return nil, status.Error(codes.AlreadyExists, err.Error())
+		}
 		return nil, status.Errorf(codes.Internal, "failed to create product: %v", err)
 	}
+
+	audit.Record(ctx, "product", product.ID, nil, product)
 	return &proto.CreateProductResponse{
 		Product: adapters.ToProtoProduct(*product),
 	}, nil
 }
 
+// GetProductByID also returns archived products, which orders still refer
+// to. Drafts are only shown to staff.
 func (s *service) GetProductByID(ctx context.Context, req *proto.GetProductByIDRequest) (*proto.GetProductByIDResponse, error) {
-	product, err := s.repo.GetProductByID(req.Id)
+	product, err := s.getProduct(req.Id)
 	if err != nil {
-		return nil, status.Errorf(codes.Internal, "failed to get product: %v", err)
+		return nil, err
+	}
+	if product.Status == domain.ProductDraft && !isCatalogStaff(ctx) {
+		return nil, status.Error(codes.NotFound, domain.ErrProductNotFound.Error())
 	}
 	return &proto.GetProductByIDResponse{
 		Product: adapters.ToProtoProduct(*product),
@@ -64,98 +138,222 @@ func (s *service) GetProductByID(ctx context.Context, req *proto.GetProductByIDR
 }
 
 func (s *service) ListProducts(ctx context.Context, req *proto.ListProductsRequest) (*proto.ListProductsResponse, error) {
-	products, err := s.repo.ListProducts()
+	statuses, err := listedStatuses(ctx, req.Status)
+	if err != nil {
+		return nil, err
+	}
+
+	products, err := s.repo.ListProducts(&domain.ProductFilter{
+		Query:    strings.TrimSpace(req.Query),
+		Statuses: statuses,
+	})
 	if err != nil {
 		return nil, status.Errorf(codes.Internal, "failed to list products: %v", err)
 	}
 
+	for _, product := range products {
+		s.resolveImageURLs(product.Images)
+	}
+
 	return &proto.ListProductsResponse{
 		Products: adapters.ToProtoProducts(products),
 	}, nil
 }
 
+// UpdateProduct changes the fields named by the update mask, or the non-zero
+// fields of the request if it has none.
 func (s *service) UpdateProduct(ctx context.Context, req *proto.UpdateProductRequest) (*proto.UpdateProductResponse, error) {
+	paths, err := updatePaths(req.UpdateMask, req, productUpdateFields)
+	if err != nil {
+		return nil, err
+	}
+
 	product, err := s.repo.GetProductByID(req.Id)
 	if err != nil {
 		return nil, err
 	}
+	if err := checkVersion(req.ExpectedVersion, product.Version); err != nil {
+		return nil, err
+	}
+	before := *product
 
-	if req.Name != "" {
+	if paths["sku"] {
+		product.SKU = strings.TrimSpace(req.Sku)
+	}
+	if paths["name"] {
+		if req.Name == "" {
+			return nil, status.Error(codes.InvalidArgument, "name must not be empty")
+		}
 		product.Name = req.Name
 	}
-	if req.Image != "" {
+	if paths["image"] {
+		if req.Image == "" {
+			return nil, status.Error(codes.InvalidArgument, "image must not be empty")
+		}
 		product.Image = req.Image
 	}
-	if req.Category != "" {
-		product.Category = req.Category
+	if paths["category_id"] {
+		if req.CategoryId == "" {
+			return nil, status.Error(codes.InvalidArgument, "category_id must not be empty")
+		}
+		product.CategoryID = req.CategoryId
 	}
-	if req.Description != "" {
+	if paths["description"] {
 		product.Description = req.Description
 	}
-	if req.Rating != 0 {
+	if paths["rating"] {
+		if req.Rating < 0 {
+			return nil, status.Error(codes.InvalidArgument, "rating must not be negative")
+		}
 		product.Rating = int(req.Rating)
 	}
-	if req.NumberOfReviews != 0 {
+	if paths["number_of_reviews"] {
+		if req.NumberOfReviews < 0 {
+			return nil, status.Error(codes.InvalidArgument, "number_of_reviews must not be negative")
+		}
 		product.NumberOfReviews = int(req.NumberOfReviews)
 	}
-	if req.Price != 0 {
+	if paths["price"] {
+		if req.Price < 0 {
+			return nil, status.Error(codes.InvalidArgument, "price must not be negative")
+		}
 		product.Price = req.Price
 	}
-	if req.CountInStock != 0 {
-		product.CountInStock = int(req.CountInStock)
+	if paths["status"] {
+		if req.Status == "" {
+			return nil, status.Error(codes.InvalidArgument, "status must be draft or active")
+		}
+		if product.Status, err = productStatus(req.Status, product.Status); err != nil {
+			return nil, err
+		}
+	}
+	// The stock is moved through the ledger.
+	var stock *domain.InventoryMovement
+	if paths["count_in_stock"] {
+		if req.CountInStock < 0 {
+			return nil, status.Error(codes.InvalidArgument, "count_in_stock must not be negative")
+		}
+		stock = stockTarget(ctx, product.ID, "", int(req.CountInStock), "set by product update")
+	}
+
+	if err := s.repo.UpdateProduct(product, stock, actorID(ctx)); err != nil {
+		if errors.Is(err, domain.ErrVersionMismatch) {
+			return nil, versionConflict(err.Error())
+		}
+		if errors.Is(err, domain.ErrCategoryNotFound) {
+			return nil, status.Error(codes.InvalidArgument, err.Error())
+		}
+		if errors.Is(err, domain.ErrSKUTaken) {
+			return nil, status.Error(codes.AlreadyExists, err.Error())
+		}
+		if errors.Is(err, domain.ErrInsufficientStock) || errors.Is(err, domain.ErrNoWarehouse) {
+			return nil, inventoryError(err)
+		}
+		return nil, err
 	}
 
-	if err := s.repo.UpdateProduct(product); err != nil {
+	audit.Record(ctx, "product", product.ID, before, product)
+
+	if product, err = s.getProduct(product.ID); err != nil {
 		return nil, err
 	}
 
-	return &proto.UpdateProductResponse{}, nil
+	return &proto.UpdateProductResponse{
+		Product: adapters.ToProtoProduct(*product),
+	}, nil
 }
 
 func (s *service) DeleteProduct(ctx context.Context, req *proto.DeleteProductRequest) (*proto.DeleteProductResponse, error) {
-	if err := s.repo.DeleteProduct(req.Id); err != nil {
+	product, err := s.repo.GetProductByID(req.Id)
+	if err != nil {
 		return nil, err
 	}
+	if err := checkVersion(req.ExpectedVersion, product.Version); err != nil {
+		return nil, err
+	}
+
+	// Products are archived rather than deleted so that the orders which
+	// reference them keep resolving. The images are kept for a restore.
+	if err := s.repo.ArchiveProduct(req.Id, product.Version); err != nil {
+		if errors.Is(err, domain.ErrVersionMismatch) {
+			return nil, versionConflict(err.Error())
+		}
+		return nil, err
+	}
+
+	after, err := s.repo.GetProductByID(req.Id)
+	if err != nil {
+		return nil, err
+	}
+
+	audit.Record(ctx, "product", req.Id, product, after)
 	return &proto.DeleteProductResponse{
 		Id: req.Id,
 	}, nil
 }
 
+// roundPrice rounds a price to whole cents.
+func roundPrice(price float64) float64 {
+	return math.Round(price*100) / 100
+}
+
+// CreateOrder prices the items from the catalog, at their sale price while a
+// sale runs, and computes the total, so that clients cannot choose what they
+// pay.
 func (s *service) CreateOrder(ctx context.Context, req *proto.CreateOrderRequest) (*proto.CreateOrderResponse, error) {
-	for _, item := range req.OrderItems {
-		_, err := s.repo.GetProductByID(item.ProductId)
+	now := uint64(time.Now().Unix())
+	orderItems := make([]*domain.OrderItem, len(req.OrderItems))
+	itemsPrice := 0.0
+	for i, item := range req.OrderItems {
+		product, err := s.repo.GetProductByID(item.ProductId)
 		if err != nil {
 			return nil, err
 		}
-	}
 
-	orderItems := make([]*domain.OrderItem, len(req.OrderItems))
-	for i, item := range req.OrderItems {
-		orderItems[i] = &domain.OrderItem{
-			ID:        item.Id,
-			OrderID:   item.OrderId,
-			ProductID: item.ProductId,
-			Name:      item.Name,
-			Quantity:  int(item.Quantity),
-			Image:     item.Image,
-			Price:     item.Price,
+		if err := checkProductAvailable(product); err != nil {
+			return nil, err
+		}
+
+		if err := checkOrderItemVariant(product, item.VariantId); err != nil {
+			return nil, err
 		}
+
+		if item.Quantity <= 0 {
+			return nil, status.Error(codes.InvalidArgument, "quantity must be positive")
+		}
+
+		orderItems[i] = newOrderItem(product, item.VariantId, int(item.Quantity), now)
+		itemsPrice += orderItems[i].Price * float64(orderItems[i].Quantity)
+	}
+
+	if err := checkLocation(req.ShippingLocation); err != nil {
+		return nil, err
 	}
 
 	order := &domain.Order{
-		PaymentMethod: req.PaymentMethod,
-		TaxPrice:      req.TaxPrice,
-		ShippingPrice: req.ShippingPrice,
-		TotalPrice:    req.TotalPrice,
-		OrderItems:    orderItems,
-		UserID:        req.UserId,
+		PaymentMethod:    req.PaymentMethod,
+		TaxPrice:         req.TaxPrice,
+		ShippingPrice:    req.ShippingPrice,
+		TotalPrice:       roundPrice(itemsPrice + req.TaxPrice + req.ShippingPrice),
+		OrderItems:       orderItems,
+		UserID:           req.UserId,
+		ShippingLocation: adapters.FromProtoLocation(req.ShippingLocation),
+	}
+
+	if err := s.allocateOrderItems(orderItems, order.ShippingLocation); err != nil {
+		return nil, err
 	}
 
 	order, err := s.repo.CreateOrder(order)
 	if err != nil {
+		if errors.Is(err, domain.ErrInsufficientStock) || errors.Is(err, domain.ErrWarehouseNotFound) {
+			return nil, status.Error(codes.FailedPrecondition, err.Error())
+		}
 		return nil, err
 	}
 
+	audit.Record(ctx, "order", order.ID, nil, order)
+
 	return &proto.CreateOrderResponse{
 		Order: adapters.ToProtoOrder(*order),
 	}, nil
@@ -184,16 +382,25 @@ func (s *service) ListOrders(ctx context.Context, req *proto.ListOrdersRequest)
 }
 
 func (s *service) DeleteOrder(ctx context.Context, req *proto.DeleteOrderRequest) (*proto.DeleteOrderResponse, error) {
-	if err := s.repo.DeleteOrder(req.Id); err != nil {
+	if err := s.repo.DeleteOrder(req.Id, actorID(ctx), req.ExpectedVersion); err != nil {
+		if errors.Is(err, domain.ErrVersionMismatch) {
+			return nil, versionConflict(err.Error())
+		}
 		return nil, err
 	}
+
+	audit.Record(ctx, "order", req.Id, nil, nil)
 	return &proto.DeleteOrderResponse{
 		Id: req.Id,
 	}, nil
 }
 
 func (s *service) CreateUser(ctx context.Context, req *proto.CreateUserRequest) (*proto.CreateUserResponse, error) {
-	hashed, err := bcrypt.GenerateFromPassword([]byte(req.Password), bcrypt.DefaultCost)
+	if err := s.checkPasswordStrength(req.Password, req.Email, req.Name); err != nil {
+		return nil, err
+	}
+
+	hashed, err := s.hashPassword(req.Password)
 	if err != nil {
 		return nil, err
 	}
@@ -201,15 +408,19 @@ func (s *service) CreateUser(ctx context.Context, req *proto.CreateUserRequest)
 	user := &domain.User{
 		Name:     req.Name,
 		Email:    req.Email,
-		Password: string(hashed),
-		IsAdmin:  req.IsAdmin,
+		Password: hashed,
 	}
 
 	createdUser, err := s.repo.CreateUser(user)
 	if err != nil {
-		return nil, err
+		if errors.Is(err, domain.ErrEmailTaken) {
+			return nil, status.Error(codes.AlreadyExists, err.Error())
+		}
+		return nil, status.Errorf(codes.Internal, "failed to create user: %v", err)
 	}
 
+	audit.Record(ctx, "user", createdUser.ID, nil, createdUser)
+
 	return &proto.CreateUserResponse{
 		Id:      createdUser.ID,
 		Name:    createdUser.Name,
@@ -229,62 +440,123 @@ func (s *service) GetUser(ctx context.Context, req *proto.GetUserRequest) (*prot
 	}, nil
 }
 
-func (s *service) ListUsers(ctx context.Context, req *proto.ListUsersRequest) (*proto.ListUsersResponse, error) {
-	users, err := s.repo.ListUsers()
-	if err != nil {
+// UpdateUser changes the caller's profile. Passwords and email addresses are
+// changed through ChangePassword and RequestEmailChange, which require the
+// current password.
+func (s *service) UpdateUser(ctx context.Context, req *proto.UpdateUserRequest) (*proto.UpdateUserResponse, error) {
+	if err := authorizeUserAccess(ctx, req.Id, domain.PermissionUsersUpdate); err != nil {
 		return nil, err
 	}
 
-	return &proto.ListUsersResponse{
-		Users: adapters.ToProtoUsers(users),
-	}, nil
-}
-
-func (s *service) UpdateUser(ctx context.Context, req *proto.UpdateUserRequest) (*proto.UpdateUserResponse, error) {
-	user, err := s.repo.GetUser(req.Email)
+	paths, err := updatePaths(req.UpdateMask, req, userUpdateFields)
 	if err != nil {
 		return nil, err
 	}
 
-	if req.Name != "" {
-		user.Name = req.Name
+	user, err := s.getUserByID(req.Id)
+	if err != nil {
+		return nil, err
 	}
-	if req.Password != "" {
-		user.Password = req.Password
+	if err := checkVersion(req.ExpectedVersion, user.Version); err != nil {
+		return nil, err
 	}
-	if req.IsAdmin != user.IsAdmin {
-		user.IsAdmin = req.IsAdmin
+	before := *user
+
+	if paths["name"] {
+		if req.Name == "" {
+			return nil, status.Error(codes.InvalidArgument, "name must not be empty")
+		}
+		user.Name = req.Name
 	}
 
-	user.UpdatedAt = uint64(time.Now().Unix())
 	if err := s.repo.UpdateUser(user); err != nil {
-		return nil, err
+		if errors.Is(err, domain.ErrVersionMismatch) {
+			return nil, versionConflict(err.Error())
+		}
+		return nil, status.Errorf(codes.Internal, "failed to update user: %v", err)
 	}
 
+	audit.Record(ctx, "user", user.ID, before, user)
+
 	return &proto.UpdateUserResponse{
 		User: adapters.ToProtoUser(*user),
 	}, nil
 }
 
-func (s *service) DeleteUser(ctx context.Context, req *proto.DeleteUserRequest) (*proto.DeleteUserResponse, error) {
-	if err := s.repo.DeleteUser(req.UserId); err != nil {
-		return nil, err
+func (s *service) SetUserAdmin(ctx context.Context, req *proto.SetUserAdminRequest) (*proto.SetUserAdminResponse, error) {
+	before, err := s.repo.GetUserByID(req.UserId)
+	if err != nil {
+		if errors.Is(err, domain.ErrUserNotFound) {
+			return nil, status.Error(codes.NotFound, err.Error())
+		}
+		return nil, status.Errorf(codes.Internal, "failed to get user: %v", err)
 	}
 
-	if err := s.repo.DeleteSession(req.SessionId); err != nil {
-		return nil, err
+	if err := s.repo.SetUserAdmin(req.UserId, req.IsAdmin); err != nil {
+		if errors.Is(err, domain.ErrUserNotFound) {
+			return nil, status.Error(codes.NotFound, err.Error())
+		}
+		return nil, status.Errorf(codes.Internal, "failed to update user: %v", err)
+	}
+
+	// Sessions of a demoted admin would otherwise keep minting admin tokens.
+	if before.IsAdmin && !req.IsAdmin {
+		if err := s.repo.RevokeUserSessions(before.Email); err != nil {
+			return nil, status.Errorf(codes.Internal, "failed to revoke sessions: %v", err)
+		}
+	}
+
+	user, err := s.repo.GetUserByID(req.UserId)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to get user: %v", err)
 	}
 
-	return &proto.DeleteUserResponse{}, nil
+	audit.Record(ctx, "user", user.ID, before, user)
+	return &proto.SetUserAdminResponse{
+		User: adapters.ToProtoUser(*user),
+	}, nil
 }
 
 func (s *service) Login(ctx context.Context, req *proto.LoginRequest) (*proto.LoginResponse, error) {
-	user, err := s.repo.GetUser(req.Email)
+	user, err := s.authenticate(req.Email, req.Password, req.ClientIp)
 	if err != nil {
 		return nil, err
 	}
 
-	if err := bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(req.Password)); err != nil {
+	return s.completeLogin(user)
+}
+
+// completeLogin finishes a login for a user whose primary credentials were
+// verified, either issuing a session or an MFA challenge.
+func (s *service) completeLogin(user *domain.User) (*proto.LoginResponse, error) {
+	if err := checkAccountActive(user); err != nil {
+		return nil, err
+	}
+
+	mfaEnabled, err := s.mfaEnabled(user.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	if mfaEnabled {
+		mfaToken, err := s.jwtManager.GenerateMFAChallenge(user.ID, time.Now().Add(mfaChallengeTTL))
+		if err != nil {
+			return nil, err
+		}
+
+		return &proto.LoginResponse{
+			MfaRequired: true,
+			MfaToken:    mfaToken,
+		}, nil
+	}
+
+	return s.issueSession(user, false)
+}
+
+// issueSession creates a session for an authenticated user and returns its
+// access and refresh tokens.
+func (s *service) issueSession(user *domain.User, mfaVerified bool) (*proto.LoginResponse, error) {
+	if err := checkAccountActive(user); err != nil {
 		return nil, err
 	}
 
@@ -293,13 +565,18 @@ func (s *service) Login(ctx context.Context, req *proto.LoginRequest) (*proto.Lo
 		return nil, err
 	}
 
+	isAdmin, permissions, enrollmentRequired, err := s.tokenGrants(user, mfaVerified)
+	if err != nil {
+		return nil, err
+	}
+
 	tokenID := UUID.String()
-	accessToken, _, err := s.jwtManager.GenerateToken(user.Email, user.ID, tokenID, user.IsAdmin, time.Now().Add(3*time.Hour))
+	accessToken, _, err := s.jwtManager.GenerateToken(user.Email, user.ID, tokenID, isAdmin, permissions, time.Now().Add(3*time.Hour))
 	if err != nil {
 		return nil, err
 	}
 
-	refreshToken, refreshClaims, err := s.jwtManager.GenerateToken(user.Email, user.ID, tokenID, user.IsAdmin, time.Now().Add(3*24*time.Hour))
+	refreshToken, refreshClaims, err := s.jwtManager.GenerateToken(user.Email, user.ID, tokenID, isAdmin, permissions, time.Now().Add(3*24*time.Hour))
 	if err != nil {
 		return nil, err
 	}
@@ -315,9 +592,10 @@ func (s *service) Login(ctx context.Context, req *proto.LoginRequest) (*proto.Lo
 	}
 
 	return &proto.LoginResponse{
-		SessionId:    refreshClaims.RegisteredClaims.ID,
-		AccessToken:  accessToken,
-		RefreshToken: refreshToken,
+		SessionId:             refreshClaims.RegisteredClaims.ID,
+		AccessToken:           accessToken,
+		RefreshToken:          refreshToken,
+		MfaEnrollmentRequired: enrollmentRequired,
 	}, nil
 }
 
@@ -344,7 +622,7 @@ func (s *service) RefreshToken(ctx context.Context, req *proto.RefreshAccessToke
 		return nil, err
 	}
 
-	if session.ExpiresAt > uint64(time.Now().Unix()) {
+	if session.ExpiresAt <= uint64(time.Now().Unix()) {
 		if err := s.repo.RevokeSession(req.SessionId); err != nil {
 			return nil, err
 		}
@@ -365,7 +643,23 @@ func (s *service) RefreshToken(ctx context.Context, req *proto.RefreshAccessToke
 		return nil, err
 	}
 
-	token, _, err := s.jwtManager.GenerateToken(user.Email, user.ID, session.ID, user.IsAdmin, time.Now().Add(3*time.Hour))
+	if err := checkAccountActive(user); err != nil {
+		return nil, err
+	}
+
+	mfaEnabled, err := s.mfaEnabled(user.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	// A session can only exist for an MFA user if the second factor was
+	// verified when it was created.
+	isAdmin, permissions, _, err := s.tokenGrants(user, mfaEnabled)
+	if err != nil {
+		return nil, err
+	}
+
+	token, _, err := s.jwtManager.GenerateToken(user.Email, user.ID, session.ID, isAdmin, permissions, time.Now().Add(3*time.Hour))
 	if err != nil {
 		return nil, err
 	}
@@ -375,6 +669,16 @@ func (s *service) RefreshToken(ctx context.Context, req *proto.RefreshAccessToke
 	}, nil
 }
 
+// sendMail delivers an email in the background, so that callers respond in
+// the same time whether or not they send one. Failures are only logged.
+func (s *service) sendMail(to, subject, body string) {
+	go func() {
+		if err := s.mailer.Send(to, subject, body); err != nil {
+			pkg.ErrorLogger.Printf("failed to send email %q: %v", subject, err)
+		}
+	}()
+}
+
 func (s *service) RevokeSession(ctx context.Context, req *proto.RevokeSessionRequest) (*proto.RevokeSessionResponse, error) {
 	if err := s.repo.RevokeSession(req.SessionId); err != nil {
 		return nil, err
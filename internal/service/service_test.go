@@ -0,0 +1,658 @@
+package service
+
+import (
+	"ecomm/internal/domain"
+	"fmt"
+	"slices"
+	"sync"
+)
+
+// fakeRepo implements the parts of domain.Repository that the tests use.
+// Calling any other method panics on the nil embedded interface.
+type fakeRepo struct {
+	domain.Repository
+
+	mu    sync.Mutex
+	users map[string]*domain.User
+	// permissions holds the permissions granted to users through roles.
+	permissions map[string][]string
+	resetTokens []*domain.PasswordResetToken
+	// rehashes counts the UpdatePasswordHash calls.
+	rehashes    int
+	mfa         map[string]*domain.UserMFA
+	apiKeys     []*domain.APIKey
+	identities  []*domain.UserIdentity
+	auditEvents []*domain.AuditEvent
+	sessions    map[string]*domain.Session
+	// revokedSessions holds the emails whose sessions were revoked.
+	revokedSessions []string
+	// clearedThrottles holds the login throttle keys that were cleared.
+	clearedThrottles []string
+	// listUsersArgs holds the query, limit and offset of the last ListUsers call.
+	listUsersArgs []any
+
+	loginThrottles    map[string]*domain.LoginThrottle
+	emailChangeTokens []*domain.EmailChangeToken
+	// changedPasswords maps users to the session kept when their password
+	// was changed.
+	changedPasswords map[string]string
+	categories       []*domain.Category
+
+	products       map[string]*domain.Product
+	warehouses     []*domain.Warehouse
+	warehouseStock []*domain.WarehouseStock
+	orders         []*domain.Order
+	// stockMovements holds the movements recorded and the stock changes
+	// passed along with product updates.
+	stockMovements []*domain.InventoryMovement
+	discrepancies  []*domain.StockDiscrepancy
+	// reconcileApplied holds the apply argument of every ReconcileStock call.
+	reconcileApplied []bool
+
+	priceChanges []*domain.ScheduledPriceChange
+}
+
+func newFakeRepo(users ...*domain.User) *fakeRepo {
+	repo := &fakeRepo{users: make(map[string]*domain.User)}
+	for _, user := range users {
+		repo.users[user.ID] = user
+	}
+	return repo
+}
+
+func (r *fakeRepo) GetUser(email string) (*domain.User, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, user := range r.users {
+		if user.Email == email {
+			copied := *user
+			return &copied, nil
+		}
+	}
+	return nil, domain.ErrUserNotFound
+}
+
+func (r *fakeRepo) GetUserByID(id string) (*domain.User, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	user, ok := r.users[id]
+	if !ok {
+		return nil, domain.ErrUserNotFound
+	}
+	copied := *user
+	return &copied, nil
+}
+
+func (r *fakeRepo) CreatePasswordResetToken(token *domain.PasswordResetToken) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.resetTokens = append(r.resetTokens, token)
+	return nil
+}
+
+func (r *fakeRepo) GetPasswordResetToken(tokenHash string) (*domain.PasswordResetToken, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, token := range r.resetTokens {
+		if token.TokenHash == tokenHash {
+			copied := *token
+			return &copied, nil
+		}
+	}
+	return nil, domain.ErrPasswordResetTokenNotFound
+}
+
+func (r *fakeRepo) ResetPassword(tokenID, userID, passwordHash string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, token := range r.resetTokens {
+		if token.ID == tokenID && token.UsedAt == 0 {
+			token.UsedAt = 1
+			r.users[userID].Password = passwordHash
+			return nil
+		}
+	}
+	return domain.ErrPasswordResetTokenNotFound
+}
+
+// UpdatePasswordHash, like the repository, only replaces oldHash.
+func (r *fakeRepo) UpdatePasswordHash(userID, oldHash, newHash string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.rehashes++
+	if user, ok := r.users[userID]; ok && user.Password == oldHash {
+		user.Password = newHash
+	}
+	return nil
+}
+
+func (r *fakeRepo) SetUserAdmin(id string, isAdmin bool) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	user, ok := r.users[id]
+	if !ok {
+		return domain.ErrUserNotFound
+	}
+	user.IsAdmin = isAdmin
+	return nil
+}
+
+func (r *fakeRepo) EraseUser(userID string, version int64) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	user, ok := r.users[userID]
+	if !ok {
+		return domain.ErrUserNotFound
+	}
+	if user.Version != version {
+		return domain.ErrVersionMismatch
+	}
+	delete(r.users, userID)
+	return nil
+}
+
+func (r *fakeRepo) ClearLoginFailures(key string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.clearedThrottles = append(r.clearedThrottles, key)
+	return nil
+}
+
+func (r *fakeRepo) GetSession(id string) (*domain.Session, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	session, ok := r.sessions[id]
+	if !ok {
+		return nil, domain.ErrSessionNotFound
+	}
+	copied := *session
+	return &copied, nil
+}
+
+func (r *fakeRepo) RevokeSession(id string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	session, ok := r.sessions[id]
+	if !ok {
+		return domain.ErrSessionNotFound
+	}
+	session.IsRevoked = true
+	return nil
+}
+
+func (r *fakeRepo) RevokeUserSessions(email string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.revokedSessions = append(r.revokedSessions, email)
+	return nil
+}
+
+func (r *fakeRepo) GetUserPermissions(userID string) ([]string, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return r.permissions[userID], nil
+}
+
+func (r *fakeRepo) GetUserMFA(userID string) (*domain.UserMFA, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	mfa, ok := r.mfa[userID]
+	if !ok {
+		return nil, domain.ErrMFANotEnrolled
+	}
+	copied := *mfa
+	return &copied, nil
+}
+
+func (r *fakeRepo) SaveMFASecret(userID, secret string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.mfa == nil {
+		r.mfa = make(map[string]*domain.UserMFA)
+	}
+	r.mfa[userID] = &domain.UserMFA{UserID: userID, Secret: secret}
+	return nil
+}
+
+func (r *fakeRepo) CreateAPIKey(key *domain.APIKey) (*domain.APIKey, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	key.ID = "key-1"
+	r.apiKeys = append(r.apiKeys, key)
+	return key, nil
+}
+
+func (r *fakeRepo) CreateUser(user *domain.User) (*domain.User, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	user.ID = fmt.Sprintf("user-%d", len(r.users)+1)
+	r.users[user.ID] = user
+	copied := *user
+	return &copied, nil
+}
+
+func (r *fakeRepo) GetUserIdentity(provider, subject string) (*domain.UserIdentity, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, identity := range r.identities {
+		if identity.Provider == provider && identity.Subject == subject {
+			return identity, nil
+		}
+	}
+	return nil, domain.ErrUserIdentityNotFound
+}
+
+func (r *fakeRepo) CreateUserIdentity(identity *domain.UserIdentity) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.identities = append(r.identities, identity)
+	return nil
+}
+
+func (r *fakeRepo) CreateAuditEvent(event *domain.AuditEvent) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.auditEvents = append(r.auditEvents, event)
+	return nil
+}
+
+type sentMail struct {
+	to, subject, body string
+}
+
+// fakeMailer records the emails sent on a channel, since the service sends
+// them in the background.
+type fakeMailer struct {
+	sent chan sentMail
+}
+
+func newFakeMailer() *fakeMailer {
+	return &fakeMailer{sent: make(chan sentMail, 10)}
+}
+
+func (m *fakeMailer) Send(to, subject, body string) error {
+	m.sent <- sentMail{to: to, subject: subject, body: body}
+	return nil
+}
+
+func (r *fakeRepo) ListUsers(query string, limit, offset int) ([]*domain.User, int64, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.listUsersArgs = []any{query, limit, offset}
+	var users []*domain.User
+	for _, user := range r.users {
+		users = append(users, user)
+	}
+	return users, int64(len(users)), nil
+}
+
+func (r *fakeRepo) SetUserStatus(id, userStatus, reason string, suspendedUntil uint64) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	user, ok := r.users[id]
+	if !ok {
+		return domain.ErrUserNotFound
+	}
+	user.Status = userStatus
+	user.StatusReason = reason
+	user.SuspendedUntil = suspendedUntil
+	return nil
+}
+
+func (r *fakeRepo) GetLoginThrottle(key string) (*domain.LoginThrottle, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if throttle, ok := r.loginThrottles[key]; ok {
+		copied := *throttle
+		return &copied, nil
+	}
+	return &domain.LoginThrottle{Key: key}, nil
+}
+
+func (r *fakeRepo) RecordLoginFailure(key string, windowStart uint64) (*domain.LoginThrottle, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.loginThrottles == nil {
+		r.loginThrottles = make(map[string]*domain.LoginThrottle)
+	}
+	throttle, ok := r.loginThrottles[key]
+	if !ok {
+		throttle = &domain.LoginThrottle{Key: key}
+		r.loginThrottles[key] = throttle
+	}
+	throttle.Failures++
+	copied := *throttle
+	return &copied, nil
+}
+
+func (r *fakeRepo) LockLogin(key string, until uint64) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.loginThrottles[key].LockedUntil = until
+	return nil
+}
+
+func (r *fakeRepo) ChangePassword(userID, passwordHash, keepSessionID string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.changedPasswords == nil {
+		r.changedPasswords = make(map[string]string)
+	}
+	r.changedPasswords[userID] = keepSessionID
+	r.users[userID].Password = passwordHash
+	return nil
+}
+
+func (r *fakeRepo) CreateEmailChangeToken(token *domain.EmailChangeToken) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	token.ID = fmt.Sprintf("token-%d", len(r.emailChangeTokens)+1)
+	r.emailChangeTokens = append(r.emailChangeTokens, token)
+	return nil
+}
+
+func (r *fakeRepo) GetEmailChangeToken(tokenHash string) (*domain.EmailChangeToken, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, token := range r.emailChangeTokens {
+		if token.TokenHash == tokenHash {
+			copied := *token
+			return &copied, nil
+		}
+	}
+	return nil, domain.ErrEmailChangeTokenNotFound
+}
+
+func (r *fakeRepo) ChangeEmail(tokenID, userID, newEmail string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, token := range r.emailChangeTokens {
+		if token.ID == tokenID {
+			token.UsedAt = 1
+		}
+	}
+	r.users[userID].Email = newEmail
+	return nil
+}
+
+func (r *fakeRepo) ListCategories() ([]*domain.Category, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return r.categories, nil
+}
+
+func (r *fakeRepo) GetCategoryByID(id string) (*domain.Category, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, category := range r.categories {
+		if category.ID == id {
+			copied := *category
+			return &copied, nil
+		}
+	}
+	return nil, domain.ErrCategoryNotFound
+}
+
+func (r *fakeRepo) UpdateCategory(category *domain.Category) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for i, existing := range r.categories {
+		if existing.ID == category.ID {
+			copied := *category
+			r.categories[i] = &copied
+			return nil
+		}
+	}
+	return domain.ErrCategoryNotFound
+}
+
+func (r *fakeRepo) GetProductByID(id string) (*domain.Product, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	product, ok := r.products[id]
+	if !ok {
+		return nil, domain.ErrProductNotFound
+	}
+	copied := *product
+	return &copied, nil
+}
+
+func (r *fakeRepo) ListWarehouses() ([]*domain.Warehouse, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return r.warehouses, nil
+}
+
+func (r *fakeRepo) ListWarehouseStock(productID string) ([]*domain.WarehouseStock, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var stock []*domain.WarehouseStock
+	for _, level := range r.warehouseStock {
+		if level.ProductID == productID {
+			stock = append(stock, level)
+		}
+	}
+	return stock, nil
+}
+
+func (r *fakeRepo) CreateOrder(order *domain.Order) (*domain.Order, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	order.ID = fmt.Sprintf("order-%d", len(r.orders)+1)
+	r.orders = append(r.orders, order)
+	return order, nil
+}
+
+func (r *fakeRepo) UpdateProduct(product *domain.Product, stock *domain.InventoryMovement, actorID string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	current, ok := r.products[product.ID]
+	if !ok {
+		return domain.ErrProductNotFound
+	}
+	if current.Version != product.Version {
+		return domain.ErrVersionMismatch
+	}
+
+	product.Version++
+	if stock != nil {
+		stock.Quantity = *stock.SetTo - current.CountInStock
+		stock.Balance = *stock.SetTo
+		r.stockMovements = append(r.stockMovements, stock)
+		product.CountInStock = stock.Balance
+	}
+	copied := *product
+	r.products[product.ID] = &copied
+	return nil
+}
+
+func (r *fakeRepo) RestoreProduct(id string, version int64) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	product, ok := r.products[id]
+	if !ok {
+		return domain.ErrProductNotFound
+	}
+	if version != 0 && product.Version != version {
+		return domain.ErrVersionMismatch
+	}
+	if product.Status != domain.ProductArchived {
+		return domain.ErrProductNotArchived
+	}
+	product.Status = domain.ProductActive
+	product.Version++
+	return nil
+}
+
+func (r *fakeRepo) SetProductSale(productID string, sale *domain.ProductSale, version int64, actorID string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	product, ok := r.products[productID]
+	if !ok {
+		return domain.ErrProductNotFound
+	}
+	if product.Version != version {
+		return domain.ErrVersionMismatch
+	}
+	product.Sale = sale
+	product.Version++
+	return nil
+}
+
+func (r *fakeRepo) DeleteScheduledPriceChange(id, productID string) (*domain.ScheduledPriceChange, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for i, change := range r.priceChanges {
+		if change.ID == id && change.ProductID == productID && change.AppliedAt == 0 {
+			r.priceChanges = slices.Delete(r.priceChanges, i, i+1)
+			return change, nil
+		}
+	}
+	return nil, domain.ErrPriceChangeNotFound
+}
+
+func (r *fakeRepo) RecordInventoryMovement(movement *domain.InventoryMovement) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	product, ok := r.products[movement.ProductID]
+	if !ok {
+		return domain.ErrProductNotFound
+	}
+	if product.CountInStock+movement.Quantity < 0 {
+		return domain.ErrInsufficientStock
+	}
+	product.CountInStock += movement.Quantity
+	movement.ID = fmt.Sprintf("movement-%d", len(r.stockMovements)+1)
+	movement.Balance = product.CountInStock
+	r.stockMovements = append(r.stockMovements, movement)
+	return nil
+}
+
+func (r *fakeRepo) ReconcileStock(apply bool) ([]*domain.StockDiscrepancy, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.reconcileApplied = append(r.reconcileApplied, apply)
+	return r.discrepancies, nil
+}
+
+func (r *fakeRepo) GetWarehouse(id string) (*domain.Warehouse, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, warehouse := range r.warehouses {
+		if warehouse.ID == id {
+			copied := *warehouse
+			return &copied, nil
+		}
+	}
+	return nil, domain.ErrWarehouseNotFound
+}
+
+func (r *fakeRepo) UpdateWarehouse(warehouse *domain.Warehouse) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	index := -1
+	active := 0
+	for i, existing := range r.warehouses {
+		if existing.ID == warehouse.ID {
+			index = i
+		}
+		if existing.Active && existing.ID != warehouse.ID {
+			active++
+		}
+	}
+	if index < 0 {
+		return domain.ErrWarehouseNotFound
+	}
+	if r.warehouses[index].Active && !warehouse.Active && active == 0 {
+		return domain.ErrLastActiveWarehouse
+	}
+
+	copied := *warehouse
+	r.warehouses[index] = &copied
+	return nil
+}
+
+func (r *fakeRepo) GetProductStatusesBySKU(skus []string) (map[string]string, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	statuses := make(map[string]string)
+	for _, product := range r.products {
+		if slices.Contains(skus, product.SKU) {
+			statuses[product.SKU] = product.Status
+		}
+	}
+	return statuses, nil
+}
+
+func (r *fakeRepo) UpsertProducts(products []*domain.Product, actorID string) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.products == nil {
+		r.products = make(map[string]*domain.Product)
+	}
+
+	created := 0
+	for _, product := range products {
+		found := false
+		for _, existing := range r.products {
+			if existing.SKU == product.SKU {
+				found = true
+				product.ID = existing.ID
+			}
+		}
+		if !found {
+			created++
+			product.ID = fmt.Sprintf("product-%d", len(r.products)+1)
+			product.Status = domain.ProductActive
+		}
+		copied := *product
+		r.products[product.ID] = &copied
+	}
+	return created, nil
+}
@@ -0,0 +1,158 @@
+package service
+
+import (
+	"context"
+	"ecomm/internal/adapters"
+	"ecomm/internal/audit"
+	"ecomm/internal/domain"
+	"ecomm/proto"
+	"errors"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	defaultStockAlertLimit = 50
+	maxStockAlertLimit     = 500
+)
+
+// SetReorderThreshold sets the stock at or below which a product and its
+// variants raise low-stock alerts. Items already at or below the new
+// threshold raise an alert straight away.
+func (s *service) SetReorderThreshold(ctx context.Context, req *proto.SetReorderThresholdRequest) (*proto.SetReorderThresholdResponse, error) {
+	if req.Threshold < 0 {
+		return nil, status.Error(codes.InvalidArgument, "threshold must not be negative")
+	}
+
+	before, err := s.getProduct(req.ProductId)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.repo.SetReorderThreshold(req.ProductId, int(req.Threshold)); err != nil {
+		if errors.Is(err, domain.ErrProductNotFound) {
+			return nil, status.Error(codes.NotFound, err.Error())
+		}
+		return nil, status.Errorf(codes.Internal, "failed to set reorder threshold: %v", err)
+	}
+
+	product, err := s.getProduct(req.ProductId)
+	if err != nil {
+		return nil, err
+	}
+
+	audit.Record(ctx, "product", product.ID,
+		map[string]int{"reorder_threshold": before.ReorderThreshold},
+		map[string]int{"reorder_threshold": product.ReorderThreshold})
+
+	return &proto.SetReorderThresholdResponse{
+		Product: adapters.ToProtoProduct(*product),
+	}, nil
+}
+
+func (s *service) ListStockAlerts(ctx context.Context, req *proto.ListStockAlertsRequest) (*proto.ListStockAlertsResponse, error) {
+	limit := int(req.Limit)
+	if limit <= 0 {
+		limit = defaultStockAlertLimit
+	}
+	if limit > maxStockAlertLimit {
+		limit = maxStockAlertLimit
+	}
+
+	if req.Offset < 0 {
+		return nil, status.Error(codes.InvalidArgument, "offset must not be negative")
+	}
+
+	alerts, err := s.repo.ListStockAlerts(&domain.StockAlertFilter{
+		OpenOnly: req.OpenOnly,
+		Limit:    limit,
+		Offset:   int(req.Offset),
+	})
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to list stock alerts: %v", err)
+	}
+
+	return &proto.ListStockAlertsResponse{
+		Alerts: adapters.ToProtoStockAlerts(alerts),
+	}, nil
+}
+
+// SubscribeBackInStock asks for an email once an out-of-stock product, or
+// variant, is back in stock. The subscription ends after the email is sent.
+func (s *service) SubscribeBackInStock(ctx context.Context, req *proto.SubscribeBackInStockRequest) (*proto.SubscribeBackInStockResponse, error) {
+	if err := authorizeSelf(ctx, req.UserId); err != nil {
+		return nil, err
+	}
+
+	product, err := s.getProduct(req.ProductId)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := checkProductAvailable(product); err != nil {
+		return nil, err
+	}
+
+	if err := checkOrderItemVariant(product, req.VariantId); err != nil {
+		return nil, err
+	}
+
+	stock := product.CountInStock
+	for _, variant := range product.Variants {
+		if variant.ID == req.VariantId {
+			stock = variant.CountInStock
+		}
+	}
+	if stock > 0 {
+		return nil, status.Error(codes.FailedPrecondition, "item is in stock")
+	}
+
+	subscription, err := s.repo.CreateStockSubscription(&domain.StockSubscription{
+		UserID:    req.UserId,
+		ProductID: req.ProductId,
+		VariantID: req.VariantId,
+	})
+	if err != nil {
+		if errors.Is(err, domain.ErrAlreadySubscribed) {
+			return nil, status.Error(codes.AlreadyExists, err.Error())
+		}
+		return nil, status.Errorf(codes.Internal, "failed to subscribe: %v", err)
+	}
+
+	return &proto.SubscribeBackInStockResponse{
+		Subscription: adapters.ToProtoStockSubscription(*subscription),
+	}, nil
+}
+
+func (s *service) UnsubscribeBackInStock(ctx context.Context, req *proto.UnsubscribeBackInStockRequest) (*proto.UnsubscribeBackInStockResponse, error) {
+	if err := authorizeSelf(ctx, req.UserId); err != nil {
+		return nil, err
+	}
+
+	if err := s.repo.DeleteStockSubscription(req.Id, req.UserId); err != nil {
+		if errors.Is(err, domain.ErrSubscriptionNotFound) {
+			return nil, status.Error(codes.NotFound, err.Error())
+		}
+		return nil, status.Errorf(codes.Internal, "failed to unsubscribe: %v", err)
+	}
+
+	return &proto.UnsubscribeBackInStockResponse{
+		Id: req.Id,
+	}, nil
+}
+
+func (s *service) ListStockSubscriptions(ctx context.Context, req *proto.ListStockSubscriptionsRequest) (*proto.ListStockSubscriptionsResponse, error) {
+	if err := authorizeSelf(ctx, req.UserId); err != nil {
+		return nil, err
+	}
+
+	subscriptions, err := s.repo.ListUserStockSubscriptions(req.UserId)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to list stock subscriptions: %v", err)
+	}
+
+	return &proto.ListStockSubscriptionsResponse{
+		Subscriptions: adapters.ToProtoStockSubscriptions(subscriptions),
+	}, nil
+}
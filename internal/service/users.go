@@ -0,0 +1,263 @@
+package service
+
+import (
+	"context"
+	"ecomm/internal/adapters"
+	"ecomm/internal/audit"
+	"ecomm/internal/controller/auth"
+	"ecomm/internal/domain"
+	"ecomm/proto"
+	"errors"
+	"slices"
+	"strings"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	defaultUserPageSize = 50
+	maxUserPageSize     = 200
+)
+
+// checkAccountActive rejects logins, token refreshes and requests made with
+// existing access tokens for disabled users, and for suspended users until the
+// suspension ends.
+func checkAccountActive(user *domain.User) error {
+	switch user.Status {
+	case domain.UserStatusActive:
+		return nil
+	case domain.UserStatusSuspended:
+		if user.SuspendedUntil <= uint64(time.Now().Unix()) {
+			return nil
+		}
+		return status.Errorf(codes.PermissionDenied, "account is suspended until %s",
+			time.Unix(int64(user.SuspendedUntil), 0).UTC().Format(time.RFC3339))
+	default:
+		return status.Error(codes.PermissionDenied, "account is disabled")
+	}
+}
+
+// activeAccount returns the user a token was issued to if they may still use
+// it.
+func activeAccount(repo domain.Repository, userID string) (*domain.User, error) {
+	user, err := repo.GetUserByID(userID)
+	if err != nil {
+		if errors.Is(err, domain.ErrUserNotFound) {
+			return nil, status.Error(codes.Unauthenticated, "account no longer exists")
+		}
+		return nil, status.Errorf(codes.Internal, "failed to get user: %v", err)
+	}
+	if err := checkAccountActive(user); err != nil {
+		return nil, err
+	}
+	return user, nil
+}
+
+type accountChecker struct {
+	repo domain.Repository
+}
+
+// NewAccountChecker returns the checker used by the gRPC interceptor to reject
+// access tokens of users who were suspended, disabled or deleted after the
+// token was issued, and to withdraw the admin rights and permissions the user
+// has lost since. Permissions granted since are picked up when the token is
+// next refreshed.
+func NewAccountChecker(repo domain.Repository) auth.AccountChecker {
+	return &accountChecker{repo: repo}
+}
+
+func (c *accountChecker) CheckAccount(ctx context.Context, claims *auth.Claims) error {
+	user, err := activeAccount(c.repo, claims.ID)
+	if err != nil {
+		return err
+	}
+
+	if user.IsAdmin || len(claims.Permissions) == 0 {
+		return nil
+	}
+
+	claims.IsAdmin = false
+	current, err := c.repo.GetUserPermissions(user.ID)
+	if err != nil {
+		return status.Errorf(codes.Internal, "failed to get permissions: %v", err)
+	}
+	claims.Permissions = slices.DeleteFunc(slices.Clone(claims.Permissions), func(permission string) bool {
+		return !slices.Contains(current, permission)
+	})
+	return nil
+}
+
+func (s *service) ListUsers(ctx context.Context, req *proto.ListUsersRequest) (*proto.ListUsersResponse, error) {
+	limit := int(req.Limit)
+	if limit <= 0 {
+		limit = defaultUserPageSize
+	}
+	if limit > maxUserPageSize {
+		limit = maxUserPageSize
+	}
+
+	if req.Offset < 0 {
+		return nil, status.Error(codes.InvalidArgument, "offset must not be negative")
+	}
+
+	users, total, err := s.repo.ListUsers(strings.TrimSpace(req.Query), limit, int(req.Offset))
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to list users: %v", err)
+	}
+
+	return &proto.ListUsersResponse{
+		Users: adapters.ToProtoUsers(users),
+		Total: total,
+	}, nil
+}
+
+func (s *service) GetUserByID(ctx context.Context, req *proto.GetUserByIDRequest) (*proto.GetUserByIDResponse, error) {
+	user, err := s.getUserByID(req.UserId)
+	if err != nil {
+		return nil, err
+	}
+
+	return &proto.GetUserByIDResponse{
+		User: adapters.ToProtoUser(*user),
+	}, nil
+}
+
+// AdminUpdateUser changes another user's profile. Passwords and admin status
+// have dedicated flows and cannot be changed here.
+func (s *service) AdminUpdateUser(ctx context.Context, req *proto.AdminUpdateUserRequest) (*proto.AdminUpdateUserResponse, error) {
+	paths, err := updatePaths(req.UpdateMask, req, adminUserUpdateFields)
+	if err != nil {
+		return nil, err
+	}
+
+	user, err := s.getUserByID(req.UserId)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkVersion(req.ExpectedVersion, user.Version); err != nil {
+		return nil, err
+	}
+	before := *user
+
+	if paths["name"] {
+		if req.Name == "" {
+			return nil, status.Error(codes.InvalidArgument, "name must not be empty")
+		}
+		user.Name = req.Name
+	}
+	if paths["email"] {
+		if req.Email == "" {
+			return nil, status.Error(codes.InvalidArgument, "email must not be empty")
+		}
+		user.Email = req.Email
+	}
+
+	if err := s.repo.UpdateUser(user); err != nil {
+		if errors.Is(err, domain.ErrVersionMismatch) {
+			return nil, versionConflict(err.Error())
+		}
+		if errors.Is(err, domain.ErrEmailTaken) {
+			return nil, status.Error(codes.AlreadyExists, err.Error())
+		}
+		if errors.Is(err, domain.ErrUserNotFound) {
+			return nil, status.Error(codes.NotFound, err.Error())
+		}
+		return nil, status.Errorf(codes.Internal, "failed to update user: %v", err)
+	}
+
+	if user, err = s.getUserByID(user.ID); err != nil {
+		return nil, err
+	}
+
+	audit.Record(ctx, "user", user.ID, before, user)
+	return &proto.AdminUpdateUserResponse{
+		User: adapters.ToProtoUser(*user),
+	}, nil
+}
+
+// SuspendUser blocks the user until req.Until and revokes all their sessions.
+func (s *service) SuspendUser(ctx context.Context, req *proto.SuspendUserRequest) (*proto.SuspendUserResponse, error) {
+	if req.Until <= uint64(time.Now().Unix()) {
+		return nil, status.Error(codes.InvalidArgument, "until must be in the future")
+	}
+
+	user, err := s.blockUser(ctx, req.UserId, domain.UserStatusSuspended, req.Reason, req.Until)
+	if err != nil {
+		return nil, err
+	}
+
+	return &proto.SuspendUserResponse{
+		User: adapters.ToProtoUser(*user),
+	}, nil
+}
+
+// DisableUser closes the account until it is re-enabled and revokes all the
+// user's sessions.
+func (s *service) DisableUser(ctx context.Context, req *proto.DisableUserRequest) (*proto.DisableUserResponse, error) {
+	user, err := s.blockUser(ctx, req.UserId, domain.UserStatusDisabled, req.Reason, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	return &proto.DisableUserResponse{
+		User: adapters.ToProtoUser(*user),
+	}, nil
+}
+
+// EnableUser lifts a suspension or re-opens a disabled account. Sessions
+// revoked when the account was blocked stay revoked.
+func (s *service) EnableUser(ctx context.Context, req *proto.EnableUserRequest) (*proto.EnableUserResponse, error) {
+	user, err := s.setUserStatus(ctx, req.UserId, domain.UserStatusActive, "", 0)
+	if err != nil {
+		return nil, err
+	}
+
+	return &proto.EnableUserResponse{
+		User: adapters.ToProtoUser(*user),
+	}, nil
+}
+
+func (s *service) blockUser(ctx context.Context, userID, userStatus, reason string, until uint64) (*domain.User, error) {
+	if claims := auth.ClaimsFromContext(ctx); claims != nil && claims.ID == userID {
+		return nil, status.Error(codes.InvalidArgument, "cannot block your own account")
+	}
+
+	if strings.TrimSpace(reason) == "" {
+		return nil, status.Error(codes.InvalidArgument, "a reason is required")
+	}
+
+	user, err := s.setUserStatus(ctx, userID, userStatus, reason, until)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.repo.RevokeUserSessions(user.Email); err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to revoke sessions: %v", err)
+	}
+
+	return user, nil
+}
+
+func (s *service) setUserStatus(ctx context.Context, userID, userStatus, reason string, until uint64) (*domain.User, error) {
+	before, err := s.getUserByID(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.repo.SetUserStatus(userID, userStatus, reason, until); err != nil {
+		if errors.Is(err, domain.ErrUserNotFound) {
+			return nil, status.Error(codes.NotFound, err.Error())
+		}
+		return nil, status.Errorf(codes.Internal, "failed to update user status: %v", err)
+	}
+
+	user, err := s.getUserByID(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	audit.Record(ctx, "user", user.ID, before, user)
+	return user, nil
+}
@@ -0,0 +1,288 @@
+package service
+
+import (
+	"context"
+	"ecomm/internal/controller/auth"
+	"ecomm/internal/domain"
+	"ecomm/proto"
+	"reflect"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestAccountCheckerWithdrawsAdminFromDemotedUser(t *testing.T) {
+	repo := newFakeRepo(&domain.User{ID: "user-1", Status: domain.UserStatusActive})
+	repo.permissions = map[string][]string{"user-1": {domain.PermissionOrdersRead}}
+	claims := &auth.Claims{
+		ID:          "user-1",
+		IsAdmin:     true,
+		Permissions: []string{domain.PermissionAll, domain.PermissionOrdersRead},
+	}
+
+	if err := NewAccountChecker(repo).CheckAccount(context.Background(), claims); err != nil {
+		t.Fatalf("CheckAccount() error = %v", err)
+	}
+	if claims.IsAdmin {
+		t.Fatal("IsAdmin kept for a demoted user")
+	}
+	if want := []string{domain.PermissionOrdersRead}; !reflect.DeepEqual(claims.Permissions, want) {
+		t.Fatalf("Permissions = %v; want %v", claims.Permissions, want)
+	}
+}
+
+func TestAccountCheckerWithdrawsRevokedPermissions(t *testing.T) {
+	repo := newFakeRepo(&domain.User{ID: "user-1", Status: domain.UserStatusActive})
+	repo.permissions = map[string][]string{"user-1": {domain.PermissionOrdersRead}}
+	claims := &auth.Claims{
+		ID:          "user-1",
+		Permissions: []string{domain.PermissionOrdersRead, domain.PermissionRolesManage},
+	}
+
+	if err := NewAccountChecker(repo).CheckAccount(context.Background(), claims); err != nil {
+		t.Fatalf("CheckAccount() error = %v", err)
+	}
+	if want := []string{domain.PermissionOrdersRead}; !reflect.DeepEqual(claims.Permissions, want) {
+		t.Fatalf("Permissions = %v; want %v", claims.Permissions, want)
+	}
+}
+
+func TestAccountCheckerKeepsAdmin(t *testing.T) {
+	repo := newFakeRepo(&domain.User{ID: "user-1", Status: domain.UserStatusActive, IsAdmin: true})
+	claims := &auth.Claims{ID: "user-1", IsAdmin: true, Permissions: []string{domain.PermissionAll}}
+
+	if err := NewAccountChecker(repo).CheckAccount(context.Background(), claims); err != nil {
+		t.Fatalf("CheckAccount() error = %v", err)
+	}
+	if !claims.IsAdmin || !claims.HasPermission(domain.PermissionUsersRead) {
+		t.Fatalf("claims = %+v; want admin rights kept", claims)
+	}
+}
+
+func TestAccountCheckerRejectsSuspendedUser(t *testing.T) {
+	until := uint64(time.Now().Add(time.Hour).Unix())
+	repo := newFakeRepo(&domain.User{ID: "user-1", Status: domain.UserStatusSuspended, SuspendedUntil: until})
+
+	err := NewAccountChecker(repo).CheckAccount(context.Background(), &auth.Claims{ID: "user-1"})
+	if status.Code(err) != codes.PermissionDenied {
+		t.Fatalf("CheckAccount() error = %v; want PermissionDenied", err)
+	}
+}
+
+func TestSetUserAdminRevokesSessionsOnDemotion(t *testing.T) {
+	repo := newFakeRepo(
+		&domain.User{ID: "admin-1", Email: "admin@example.com", IsAdmin: true},
+		&domain.User{ID: "user-1", Email: "user@example.com"},
+	)
+	s := &service{repo: repo}
+
+	if _, err := s.SetUserAdmin(context.Background(), &proto.SetUserAdminRequest{UserId: "user-1", IsAdmin: true}); err != nil {
+		t.Fatalf("SetUserAdmin(promote) error = %v", err)
+	}
+	if _, err := s.SetUserAdmin(context.Background(), &proto.SetUserAdminRequest{UserId: "admin-1", IsAdmin: false}); err != nil {
+		t.Fatalf("SetUserAdmin(demote) error = %v", err)
+	}
+
+	if want := []string{"admin@example.com"}; !reflect.DeepEqual(repo.revokedSessions, want) {
+		t.Fatalf("revoked sessions of %v; want %v", repo.revokedSessions, want)
+	}
+}
+
+func TestListUsersClampsPageSize(t *testing.T) {
+	tests := []struct {
+		limit int32
+		want  int
+	}{
+		{0, defaultUserPageSize},
+		{-1, defaultUserPageSize},
+		{10, 10},
+		{maxUserPageSize + 1, maxUserPageSize},
+	}
+
+	for _, tt := range tests {
+		repo := newFakeRepo(&domain.User{ID: "user-1"})
+		s := &service{repo: repo}
+
+		response, err := s.ListUsers(context.Background(), &proto.ListUsersRequest{Query: " ada ", Limit: tt.limit, Offset: 5})
+		if err != nil {
+			t.Fatalf("ListUsers(limit %d) error = %v", tt.limit, err)
+		}
+		if want := []any{"ada", tt.want, 5}; !reflect.DeepEqual(repo.listUsersArgs, want) {
+			t.Errorf("ListUsers(limit %d) queried %v; want %v", tt.limit, repo.listUsersArgs, want)
+		}
+		if response.Total != 1 || len(response.Users) != 1 {
+			t.Errorf("ListUsers(limit %d) = %d of %d users; want 1 of 1", tt.limit, len(response.Users), response.Total)
+		}
+	}
+}
+
+func TestListUsersRejectsNegativeOffset(t *testing.T) {
+	s := &service{repo: newFakeRepo()}
+
+	_, err := s.ListUsers(context.Background(), &proto.ListUsersRequest{Offset: -1})
+	if status.Code(err) != codes.InvalidArgument {
+		t.Fatalf("ListUsers() error = %v; want InvalidArgument", err)
+	}
+}
+
+func TestDisableUserRevokesSessions(t *testing.T) {
+	repo := newFakeRepo(&domain.User{ID: "user-1", Email: "ada@example.com", Status: domain.UserStatusActive})
+	s := &service{repo: repo}
+
+	ctx := auth.ContextWithClaims(context.Background(), &auth.Claims{ID: "admin-1"})
+	response, err := s.DisableUser(ctx, &proto.DisableUserRequest{UserId: "user-1", Reason: "fraud"})
+	if err != nil {
+		t.Fatalf("DisableUser() error = %v", err)
+	}
+	if response.User.Status != domain.UserStatusDisabled || repo.users["user-1"].StatusReason != "fraud" {
+		t.Fatalf("user = %+v; want disabled for fraud", repo.users["user-1"])
+	}
+	if !reflect.DeepEqual(repo.revokedSessions, []string{"ada@example.com"}) {
+		t.Fatalf("revoked sessions = %v; want ada@example.com", repo.revokedSessions)
+	}
+
+	if _, err := s.EnableUser(ctx, &proto.EnableUserRequest{UserId: "user-1"}); err != nil {
+		t.Fatalf("EnableUser() error = %v", err)
+	}
+	if user := repo.users["user-1"]; user.Status != domain.UserStatusActive || user.StatusReason != "" {
+		t.Fatalf("user = %+v; want active without a reason", user)
+	}
+}
+
+func TestDisableUserRejections(t *testing.T) {
+	tests := []struct {
+		name string
+		req  *proto.DisableUserRequest
+		code codes.Code
+	}{
+		{"own account", &proto.DisableUserRequest{UserId: "admin-1", Reason: "test"}, codes.InvalidArgument},
+		{"no reason", &proto.DisableUserRequest{UserId: "user-1", Reason: " "}, codes.InvalidArgument},
+		{"unknown user", &proto.DisableUserRequest{UserId: "user-2", Reason: "fraud"}, codes.NotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := newFakeRepo(
+				&domain.User{ID: "admin-1", Status: domain.UserStatusActive},
+				&domain.User{ID: "user-1", Status: domain.UserStatusActive},
+			)
+			s := &service{repo: repo}
+
+			ctx := auth.ContextWithClaims(context.Background(), &auth.Claims{ID: "admin-1"})
+			if _, err := s.DisableUser(ctx, tt.req); status.Code(err) != tt.code {
+				t.Fatalf("DisableUser() error = %v; want %v", err, tt.code)
+			}
+			if len(repo.revokedSessions) != 0 {
+				t.Fatalf("revoked sessions = %v; want none", repo.revokedSessions)
+			}
+		})
+	}
+}
+
+func TestCheckAccountActive(t *testing.T) {
+	now := time.Now()
+	tests := []struct {
+		name string
+		user *domain.User
+		code codes.Code
+	}{
+		{"active", &domain.User{Status: domain.UserStatusActive}, codes.OK},
+		{"suspended", &domain.User{Status: domain.UserStatusSuspended, SuspendedUntil: uint64(now.Add(time.Hour).Unix())}, codes.PermissionDenied},
+		{"suspension ended", &domain.User{Status: domain.UserStatusSuspended, SuspendedUntil: uint64(now.Add(-time.Second).Unix())}, codes.OK},
+		{"disabled", &domain.User{Status: domain.UserStatusDisabled}, codes.PermissionDenied},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := checkAccountActive(tt.user); status.Code(err) != tt.code {
+				t.Fatalf("checkAccountActive() error = %v; want %v", err, tt.code)
+			}
+		})
+	}
+}
+
+func TestSuspendUserSetsEnd(t *testing.T) {
+	repo := newFakeRepo(&domain.User{ID: "user-1", Email: "ada@example.com", Status: domain.UserStatusActive})
+	s := &service{repo: repo}
+	ctx := auth.ContextWithClaims(context.Background(), &auth.Claims{ID: "admin-1"})
+
+	until := uint64(time.Now().Add(24 * time.Hour).Unix())
+	response, err := s.SuspendUser(ctx, &proto.SuspendUserRequest{UserId: "user-1", Reason: "spam", Until: until})
+	if err != nil {
+		t.Fatalf("SuspendUser() error = %v", err)
+	}
+	if response.User.Status != domain.UserStatusSuspended || response.User.SuspendedUntil != until {
+		t.Fatalf("user = %+v; want suspended until %d", response.User, until)
+	}
+	if !reflect.DeepEqual(repo.revokedSessions, []string{"ada@example.com"}) {
+		t.Fatalf("revoked sessions = %v; want ada@example.com", repo.revokedSessions)
+	}
+
+	if _, err := s.EnableUser(ctx, &proto.EnableUserRequest{UserId: "user-1"}); err != nil {
+		t.Fatalf("EnableUser() error = %v", err)
+	}
+	if repo.users["user-1"].SuspendedUntil != 0 {
+		t.Fatalf("suspended until = %d after enabling; want 0", repo.users["user-1"].SuspendedUntil)
+	}
+}
+
+func TestSuspendUserRequiresFutureEnd(t *testing.T) {
+	repo := newFakeRepo(&domain.User{ID: "user-1", Status: domain.UserStatusActive})
+	s := &service{repo: repo}
+	ctx := auth.ContextWithClaims(context.Background(), &auth.Claims{ID: "admin-1"})
+
+	for _, until := range []uint64{0, uint64(time.Now().Add(-time.Minute).Unix())} {
+		_, err := s.SuspendUser(ctx, &proto.SuspendUserRequest{UserId: "user-1", Reason: "spam", Until: until})
+		if status.Code(err) != codes.InvalidArgument {
+			t.Fatalf("SuspendUser(until %d) error = %v; want InvalidArgument", until, err)
+		}
+	}
+	if repo.users["user-1"].Status != domain.UserStatusActive {
+		t.Fatal("user was suspended")
+	}
+}
+
+func TestRefreshToken(t *testing.T) {
+	now := uint64(time.Now().Unix())
+	tests := []struct {
+		name      string
+		status    string
+		expiresAt uint64
+		ok        bool
+		revoked   bool
+	}{
+		{"live session of active user", domain.UserStatusActive, now + 3600, true, false},
+		{"live session of suspended user", domain.UserStatusSuspended, now + 3600, false, false},
+		{"live session of disabled user", domain.UserStatusDisabled, now + 3600, false, false},
+		{"expired session of active user", domain.UserStatusActive, now - 1, false, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("JWT_KEY", "test-key")
+			jwtManager, err := auth.NewTokenGenerator()
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			repo := newFakeRepo(&domain.User{ID: "user-1", Email: "ada@example.com", Status: tt.status, SuspendedUntil: now + 3600})
+			repo.sessions = map[string]*domain.Session{
+				"session-1": {ID: "session-1", Email: "ada@example.com", RefreshToken: "refresh", ExpiresAt: tt.expiresAt},
+			}
+			s := &service{repo: repo, jwtManager: jwtManager}
+
+			res, err := s.RefreshToken(context.Background(), &proto.RefreshAccessTokenRequest{SessionId: "session-1", RefreshToken: "refresh"})
+			if (err == nil) != tt.ok {
+				t.Fatalf("RefreshToken() error = %v; want ok = %v", err, tt.ok)
+			}
+			if tt.ok && res.AccessToken == "" {
+				t.Fatal("no access token issued")
+			}
+			if revoked := repo.sessions["session-1"].IsRevoked; revoked != tt.revoked {
+				t.Fatalf("session revoked = %v; want %v", revoked, tt.revoked)
+			}
+		})
+	}
+}
@@ -0,0 +1,282 @@
+package service
+
+import (
+	"context"
+	"ecomm/internal/adapters"
+	"ecomm/internal/audit"
+	"ecomm/internal/domain"
+	"ecomm/proto"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func variantError(err error) error {
+	switch {
+	case errors.Is(err, domain.ErrProductNotFound), errors.Is(err, domain.ErrVariantNotFound):
+		return status.Error(codes.NotFound, err.Error())
+	case errors.Is(err, domain.ErrSKUTaken), errors.Is(err, domain.ErrVariantExists):
+		return status.Error(codes.AlreadyExists, err.Error())
+	default:
+		return status.Errorf(codes.Internal, "failed to save product variant: %v", err)
+	}
+}
+
+func (s *service) getProduct(id string) (*domain.Product, error) {
+	product, err := s.repo.GetProductByID(id)
+	if err != nil {
+		if errors.Is(err, domain.ErrProductNotFound) {
+			return nil, status.Error(codes.NotFound, err.Error())
+		}
+		return nil, status.Errorf(codes.Internal, "failed to get product: %v", err)
+	}
+	s.resolveImageURLs(product.Images)
+	return product, nil
+}
+
+// normalizeOptions trims option names and values and rejects duplicates.
+func normalizeOptions(options []*proto.ProductOption) ([]domain.ProductOption, error) {
+	normalized := make([]domain.ProductOption, len(options))
+	names := make(map[string]bool, len(options))
+	for i, option := range options {
+		name := strings.TrimSpace(option.Name)
+		if name == "" {
+			return nil, errors.New("option names must not be empty")
+		}
+		if names[strings.ToLower(name)] {
+			return nil, fmt.Errorf("option %q is listed twice", name)
+		}
+		names[strings.ToLower(name)] = true
+
+		if len(option.Values) == 0 {
+			return nil, fmt.Errorf("option %q needs at least one value", name)
+		}
+
+		values := make([]string, len(option.Values))
+		for j, value := range option.Values {
+			value = strings.TrimSpace(value)
+			if value == "" {
+				return nil, fmt.Errorf("option %q has an empty value", name)
+			}
+			if slices.Contains(values[:j], value) {
+				return nil, fmt.Errorf("option %q lists %q twice", name, value)
+			}
+			values[j] = value
+		}
+
+		normalized[i] = domain.ProductOption{Name: name, Values: values}
+	}
+	return normalized, nil
+}
+
+// matchOptions checks that selected picks exactly one offered value for
+// every option of the product.
+func matchOptions(options []domain.ProductOption, selected map[string]string) error {
+	if len(options) == 0 {
+		return errors.New("the product has no options to create variants from")
+	}
+
+	for _, option := range options {
+		value, ok := selected[option.Name]
+		if !ok {
+			return fmt.Errorf("a value for option %q is required", option.Name)
+		}
+		if !slices.Contains(option.Values, value) {
+			return fmt.Errorf("%q is not a value of option %q", value, option.Name)
+		}
+	}
+
+	if len(selected) != len(options) {
+		return errors.New("variants may only set the product's options")
+	}
+	return nil
+}
+
+// SetProductOptions replaces the option types of a product. Existing variants
+// must still match the new options, so values that are in use cannot be
+// removed.
+func (s *service) SetProductOptions(ctx context.Context, req *proto.SetProductOptionsRequest) (*proto.SetProductOptionsResponse, error) {
+	product, err := s.getProduct(req.ProductId)
+	if err != nil {
+		return nil, err
+	}
+
+	options, err := normalizeOptions(req.Options)
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	for _, variant := range product.Variants {
+		if err := matchOptions(options, variant.Options); err != nil {
+			return nil, status.Errorf(codes.FailedPrecondition, "variant %s: %v", variant.SKU, err)
+		}
+	}
+
+	if err := s.repo.SetProductOptions(product.ID, options); err != nil {
+		return nil, variantError(err)
+	}
+
+	audit.Record(ctx, "product", product.ID,
+		map[string]any{"options": product.Options},
+		map[string]any{"options": options})
+
+	return &proto.SetProductOptionsResponse{
+		Options: adapters.ToProtoProductOptions(options),
+	}, nil
+}
+
+func (s *service) CreateProductVariant(ctx context.Context, req *proto.CreateProductVariantRequest) (*proto.CreateProductVariantResponse, error) {
+	product, err := s.getProduct(req.ProductId)
+	if err != nil {
+		return nil, err
+	}
+
+	variant := &domain.ProductVariant{ProductID: product.ID}
+	if err := applyVariantRequest(product, variant, req.Sku, req.Options, req.Price, req.CountInStock, req.Image); err != nil {
+		return nil, err
+	}
+
+	variant, err = s.repo.CreateProductVariant(variant, actorID(ctx))
+	if err != nil {
+		return nil, variantError(err)
+	}
+
+	audit.Record(ctx, "product_variant", variant.ID, nil, variant)
+
+	return &proto.CreateProductVariantResponse{
+		Variant: adapters.ToProtoProductVariant(*variant, *product),
+	}, nil
+}
+
+func (s *service) UpdateProductVariant(ctx context.Context, req *proto.UpdateProductVariantRequest) (*proto.UpdateProductVariantResponse, error) {
+	product, err := s.getProduct(req.ProductId)
+	if err != nil {
+		return nil, err
+	}
+
+	variant, err := s.repo.GetProductVariant(req.Id)
+	if err != nil || variant.ProductID != product.ID {
+		if err == nil || errors.Is(err, domain.ErrVariantNotFound) {
+			return nil, status.Error(codes.NotFound, domain.ErrVariantNotFound.Error())
+		}
+		return nil, status.Errorf(codes.Internal, "failed to get product variant: %v", err)
+	}
+	before := *variant
+
+	if err := applyVariantRequest(product, variant, req.Sku, req.Options, req.Price, req.CountInStock, req.Image); err != nil {
+		return nil, err
+	}
+
+	stock := stockTarget(ctx, product.ID, variant.ID, variant.CountInStock, "set by variant update")
+	if err := s.repo.UpdateProductVariant(variant, stock); err != nil {
+		if errors.Is(err, domain.ErrInsufficientStock) || errors.Is(err, domain.ErrNoWarehouse) {
+			return nil, inventoryError(err)
+		}
+		return nil, variantError(err)
+	}
+
+	audit.Record(ctx, "product_variant", variant.ID, before, variant)
+
+	return &proto.UpdateProductVariantResponse{
+		Variant: adapters.ToProtoProductVariant(*variant, *product),
+	}, nil
+}
+
+func applyVariantRequest(product *domain.Product, variant *domain.ProductVariant, sku string, options map[string]string, price float64, countInStock int32, image string) error {
+	sku = strings.TrimSpace(sku)
+	if sku == "" {
+		return status.Error(codes.InvalidArgument, "sku is required")
+	}
+
+	if err := matchOptions(product.Options, options); err != nil {
+		return status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	if price < 0 || countInStock < 0 {
+		return status.Error(codes.InvalidArgument, "price and count_in_stock must not be negative")
+	}
+
+	variant.SKU = sku
+	variant.Options = options
+	variant.Price = nil
+	if price > 0 {
+		variant.Price = &price
+	}
+	variant.CountInStock = int(countInStock)
+	variant.Image = image
+	return nil
+}
+
+func (s *service) DeleteProductVariant(ctx context.Context, req *proto.DeleteProductVariantRequest) (*proto.DeleteProductVariantResponse, error) {
+	variant, err := s.repo.GetProductVariant(req.Id)
+	if err != nil || variant.ProductID != req.ProductId {
+		if err == nil || errors.Is(err, domain.ErrVariantNotFound) {
+			return nil, status.Error(codes.NotFound, domain.ErrVariantNotFound.Error())
+		}
+		return nil, status.Errorf(codes.Internal, "failed to get product variant: %v", err)
+	}
+
+	if err := s.repo.DeleteProductVariant(variant.ID); err != nil {
+		return nil, variantError(err)
+	}
+
+	audit.Record(ctx, "product_variant", variant.ID, variant, nil)
+
+	return &proto.DeleteProductVariantResponse{
+		Id: variant.ID,
+	}, nil
+}
+
+// newOrderItem copies the name, image and price at now of the ordered product
+// or variant into an order item. Variants without their own price or image
+// use the product's, including its sale price.
+func newOrderItem(product *domain.Product, variantID string, quantity int, now uint64) *domain.OrderItem {
+	price, _ := product.CurrentPrice(now)
+	item := &domain.OrderItem{
+		ProductID: product.ID,
+		VariantID: variantID,
+		Name:      product.Name,
+		Quantity:  quantity,
+		Image:     product.Image,
+		Price:     price,
+	}
+
+	for _, variant := range product.Variants {
+		if variant.ID != variantID {
+			continue
+		}
+		if variant.Price != nil {
+			item.Price = *variant.Price
+		}
+		if variant.Image != "" {
+			item.Image = variant.Image
+		}
+	}
+	return item
+}
+
+// checkOrderItemVariant requires a variant for products that have variants
+// and checks that it belongs to the ordered product.
+func checkOrderItemVariant(product *domain.Product, variantID string) error {
+	if len(product.Variants) == 0 {
+		if variantID != "" {
+			return status.Errorf(codes.InvalidArgument, "product %s has no variants", product.ID)
+		}
+		return nil
+	}
+
+	if variantID == "" {
+		return status.Errorf(codes.InvalidArgument, "a variant of product %s must be chosen", product.ID)
+	}
+
+	for _, variant := range product.Variants {
+		if variant.ID == variantID {
+			return nil
+		}
+	}
+	return status.Errorf(codes.InvalidArgument, "variant %s does not belong to product %s", variantID, product.ID)
+}
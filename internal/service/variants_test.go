@@ -0,0 +1,226 @@
+package service
+
+import (
+	"context"
+	"ecomm/internal/domain"
+	"ecomm/internal/inventory"
+	"ecomm/proto"
+	"reflect"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestNormalizeOptions(t *testing.T) {
+	options, err := normalizeOptions([]*proto.ProductOption{
+		{Name: " Size ", Values: []string{" S", "M "}},
+		{Name: "Colour", Values: []string{"red"}},
+	})
+	if err != nil {
+		t.Fatalf("normalizeOptions() error = %v", err)
+	}
+	want := []domain.ProductOption{
+		{Name: "Size", Values: []string{"S", "M"}},
+		{Name: "Colour", Values: []string{"red"}},
+	}
+	if !reflect.DeepEqual(options, want) {
+		t.Fatalf("normalizeOptions() = %+v; want %+v", options, want)
+	}
+}
+
+func TestNormalizeOptionsRejections(t *testing.T) {
+	tests := []struct {
+		name    string
+		options []*proto.ProductOption
+	}{
+		{"empty name", []*proto.ProductOption{{Name: " ", Values: []string{"S"}}}},
+		{"duplicate name", []*proto.ProductOption{{Name: "Size", Values: []string{"S"}}, {Name: "size", Values: []string{"M"}}}},
+		{"no values", []*proto.ProductOption{{Name: "Size"}}},
+		{"empty value", []*proto.ProductOption{{Name: "Size", Values: []string{"S", " "}}}},
+		{"duplicate value", []*proto.ProductOption{{Name: "Size", Values: []string{"S", " S"}}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := normalizeOptions(tt.options); err == nil {
+				t.Fatal("normalizeOptions() error = nil")
+			}
+		})
+	}
+}
+
+func TestMatchOptions(t *testing.T) {
+	options := []domain.ProductOption{
+		{Name: "Size", Values: []string{"S", "M"}},
+		{Name: "Colour", Values: []string{"red"}},
+	}
+
+	tests := []struct {
+		name     string
+		options  []domain.ProductOption
+		selected map[string]string
+		ok       bool
+	}{
+		{"every option", options, map[string]string{"Size": "M", "Colour": "red"}, true},
+		{"missing option", options, map[string]string{"Size": "M"}, false},
+		{"unknown value", options, map[string]string{"Size": "XL", "Colour": "red"}, false},
+		{"extra option", options, map[string]string{"Size": "M", "Colour": "red", "Fit": "slim"}, false},
+		{"product without options", nil, map[string]string{}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := matchOptions(tt.options, tt.selected); (err == nil) != tt.ok {
+				t.Fatalf("matchOptions() error = %v; want ok = %v", err, tt.ok)
+			}
+		})
+	}
+}
+
+func TestCheckOrderItemVariant(t *testing.T) {
+	plain := &domain.Product{ID: "product-1"}
+	withVariants := &domain.Product{ID: "product-2", Variants: []*domain.ProductVariant{{ID: "variant-1"}}}
+
+	tests := []struct {
+		name      string
+		product   *domain.Product
+		variantID string
+		ok        bool
+	}{
+		{"product without variants", plain, "", true},
+		{"variant of product without variants", plain, "variant-1", false},
+		{"variant chosen", withVariants, "variant-1", true},
+		{"no variant chosen", withVariants, "", false},
+		{"variant of another product", withVariants, "variant-2", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := checkOrderItemVariant(tt.product, tt.variantID)
+			if tt.ok && err != nil {
+				t.Fatalf("checkOrderItemVariant() error = %v", err)
+			}
+			if !tt.ok && status.Code(err) != codes.InvalidArgument {
+				t.Fatalf("checkOrderItemVariant() error = %v; want InvalidArgument", err)
+			}
+		})
+	}
+}
+
+func TestNewOrderItemUsesVariantPriceAndImage(t *testing.T) {
+	price := 12.5
+	product := &domain.Product{
+		ID:    "product-1",
+		Name:  "Shirt",
+		Image: "shirt.png",
+		Price: 10,
+		Variants: []*domain.ProductVariant{
+			{ID: "variant-1", Price: &price, Image: "red.png"},
+			{ID: "variant-2"},
+		},
+	}
+
+	item := newOrderItem(product, "variant-1", 2, 0)
+	if item.Name != "Shirt" || item.Price != 12.5 || item.Image != "red.png" || item.Quantity != 2 {
+		t.Fatalf("variant-1 item = %+v; want the variant's price and image", item)
+	}
+
+	item = newOrderItem(product, "variant-2", 1, 0)
+	if item.Price != 10 || item.Image != "shirt.png" {
+		t.Fatalf("variant-2 item = %+v; want the product's price and image", item)
+	}
+}
+
+func TestCreateOrderPricesItemsFromCatalog(t *testing.T) {
+	repo := newFakeRepo()
+	repo.products = map[string]*domain.Product{
+		"product-1": {ID: "product-1", Name: "Mug", Image: "mug.png", Price: 4.99, Status: domain.ProductActive},
+	}
+	repo.warehouses = []*domain.Warehouse{{ID: "warehouse-1", Active: true}}
+	repo.warehouseStock = []*domain.WarehouseStock{{WarehouseID: "warehouse-1", ProductID: "product-1", Quantity: 10}}
+	s := &service{repo: repo, strategy: inventory.Priority{}}
+
+	res, err := s.CreateOrder(context.Background(), &proto.CreateOrderRequest{
+		PaymentMethod: "card",
+		TaxPrice:      1.5,
+		ShippingPrice: 3,
+		TotalPrice:    0.01,
+		OrderItems: []*proto.OrderItem{
+			{ProductId: "product-1", Name: "Free mug", Price: 0.01, Quantity: 3},
+		},
+	})
+	if err != nil {
+		t.Fatalf("CreateOrder() error = %v", err)
+	}
+
+	item := res.Order.OrderItems[0]
+	if item.Price != 4.99 || item.Name != "Mug" || item.Image != "mug.png" {
+		t.Fatalf("order item = %+v; want the catalog's name, image and price", item)
+	}
+	if res.Order.TotalPrice != 19.47 {
+		t.Fatalf("total = %v; want 19.47", res.Order.TotalPrice)
+	}
+}
+
+func TestNewOrderItemUsesSalePrice(t *testing.T) {
+	price := 12.5
+	product := &domain.Product{
+		ID:    "product-1",
+		Price: 10,
+		Sale:  &domain.ProductSale{Price: 8, StartsAt: 100, EndsAt: 200},
+		Variants: []*domain.ProductVariant{
+			{ID: "variant-1", Price: &price},
+			{ID: "variant-2"},
+		},
+	}
+
+	tests := []struct {
+		name      string
+		variantID string
+		now       uint64
+		price     float64
+	}{
+		{"variant during sale", "variant-2", 150, 8},
+		{"variant before sale", "variant-2", 99, 10},
+		{"variant after sale", "variant-2", 200, 10},
+		{"variant with own price", "variant-1", 150, 12.5},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if item := newOrderItem(product, tt.variantID, 1, tt.now); item.Price != tt.price {
+				t.Fatalf("price = %v; want %v", item.Price, tt.price)
+			}
+		})
+	}
+}
+
+func TestCreateOrderChargesSalePrice(t *testing.T) {
+	now := uint64(time.Now().Unix())
+	repo := newFakeRepo()
+	repo.products = map[string]*domain.Product{
+		"product-1": {ID: "product-1", Name: "Mug", Price: 4.99, Status: domain.ProductActive,
+			Sale: &domain.ProductSale{Price: 3.5, StartsAt: now - 60, EndsAt: now + 3600}},
+	}
+	repo.warehouses = []*domain.Warehouse{{ID: "warehouse-1", Active: true}}
+	repo.warehouseStock = []*domain.WarehouseStock{{WarehouseID: "warehouse-1", ProductID: "product-1", Quantity: 10}}
+	s := &service{repo: repo, strategy: inventory.Priority{}}
+
+	res, err := s.CreateOrder(context.Background(), &proto.CreateOrderRequest{
+		PaymentMethod: "card",
+		ShippingPrice: 3,
+		OrderItems:    []*proto.OrderItem{{ProductId: "product-1", Quantity: 2}},
+	})
+	if err != nil {
+		t.Fatalf("CreateOrder() error = %v", err)
+	}
+
+	if price := res.Order.OrderItems[0].Price; price != 3.5 {
+		t.Fatalf("item price = %v; want the sale price 3.5", price)
+	}
+	if res.Order.TotalPrice != 10 {
+		t.Fatalf("total = %v; want 10", res.Order.TotalPrice)
+	}
+}
@@ -0,0 +1,34 @@
+package service
+
+import (
+	"ecomm/internal/domain"
+	"fmt"
+
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// checkVersion fails with a version conflict unless the version the caller
+// expects is the current one. An expected version of 0 skips the check.
+func checkVersion(expected, current int64) error {
+	if expected != 0 && expected != current {
+		return versionConflict(fmt.Sprintf("%v: expected version %d, current version is %d",
+			domain.ErrVersionMismatch, expected, current))
+	}
+	return nil
+}
+
+// versionConflict returns an Aborted error with an ErrorInfo detail whose
+// reason is domain.VersionMismatchReason, so that a stale version is not
+// mistaken for any other failed precondition.
+func versionConflict(msg string) error {
+	st, err := status.New(codes.Aborted, msg).WithDetails(&errdetails.ErrorInfo{
+		Reason: domain.VersionMismatchReason,
+		Domain: "ecomm",
+	})
+	if err != nil {
+		return status.Error(codes.Aborted, msg)
+	}
+	return st.Err()
+}
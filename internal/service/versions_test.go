@@ -0,0 +1,131 @@
+package service
+
+import (
+	"context"
+	"ecomm/internal/domain"
+	"ecomm/proto"
+	"testing"
+
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/fieldmaskpb"
+)
+
+// racingRepo moves a product on to the next version after every read, as if
+// another request had updated it in between.
+type racingRepo struct {
+	*fakeRepo
+}
+
+func (r racingRepo) GetProductByID(id string) (*domain.Product, error) {
+	product, err := r.fakeRepo.GetProductByID(id)
+	if err == nil {
+		r.mu.Lock()
+		r.products[id].Version++
+		r.mu.Unlock()
+	}
+	return product, err
+}
+
+// wantVersionConflict fails the test unless err is an Aborted error with the
+// version mismatch detail.
+func wantVersionConflict(t *testing.T, err error) {
+	t.Helper()
+	st := status.Convert(err)
+	if st.Code() != codes.Aborted {
+		t.Fatalf("error = %v; want Aborted", err)
+	}
+	for _, detail := range st.Details() {
+		if info, ok := detail.(*errdetails.ErrorInfo); ok && info.Reason == domain.VersionMismatchReason {
+			return
+		}
+	}
+	t.Fatalf("error = %v; want a %s detail", err, domain.VersionMismatchReason)
+}
+
+func TestCheckVersion(t *testing.T) {
+	if err := checkVersion(0, 3); err != nil {
+		t.Fatalf("checkVersion(0, 3) error = %v", err)
+	}
+	if err := checkVersion(3, 3); err != nil {
+		t.Fatalf("checkVersion(3, 3) error = %v", err)
+	}
+	wantVersionConflict(t, checkVersion(2, 3))
+}
+
+func TestUpdateProductWithStaleExpectedVersion(t *testing.T) {
+	repo := inventoryRepo()
+	s := &service{repo: repo}
+
+	_, err := s.UpdateProduct(context.Background(), &proto.UpdateProductRequest{
+		Id:              "product-1",
+		Name:            "Cup",
+		UpdateMask:      &fieldmaskpb.FieldMask{Paths: []string{"name"}},
+		ExpectedVersion: 2,
+	})
+	wantVersionConflict(t, err)
+	if name := repo.products["product-1"].Name; name != "Mug" {
+		t.Fatalf("name = %q; want the product unchanged", name)
+	}
+}
+
+func TestUpdateProductLosingTheRace(t *testing.T) {
+	repo := inventoryRepo()
+	s := &service{repo: racingRepo{repo}}
+
+	_, err := s.UpdateProduct(context.Background(), &proto.UpdateProductRequest{
+		Id:         "product-1",
+		Name:       "Cup",
+		UpdateMask: &fieldmaskpb.FieldMask{Paths: []string{"name"}},
+	})
+	wantVersionConflict(t, err)
+	if name := repo.products["product-1"].Name; name != "Mug" {
+		t.Fatalf("name = %q; want the product unchanged", name)
+	}
+}
+
+func TestRestoreProduct(t *testing.T) {
+	tests := []struct {
+		name    string
+		status  string
+		version int64
+		code    codes.Code
+	}{
+		{"any version", domain.ProductArchived, 0, codes.OK},
+		{"current version", domain.ProductArchived, 1, codes.OK},
+		{"stale version", domain.ProductArchived, 2, codes.Aborted},
+		{"not archived", domain.ProductActive, 1, codes.FailedPrecondition},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := inventoryRepo()
+			repo.products["product-1"].Status = tt.status
+			s := &service{repo: repo}
+
+			_, err := s.RestoreProduct(context.Background(), &proto.RestoreProductRequest{Id: "product-1", ExpectedVersion: tt.version})
+			if status.Code(err) != tt.code {
+				t.Fatalf("RestoreProduct() error = %v; want %v", err, tt.code)
+			}
+			if tt.code == codes.Aborted {
+				wantVersionConflict(t, err)
+			}
+			if restored := repo.products["product-1"].Status == domain.ProductActive; tt.code == codes.OK && !restored {
+				t.Fatal("product was not restored")
+			}
+		})
+	}
+}
+
+func TestRestoreProductLosingTheRace(t *testing.T) {
+	repo := inventoryRepo()
+	repo.products["product-1"].Status = domain.ProductArchived
+	s := &service{repo: racingRepo{repo}}
+
+	_, err := s.RestoreProduct(context.Background(), &proto.RestoreProductRequest{Id: "product-1"})
+	wantVersionConflict(t, err)
+	if repo.products["product-1"].Status != domain.ProductArchived {
+		t.Fatal("product was restored")
+	}
+}
@@ -0,0 +1,259 @@
+package service
+
+import (
+	"context"
+	"ecomm/internal/adapters"
+	"ecomm/internal/audit"
+	"ecomm/internal/domain"
+	"ecomm/internal/inventory"
+	"ecomm/proto"
+	"errors"
+	"strings"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func warehouseError(err error) error {
+	switch {
+	case errors.Is(err, domain.ErrWarehouseNotFound):
+		return status.Error(codes.NotFound, err.Error())
+	case errors.Is(err, domain.ErrWarehouseCodeTaken):
+		return status.Error(codes.AlreadyExists, err.Error())
+	case errors.Is(err, domain.ErrLastActiveWarehouse), errors.Is(err, domain.ErrWarehouseNotEmpty):
+		return status.Error(codes.FailedPrecondition, err.Error())
+	default:
+		return status.Errorf(codes.Internal, "failed to save warehouse: %v", err)
+	}
+}
+
+func checkLocation(location *proto.Location) error {
+	if location == nil {
+		return nil
+	}
+	if location.Latitude < -90 || location.Latitude > 90 || location.Longitude < -180 || location.Longitude > 180 {
+		return status.Error(codes.InvalidArgument, "location is out of range")
+	}
+	return nil
+}
+
+func (s *service) CreateWarehouse(ctx context.Context, req *proto.CreateWarehouseRequest) (*proto.CreateWarehouseResponse, error) {
+	code := strings.TrimSpace(req.Code)
+	name := strings.TrimSpace(req.Name)
+	if code == "" || name == "" {
+		return nil, status.Error(codes.InvalidArgument, "code and name are required")
+	}
+	if err := checkLocation(req.Location); err != nil {
+		return nil, err
+	}
+
+	warehouse, err := s.repo.CreateWarehouse(&domain.Warehouse{
+		Code:     code,
+		Name:     name,
+		Location: adapters.FromProtoLocation(req.Location),
+		Priority: int(req.Priority),
+		Active:   true,
+	})
+	if err != nil {
+		return nil, warehouseError(err)
+	}
+
+	audit.Record(ctx, "warehouse", warehouse.ID, nil, warehouse)
+
+	return &proto.CreateWarehouseResponse{
+		Warehouse: adapters.ToProtoWarehouse(*warehouse),
+	}, nil
+}
+
+func (s *service) ListWarehouses(ctx context.Context, req *proto.ListWarehousesRequest) (*proto.ListWarehousesResponse, error) {
+	warehouses, err := s.repo.ListWarehouses()
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to list warehouses: %v", err)
+	}
+
+	return &proto.ListWarehousesResponse{
+		Warehouses: adapters.ToProtoWarehouses(warehouses),
+	}, nil
+}
+
+// UpdateWarehouse changes the fields in the update mask. A warehouse is
+// emptied by transferring its stock before it is deactivated.
+func (s *service) UpdateWarehouse(ctx context.Context, req *proto.UpdateWarehouseRequest) (*proto.UpdateWarehouseResponse, error) {
+	paths, err := updatePaths(req.UpdateMask, req, warehouseUpdateFields)
+	if err != nil {
+		return nil, err
+	}
+
+	before, err := s.repo.GetWarehouse(req.Id)
+	if err != nil {
+		return nil, warehouseError(err)
+	}
+
+	warehouse := *before
+	if paths["name"] {
+		warehouse.Name = strings.TrimSpace(req.Name)
+		if warehouse.Name == "" {
+			return nil, status.Error(codes.InvalidArgument, "name is required")
+		}
+	}
+	if paths["location"] {
+		if err := checkLocation(req.Location); err != nil {
+			return nil, err
+		}
+		warehouse.Location = adapters.FromProtoLocation(req.Location)
+	}
+	if paths["priority"] {
+		warehouse.Priority = int(req.Priority)
+	}
+	if paths["active"] {
+		warehouse.Active = req.Active
+	}
+	if err := s.repo.UpdateWarehouse(&warehouse); err != nil {
+		return nil, warehouseError(err)
+	}
+
+	audit.Record(ctx, "warehouse", warehouse.ID, before, &warehouse)
+
+	return &proto.UpdateWarehouseResponse{
+		Warehouse: adapters.ToProtoWarehouse(warehouse),
+	}, nil
+}
+
+func (s *service) GetProductStock(ctx context.Context, req *proto.GetProductStockRequest) (*proto.GetProductStockResponse, error) {
+	if _, err := s.getProduct(req.ProductId); err != nil {
+		return nil, err
+	}
+
+	stock, err := s.repo.ListWarehouseStock(req.ProductId)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to list stock: %v", err)
+	}
+
+	return &proto.GetProductStockResponse{
+		Stock: adapters.ToProtoWarehouseStock(stock),
+	}, nil
+}
+
+// TransferStock moves stock of a product or variant from one warehouse to
+// another. Stock may be moved out of an inactive warehouse but not into one.
+func (s *service) TransferStock(ctx context.Context, req *proto.TransferStockRequest) (*proto.TransferStockResponse, error) {
+	if req.Quantity <= 0 {
+		return nil, status.Error(codes.InvalidArgument, "quantity must be positive")
+	}
+	if req.FromWarehouseId == req.ToWarehouseId {
+		return nil, status.Error(codes.InvalidArgument, "source and destination warehouse must differ")
+	}
+
+	if _, err := s.repo.GetWarehouse(req.FromWarehouseId); err != nil {
+		return nil, warehouseError(err)
+	}
+	destination, err := s.repo.GetWarehouse(req.ToWarehouseId)
+	if err != nil {
+		return nil, warehouseError(err)
+	}
+	if !destination.Active {
+		return nil, status.Error(codes.FailedPrecondition, "destination warehouse is inactive")
+	}
+
+	reason := strings.TrimSpace(req.Reason)
+	from := &domain.InventoryMovement{
+		ProductID:   req.ProductId,
+		VariantID:   req.VariantId,
+		WarehouseID: req.FromWarehouseId,
+		Type:        domain.MovementTransfer,
+		Quantity:    -int(req.Quantity),
+		Reason:      reason,
+		ActorID:     actorID(ctx),
+	}
+	to := *from
+	to.WarehouseID = req.ToWarehouseId
+	to.Quantity = int(req.Quantity)
+
+	if err := s.repo.TransferStock(from, &to); err != nil {
+		return nil, inventoryError(err)
+	}
+
+	audit.Record(ctx, "inventory_movement", from.ID, nil, []*domain.InventoryMovement{from, &to})
+
+	return &proto.TransferStockResponse{
+		Movements: adapters.ToProtoInventoryMovements([]*domain.InventoryMovement{from, &to}),
+	}, nil
+}
+
+// allocateOrderItems assigns every line of an order to the warehouses that
+// ship it, using the configured fulfilment strategy. Lines for the same item
+// draw on the same stock. The repository rejects the order if the stock has
+// moved in the meantime.
+func (s *service) allocateOrderItems(items []*domain.OrderItem, destination *domain.Location) error {
+	warehouses, err := s.repo.ListWarehouses()
+	if err != nil {
+		return status.Errorf(codes.Internal, "failed to list warehouses: %v", err)
+	}
+
+	active := make([]*domain.Warehouse, 0, len(warehouses))
+	for _, warehouse := range warehouses {
+		if warehouse.Active {
+			active = append(active, warehouse)
+		}
+	}
+	if len(active) == 0 {
+		return status.Error(codes.FailedPrecondition, domain.ErrNoWarehouse.Error())
+	}
+
+	// available holds the unallocated stock keyed by product, variant and
+	// warehouse, loaded once per product.
+	available := make(map[string]int)
+	loaded := make(map[string]bool)
+	key := func(productID, variantID, warehouseID string) string {
+		return productID + "/" + variantID + "/" + warehouseID
+	}
+
+	for _, item := range items {
+		if !loaded[item.ProductID] {
+			stock, err := s.repo.ListWarehouseStock(item.ProductID)
+			if err != nil {
+				return status.Errorf(codes.Internal, "failed to list stock: %v", err)
+			}
+			for _, level := range stock {
+				available[key(level.ProductID, level.VariantID, level.WarehouseID)] = level.Quantity
+			}
+			loaded[item.ProductID] = true
+		}
+
+		sources := make([]inventory.Source, len(active))
+		for i, warehouse := range active {
+			sources[i] = inventory.Source{
+				WarehouseID: warehouse.ID,
+				Location:    toInventoryLocation(warehouse.Location),
+				Priority:    warehouse.Priority,
+				Available:   available[key(item.ProductID, item.VariantID, warehouse.ID)],
+			}
+		}
+
+		allocations, err := s.strategy.Allocate(item.Quantity, sources, toInventoryLocation(destination))
+		if err != nil {
+			if errors.Is(err, inventory.ErrUnfulfillable) {
+				return status.Errorf(codes.FailedPrecondition, "%s: %v", item.Name, err)
+			}
+			return status.Errorf(codes.Internal, "failed to allocate order item: %v", err)
+		}
+
+		item.Allocations = make([]domain.OrderItemAllocation, len(allocations))
+		for i, allocation := range allocations {
+			item.Allocations[i] = domain.OrderItemAllocation{
+				WarehouseID: allocation.WarehouseID,
+				Quantity:    allocation.Quantity,
+			}
+			available[key(item.ProductID, item.VariantID, allocation.WarehouseID)] -= allocation.Quantity
+		}
+	}
+
+	return nil
+}
+
+func toInventoryLocation(location *domain.Location) *inventory.Location {
+	if location == nil {
+		return nil
+	}
+	return &inventory.Location{Latitude: location.Latitude, Longitude: location.Longitude}
+}
@@ -0,0 +1,99 @@
+package service
+
+import (
+	"context"
+	"ecomm/internal/domain"
+	"ecomm/proto"
+	"testing"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/fieldmaskpb"
+)
+
+func warehouseRepo() *fakeRepo {
+	repo := newFakeRepo()
+	repo.warehouses = []*domain.Warehouse{
+		{ID: "warehouse-1", Code: "BER", Name: "Berlin", Location: &domain.Location{Latitude: 52.5, Longitude: 13.4}, Priority: 1, Active: true},
+		{ID: "warehouse-2", Code: "HAM", Name: "Hamburg", Priority: 2, Active: true},
+	}
+	return repo
+}
+
+func TestUpdateWarehouseKeepsOmittedFields(t *testing.T) {
+	repo := warehouseRepo()
+	s := &service{repo: repo}
+
+	// Clients without a mask only update the fields they set.
+	_, err := s.UpdateWarehouse(context.Background(), &proto.UpdateWarehouseRequest{Id: "warehouse-1", Name: " Berlin Nord "})
+	if err != nil {
+		t.Fatalf("UpdateWarehouse() error = %v", err)
+	}
+
+	warehouse := repo.warehouses[0]
+	if warehouse.Name != "Berlin Nord" {
+		t.Errorf("name = %q; want Berlin Nord", warehouse.Name)
+	}
+	if !warehouse.Active || warehouse.Priority != 1 || warehouse.Location == nil {
+		t.Fatalf("warehouse = %+v; want active, priority and location kept", warehouse)
+	}
+}
+
+func TestUpdateWarehouseDeactivatesThroughMask(t *testing.T) {
+	repo := warehouseRepo()
+	s := &service{repo: repo}
+
+	res, err := s.UpdateWarehouse(context.Background(), &proto.UpdateWarehouseRequest{
+		Id:         "warehouse-2",
+		Active:     false,
+		UpdateMask: &fieldmaskpb.FieldMask{Paths: []string{"active"}},
+	})
+	if err != nil {
+		t.Fatalf("UpdateWarehouse() error = %v", err)
+	}
+	if res.Warehouse.Active || repo.warehouses[1].Active {
+		t.Fatal("warehouse is still active")
+	}
+	if repo.warehouses[1].Name != "Hamburg" {
+		t.Fatalf("name = %q; want it kept", repo.warehouses[1].Name)
+	}
+}
+
+func TestUpdateWarehouseRejections(t *testing.T) {
+	tests := []struct {
+		name string
+		req  *proto.UpdateWarehouseRequest
+		code codes.Code
+	}{
+		{"empty name", &proto.UpdateWarehouseRequest{Id: "warehouse-1", Name: " ", UpdateMask: &fieldmaskpb.FieldMask{Paths: []string{"name"}}}, codes.InvalidArgument},
+		{"location out of range", &proto.UpdateWarehouseRequest{Id: "warehouse-1", Location: &proto.Location{Latitude: 91}}, codes.InvalidArgument},
+		{"unknown field", &proto.UpdateWarehouseRequest{Id: "warehouse-1", UpdateMask: &fieldmaskpb.FieldMask{Paths: []string{"code"}}}, codes.InvalidArgument},
+		{"unknown warehouse", &proto.UpdateWarehouseRequest{Id: "warehouse-3", Name: "Munich"}, codes.NotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := &service{repo: warehouseRepo()}
+			if _, err := s.UpdateWarehouse(context.Background(), tt.req); status.Code(err) != tt.code {
+				t.Fatalf("UpdateWarehouse() error = %v; want %v", err, tt.code)
+			}
+		})
+	}
+}
+
+func TestUpdateWarehouseKeepsLastActiveWarehouse(t *testing.T) {
+	repo := warehouseRepo()
+	repo.warehouses[1].Active = false
+	s := &service{repo: repo}
+
+	_, err := s.UpdateWarehouse(context.Background(), &proto.UpdateWarehouseRequest{
+		Id:         "warehouse-1",
+		UpdateMask: &fieldmaskpb.FieldMask{Paths: []string{"active"}},
+	})
+	if status.Code(err) != codes.FailedPrecondition {
+		t.Fatalf("UpdateWarehouse() error = %v; want FailedPrecondition", err)
+	}
+	if !repo.warehouses[0].Active {
+		t.Fatal("the last active warehouse was deactivated")
+	}
+}
@@ -0,0 +1,44 @@
+package storage
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestLocal(t *testing.T) {
+	root := t.TempDir()
+	local, err := NewLocal(root, "/uploads/")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx := context.Background()
+	if err := local.Put(ctx, "products/1/original.png", strings.NewReader("data"), "image/png"); err != nil {
+		t.Fatal(err)
+	}
+
+	stored, err := os.ReadFile(filepath.Join(root, "products", "1", "original.png"))
+	if err != nil || string(stored) != "data" {
+		t.Fatalf("stored file = %q, %v; want \"data\"", stored, err)
+	}
+
+	if got := local.URL("products/1/original.png"); got != "/uploads/products/1/original.png" {
+		t.Errorf("URL() = %q", got)
+	}
+
+	if err := local.Delete(ctx, "products/1/original.png"); err != nil {
+		t.Fatal(err)
+	}
+	if err := local.Delete(ctx, "products/1/original.png"); err != nil {
+		t.Errorf("deleting a missing file: %v", err)
+	}
+
+	for _, key := range []string{"", "../escape", "products/../../escape", "/absolute", "products//double"} {
+		if err := local.Put(ctx, key, strings.NewReader("data"), "text/plain"); err == nil {
+			t.Errorf("Put(%q) succeeded; want an error", key)
+		}
+	}
+}
@@ -0,0 +1,68 @@
+// Package storage stores uploaded files such as product images.
+package storage
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+)
+
+// Storage is a flat key-value file store. Keys are slash-separated paths such
+// as "products/<id>/original.jpg".
+type Storage interface {
+	Put(ctx context.Context, key string, data io.Reader, contentType string) error
+	// Delete removes the file stored under key. Deleting a missing file is
+	// not an error.
+	Delete(ctx context.Context, key string) error
+	// URL returns the address clients download the file from.
+	URL(key string) string
+}
+
+const (
+	defaultLocalDir = "uploads"
+	defaultBaseURL  = "/uploads"
+)
+
+// FromEnv returns the storage configured by STORAGE_DRIVER. Only "local",
+// the default, is supported; it stores files below STORAGE_LOCAL_DIR and
+// serves them from STORAGE_BASE_URL.
+func FromEnv() (Storage, error) {
+	switch driver := os.Getenv("STORAGE_DRIVER"); driver {
+	case "", "local":
+		dir, baseURL := localConfig()
+		return NewLocal(dir, baseURL)
+	default:
+		return nil, fmt.Errorf("unsupported STORAGE_DRIVER %q", driver)
+	}
+}
+
+// LocalServeConfigFromEnv returns the directory of the local storage and the
+// URL path it is served under, so the HTTP gateway can serve the files. ok is
+// false if another driver is configured.
+func LocalServeConfigFromEnv() (dir, urlPath string, ok bool) {
+	if driver := os.Getenv("STORAGE_DRIVER"); driver != "" && driver != "local" {
+		return "", "", false
+	}
+
+	dir, baseURL := localConfig()
+	parsed, err := url.Parse(baseURL)
+	if err != nil || parsed.Path == "" {
+		return "", "", false
+	}
+	return dir, parsed.Path, true
+}
+
+func localConfig() (dir, baseURL string) {
+	dir = os.Getenv("STORAGE_LOCAL_DIR")
+	if dir == "" {
+		dir = defaultLocalDir
+	}
+
+	baseURL = os.Getenv("STORAGE_BASE_URL")
+	if baseURL == "" {
+		baseURL = defaultBaseURL
+	}
+	return dir, baseURL
+}
@@ -22,6 +22,12 @@ func ValidateStruct(s any) []string {
 				errorMessages = append(errorMessages, fmt.Sprintf("%s field must be at most %s characters long.", fieldError.Field(), fieldError.Param()))
 			case "oneof":
 				errorMessages = append(errorMessages, fmt.Sprintf("%s field must be one of: %s", fieldError.Field(), fieldError.Param()))
+			case "gte":
+				errorMessages = append(errorMessages, fmt.Sprintf("%s field must be at least %s.", fieldError.Field(), fieldError.Param()))
+			case "lte":
+				errorMessages = append(errorMessages, fmt.Sprintf("%s field must be at most %s.", fieldError.Field(), fieldError.Param()))
+			default:
+				errorMessages = append(errorMessages, fmt.Sprintf("%s field is invalid.", fieldError.Field()))
 			}
 		}
 		return errorMessages